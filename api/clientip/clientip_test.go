@@ -0,0 +1,56 @@
+package clientip
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("secret")
+	at := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	const method = "/auth.AuthService/Login"
+	sig := Sign(secret, "192.0.2.1", method, at)
+
+	tests := []struct {
+		name   string
+		secret []byte
+		ip     string
+		method string
+		sig    string
+		now    time.Time
+		want   error
+	}{
+		{"valid", secret, "192.0.2.1", method, sig, at, nil},
+		{"within skew ahead", secret, "192.0.2.1", method, sig, at.Add(MaxSkew), nil},
+		{"within skew behind", secret, "192.0.2.1", method, sig, at.Add(-MaxSkew), nil},
+		{"stale", secret, "192.0.2.1", method, sig, at.Add(MaxSkew + time.Second), ErrStaleSignature},
+		{"from the future", secret, "192.0.2.1", method, sig, at.Add(-MaxSkew - time.Second), ErrStaleSignature},
+		{"other ip", secret, "192.0.2.2", method, sig, at, ErrInvalidSignature},
+		{"other method", secret, "192.0.2.1", "/auth.AuthService/Register", sig, at, ErrInvalidSignature},
+		{"other secret", []byte("other"), "192.0.2.1", method, sig, at, ErrInvalidSignature},
+		{"empty", secret, "192.0.2.1", method, "", at, ErrMalformedSignature},
+		{"no timestamp", secret, "192.0.2.1", method, "abc", at, ErrMalformedSignature},
+		{"bad timestamp", secret, "192.0.2.1", method, "x.abc", at, ErrMalformedSignature},
+		{"bad encoding", secret, "192.0.2.1", method, "1761998400.!!", at, ErrMalformedSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.ip, tt.method, tt.sig, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if ip := FromContext(context.Background()); ip != "" {
+		t.Errorf("FromContext(empty) = %q; want \"\"", ip)
+	}
+	ctx := NewContext(context.Background(), "192.0.2.1")
+	if ip := FromContext(ctx); ip != "192.0.2.1" {
+		t.Errorf("FromContext = %q; want 192.0.2.1", ip)
+	}
+}
@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Pinger is implemented by dependencies that expose a connectivity probe,
+// such as the Kafka producer and consumer or a gRPC client connection.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// DBer is implemented by *gorm.DB, which exposes its underlying pool.
+type DBer interface {
+	DB() (*sql.DB, error)
+}
+
+// GormCheck pings the database behind a GORM connection.
+func GormCheck(db DBer) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// RedisCheck issues a PING against Redis.
+func RedisCheck(client *redis.Client) CheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// PingCheck adapts any Pinger to a CheckFunc.
+func PingCheck(p Pinger) CheckFunc {
+	return func(ctx context.Context) error {
+		return p.Ping(ctx)
+	}
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"music-player/api/logger"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var lg = logger.For("health")
+
+// WatchGRPC keeps the grpc.health.v1 serving status of the given services in
+// sync with the registry until ctx is cancelled. The empty service name, which
+// reports overall server health, is always updated.
+func (r *Registry) WatchGRPC(ctx context.Context, srv *grpchealth.Server, interval time.Duration, services ...string) {
+	services = append([]string{""}, services...)
+	last := healthpb.HealthCheckResponse_UNKNOWN
+
+	update := func() {
+		status := healthpb.HealthCheckResponse_SERVING
+		report := r.Check(ctx)
+		if !report.Healthy() {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if status != last {
+			lg.Info("gRPC health status changed", "status", status.String())
+			last = status
+		}
+		for _, svc := range services {
+			srv.SetServingStatus(svc, status)
+		}
+	}
+
+	update()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			update()
+		}
+	}
+}
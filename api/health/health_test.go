@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistryCheck(t *testing.T) {
+	r := NewRegistry(50 * time.Millisecond)
+	r.Register("ok", func(ctx context.Context) error { return nil })
+
+	if report := r.Check(context.Background()); !report.Healthy() {
+		t.Fatalf("report = %+v, want up", report)
+	}
+
+	r.Register("db", func(ctx context.Context) error { return errors.New("dial tcp 10.0.0.5:5432: refused") })
+	r.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := r.Check(context.Background())
+	if report.Healthy() {
+		t.Fatal("report healthy, want down")
+	}
+	if got := report.Checks["ok"].Status; got != StatusUp {
+		t.Errorf("ok = %s, want up", got)
+	}
+	if got := report.Checks["slow"]; got.Status != StatusDown || got.Error == "" {
+		t.Errorf("slow = %+v, want down after timeout", got)
+	}
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "10.0.0.5") {
+		t.Errorf("report JSON leaks probe error: %s", b)
+	}
+	var body struct {
+		Status string `json:"status"`
+		Checks map[string]map[string]string
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		t.Fatal(err)
+	}
+	db := body.Checks["db"]
+	if body.Status != StatusDown || db["status"] != StatusDown || db["latency"] == "" || len(db) != 2 {
+		t.Errorf("report JSON = %s; want each dependency with only its status and latency", b)
+	}
+}
@@ -0,0 +1,54 @@
+// Package locale matches language tags and Accept-Language values against
+// the locales a service supports. auth-service uses it to pick a user's
+// locale and notification-service to pick the locale of a message or page.
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Match returns the supported locale for a language tag such as "vi-VN",
+// or the best match of an Accept-Language list such as
+// "fr-FR,en;q=0.8,vi;q=0.5". Tags are compared by their primary language,
+// case-insensitively, and "_" is accepted for "-". It returns "" when
+// nothing matches.
+func Match(s string, supported ...string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(s, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		base := primary(tag)
+		if q <= 0 || base == "" {
+			continue
+		}
+		for _, l := range supported {
+			if l == base {
+				candidates = append(candidates, candidate{base, q})
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].locale
+}
+
+// primary returns the primary language of tag in lower case: "vi" for
+// "vi-VN" or "VI_vn".
+func primary(tag string) string {
+	base, _, _ := strings.Cut(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	return strings.ToLower(base)
+}
@@ -0,0 +1,33 @@
+package locale
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"vi", "vi"},
+		{"vi-VN", "vi"},
+		{"EN_us", "en"},
+		{" en ", "en"},
+		{"fr-FR,en;q=0.8,vi;q=0.5", "en"},
+		{"fr-FR,vi;q=0.9,en;q=0.8", "vi"},
+		{"en;q=0.5,vi", "vi"},
+		{"en;q=0,vi;q=0.1", "vi"},
+		{"en;q=0", ""},
+		{"en;q=bad", "en"},
+		{"fr, de", ""},
+		{"*", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.in, "vi", "en"); got != tt.want {
+			t.Errorf("Match(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+	// Equal weights keep the order of the header.
+	if got := Match("en,vi", "vi", "en"); got != "en" {
+		t.Errorf("Match(en,vi) = %q; want en", got)
+	}
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LevelHandler exposes the per-component levels for runtime changes. Every
+// request must carry the admin token as a bearer token. Responses use the
+// services' {"data": ...} and {"error": {"code", "message"}} shape.
+type LevelHandler struct {
+	token string
+}
+
+func NewLevelHandler(token string) *LevelHandler {
+	return &LevelHandler{token: token}
+}
+
+// Enabled reports whether an admin token is configured. Routes are not
+// registered otherwise.
+func (h *LevelHandler) Enabled() bool {
+	return h.token != ""
+}
+
+// Authorize rejects requests without the admin bearer token.
+func (h *LevelHandler) Authorize(c *gin.Context) {
+	got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) != 1 {
+		fail(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid admin token")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// List returns the default level under "" and every component override.
+func (h *LevelHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": Levels()})
+}
+
+type setLevelRequest struct {
+	Component string `json:"component"`
+	Level     string `json:"level" binding:"required"`
+}
+
+// Set changes the level of a component, or the default level when component
+// is empty. Level "reset" drops a component override.
+func (h *LevelHandler) Set(c *gin.Context) {
+	var req setLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	if req.Level == "reset" && req.Component != "" {
+		ResetLevel(req.Component)
+		c.JSON(http.StatusOK, gin.H{"data": Levels()})
+		return
+	}
+	lvl, err := ParseLevel(req.Level)
+	if err != nil {
+		fail(c, http.StatusBadRequest, "INVALID_LOG_LEVEL", err.Error())
+		return
+	}
+	SetLevel(req.Component, lvl)
+	c.JSON(http.StatusOK, gin.H{"data": Levels()})
+}
+
+func fail(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{"error": gin.H{"code": code, "message": message}})
+}
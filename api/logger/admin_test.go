@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func newTestAdmin(token string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewLevelHandler(token)
+	r := gin.New()
+	admin := r.Group("/admin", h.Authorize)
+	admin.GET("/log-levels", h.List)
+	admin.PUT("/log-levels", h.Set)
+	return r
+}
+
+func TestLevelHandler(t *testing.T) {
+	r := newTestAdmin("admin-token")
+	t.Cleanup(func() { ResetLevel("test.admin") })
+
+	tests := []struct {
+		name, method, token, body string
+		wantStatus                int
+		wantCode                  string
+		wantLevel                 string
+	}{
+		{"no token", http.MethodGet, "", "", http.StatusUnauthorized, "UNAUTHORIZED", ""},
+		{"wrong token", http.MethodGet, "other", "", http.StatusUnauthorized, "UNAUTHORIZED", ""},
+		{"set", http.MethodPut, "admin-token", `{"component":"test.admin","level":"debug"}`, http.StatusOK, "", "DEBUG"},
+		{"list", http.MethodGet, "admin-token", "", http.StatusOK, "", "DEBUG"},
+		{"bad level", http.MethodPut, "admin-token", `{"component":"test.admin","level":"loud"}`, http.StatusBadRequest, "INVALID_LOG_LEVEL", ""},
+		{"missing level", http.MethodPut, "admin-token", `{"component":"test.admin"}`, http.StatusBadRequest, "INVALID_REQUEST", ""},
+		{"reset", http.MethodPut, "admin-token", `{"component":"test.admin","level":"reset"}`, http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/admin/log-levels", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var resp struct {
+			Data  map[string]string      `json:"data"`
+			Error *struct{ Code string } `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: body %s: %v", tt.name, w.Body, err)
+		}
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d; want %d", tt.name, w.Code, tt.wantStatus)
+		}
+		if tt.wantCode != "" && (resp.Error == nil || resp.Error.Code != tt.wantCode) {
+			t.Errorf("%s: body = %s; want error %s", tt.name, w.Body, tt.wantCode)
+		}
+		if w.Code == http.StatusOK && resp.Data["test.admin"] != tt.wantLevel {
+			t.Errorf("%s: test.admin level = %q; want %q", tt.name, resp.Data["test.admin"], tt.wantLevel)
+		}
+	}
+}
+
+func TestLevelHandlerEnabled(t *testing.T) {
+	if NewLevelHandler("").Enabled() {
+		t.Error("handler without a token is enabled")
+	}
+	if !NewLevelHandler("t").Enabled() {
+		t.Error("handler with a token is disabled")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	viper.Set("LOG_LEVELS", " kafka.producer=debug, grpc = warn ,broken,=info")
+	t.Cleanup(func() { viper.Set("LOG_LEVELS", "") })
+
+	cfg := LoadConfig()
+	if cfg.Level != "info" {
+		t.Errorf("Level = %q; want the default info", cfg.Level)
+	}
+	want := map[string]string{"kafka.producer": "debug", "grpc": "warn"}
+	if len(cfg.Levels) != len(want) {
+		t.Errorf("Levels = %v; want %v", cfg.Levels, want)
+	}
+	for k, v := range want {
+		if cfg.Levels[k] != v {
+			t.Errorf("Levels[%q] = %q; want %q", k, cfg.Levels[k], v)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Config controls the structured logger. Levels holds per-component
+// overrides parsed from LOG_LEVELS, e.g. "kafka.producer=debug,grpc=warn".
+// AdminToken enables the LevelHandler routes.
+type Config struct {
+	Level      string
+	Levels     map[string]string
+	AdminToken string
+}
+
+// LoadConfig reads LOG_LEVEL, LOG_LEVELS and LOG_ADMIN_TOKEN.
+func LoadConfig() *Config {
+	viper.SetDefault("LOG_LEVEL", "info")
+
+	cfg := &Config{
+		Level:      viper.GetString("LOG_LEVEL"),
+		Levels:     map[string]string{},
+		AdminToken: viper.GetString("LOG_ADMIN_TOKEN"),
+	}
+	for _, pair := range strings.Split(viper.GetString("LOG_LEVELS"), ",") {
+		component, level, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || component == "" {
+			continue
+		}
+		cfg.Levels[strings.TrimSpace(component)] = strings.TrimSpace(level)
+	}
+	return cfg
+}
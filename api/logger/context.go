@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"music-player/api/requestid"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type userIDKey struct{}
+
+// WithUserID returns a copy of ctx whose log records carry user_id.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	if id := requestid.FromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	if id, ok := ctx.Value(userIDKey{}).(string); ok && id != "" {
+		attrs = append(attrs, slog.String("user_id", id))
+	}
+	return attrs
+}
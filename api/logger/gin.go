@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"log/slog"
+	"music-player/api/clientip"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GinMiddleware replaces gin's text access log with one JSON record per
+// request. It must run after the request ID and tracing middleware so their
+// values are in the request context.
+func GinMiddleware() gin.HandlerFunc {
+	lg := For("http")
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		lvl := slog.LevelInfo
+		switch {
+		case route == "/livez" || route == "/readyz" || route == "/metrics":
+			lvl = slog.LevelDebug
+		case status >= 500:
+			lvl = slog.LevelError
+		case status >= 400:
+			lvl = slog.LevelWarn
+		}
+		clientIP := clientip.FromContext(c.Request.Context())
+		if clientIP == "" {
+			clientIP = c.ClientIP()
+		}
+		lg.Log(c.Request.Context(), lvl, "http request",
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", clientIP),
+		)
+	}
+}
@@ -0,0 +1,176 @@
+// Package logger writes the services' JSON logs through log/slog, with
+// per-component levels that can be changed at runtime, request and trace
+// correlation from the context, and redaction of secrets.
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// registry holds the default level and per-component overrides. Levels are
+// read on every Enabled call so changes apply immediately to existing loggers.
+type registry struct {
+	mu        sync.RWMutex
+	def       slog.Level
+	overrides map[string]slog.Level
+}
+
+var levels = &registry{overrides: map[string]slog.Level{}}
+
+func (r *registry) level(component string) slog.Level {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if lvl, ok := r.overrides[component]; ok {
+		return lvl
+	}
+	return r.def
+}
+
+// root is the JSON handler every logger writes through. It is swapped by Init;
+// loggers created earlier (package-level For calls) pick up the new root on
+// their next record.
+var root atomic.Pointer[slog.Handler]
+
+func init() {
+	setRoot(newJSONHandler(""))
+}
+
+func newJSONHandler(service string) slog.Handler {
+	h := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       slog.LevelDebug - 4,
+		ReplaceAttr: redact,
+	})
+	if service == "" {
+		return h
+	}
+	return h.WithAttrs([]slog.Attr{slog.String("service", service)})
+}
+
+func setRoot(h slog.Handler) {
+	root.Store(&h)
+	slog.SetDefault(slog.New(&handler{}))
+}
+
+// Init installs the JSON logger as the slog and standard library default for
+// the given service, with a default level and per-component overrides. Calls
+// to log.Printf from dependencies are routed through it at info level.
+func Init(service, level string, componentLevels map[string]string) error {
+	def, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	overrides := make(map[string]slog.Level, len(componentLevels))
+	for component, s := range componentLevels {
+		lvl, err := ParseLevel(s)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component, err)
+		}
+		overrides[component] = lvl
+	}
+
+	levels.mu.Lock()
+	levels.def = def
+	levels.overrides = overrides
+	levels.mu.Unlock()
+
+	setRoot(newJSONHandler(service))
+	return nil
+}
+
+// For returns a logger tagged with component whose level can be changed at
+// runtime with SetLevel. Components are named after the package, e.g.
+// "kafka.producer" or "interceptors". It is safe to call before Init.
+func For(component string) *slog.Logger {
+	return slog.New(&handler{component: component}).With(slog.String("component", component))
+}
+
+// SetLevel overrides the level of a component, or the default level when
+// component is empty.
+func SetLevel(component string, lvl slog.Level) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if component == "" {
+		levels.def = lvl
+		return
+	}
+	levels.overrides[component] = lvl
+}
+
+// ResetLevel removes a component override so it follows the default level.
+func ResetLevel(component string) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	delete(levels.overrides, component)
+}
+
+// Levels returns the default level under "" and every component override.
+func Levels() map[string]string {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	out := make(map[string]string, len(levels.overrides)+1)
+	out[""] = levels.def.String()
+	keys := make([]string, 0, len(levels.overrides))
+	for k := range levels.overrides {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out[k] = levels.overrides[k].String()
+	}
+	return out
+}
+
+// ParseLevel accepts debug, info, warn and error in any case.
+func ParseLevel(s string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return lvl, nil
+}
+
+// handler applies the component level and adds request-scoped attributes
+// from the context before delegating to the current root handler. WithAttrs
+// and WithGroup are recorded and replayed against the root so they survive
+// Init swapping it.
+type handler struct {
+	component string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return lvl >= levels.level(h.component)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		r.AddAttrs(contextAttrs(ctx)...)
+	}
+	next := *root.Load()
+	for _, op := range h.ops {
+		next = op(next)
+	}
+	return next.Handle(ctx, r)
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{component: h.component, ops: append(ops, op)}
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
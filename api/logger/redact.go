@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched as substrings of the lower-cased attribute key, so
+// "refresh_token", "totp_secret" and "new_password" are all covered.
+var sensitiveKeys = []string{
+	"password",
+	"token",
+	"secret",
+	"authorization",
+	"cookie",
+	"otp",
+}
+
+// contentKeys carry message text such as an SMS or email body, which holds
+// one-time codes and links. They are matched exactly because as substrings
+// they would also catch keys like "context".
+var contentKeys = []string{
+	"text",
+	"body",
+	"html",
+}
+
+// redact is the ReplaceAttr hook of the JSON handler. Sensitive values are
+// replaced outright; emails keep their first character and domain so support
+// can still correlate log lines.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	for _, k := range contentKeys {
+		if key == k {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	if strings.Contains(key, "email") {
+		return slog.String(a.Key, maskEmail(a.Value.String()))
+	}
+	return a
+}
+
+func maskEmail(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 {
+		return redacted
+	}
+	return email[:1] + "***" + email[at:]
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{"password", "hunter2", redacted},
+		{"refresh_token", "eyJ...", redacted},
+		{"totp_secret", "JBSWY3DP", redacted},
+		{"Authorization", "Bearer abc", redacted},
+		{"otp", "123456", redacted},
+		{"text", "Your code is 123456", redacted},
+		{"body", "Your code is 123456", redacted},
+		{"html", "<p>123456</p>", redacted},
+		{"context", "signup", "signup"},
+		{"text_len", "19", "19"},
+		{"email", "jane@example.com", "j***@example.com"},
+		{"to_email", "not-an-email", redacted},
+		{"user_id", "42", "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := redact(nil, slog.String(tt.key, tt.value))
+			if got.Key != tt.key || got.Value.String() != tt.want {
+				t.Errorf("redact(%s=%q) = %s=%q, want %q", tt.key, tt.value, got.Key, got.Value.String(), tt.want)
+			}
+		})
+	}
+}
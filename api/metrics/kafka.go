@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+var (
+	kafkaProducedRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_produced_records_total",
+		Help: "Records successfully produced by topic.",
+	}, []string{"topic"})
+
+	kafkaProducedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_produced_bytes_total",
+		Help: "Key and value bytes successfully produced by topic.",
+	}, []string{"topic"})
+
+	kafkaProduceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_produce_errors_total",
+		Help: "Records that failed to produce by topic.",
+	}, []string{"topic"})
+
+	kafkaConsumedRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumed_records_total",
+		Help: "Records polled by the consumer by topic.",
+	}, []string{"topic"})
+
+	kafkaConsumedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumed_bytes_total",
+		Help: "Key and value bytes polled by the consumer by topic.",
+	}, []string{"topic"})
+
+	kafkaBrokerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_broker_errors_total",
+		Help: "Broker connection, read and write errors by operation.",
+	}, []string{"op"})
+)
+
+// KafkaHooks implements franz-go client hooks that feed the Kafka metrics.
+// Install it with kgo.WithHooks on both producer and consumer clients.
+type KafkaHooks struct{}
+
+var (
+	_ kgo.HookProduceRecordUnbuffered = KafkaHooks{}
+	_ kgo.HookFetchRecordUnbuffered   = KafkaHooks{}
+	_ kgo.HookBrokerConnect           = KafkaHooks{}
+	_ kgo.HookBrokerWrite             = KafkaHooks{}
+	_ kgo.HookBrokerRead              = KafkaHooks{}
+)
+
+func (KafkaHooks) OnProduceRecordUnbuffered(r *kgo.Record, err error) {
+	if err != nil {
+		kafkaProduceErrors.WithLabelValues(r.Topic).Inc()
+		return
+	}
+	kafkaProducedRecords.WithLabelValues(r.Topic).Inc()
+	kafkaProducedBytes.WithLabelValues(r.Topic).Add(float64(len(r.Key) + len(r.Value)))
+}
+
+func (KafkaHooks) OnFetchRecordUnbuffered(r *kgo.Record, polled bool) {
+	if !polled {
+		return
+	}
+	kafkaConsumedRecords.WithLabelValues(r.Topic).Inc()
+	kafkaConsumedBytes.WithLabelValues(r.Topic).Add(float64(len(r.Key) + len(r.Value)))
+}
+
+func (KafkaHooks) OnBrokerConnect(_ kgo.BrokerMetadata, _ time.Duration, _ net.Conn, err error) {
+	if err != nil {
+		kafkaBrokerErrors.WithLabelValues("connect").Inc()
+	}
+}
+
+func (KafkaHooks) OnBrokerWrite(_ kgo.BrokerMetadata, _ int16, _ int, _, _ time.Duration, err error) {
+	if err != nil {
+		kafkaBrokerErrors.WithLabelValues("write").Inc()
+	}
+}
+
+func (KafkaHooks) OnBrokerRead(_ kgo.BrokerMetadata, _ int16, _ int, _, _ time.Duration, err error) {
+	if err != nil {
+		kafkaBrokerErrors.WithLabelValues("read").Inc()
+	}
+}
@@ -0,0 +1,53 @@
+// Package pgclient opens the PostgreSQL connection of the services that
+// have one, through GORM with the tracing plugin installed.
+package pgclient
+
+import (
+	"fmt"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/tracing"
+)
+
+// Config holds the connection settings, read by each service from
+// POSTGRES_*.
+type Config struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	DBName   string
+}
+
+// Missing returns the names of the settings that are empty.
+func (c *Config) Missing() []string {
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"POSTGRES_HOST", c.Host},
+		{"POSTGRES_PORT", c.Port},
+		{"POSTGRES_USER", c.User},
+		{"POSTGRES_PASSWORD", c.Password},
+		{"POSTGRES_DB", c.DBName},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	return missing
+}
+
+// New opens a connection pool for cfg. Queries run through WithContext get
+// a child span of the caller's trace.
+func New(cfg *Config) (*gorm.DB, error) {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(tracing.NewPlugin(tracing.WithoutMetrics())); err != nil {
+		return nil, fmt.Errorf("failed to install gorm tracing plugin: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,62 @@
+// Package phone handles phone numbers in E.164 form, "+" followed by the
+// country code and subscriber number, at most 15 digits. auth-service stores
+// numbers in that form and notification-service only texts numbers that are.
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalid is returned for numbers that are not in international form.
+var ErrInvalid = errors.New("phone: not an E.164 number")
+
+// Valid reports whether s is an E.164 number: "+", a non-zero digit and up
+// to 14 more digits, with no separators.
+func Valid(s string) bool {
+	if len(s) < 3 || len(s) > 16 || s[0] != '+' || s[1] == '0' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalize returns s in E.164 form, dropping the spaces, dots, dashes and
+// parentheses people type. A leading "00" is read as the international
+// prefix. Numbers without a country code are refused rather than guessed.
+func Normalize(s string) (string, error) {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '.' || r == '-' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalid
+		}
+	}
+	n := b.String()
+	if strings.HasPrefix(n, "00") {
+		n = "+" + n[2:]
+	}
+	if !Valid(n) {
+		return "", ErrInvalid
+	}
+	return n, nil
+}
+
+// Mask hides all but the first two and last three digits of n, for logs and
+// for telling users where a code was sent: "+84******678".
+func Mask(n string) string {
+	if len(n) < 7 {
+		return strings.Repeat("*", len(n))
+	}
+	return n[:3] + strings.Repeat("*", len(n)-6) + n[len(n)-3:]
+}
@@ -0,0 +1,88 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		n    string
+		want bool
+	}{
+		{"+84912345678", true},
+		{"+12025550123", true},
+		{"+123456789012345", true},
+		{"+1234567890123456", false}, // 16 digits
+		{"+12", true},
+		{"+1", false},
+		{"+0912345678", false},
+		{"84912345678", false},
+		{"0912345678", false},
+		{"+84 912 345 678", false},
+		{"+84-912345678", false},
+		{"++84912345678", false},
+		{"+84912345678x", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.n); got != tt.want {
+			t.Errorf("Valid(%q) = %v; want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // "" means ErrInvalid
+	}{
+		{"+84912345678", "+84912345678"},
+		{"+84 912 345 678", "+84912345678"},
+		{"  +84 912 345 678  ", "+84912345678"},
+		{"+1 (202) 555-0123", "+12025550123"},
+		{"+84.912.345.678", "+84912345678"},
+		{"0084912345678", "+84912345678"},
+		{"00 84 912 345 678", "+84912345678"},
+		// Without a country code the number is not guessed.
+		{"0912345678", ""},
+		{"912345678", ""},
+		{"84+912345678", ""},
+		{"+84/912345678", ""},
+		{"+84912345678 ext 12", ""},
+		{"+８４912345678", ""},
+		{"+1234567890123456", ""},
+		{"+", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Normalize(%q) = %q, %v; want ErrInvalid", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		n    string
+		want string
+	}{
+		{"+84912345678", "+84******678"},
+		{"+12025550123", "+12******123"},
+		{"+123456", "+12*456"},
+		{"+12345", "******"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Mask(tt.n); got != tt.want {
+			t.Errorf("Mask(%q) = %q; want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -29,8 +29,13 @@ const (
 	AuthService_EnableTwoFA_FullMethodName       = "/auth.v1.AuthService/EnableTwoFA"
 	AuthService_DisableTwoFA_FullMethodName      = "/auth.v1.AuthService/DisableTwoFA"
 	AuthService_VerifyTwoFA_FullMethodName       = "/auth.v1.AuthService/VerifyTwoFA"
+	AuthService_SetupSMSTwoFA_FullMethodName     = "/auth.v1.AuthService/SetupSMSTwoFA"
+	AuthService_EnableSMSTwoFA_FullMethodName    = "/auth.v1.AuthService/EnableSMSTwoFA"
+	AuthService_SendTwoFACode_FullMethodName     = "/auth.v1.AuthService/SendTwoFACode"
 	AuthService_GetUserProfile_FullMethodName    = "/auth.v1.AuthService/GetUserProfile"
 	AuthService_UpdateUserProfile_FullMethodName = "/auth.v1.AuthService/UpdateUserProfile"
+	AuthService_ListSigningKeys_FullMethodName   = "/auth.v1.AuthService/ListSigningKeys"
+	AuthService_ReloadSigningKeys_FullMethodName = "/auth.v1.AuthService/ReloadSigningKeys"
 )
 
 // AuthServiceClient is the client API for AuthService service.
@@ -52,9 +57,15 @@ type AuthServiceClient interface {
 	EnableTwoFA(ctx context.Context, in *EnableTwoFARequest, opts ...grpc.CallOption) (*EnableTwoFAResponse, error)
 	DisableTwoFA(ctx context.Context, in *DisableTwoFARequest, opts ...grpc.CallOption) (*DisableTwoFAResponse, error)
 	VerifyTwoFA(ctx context.Context, in *VerifyTwoFARequest, opts ...grpc.CallOption) (*VerifyTwoFAResponse, error)
+	SetupSMSTwoFA(ctx context.Context, in *SetupSMSTwoFARequest, opts ...grpc.CallOption) (*SetupSMSTwoFAResponse, error)
+	EnableSMSTwoFA(ctx context.Context, in *EnableSMSTwoFARequest, opts ...grpc.CallOption) (*EnableSMSTwoFAResponse, error)
+	SendTwoFACode(ctx context.Context, in *SendTwoFACodeRequest, opts ...grpc.CallOption) (*SendTwoFACodeResponse, error)
 	// User management
 	GetUserProfile(ctx context.Context, in *GetUserProfileRequest, opts ...grpc.CallOption) (*GetUserProfileResponse, error)
 	UpdateUserProfile(ctx context.Context, in *UpdateUserProfileRequest, opts ...grpc.CallOption) (*UpdateUserProfileResponse, error)
+	// Signing key administration (requires x-admin-token metadata)
+	ListSigningKeys(ctx context.Context, in *ListSigningKeysRequest, opts ...grpc.CallOption) (*ListSigningKeysResponse, error)
+	ReloadSigningKeys(ctx context.Context, in *ReloadSigningKeysRequest, opts ...grpc.CallOption) (*ReloadSigningKeysResponse, error)
 }
 
 type authServiceClient struct {
@@ -165,6 +176,36 @@ func (c *authServiceClient) VerifyTwoFA(ctx context.Context, in *VerifyTwoFARequ
 	return out, nil
 }
 
+func (c *authServiceClient) SetupSMSTwoFA(ctx context.Context, in *SetupSMSTwoFARequest, opts ...grpc.CallOption) (*SetupSMSTwoFAResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetupSMSTwoFAResponse)
+	err := c.cc.Invoke(ctx, AuthService_SetupSMSTwoFA_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) EnableSMSTwoFA(ctx context.Context, in *EnableSMSTwoFARequest, opts ...grpc.CallOption) (*EnableSMSTwoFAResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EnableSMSTwoFAResponse)
+	err := c.cc.Invoke(ctx, AuthService_EnableSMSTwoFA_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) SendTwoFACode(ctx context.Context, in *SendTwoFACodeRequest, opts ...grpc.CallOption) (*SendTwoFACodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendTwoFACodeResponse)
+	err := c.cc.Invoke(ctx, AuthService_SendTwoFACode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *authServiceClient) GetUserProfile(ctx context.Context, in *GetUserProfileRequest, opts ...grpc.CallOption) (*GetUserProfileResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetUserProfileResponse)
@@ -185,6 +226,26 @@ func (c *authServiceClient) UpdateUserProfile(ctx context.Context, in *UpdateUse
 	return out, nil
 }
 
+func (c *authServiceClient) ListSigningKeys(ctx context.Context, in *ListSigningKeysRequest, opts ...grpc.CallOption) (*ListSigningKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSigningKeysResponse)
+	err := c.cc.Invoke(ctx, AuthService_ListSigningKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) ReloadSigningKeys(ctx context.Context, in *ReloadSigningKeysRequest, opts ...grpc.CallOption) (*ReloadSigningKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReloadSigningKeysResponse)
+	err := c.cc.Invoke(ctx, AuthService_ReloadSigningKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AuthServiceServer is the server API for AuthService service.
 // All implementations must embed UnimplementedAuthServiceServer
 // for forward compatibility.
@@ -204,9 +265,15 @@ type AuthServiceServer interface {
 	EnableTwoFA(context.Context, *EnableTwoFARequest) (*EnableTwoFAResponse, error)
 	DisableTwoFA(context.Context, *DisableTwoFARequest) (*DisableTwoFAResponse, error)
 	VerifyTwoFA(context.Context, *VerifyTwoFARequest) (*VerifyTwoFAResponse, error)
+	SetupSMSTwoFA(context.Context, *SetupSMSTwoFARequest) (*SetupSMSTwoFAResponse, error)
+	EnableSMSTwoFA(context.Context, *EnableSMSTwoFARequest) (*EnableSMSTwoFAResponse, error)
+	SendTwoFACode(context.Context, *SendTwoFACodeRequest) (*SendTwoFACodeResponse, error)
 	// User management
 	GetUserProfile(context.Context, *GetUserProfileRequest) (*GetUserProfileResponse, error)
 	UpdateUserProfile(context.Context, *UpdateUserProfileRequest) (*UpdateUserProfileResponse, error)
+	// Signing key administration (requires x-admin-token metadata)
+	ListSigningKeys(context.Context, *ListSigningKeysRequest) (*ListSigningKeysResponse, error)
+	ReloadSigningKeys(context.Context, *ReloadSigningKeysRequest) (*ReloadSigningKeysResponse, error)
 	mustEmbedUnimplementedAuthServiceServer()
 }
 
@@ -247,12 +314,27 @@ func (UnimplementedAuthServiceServer) DisableTwoFA(context.Context, *DisableTwoF
 func (UnimplementedAuthServiceServer) VerifyTwoFA(context.Context, *VerifyTwoFARequest) (*VerifyTwoFAResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method VerifyTwoFA not implemented")
 }
+func (UnimplementedAuthServiceServer) SetupSMSTwoFA(context.Context, *SetupSMSTwoFARequest) (*SetupSMSTwoFAResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetupSMSTwoFA not implemented")
+}
+func (UnimplementedAuthServiceServer) EnableSMSTwoFA(context.Context, *EnableSMSTwoFARequest) (*EnableSMSTwoFAResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnableSMSTwoFA not implemented")
+}
+func (UnimplementedAuthServiceServer) SendTwoFACode(context.Context, *SendTwoFACodeRequest) (*SendTwoFACodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendTwoFACode not implemented")
+}
 func (UnimplementedAuthServiceServer) GetUserProfile(context.Context, *GetUserProfileRequest) (*GetUserProfileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUserProfile not implemented")
 }
 func (UnimplementedAuthServiceServer) UpdateUserProfile(context.Context, *UpdateUserProfileRequest) (*UpdateUserProfileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateUserProfile not implemented")
 }
+func (UnimplementedAuthServiceServer) ListSigningKeys(context.Context, *ListSigningKeysRequest) (*ListSigningKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSigningKeys not implemented")
+}
+func (UnimplementedAuthServiceServer) ReloadSigningKeys(context.Context, *ReloadSigningKeysRequest) (*ReloadSigningKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadSigningKeys not implemented")
+}
 func (UnimplementedAuthServiceServer) mustEmbedUnimplementedAuthServiceServer() {}
 func (UnimplementedAuthServiceServer) testEmbeddedByValue()                     {}
 
@@ -454,6 +536,60 @@ func _AuthService_VerifyTwoFA_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AuthService_SetupSMSTwoFA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetupSMSTwoFARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).SetupSMSTwoFA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_SetupSMSTwoFA_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).SetupSMSTwoFA(ctx, req.(*SetupSMSTwoFARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_EnableSMSTwoFA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnableSMSTwoFARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).EnableSMSTwoFA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_EnableSMSTwoFA_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).EnableSMSTwoFA(ctx, req.(*EnableSMSTwoFARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_SendTwoFACode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendTwoFACodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).SendTwoFACode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_SendTwoFACode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).SendTwoFACode(ctx, req.(*SendTwoFACodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AuthService_GetUserProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetUserProfileRequest)
 	if err := dec(in); err != nil {
@@ -490,6 +626,42 @@ func _AuthService_UpdateUserProfile_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AuthService_ListSigningKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSigningKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).ListSigningKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_ListSigningKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).ListSigningKeys(ctx, req.(*ListSigningKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_ReloadSigningKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadSigningKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).ReloadSigningKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_ReloadSigningKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).ReloadSigningKeys(ctx, req.(*ReloadSigningKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AuthService_ServiceDesc is the grpc.ServiceDesc for AuthService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -537,6 +709,18 @@ var AuthService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "VerifyTwoFA",
 			Handler:    _AuthService_VerifyTwoFA_Handler,
 		},
+		{
+			MethodName: "SetupSMSTwoFA",
+			Handler:    _AuthService_SetupSMSTwoFA_Handler,
+		},
+		{
+			MethodName: "EnableSMSTwoFA",
+			Handler:    _AuthService_EnableSMSTwoFA_Handler,
+		},
+		{
+			MethodName: "SendTwoFACode",
+			Handler:    _AuthService_SendTwoFACode_Handler,
+		},
 		{
 			MethodName: "GetUserProfile",
 			Handler:    _AuthService_GetUserProfile_Handler,
@@ -545,6 +729,14 @@ var AuthService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateUserProfile",
 			Handler:    _AuthService_UpdateUserProfile_Handler,
 		},
+		{
+			MethodName: "ListSigningKeys",
+			Handler:    _AuthService_ListSigningKeys_Handler,
+		},
+		{
+			MethodName: "ReloadSigningKeys",
+			Handler:    _AuthService_ReloadSigningKeys_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/auth/v1/auth.proto",
@@ -0,0 +1,91 @@
+// Package realtime holds the contract between notification-service, which
+// publishes events for users, and the gateway, which pushes them to the
+// users' open WebSocket and Server-Sent Events connections.
+//
+// Each event is appended to the user's Redis stream, StreamKey(userID), whose
+// entry ID becomes the event ID, and then announced on EventsChannel so that
+// every gateway replica sees it and delivers it to the connections it holds.
+// A client that reconnects sends the last ID it received, and the gateway
+// replays the stream from there.
+package realtime
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// EventsChannel is the Redis pub/sub channel on which every event added to a
+// user stream is announced.
+const EventsChannel = "realtime:events"
+
+// StreamKeyPrefix prefixes the per-user streams. Streams are capped in
+// length and expire when the user receives nothing for a while, so a client
+// that was away longer must reload its state.
+const StreamKeyPrefix = "realtime:user:"
+
+// Fields of a stream entry.
+const (
+	FieldType = "type"
+	FieldData = "data"
+)
+
+// Event types published by notification-service.
+const (
+	// EventNotificationCreated carries a new inbox notification and the
+	// unread count.
+	EventNotificationCreated = "notification.created"
+	// EventNotificationUpdated carries a notification that was read or
+	// archived, from any of the user's devices, and the unread count.
+	EventNotificationUpdated = "notification.updated"
+	// EventNotificationsReadAll carries the unread count after every
+	// notification was marked read.
+	EventNotificationsReadAll = "notification.read_all"
+)
+
+// StreamKey returns the Redis key of a user's stream.
+func StreamKey(userID string) string {
+	return StreamKeyPrefix + userID
+}
+
+// Event is published on EventsChannel. ID is the entry ID in the user's
+// stream.
+type Event struct {
+	ID     string          `json:"id"`
+	UserID string          `json:"user_id"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// ParseID splits a stream entry ID ("<ms>-<seq>") into its parts.
+func ParseID(id string) (ms, seq uint64, ok bool) {
+	a, b, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	ms, err := strconv.ParseUint(a, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err = strconv.ParseUint(b, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
+
+// After reports whether stream entry ID a comes after b. An invalid ID comes
+// before every valid one.
+func After(a, b string) bool {
+	ams, aseq, aok := ParseID(a)
+	bms, bseq, bok := ParseID(b)
+	switch {
+	case !aok:
+		return false
+	case !bok:
+		return true
+	case ams != bms:
+		return ams > bms
+	}
+	return aseq > bseq
+}
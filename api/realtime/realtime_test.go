@@ -0,0 +1,52 @@
+package realtime
+
+import "testing"
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		id      string
+		ms, seq uint64
+		ok      bool
+	}{
+		{"1700000000000-0", 1700000000000, 0, true},
+		{"5-12", 5, 12, true},
+		{"", 0, 0, false},
+		{"5", 0, 0, false},
+		{"5-", 0, 0, false},
+		{"-1", 0, 0, false},
+		{"a-1", 0, 0, false},
+		{"5-b", 0, 0, false},
+		{"5-1-2", 0, 0, false},
+	}
+	for _, tt := range tests {
+		ms, seq, ok := ParseID(tt.id)
+		if ms != tt.ms || seq != tt.seq || ok != tt.ok {
+			t.Errorf("ParseID(%q) = %d, %d, %v; want %d, %d, %v", tt.id, ms, seq, ok, tt.ms, tt.seq, tt.ok)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"2-0", "1-0", true},
+		{"1-0", "2-0", false},
+		{"1-1", "1-0", true},
+		{"1-0", "1-1", false},
+		{"1-0", "1-0", false},
+		// Sequences compare as numbers, not strings.
+		{"1-10", "1-9", true},
+		{"10-0", "9-0", true},
+		// An invalid ID comes before every valid one.
+		{"1-0", "x", true},
+		{"x", "1-0", false},
+		{"x", "y", false},
+	}
+	for _, tt := range tests {
+		if got := After(tt.a, tt.b); got != tt.want {
+			t.Errorf("After(%q, %q) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
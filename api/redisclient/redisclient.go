@@ -0,0 +1,37 @@
+// Package redisclient builds the Redis client every service uses, with the
+// latency metrics and tracing hooks installed.
+package redisclient
+
+import (
+	"fmt"
+	"music-player/api/logger"
+	"music-player/api/metrics"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+var lg = logger.For("redis")
+
+// Config holds the connection settings, read by each service from REDIS_*.
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// New returns a client for cfg. It does not connect; the first command or a
+// health check does.
+func New(cfg *Config) *redis.Client {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		Username: cfg.Username,
+		Password: cfg.Password,
+	})
+	client.AddHook(metrics.RedisHook{})
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		lg.Warn("Failed to instrument Redis tracing", "error", err)
+	}
+	return client
+}
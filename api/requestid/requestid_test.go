@@ -0,0 +1,43 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"hex", New(), true},
+		{"uuid", "3f2b8c1e-9d4a-4b7e-8f10-2a6c5e9b7d31", true},
+		{"dots and underscores", "web.app_01", true},
+		{"empty", "", false},
+		{"max length", strings.Repeat("a", MaxLen), true},
+		{"too long", strings.Repeat("a", MaxLen+1), false},
+		{"space", "abc def", false},
+		{"newline", "abc\ndef", false},
+		{"quote", `abc"def`, false},
+		{"non-ascii", "abcé", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Valid(tt.id); got != tt.want {
+				t.Errorf("Valid(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != "" {
+		t.Errorf("FromContext(empty) = %q, want empty", got)
+	}
+	ctx := NewContext(context.Background(), "abc")
+	if got := FromContext(ctx); got != "abc" {
+		t.Errorf("FromContext = %q, want abc", got)
+	}
+}
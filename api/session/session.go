@@ -0,0 +1,116 @@
+// Package session holds the access-token session checks shared by
+// auth-service and the gateway. auth-service's TokenManager writes the
+// session JSON to Redis under Key(sid); an access token is accepted only
+// while that session is active, was issued for its current access version
+// and has not expired.
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	KeyPrefix = "auth:session:"
+
+	StatusActive  = "active"
+	StatusRevoked = "revoked"
+)
+
+// EventsChannel is the Redis pub/sub channel on which auth-service announces
+// session changes that invalidate access tokens, so caches of Snapshot can
+// drop them without waiting for their TTL.
+const EventsChannel = "auth:session:events"
+
+const (
+	EventRevoked = "revoked"
+	EventRotated = "rotated"
+)
+
+// Event is published on EventsChannel. AV is the new access version of a
+// rotated session.
+type Event struct {
+	SID    string `json:"sid"`
+	Reason string `json:"reason"`
+	AV     uint64 `json:"av,omitempty"`
+}
+
+var (
+	ErrNotFound = errors.New("session: not found")
+	ErrRevoked  = errors.New("session: revoked")
+	ErrRotated  = errors.New("session: access token superseded by a refresh")
+	ErrExpired  = errors.New("session: access token expired")
+)
+
+// Key returns the Redis key of a session.
+func Key(sid string) string {
+	return KeyPrefix + sid
+}
+
+// Snapshot is the part of the stored session that access-token checks need.
+type Snapshot struct {
+	Status string `json:"status"`
+	AV     uint64 `json:"av"`
+}
+
+// Token is what the checks need from access token claims.
+type Token struct {
+	SID       string
+	AV        uint64
+	ExpiresAt time.Time
+}
+
+// Store loads JSON values by key; both services' RedisUtil satisfy it.
+type Store interface {
+	GetJSON(ctx context.Context, key string, dest interface{}) error
+}
+
+// Load reads the snapshot of sid. Any lookup failure, including a missing
+// key, is reported as ErrNotFound so callers fail closed.
+func Load(ctx context.Context, store Store, sid string) (Snapshot, error) {
+	var snap Snapshot
+	if err := store.GetJSON(ctx, Key(sid), &snap); err != nil {
+		return Snapshot{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return snap, nil
+}
+
+// Check validates an access token against its session snapshot at now.
+func Check(snap Snapshot, tok Token, now time.Time) error {
+	if !tok.ExpiresAt.IsZero() && !now.Before(tok.ExpiresAt) {
+		return ErrExpired
+	}
+	if snap.Status != StatusActive {
+		return ErrRevoked
+	}
+	if snap.AV != tok.AV {
+		return ErrRotated
+	}
+	return nil
+}
+
+// Validate loads the session of tok and checks it.
+func Validate(ctx context.Context, store Store, tok Token, now time.Time) error {
+	snap, err := Load(ctx, store, tok.SID)
+	if err != nil {
+		return err
+	}
+	return Check(snap, tok, now)
+}
+
+// ErrorCode maps a validation error to the API error code and message both
+// services return with 401 Unauthorized.
+func ErrorCode(err error) (code, message string) {
+	switch {
+	case errors.Is(err, ErrRevoked):
+		return "SESSION_REVOKED", "Session has been revoked - please login again"
+	case errors.Is(err, ErrRotated):
+		return "TOKEN_ROTATED", "Access token was superseded - please refresh"
+	case errors.Is(err, ErrExpired):
+		return "TOKEN_EXPIRED", "Access token has expired"
+	default:
+		return "SESSION_INVALID", "User session is invalid or has expired"
+	}
+}
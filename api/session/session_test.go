@@ -0,0 +1,102 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeStore map[string]string
+
+func (s fakeStore) GetJSON(_ context.Context, key string, dest interface{}) error {
+	data, ok := s[key]
+	if !ok {
+		return errors.New("redis: nil")
+	}
+	return json.Unmarshal([]byte(data), dest)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	store := fakeStore{
+		Key("active"):  `{"user_id":"u1","status":"active","av":3}`,
+		Key("revoked"): `{"user_id":"u1","status":"revoked","av":3}`,
+		Key("rotated"): `{"user_id":"u1","status":"active","av":4}`,
+		Key("garbage"): `not json`,
+	}
+
+	tests := []struct {
+		name    string
+		tok     Token
+		wantErr error
+	}{
+		{
+			name: "active session with current access version",
+			tok:  Token{SID: "active", AV: 3, ExpiresAt: now.Add(time.Minute)},
+		},
+		{
+			name: "token without expiry",
+			tok:  Token{SID: "active", AV: 3},
+		},
+		{
+			name:    "revoked session",
+			tok:     Token{SID: "revoked", AV: 3, ExpiresAt: now.Add(time.Minute)},
+			wantErr: ErrRevoked,
+		},
+		{
+			name:    "rotated session rejects previous access version",
+			tok:     Token{SID: "rotated", AV: 3, ExpiresAt: now.Add(time.Minute)},
+			wantErr: ErrRotated,
+		},
+		{
+			name:    "expired access token",
+			tok:     Token{SID: "active", AV: 3, ExpiresAt: now.Add(-time.Second)},
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "token expiring exactly now",
+			tok:     Token{SID: "active", AV: 3, ExpiresAt: now},
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "expired session key",
+			tok:     Token{SID: "gone", AV: 1, ExpiresAt: now.Add(time.Minute)},
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "unreadable session",
+			tok:     Token{SID: "garbage", AV: 1, ExpiresAt: now.Add(time.Minute)},
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(context.Background(), store, tt.tok, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{ErrRevoked, "SESSION_REVOKED"},
+		{ErrRotated, "TOKEN_ROTATED"},
+		{ErrExpired, "TOKEN_EXPIRED"},
+		{ErrNotFound, "SESSION_INVALID"},
+		{errors.New("other"), "SESSION_INVALID"},
+	}
+
+	for _, tt := range tests {
+		if code, _ := ErrorCode(tt.err); code != tt.code {
+			t.Errorf("ErrorCode(%v) = %s, want %s", tt.err, code, tt.code)
+		}
+	}
+}
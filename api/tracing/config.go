@@ -0,0 +1,23 @@
+package tracing
+
+import "github.com/spf13/viper"
+
+// LoadConfig reads the exporter settings from the standard OTEL_*
+// variables. defaultServiceName applies when OTEL_SERVICE_NAME is unset.
+func LoadConfig(defaultServiceName string) *Config {
+	viper.SetDefault("OTEL_SERVICE_NAME", defaultServiceName)
+	viper.SetDefault("OTEL_TRACES_EXPORTER", "none")
+	viper.SetDefault("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4317")
+	viper.SetDefault("OTEL_EXPORTER_OTLP_INSECURE", true)
+	viper.SetDefault("OTEL_TRACES_FILE", "traces.jsonl")
+	viper.SetDefault("OTEL_TRACES_SAMPLER_ARG", 1.0)
+
+	return &Config{
+		ServiceName:  viper.GetString("OTEL_SERVICE_NAME"),
+		Exporter:     viper.GetString("OTEL_TRACES_EXPORTER"),
+		OTLPEndpoint: viper.GetString("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		OTLPInsecure: viper.GetBool("OTEL_EXPORTER_OTLP_INSECURE"),
+		FilePath:     viper.GetString("OTEL_TRACES_FILE"),
+		SampleRatio:  viper.GetFloat64("OTEL_TRACES_SAMPLER_ARG"),
+	}
+}
@@ -0,0 +1,27 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// untracedPaths are probe and scrape endpoints that would otherwise flood the
+// trace backend with spans nobody looks at.
+var untracedPaths = []string{"/livez", "/readyz", "/metrics"}
+
+// GinMiddleware starts a server span per request, continuing any W3C
+// traceparent sent by the client, and stores it in the request context so
+// downstream gRPC calls are linked to it. Requests to the probe endpoints and
+// to the extra paths in skip are not traced.
+func GinMiddleware(service string, skip ...string) gin.HandlerFunc {
+	untraced := make(map[string]struct{}, len(untracedPaths)+len(skip))
+	for _, p := range append(untracedPaths, skip...) {
+		untraced[p] = struct{}{}
+	}
+	return otelgin.Middleware(service, otelgin.WithFilter(func(r *http.Request) bool {
+		_, skip := untraced[r.URL.Path]
+		return !skip
+	}))
+}
@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "music-player/api/tracing"
+
+// RecordCarrier adapts Kafka record headers to a propagation.TextMapCarrier
+// so the W3C traceparent travels with the message.
+type RecordCarrier struct {
+	record *kgo.Record
+}
+
+var _ propagation.TextMapCarrier = RecordCarrier{}
+
+func NewRecordCarrier(r *kgo.Record) RecordCarrier {
+	return RecordCarrier{record: r}
+}
+
+func (c RecordCarrier) Get(key string) string {
+	for _, h := range c.record.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c RecordCarrier) Set(key, value string) {
+	for i, h := range c.record.Headers {
+		if h.Key == key {
+			c.record.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.record.Headers = append(c.record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+}
+
+func (c RecordCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.record.Headers))
+	for _, h := range c.record.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// StartProducerSpan starts a producer span for a record and injects its
+// context into the record headers. The caller ends the span from the produce
+// callback with EndProducerSpan.
+func StartProducerSpan(ctx context.Context, r *kgo.Record) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, r.Topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(r.Topic),
+			semconv.MessagingOperationTypePublish,
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, NewRecordCarrier(r))
+	return ctx, span
+}
+
+// EndProducerSpan records the produce outcome and ends the span.
+func EndProducerSpan(span trace.Span, r *kgo.Record, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(r.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(r.Offset)),
+		)
+	}
+	span.End()
+}
+
+// ExtractRecord returns ctx carrying the remote span context found in the
+// record headers, for consumers to parent their processing spans on.
+func ExtractRecord(ctx context.Context, r *kgo.Record) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, NewRecordCarrier(r))
+}
+
+// Envelope is implemented by event envelopes that carry the producer's span
+// context in their payload, as hex trace ID, span ID and trace flags.
+type Envelope interface {
+	SetTrace(traceID, spanID, flags string)
+	Trace() (traceID, spanID, flags string)
+}
+
+// StampEnvelope copies the trace ID, span ID and sampling flags of the active
+// span into the envelope so they survive in the payload even when headers are
+// dropped.
+func StampEnvelope(ctx context.Context, env Envelope) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	env.SetTrace(sc.TraceID().String(), sc.SpanID().String(), sc.TraceFlags().String())
+}
+
+// StartConsumerSpan starts a span for processing a consumed record. The parent
+// is taken from the record headers and, when the producer did not send any,
+// from the trace stamped into the envelope.
+func StartConsumerSpan(ctx context.Context, r *kgo.Record, env Envelope) (context.Context, trace.Span) {
+	ctx = ExtractRecord(ctx, r)
+	if !trace.SpanContextFromContext(ctx).IsValid() && env != nil {
+		ctx = contextFromEnvelope(ctx, env)
+	}
+	return otel.Tracer(instrumentationName).Start(ctx, r.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(r.Topic),
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(r.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(r.Offset)),
+		),
+	)
+}
+
+// contextFromEnvelope rebuilds the producer's span context from the envelope.
+// The sampling flags are carried over as sent: a trace the producer did not
+// sample stays unsampled, and envelopes from producers that predate the flags
+// field are treated as unsampled rather than forced into the trace backend.
+func contextFromEnvelope(ctx context.Context, env Envelope) context.Context {
+	traceHex, spanHex, flagsHex := env.Trace()
+	traceID, err := trace.TraceIDFromHex(traceHex)
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(spanHex)
+	if err != nil {
+		return ctx
+	}
+	var flags trace.TraceFlags
+	if b, err := hex.DecodeString(flagsHex); err == nil && len(b) == 1 {
+		flags = trace.TraceFlags(b[0])
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
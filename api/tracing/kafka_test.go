@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type testEnvelope struct {
+	traceID, spanID, flags string
+}
+
+func (e *testEnvelope) SetTrace(traceID, spanID, flags string) {
+	e.traceID, e.spanID, e.flags = traceID, spanID, flags
+}
+
+func (e *testEnvelope) Trace() (string, string, string) {
+	return e.traceID, e.spanID, e.flags
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+
+	tests := []struct {
+		name  string
+		flags trace.TraceFlags
+	}{
+		{"sampled", trace.FlagsSampled},
+		{"not sampled", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     spanID,
+				TraceFlags: tt.flags,
+			}))
+			env := &testEnvelope{}
+			StampEnvelope(ctx, env)
+
+			sc := trace.SpanContextFromContext(contextFromEnvelope(context.Background(), env))
+			if !sc.IsValid() || !sc.IsRemote() {
+				t.Fatalf("span context = %+v, want valid remote", sc)
+			}
+			if sc.TraceID() != traceID || sc.SpanID() != spanID {
+				t.Errorf("ids = %s/%s, want %s/%s", sc.TraceID(), sc.SpanID(), traceID, spanID)
+			}
+			if sc.TraceFlags() != tt.flags {
+				t.Errorf("flags = %s, want %s", sc.TraceFlags(), tt.flags)
+			}
+		})
+	}
+}
+
+func TestContextFromEnvelopeWithoutFlags(t *testing.T) {
+	env := &testEnvelope{traceID: "4bf92f3577b34da6a3ce929d0e0e4736", spanID: "00f067aa0ba902b7"}
+	sc := trace.SpanContextFromContext(contextFromEnvelope(context.Background(), env))
+	if !sc.IsValid() {
+		t.Fatal("span context invalid")
+	}
+	if sc.IsSampled() {
+		t.Error("envelope without flags was forced to sampled")
+	}
+}
+
+func TestContextFromEnvelopeInvalid(t *testing.T) {
+	ctx := contextFromEnvelope(context.Background(), &testEnvelope{traceID: "nothex", spanID: "00f067aa0ba902b7"})
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("invalid trace ID produced a span context")
+	}
+}
@@ -42,12 +42,36 @@ else
     echo "WARN: $UPDATE_JWKS not found or not executable. Skipping JWKS update."
 fi
 
+# --- Register as the next key of a managed keys directory ---
+# auth-service picks the key up without a restart when JWT_KEYS_DIR is set;
+# it is published immediately and starts signing ACTIVATE_IN_HOURS later.
+if [[ -n "${JWT_KEYS_DIR:-}" ]]; then
+    if ! command -v jq >/dev/null 2>&1; then
+        echo "ERROR: jq is required to update ${JWT_KEYS_DIR}/keys.json" >&2
+        exit 1
+    fi
+    hours="${ACTIVATE_IN_HOURS:-24}"
+    activate_at="$(date -u -d "+${hours} hours" +%Y-%m-%dT%H:%M:%SZ 2>/dev/null || date -u -v+"${hours}"H +%Y-%m-%dT%H:%M:%SZ)"
+
+    mkdir -p "$JWT_KEYS_DIR"
+    cp "$priv" "${JWT_KEYS_DIR}/"
+    manifest="${JWT_KEYS_DIR}/keys.json"
+    [[ -f "$manifest" ]] || echo '{"keys":[]}' > "$manifest"
+
+    tmp="$(mktemp "${JWT_KEYS_DIR}/.keys.json.XXXXXX")"
+    jq --arg kid "$kid" --arg file "$(basename "$priv")" --arg at "$activate_at" \
+        '.keys += [{kid: $kid, file: $file, state: "next", activate_at: $at}]' "$manifest" > "$tmp"
+    mv "$tmp" "$manifest"
+    echo ">>> Registered kid=$kid in $manifest (activates at $activate_at)"
+    echo ">>> Mark the previous key \"retiring\" after activation and \"retired\" once its tokens expire."
+fi
+
 echo
 echo ">>> Done."
 echo "Private: $priv"
 echo "Public : $pub"
 echo "JWKS   : ${JWKS_DIR}/jwks.json (if updated)"
 echo
-echo "Set ENV for Auth:"
+echo "Set ENV for Auth (legacy single-key mode, ignored when JWT_KEYS_DIR is set):"
 echo "  JWT_ACCESS_PRIVATE_KEY_FILE=/etc/keys/jwt/private/ed25519-${kid}.pem"
 echo "  JWT_ACCESS_KID=${kid}"
\ No newline at end of file
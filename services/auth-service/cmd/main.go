@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"auth-service/configs"
+	"music-player/api/logger"
+	"music-player/api/tracing"
 )
 
 func main() {
@@ -19,10 +21,22 @@ func main() {
 	dbCfg := configs.LoadDBConfig()
 	redisCfg := configs.LoadRedisConfig()
 	kafkaCfg := configs.LoadKafkaConfig()
+	twoFACfg := configs.LoadTwoFAConfig()
+	logCfg := logger.LoadConfig()
+	tracingCfg := tracing.LoadConfig("auth-service")
 
-	app, err := InitializeApp(appCfg, dbCfg, redisCfg, kafkaCfg)
+	if err := logger.Init("auth-service", logCfg.Level, logCfg.Levels); err != nil {
+		fatal("Failed to initialize logger", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), tracingCfg)
+	if err != nil {
+		fatal("Failed to initialize tracing", err)
+	}
+
+	app, err := InitializeApp(appCfg, dbCfg, redisCfg, kafkaCfg, twoFACfg, logCfg)
 	if err != nil {
-		log.Fatalf("[FATAL] Failed to initialize app: %v", err)
+		fatal("Failed to initialize app", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -41,9 +55,9 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		log.Printf("[INFO] HTTP server starting in %s mode on port %s", appCfg.Env, appCfg.Port)
+		slog.Info("HTTP server starting", "env", appCfg.Env, "port", appCfg.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[ERROR] HTTP server error: %v", err)
+			slog.Error("HTTP server error", "error", err)
 			cancel()
 		}
 	}()
@@ -52,50 +66,68 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		log.Printf("[INFO] gRPC server starting on port %s", appCfg.GRPCPort)
+		slog.Info("gRPC server starting", "port", appCfg.GRPCPort)
 		if err := app.GRPCServer.Start(); err != nil {
-			log.Printf("[ERROR] gRPC server error: %v", err)
+			slog.Error("gRPC server error", "error", err)
 			cancel()
 		}
 	}()
 
+	// Keep grpc.health.v1 in sync with dependency readiness
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.Health.WatchGRPC(ctx, app.GRPCServer.GetHealthServer(), 10*time.Second, "auth.v1.AuthService")
+	}()
+
+	// Reload signing keys and the JWKS file on change and follow the rotation schedule
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.JWT.Watch(ctx, 30*time.Second)
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	select {
 	case <-quit:
-		log.Println("[INFO] Shutting down gracefully...")
+		slog.Info("Shutting down gracefully")
 	case <-ctx.Done():
-		log.Println("[INFO] Context cancelled, shutting down...")
+		slog.Info("Context cancelled, shutting down")
 	}
 
 	cancel()
 
-	log.Println("[INFO] Cleaning up resources...")
+	slog.Info("Cleaning up resources")
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("[ERROR] HTTP server forced to shutdown: %v", err)
+		slog.Error("HTTP server forced to shutdown", "error", err)
 	} else {
-		log.Println("[INFO] HTTP server stopped successfully")
+		slog.Info("HTTP server stopped successfully")
 	}
 
 	if app.KafkaProducer != nil {
 		app.KafkaProducer.Close()
-		log.Println("[INFO] Kafka producer closed successfully")
+		slog.Info("Kafka producer closed successfully")
 	}
 
 	if app.GRPCServer != nil {
-		log.Println("[INFO] Stopping gRPC server...")
+		slog.Info("Stopping gRPC server")
 		app.GRPCServer.Stop()
-		log.Println("[INFO] gRPC server stopped successfully")
+		slog.Info("gRPC server stopped successfully")
 	}
 
 	if app.KafkaConsumer != nil {
 		app.KafkaConsumer.Close()
-		log.Println("[INFO] Kafka consumer closed successfully")
+		slog.Info("Kafka consumer closed successfully")
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
 	}
 
 	done := make(chan struct{})
@@ -106,10 +138,16 @@ func main() {
 
 	select {
 	case <-done:
-		log.Println("[INFO] All services stopped successfully")
+		slog.Info("All services stopped successfully")
 	case <-time.After(5 * time.Second):
-		log.Println("[WARN] Timeout waiting for services to stop")
+		slog.Warn("Timeout waiting for services to stop")
 	}
 
-	log.Println("[INFO] Application shutdown complete")
+	slog.Info("Application shutdown complete")
+}
+
+// fatal logs err and exits. slog has no Fatal level.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -5,16 +5,21 @@ package main
 
 import (
 	"auth-service/configs"
-	"auth-service/internal/db"
 	"auth-service/internal/handlers"
+	"auth-service/internal/interceptors"
 	"auth-service/internal/kafka/consumer"
 	"auth-service/internal/kafka/producer"
 	"auth-service/internal/middleware"
-	"auth-service/internal/redis"
 	"auth-service/internal/repositories"
 	"auth-service/internal/routes"
 	"auth-service/internal/services"
 	redisutil "auth-service/internal/utils/redis"
+	"music-player/api/health"
+	"music-player/api/logger"
+	"music-player/api/metrics"
+	"music-player/api/pgclient"
+	"music-player/api/redisclient"
+	"music-player/api/tracing"
 
 	tokenmanager "auth-service/internal/services/TokenManager"
 	"auth-service/internal/utils/jwt"
@@ -23,9 +28,12 @@ import (
 
 	authv1 "music-player/api/proto/auth/v1"
 
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 type App struct {
@@ -33,13 +41,15 @@ type App struct {
 	GRPCServer    *configs.GRPCServer
 	KafkaProducer *producer.Producer
 	KafkaConsumer *consumer.Consumer
+	Health        *health.Registry
+	JWT           *jwt.JWTConfig
 }
 
-func InitializeApp(appCfg *configs.AppConfig, dbCfg *configs.DBConfig, redisCfg *configs.RedisConfig, kafkaCfg *configs.KafkaConfig) (*App, error) {
+func InitializeApp(appCfg *configs.AppConfig, dbCfg *pgclient.Config, redisCfg *redisclient.Config, kafkaCfg *configs.KafkaConfig, twoFACfg *configs.TwoFAConfig, logCfg *logger.Config) (*App, error) {
 	wire.Build(
 		// Infrastructure
-		db.NewGormDB,
-		redis.NewRedisClient,
+		pgclient.New,
+		redisclient.New,
 		producer.NewProducer,
 		consumer.NewConsumer,
 
@@ -52,6 +62,7 @@ func InitializeApp(appCfg *configs.AppConfig, dbCfg *configs.DBConfig, redisCfg
 		provideJWTConfig,
 		provideJWTService,
 		provideTokenManager,
+		provideHealthRegistry,
 
 		// Services
 		services.NewEventPublisher,
@@ -66,6 +77,8 @@ func InitializeApp(appCfg *configs.AppConfig, dbCfg *configs.DBConfig, redisCfg
 		handlers.NewTwoFAHandler,
 		handlers.NewAuthGRPCHandler,
 		handlers.NewJWKSHandler,
+		handlers.NewHealthHandler,
+		provideLogLevelHandler,
 
 		// Server components
 		provideRouter,
@@ -75,8 +88,15 @@ func InitializeApp(appCfg *configs.AppConfig, dbCfg *configs.DBConfig, redisCfg
 	return nil, nil
 }
 
-func provideRouter(userHandler *handlers.UserHandler, twoFAHandler *handlers.TwoFAHandler, jwksHandler *handlers.JWKSHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
-	r := gin.Default()
+func provideRouter(userHandler *handlers.UserHandler, twoFAHandler *handlers.TwoFAHandler, jwksHandler *handlers.JWKSHandler, healthHandler *handlers.HealthHandler, logLevelHandler *logger.LevelHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(tracing.GinMiddleware("auth-service"))
+	r.Use(logger.GinMiddleware())
+	r.Use(metrics.HTTPMiddleware())
+	routes.RegisterHealthRoutes(r, healthHandler)
+	routes.RegisterMetricsRoutes(r)
+	routes.RegisterLogLevelRoutes(r, logLevelHandler)
 	api := r.Group("/api/v1")
 	api.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -87,7 +107,7 @@ func provideRouter(userHandler *handlers.UserHandler, twoFAHandler *handlers.Two
 	return r
 }
 
-func provideApp(router *gin.Engine, grpcServer *configs.GRPCServer, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer, authGRPCHandler *handlers.AuthGRPCHandler) *App {
+func provideApp(router *gin.Engine, grpcServer *configs.GRPCServer, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer, authGRPCHandler *handlers.AuthGRPCHandler, healthRegistry *health.Registry, jwtCfg *jwt.JWTConfig) *App {
 	authv1.RegisterAuthServiceServer(grpcServer.GetServer(), authGRPCHandler)
 
 	return &App{
@@ -95,11 +115,17 @@ func provideApp(router *gin.Engine, grpcServer *configs.GRPCServer, kafkaProduce
 		GRPCServer:    grpcServer,
 		KafkaProducer: kafkaProducer,
 		KafkaConsumer: kafkaConsumer,
+		Health:        healthRegistry,
+		JWT:           jwtCfg,
 	}
 }
 
 func provideGRPCServer(appCfg *configs.AppConfig) (*configs.GRPCServer, error) {
-	return configs.NewGRPCServer(appCfg.GRPCPort)
+	return configs.NewGRPCServer(appCfg.GRPCPort, interceptors.ServerOptions(interceptors.DefaultDeadline, appCfg.ClientIPSigningSecret)...)
+}
+
+func provideLogLevelHandler(logCfg *logger.Config) *logger.LevelHandler {
+	return logger.NewLevelHandler(logCfg.AdminToken)
 }
 
 func provideTwoFAUtil() *twofa.TwoFAUtil {
@@ -122,6 +148,15 @@ func provideJWTService(cfg *jwt.JWTConfig) jwt.JWTService {
 	return jwt.NewJWTService(cfg)
 }
 
-func provideTokenManager(jwtSvc jwt.JWTService, redisUtil *redisutil.RedisUtil) tokenmanager.TokenManager {
-	return tokenmanager.NewTokenManager(jwtSvc, redisUtil)
+func provideTokenManager(jwtSvc jwt.JWTService, redisUtil *redisutil.RedisUtil, jwtCfg *jwt.JWTConfig) tokenmanager.TokenManager {
+	return tokenmanager.NewTokenManager(jwtSvc, redisUtil, jwtCfg.RefreshOpaque)
+}
+
+func provideHealthRegistry(gormDB *gorm.DB, redisClient *goredis.Client, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer) *health.Registry {
+	registry := health.NewRegistry(2 * time.Second)
+	registry.Register("postgres", health.GormCheck(gormDB))
+	registry.Register("redis", health.RedisCheck(redisClient))
+	registry.Register("kafka_producer", health.PingCheck(kafkaProducer))
+	registry.Register("kafka_consumer", health.PingCheck(kafkaConsumer))
+	return registry
 }
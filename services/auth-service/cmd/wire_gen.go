@@ -8,12 +8,11 @@ package main
 
 import (
 	"auth-service/configs"
-	"auth-service/internal/db"
 	"auth-service/internal/handlers"
+	"auth-service/internal/interceptors"
 	"auth-service/internal/kafka/consumer"
 	"auth-service/internal/kafka/producer"
 	"auth-service/internal/middleware"
-	"auth-service/internal/redis"
 	"auth-service/internal/repositories"
 	"auth-service/internal/routes"
 	"auth-service/internal/services"
@@ -23,45 +22,59 @@ import (
 	"auth-service/internal/utils/twofa"
 	"github.com/gin-gonic/gin"
 	redis2 "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"music-player/api/health"
+	"music-player/api/logger"
+	"music-player/api/metrics"
+	"music-player/api/pgclient"
 	"music-player/api/proto/auth/v1"
+	"music-player/api/redisclient"
+	"music-player/api/tracing"
+	"time"
 )
 
 // Injectors from wire.go:
 
-func InitializeApp(appCfg *configs.AppConfig, dbCfg *configs.DBConfig, redisCfg *configs.RedisConfig, kafkaCfg *configs.KafkaConfig) (*App, error) {
-	gormDB, err := db.NewGormDB(dbCfg)
+func InitializeApp(appCfg *configs.AppConfig, dbCfg *pgclient.Config, redisCfg *redisclient.Config, kafkaCfg *configs.KafkaConfig, twoFACfg *configs.TwoFAConfig, logCfg *logger.Config) (*App, error) {
+	gormDB, err := pgclient.New(dbCfg)
 	if err != nil {
 		return nil, err
 	}
 	userRepository := repositories.NewUserRepository(gormDB)
 	jwtConfig := provideJWTConfig()
 	jwtService := provideJWTService(jwtConfig)
-	client := redis.NewRedisClient(redisCfg)
+	client := redisclient.New(redisCfg)
 	redisUtil := provideRedisUtil(client)
-	tokenManager := provideTokenManager(jwtService, redisUtil)
+	tokenManager := provideTokenManager(jwtService, redisUtil, jwtConfig)
 	producerProducer, err := producer.NewProducer(kafkaCfg)
 	if err != nil {
 		return nil, err
 	}
-	eventPublisher := services.NewEventPublisher(producerProducer)
+	eventPublisher := services.NewEventPublisher(producerProducer, appCfg)
 	userService := services.NewUserService(userRepository, jwtService, tokenManager, eventPublisher)
 	userHandler := handlers.NewUserHandler(userService)
 	twoFAUtil := provideTwoFAUtil()
-	twoFAService := services.NewTwoFAService(userRepository, twoFAUtil, redisUtil)
+	twoFAService, err := services.NewTwoFAService(userRepository, twoFAUtil, redisUtil, eventPublisher, twoFACfg)
+	if err != nil {
+		return nil, err
+	}
 	twoFAHandler := handlers.NewTwoFAHandler(twoFAService)
 	jwksHandler := handlers.NewJWKSHandler(jwtService)
-	authMiddleware := middleware.NewAuthMiddleware(jwtService, redisUtil)
-	engine := provideRouter(userHandler, twoFAHandler, jwksHandler, authMiddleware)
-	grpcServer, err := provideGRPCServer(appCfg)
+	consumerConsumer, err := consumer.NewConsumer(kafkaCfg)
 	if err != nil {
 		return nil, err
 	}
-	consumerConsumer, err := consumer.NewConsumer(kafkaCfg)
+	registry := provideHealthRegistry(gormDB, client, producerProducer, consumerConsumer)
+	healthHandler := handlers.NewHealthHandler(registry)
+	logLevelHandler := provideLogLevelHandler(logCfg)
+	authMiddleware := middleware.NewAuthMiddleware(jwtService, redisUtil)
+	engine := provideRouter(userHandler, twoFAHandler, jwksHandler, healthHandler, logLevelHandler, authMiddleware)
+	grpcServer, err := provideGRPCServer(appCfg)
 	if err != nil {
 		return nil, err
 	}
-	authGRPCHandler := handlers.NewAuthGRPCHandler(userService, twoFAService)
-	app := provideApp(engine, grpcServer, producerProducer, consumerConsumer, authGRPCHandler)
+	authGRPCHandler := handlers.NewAuthGRPCHandler(userService, twoFAService, jwtConfig)
+	app := provideApp(engine, grpcServer, producerProducer, consumerConsumer, authGRPCHandler, registry, jwtConfig)
 	return app, nil
 }
 
@@ -72,10 +85,19 @@ type App struct {
 	GRPCServer    *configs.GRPCServer
 	KafkaProducer *producer.Producer
 	KafkaConsumer *consumer.Consumer
+	Health        *health.Registry
+	JWT           *jwt.JWTConfig
 }
 
-func provideRouter(userHandler *handlers.UserHandler, twoFAHandler *handlers.TwoFAHandler, jwksHandler *handlers.JWKSHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
-	r := gin.Default()
+func provideRouter(userHandler *handlers.UserHandler, twoFAHandler *handlers.TwoFAHandler, jwksHandler *handlers.JWKSHandler, healthHandler *handlers.HealthHandler, logLevelHandler *logger.LevelHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(tracing.GinMiddleware("auth-service"))
+	r.Use(logger.GinMiddleware())
+	r.Use(metrics.HTTPMiddleware())
+	routes.RegisterHealthRoutes(r, healthHandler)
+	routes.RegisterMetricsRoutes(r)
+	routes.RegisterLogLevelRoutes(r, logLevelHandler)
 	api := r.Group("/api/v1")
 	api.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -86,7 +108,7 @@ func provideRouter(userHandler *handlers.UserHandler, twoFAHandler *handlers.Two
 	return r
 }
 
-func provideApp(router *gin.Engine, grpcServer *configs.GRPCServer, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer, authGRPCHandler *handlers.AuthGRPCHandler) *App {
+func provideApp(router *gin.Engine, grpcServer *configs.GRPCServer, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer, authGRPCHandler *handlers.AuthGRPCHandler, healthRegistry *health.Registry, jwtCfg *jwt.JWTConfig) *App {
 	authv1.RegisterAuthServiceServer(grpcServer.GetServer(), authGRPCHandler)
 
 	return &App{
@@ -94,11 +116,17 @@ func provideApp(router *gin.Engine, grpcServer *configs.GRPCServer, kafkaProduce
 		GRPCServer:    grpcServer,
 		KafkaProducer: kafkaProducer,
 		KafkaConsumer: kafkaConsumer,
+		Health:        healthRegistry,
+		JWT:           jwtCfg,
 	}
 }
 
 func provideGRPCServer(appCfg *configs.AppConfig) (*configs.GRPCServer, error) {
-	return configs.NewGRPCServer(appCfg.GRPCPort)
+	return configs.NewGRPCServer(appCfg.GRPCPort, interceptors.ServerOptions(interceptors.DefaultDeadline, appCfg.ClientIPSigningSecret)...)
+}
+
+func provideLogLevelHandler(logCfg *logger.Config) *logger.LevelHandler {
+	return logger.NewLevelHandler(logCfg.AdminToken)
 }
 
 func provideTwoFAUtil() *twofa.TwoFAUtil {
@@ -121,6 +149,15 @@ func provideJWTService(cfg *jwt.JWTConfig) jwt.JWTService {
 	return jwt.NewJWTService(cfg)
 }
 
-func provideTokenManager(jwtSvc jwt.JWTService, redisUtil *redisutil.RedisUtil) tokenmanager.TokenManager {
-	return tokenmanager.NewTokenManager(jwtSvc, redisUtil)
+func provideTokenManager(jwtSvc jwt.JWTService, redisUtil *redisutil.RedisUtil, jwtCfg *jwt.JWTConfig) tokenmanager.TokenManager {
+	return tokenmanager.NewTokenManager(jwtSvc, redisUtil, jwtCfg.RefreshOpaque)
+}
+
+func provideHealthRegistry(gormDB *gorm.DB, redisClient *redis2.Client, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer) *health.Registry {
+	registry := health.NewRegistry(2 * time.Second)
+	registry.Register("postgres", health.GormCheck(gormDB))
+	registry.Register("redis", health.RedisCheck(redisClient))
+	registry.Register("kafka_producer", health.PingCheck(kafkaProducer))
+	registry.Register("kafka_consumer", health.PingCheck(kafkaConsumer))
+	return registry
 }
@@ -1,7 +1,7 @@
 package configs
 
 import (
-	"log"
+	"log/slog"
 
 	"github.com/spf13/viper"
 )
@@ -10,6 +10,10 @@ type AppConfig struct {
 	Port     string
 	GRPCPort string
 	Env      string
+
+	// ClientIPSigningSecret verifies the client address the gateway forwards
+	// in gRPC metadata; it must match the gateway's CLIENT_IP_SIGNING_SECRET.
+	ClientIPSigningSecret []byte
 }
 
 func LoadAppConfig() *AppConfig {
@@ -18,13 +22,18 @@ func LoadAppConfig() *AppConfig {
 
 	err := viper.ReadInConfig()
 	if err != nil {
-		log.Printf("[INFO] No .env file found or error reading config: %v", err)
+		slog.Info("No .env file found or error reading config", "error", err)
 	}
 
 	cfg := &AppConfig{
 		Port:     viper.GetString("APP_PORT"),
 		GRPCPort: viper.GetString("GRPC_PORT"),
 		Env:      viper.GetString("APP_ENV"),
+
+		ClientIPSigningSecret: []byte(viper.GetString("CLIENT_IP_SIGNING_SECRET")),
+	}
+	if len(cfg.ClientIPSigningSecret) == 0 {
+		slog.Warn("CLIENT_IP_SIGNING_SECRET is not set, ignoring client IPs forwarded by the gateway")
 	}
 	return cfg
 }
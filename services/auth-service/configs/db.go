@@ -1,32 +1,23 @@
 package configs
 
 import (
-	"log"
+	"log/slog"
+	"music-player/api/pgclient"
 
 	"github.com/spf13/viper"
 )
 
-// DBConfig holds PostgreSQL database configuration.
-type DBConfig struct {
-	Host     string // Database host
-	Port     string // Database port
-	User     string // Database user
-	Password string // Database password
-	DBName   string // Database name
-}
-
-// LoadDBConfig loads PostgreSQL configuration using viper.
-func LoadDBConfig() *DBConfig {
-	cfg := &DBConfig{
+// LoadDBConfig reads the POSTGRES_* connection settings.
+func LoadDBConfig() *pgclient.Config {
+	cfg := &pgclient.Config{
 		Host:     viper.GetString("POSTGRES_HOST"),
 		Port:     viper.GetString("POSTGRES_PORT"),
 		User:     viper.GetString("POSTGRES_USER"),
 		Password: viper.GetString("POSTGRES_PASSWORD"),
 		DBName:   viper.GetString("POSTGRES_DB"),
 	}
-	// Optionally log warning if any field is empty
-	if cfg.Host == "" || cfg.Port == "" || cfg.User == "" || cfg.Password == "" || cfg.DBName == "" {
-		log.Printf("[WARN] Some database config fields are empty. Please check your environment variables or .env file.")
+	if missing := cfg.Missing(); len(missing) > 0 {
+		slog.Warn("Database config is incomplete. Please check your environment variables or .env file", "missing", missing)
 	}
 	return cfg
 }
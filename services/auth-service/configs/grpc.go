@@ -6,16 +6,21 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 )
 
 type GRPCServer struct {
 	server   *grpc.Server
+	health   *grpchealth.Server
 	listener net.Listener
 }
 
-func NewGRPCServer(port string) (*GRPCServer, error) {
+// NewGRPCServer creates a gRPC server listening on port. Extra options, such as
+// interceptor chains, are appended to the default keepalive and size limits.
+func NewGRPCServer(port string, extraOpts ...grpc.ServerOption) (*GRPCServer, error) {
 	addr := fmt.Sprintf(":%s", port)
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -37,11 +42,15 @@ func NewGRPCServer(port string) (*GRPCServer, error) {
 		grpc.MaxRecvMsgSize(4 * 1024 * 1024),
 		grpc.MaxSendMsgSize(4 * 1024 * 1024),
 	}
+	opts = append(opts, extraOpts...)
 
 	s := grpc.NewServer(opts...)
 	reflection.Register(s)
 
-	return &GRPCServer{server: s, listener: lis}, nil
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+
+	return &GRPCServer{server: s, health: hs, listener: lis}, nil
 }
 
 // GetServer returns the underlying gRPC server
@@ -49,6 +58,11 @@ func (s *GRPCServer) GetServer() *grpc.Server {
 	return s.server
 }
 
+// GetHealthServer returns the grpc.health.v1 server registered on the gRPC server
+func (s *GRPCServer) GetHealthServer() *grpchealth.Server {
+	return s.health
+}
+
 // Start starts the gRPC server
 func (s *GRPCServer) Start() error {
 	return s.server.Serve(s.listener)
@@ -56,6 +70,7 @@ func (s *GRPCServer) Start() error {
 
 // Stop gracefully stops the gRPC server
 func (s *GRPCServer) Stop() {
+	s.health.Shutdown()
 	s.server.GracefulStop()
 }
 
@@ -1,7 +1,7 @@
 package configs
 
 import (
-	"log"
+	"log/slog"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -32,7 +32,7 @@ func LoadKafkaConfig() *KafkaConfig {
 	}
 
 	if len(cfg.Brokers) == 0 {
-		log.Println("[WARN] KAFKA_BROKERS not set, defaulting to localhost:9092")
+		slog.Warn("KAFKA_BROKERS not set, defaulting to localhost:9092")
 	}
 	return cfg
 }
@@ -1,30 +1,22 @@
 package configs
 
 import (
-	"log"
+	"log/slog"
+	"music-player/api/redisclient"
 
 	"github.com/spf13/viper"
 )
 
-// RedisConfig holds Redis configuration.
-type RedisConfig struct {
-	Host     string
-	Port     string
-	Password string
-	Username string 
-}
-
-// LoadRedisConfig loads Redis configuration using viper.
-func LoadRedisConfig() *RedisConfig {
-	cfg := &RedisConfig{
+// LoadRedisConfig reads the REDIS_* connection settings.
+func LoadRedisConfig() *redisclient.Config {
+	cfg := &redisclient.Config{
 		Host:     viper.GetString("REDIS_HOST"),
 		Port:     viper.GetString("REDIS_PORT"),
-		Password: viper.GetString("REDIS_PASSWORD"),
 		Username: viper.GetString("REDIS_USERNAME"),
+		Password: viper.GetString("REDIS_PASSWORD"),
 	}
-	// Optionally log warning if any field is empty
 	if cfg.Host == "" || cfg.Port == "" {
-		log.Printf("[WARN] Some Redis config fields are empty. Please check your environment variables or .env file.")
+		slog.Warn("Some Redis config fields are empty. Please check your environment variables or .env file")
 	}
 	return cfg
 }
@@ -0,0 +1,36 @@
+package configs
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// TwoFAConfig configures the codes texted for SMS second factor.
+type TwoFAConfig struct {
+	// SMSCodeTTL is how long a texted code stays valid.
+	SMSCodeTTL time.Duration
+	// SMSResendInterval is the least time between two codes to a user.
+	SMSResendInterval time.Duration
+	// SMSMaxAttempts is the number of wrong guesses after which a code is
+	// dropped and a new one must be requested.
+	SMSMaxAttempts int
+	// SMSCodeSecret keys the HMAC under which texted codes are stored. It
+	// is required outside development.
+	SMSCodeSecret []byte
+	Development   bool
+}
+
+func LoadTwoFAConfig() *TwoFAConfig {
+	viper.SetDefault("SMS_CODE_TTL", "5m")
+	viper.SetDefault("SMS_CODE_RESEND_INTERVAL", "60s")
+	viper.SetDefault("SMS_CODE_MAX_ATTEMPTS", 5)
+
+	return &TwoFAConfig{
+		SMSCodeTTL:        viper.GetDuration("SMS_CODE_TTL"),
+		SMSResendInterval: viper.GetDuration("SMS_CODE_RESEND_INTERVAL"),
+		SMSMaxAttempts:    viper.GetInt("SMS_CODE_MAX_ATTEMPTS"),
+		SMSCodeSecret:     []byte(viper.GetString("SMS_CODE_SECRET")),
+		Development:       viper.GetString("APP_ENV") == "development",
+	}
+}
@@ -22,4 +22,9 @@ var (
 	ErrTwoFASetupExpired  = &DomainError{"2FA_SETUP_EXPIRED", "2FA setup expired, please restart", http.StatusBadRequest}
 	ErrInvalidCredentials = &DomainError{"INVALID_CREDENTIALS", "invalid email or password", http.StatusUnauthorized}
 	ErrNoUsersFound       = &DomainError{"NO_USERS_FOUND", "no users found", http.StatusNotFound}
+	ErrUnsupportedLocale  = &DomainError{"UNSUPPORTED_LOCALE", "unsupported locale", http.StatusBadRequest}
+	ErrEmailChange        = &DomainError{"EMAIL_CHANGE_NOT_SUPPORTED", "changing the email address is not supported", http.StatusBadRequest}
+	ErrInvalidPhoneNumber = &DomainError{"INVALID_PHONE_NUMBER", "phone number must be in international format, e.g. +84912345678", http.StatusBadRequest}
+	ErrTwoFACodeCooldown  = &DomainError{"2FA_CODE_COOLDOWN", "a code was just sent, please wait before asking for another", http.StatusTooManyRequests}
+	ErrUnknownTwoFAMethod = &DomainError{"UNKNOWN_2FA_METHOD", "2FA method must be totp or sms", http.StatusBadRequest}
 )
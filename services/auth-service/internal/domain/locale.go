@@ -0,0 +1,13 @@
+package domain
+
+// Locales users can pick for their notifications. Vietnamese is the default
+// for new accounts; notification-service falls back to English for messages
+// without a Vietnamese translation.
+const (
+	LocaleVietnamese = "vi"
+	LocaleEnglish    = "en"
+	DefaultLocale    = LocaleVietnamese
+)
+
+// SupportedLocales are matched against request locales with locale.Match.
+var SupportedLocales = []string{LocaleVietnamese, LocaleEnglish}
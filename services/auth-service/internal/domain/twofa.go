@@ -0,0 +1,19 @@
+package domain
+
+// Second factors. A user may enable either or both; VerifyTwoFA checks a
+// code against the one the client names.
+const (
+	TwoFAMethodTOTP = "totp"
+	TwoFAMethodSMS  = "sms"
+)
+
+// ParseTwoFAMethod returns the method s names, TOTP when s is empty.
+func ParseTwoFAMethod(s string) (string, error) {
+	switch s {
+	case "", TwoFAMethodTOTP:
+		return TwoFAMethodTOTP, nil
+	case TwoFAMethodSMS:
+		return TwoFAMethodSMS, nil
+	}
+	return "", ErrUnknownTwoFAMethod
+}
@@ -14,7 +14,12 @@ type User struct {
 	Avatar       string  `json:"avatar"`
 	TwoFAEnabled bool    `json:"twoFAEnabled" gorm:"not null;default:false"`
 	TwoFASecret  string  `json:"twoFASecret" gorm:"size:128"`
+	Locale       string  `json:"locale" gorm:"size:16;not null;default:vi"`
 	LastLoginAt  *string `json:"lastLoginAt" gorm:"type:timestamp"`
+	// PhoneNumber is the E.164 number sign-in codes are texted to, set
+	// once the user confirmed it with a code.
+	PhoneNumber     string `json:"phoneNumber" gorm:"size:16"`
+	SMSTwoFAEnabled bool   `json:"smsTwoFAEnabled" gorm:"column:sms_two_fa_enabled;not null;default:false"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -25,6 +30,9 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 		}
 		u.Password = string(hashedPassword)
 	}
+	if u.Locale == "" {
+		u.Locale = DefaultLocale
+	}
 	u.BaseModel = NewBaseModel()
 	return nil
 }
@@ -6,6 +6,15 @@ type UserCreateRequest struct {
 	Password        string `json:"password" binding:"required,min=6,max=64"`
 	ConfirmPassword string `json:"confirmPassword" binding:"required,eqfield=Password"`
 	FullName        string `json:"fullName" binding:"omitempty,max=64"`
+	// Locale is a language tag or Accept-Language value. Unsupported values
+	// fall back to the default locale.
+	Locale string `json:"locale" binding:"omitempty,max=64"`
+}
+
+// UserUpdateRequest changes the fields that are set and leaves the rest alone.
+type UserUpdateRequest struct {
+	FullName *string `json:"fullName" binding:"omitempty,max=64"`
+	Locale   *string `json:"locale" binding:"omitempty,max=16"`
 }
 
 type UserLoginRequest struct {
@@ -19,6 +28,7 @@ type UserRegisterResponse struct {
 	Email     string `json:"email"`
 	FullName  string `json:"fullName"`
 	Avatar    string `json:"avatar"`
+	Locale    string `json:"locale"`
 	CreatedAt string `json:"createdAt"`
 }
 
@@ -28,10 +38,12 @@ type UserLoginResponse struct {
 }
 
 type UserResponse struct {
-	ID           string `json:"id"`
-	Username     string `json:"username"`
-	Email        string `json:"email"`
-	FullName     string `json:"fullName"`
-	TwoFAEnabled bool   `json:"twoFaEnabled"`
-	CreatedAt    string `json:"createdAt"`
+	ID              string `json:"id"`
+	Username        string `json:"username"`
+	Email           string `json:"email"`
+	FullName        string `json:"fullName"`
+	TwoFAEnabled    bool   `json:"twoFaEnabled"`
+	SMSTwoFAEnabled bool   `json:"smsTwoFaEnabled"`
+	Locale          string `json:"locale"`
+	CreatedAt       string `json:"createdAt"`
 }
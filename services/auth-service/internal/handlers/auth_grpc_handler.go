@@ -5,8 +5,12 @@ import (
 	"auth-service/internal/dto"
 	"auth-service/internal/services"
 	tokenmanager "auth-service/internal/services/TokenManager"
+	"auth-service/internal/utils/jwt"
 	"context"
+	"music-player/api/clientip"
+	"music-player/api/phone"
 	authv1 "music-player/api/proto/auth/v1"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc/metadata"
@@ -16,15 +20,18 @@ type AuthGRPCHandler struct {
 	authv1.UnimplementedAuthServiceServer
 	userService  services.UserService
 	twoFAService services.TwoFAService
+	jwtCfg       *jwt.JWTConfig
 }
 
 func NewAuthGRPCHandler(
 	userService services.UserService,
 	twoFAService services.TwoFAService,
+	jwtCfg *jwt.JWTConfig,
 ) *AuthGRPCHandler {
 	return &AuthGRPCHandler{
 		userService:  userService,
 		twoFAService: twoFAService,
+		jwtCfg:       jwtCfg,
 	}
 }
 
@@ -36,13 +43,13 @@ func (h *AuthGRPCHandler) Login(ctx context.Context, req *authv1.LoginRequest) (
 		}, nil
 	}
 
-	clientIP := "unknown"
+	clientIP := clientip.FromContext(ctx)
+	if clientIP == "" {
+		clientIP = "unknown"
+	}
 	userAgent := "unknown"
 
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if ips := md.Get("x-client-ip"); len(ips) > 0 {
-			clientIP = ips[0]
-		}
 		if uas := md.Get("x-user-agent"); len(uas) > 0 {
 			userAgent = uas[0]
 		}
@@ -71,18 +78,22 @@ func (h *AuthGRPCHandler) Login(ctx context.Context, req *authv1.LoginRequest) (
 	}
 
 	return &authv1.LoginResponse{
-		Success:      true,
-		Message:      "Login successful",
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    3600,
+		Success:          true,
+		Message:          "Login successful",
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int64(h.jwtCfg.AccessTTL.Seconds()),
+		RefreshExpiresIn: int64(h.jwtCfg.RefreshTTL.Seconds()),
 		User: &authv1.User{
-			Id:           user.ID,
-			Username:     user.Username,
-			Email:        user.Email,
-			FullName:     user.FullName,
-			TwoFaEnabled: user.TwoFAEnabled,
-			CreatedAt:    user.CreatedAt.Format(time.RFC3339),
+			Id:              user.ID,
+			Username:        user.Username,
+			Email:           user.Email,
+			FullName:        user.FullName,
+			TwoFaEnabled:    user.TwoFAEnabled,
+			SmsTwoFaEnabled: user.SMSTwoFAEnabled,
+			PhoneNumber:     maskedPhone(user.PhoneNumber),
+			Locale:          user.Locale,
+			CreatedAt:       user.CreatedAt.Format(time.RFC3339),
 		},
 	}, nil
 }
@@ -101,6 +112,7 @@ func (h *AuthGRPCHandler) Register(ctx context.Context, req *authv1.RegisterRequ
 		Password:        req.Password,
 		ConfirmPassword: req.Password,
 		FullName:        req.FullName,
+		Locale:          req.Locale,
 	}
 
 	createdUser, err := h.userService.Register(ctx, registerReq)
@@ -146,10 +158,14 @@ func (h *AuthGRPCHandler) GetUserProfile(ctx context.Context, req *authv1.GetUse
 		Success: true,
 		Message: "User profile retrieved successfully",
 		User: &authv1.User{
-			Id:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			FullName: user.FullName,
+			Id:              user.ID,
+			Username:        user.Username,
+			Email:           user.Email,
+			FullName:        user.FullName,
+			TwoFaEnabled:    user.TwoFAEnabled,
+			SmsTwoFaEnabled: user.SMSTwoFAEnabled,
+			PhoneNumber:     maskedPhone(user.PhoneNumber),
+			Locale:          user.Locale,
 		},
 	}, nil
 }
@@ -177,14 +193,14 @@ func (h *AuthGRPCHandler) ValidateToken(ctx context.Context, req *authv1.Validat
 
 // RefreshToken handles token refresh
 func (h *AuthGRPCHandler) RefreshToken(ctx context.Context, req *authv1.RefreshTokenRequest) (*authv1.RefreshTokenResponse, error) {
-	clientIP := "unknown"
+	clientIP := clientip.FromContext(ctx)
+	if clientIP == "" {
+		clientIP = "unknown"
+	}
 	userAgent := "unknown"
 	refreshToken := ""
 
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if ips := md.Get("x-client-ip"); len(ips) > 0 {
-			clientIP = ips[0]
-		}
 		if uas := md.Get("x-user-agent"); len(uas) > 0 {
 			userAgent = uas[0]
 		}
@@ -218,11 +234,12 @@ func (h *AuthGRPCHandler) RefreshToken(ctx context.Context, req *authv1.RefreshT
 	}
 
 	return &authv1.RefreshTokenResponse{
-		Success:      true,
-		Message:      "Token refreshed successfully",
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
-		ExpiresIn:    3600,
+		Success:          true,
+		Message:          "Token refreshed successfully",
+		AccessToken:      accessToken,
+		RefreshToken:     newRefreshToken,
+		ExpiresIn:        int64(h.jwtCfg.AccessTTL.Seconds()),
+		RefreshExpiresIn: int64(h.jwtCfg.RefreshTTL.Seconds()),
 	}, nil
 }
 
@@ -326,7 +343,7 @@ func (h *AuthGRPCHandler) DisableTwoFA(ctx context.Context, req *authv1.DisableT
 		}, nil
 	}
 
-	err := h.twoFAService.Disable2FA(ctx, req.UserId, req.Code)
+	err := h.twoFAService.Disable2FA(ctx, req.UserId, req.Method, req.Code)
 	if err != nil {
 		if derr, ok := err.(*domain.DomainError); ok {
 			return &authv1.DisableTwoFAResponse{
@@ -353,7 +370,7 @@ func (h *AuthGRPCHandler) VerifyTwoFA(ctx context.Context, req *authv1.VerifyTwo
 			Message: "User ID and code are required",
 		}, nil
 	}
-	err := h.twoFAService.Verify2FA(ctx, req.UserId, req.Code)
+	err := h.twoFAService.Verify2FA(ctx, req.UserId, req.Method, req.Code)
 	if err != nil {
 		if derr, ok := err.(*domain.DomainError); ok {
 			return &authv1.VerifyTwoFAResponse{
@@ -372,18 +389,146 @@ func (h *AuthGRPCHandler) VerifyTwoFA(ctx context.Context, req *authv1.VerifyTwo
 	}, nil
 }
 
-// UpdateUserProfile updates user profile information
+// SetupSMSTwoFA texts a code confirming the phone number to be used as
+// second factor
+func (h *AuthGRPCHandler) SetupSMSTwoFA(ctx context.Context, req *authv1.SetupSMSTwoFARequest) (*authv1.SetupSMSTwoFAResponse, error) {
+	if req.UserId == "" || req.PhoneNumber == "" {
+		return &authv1.SetupSMSTwoFAResponse{
+			Success: false,
+			Message: "User ID and phone number are required",
+		}, nil
+	}
+	sent, err := h.twoFAService.SetupSMS2FA(ctx, req.UserId, req.PhoneNumber)
+	if err != nil {
+		return &authv1.SetupSMSTwoFAResponse{
+			Success: false,
+			Message: twoFAFailureMessage(err),
+		}, nil
+	}
+	return &authv1.SetupSMSTwoFAResponse{
+		Success:     true,
+		Message:     "Verification code sent",
+		PhoneNumber: sent.PhoneNumber,
+		ExpiresIn:   int64(sent.ExpiresIn.Seconds()),
+	}, nil
+}
+
+// EnableSMSTwoFA makes the confirmed phone number the user's second factor
+func (h *AuthGRPCHandler) EnableSMSTwoFA(ctx context.Context, req *authv1.EnableSMSTwoFARequest) (*authv1.EnableSMSTwoFAResponse, error) {
+	if req.UserId == "" || req.Code == "" {
+		return &authv1.EnableSMSTwoFAResponse{
+			Success: false,
+			Message: "User ID and code are required",
+		}, nil
+	}
+	if err := h.twoFAService.EnableSMS2FA(ctx, req.UserId, req.Code); err != nil {
+		return &authv1.EnableSMSTwoFAResponse{
+			Success: false,
+			Message: twoFAFailureMessage(err),
+		}, nil
+	}
+	return &authv1.EnableSMSTwoFAResponse{
+		Success: true,
+		Message: "SMS 2FA enabled successfully",
+	}, nil
+}
+
+// SendTwoFACode texts a sign-in code to the user's verified phone number
+func (h *AuthGRPCHandler) SendTwoFACode(ctx context.Context, req *authv1.SendTwoFACodeRequest) (*authv1.SendTwoFACodeResponse, error) {
+	if req.UserId == "" {
+		return &authv1.SendTwoFACodeResponse{
+			Success: false,
+			Message: "User ID is required",
+		}, nil
+	}
+	sent, err := h.twoFAService.SendSMS2FACode(ctx, req.UserId)
+	if err != nil {
+		return &authv1.SendTwoFACodeResponse{
+			Success: false,
+			Message: twoFAFailureMessage(err),
+		}, nil
+	}
+	return &authv1.SendTwoFACodeResponse{
+		Success:     true,
+		Message:     "2FA code sent",
+		PhoneNumber: sent.PhoneNumber,
+		ExpiresIn:   int64(sent.ExpiresIn.Seconds()),
+	}, nil
+}
+
+func maskedPhone(number string) string {
+	if number == "" {
+		return ""
+	}
+	return phone.Mask(number)
+}
+
+func twoFAFailureMessage(err error) string {
+	if derr, ok := err.(*domain.DomainError); ok {
+		return derr.Message
+	}
+	return "Internal server error"
+}
+
+// UpdateUserProfile updates user profile information. Empty fields are left
+// unchanged; the email address cannot be changed.
 func (h *AuthGRPCHandler) UpdateUserProfile(ctx context.Context, req *authv1.UpdateUserProfileRequest) (*authv1.UpdateUserProfileResponse, error) {
-	// TODO: Implement update user profile using userService
+	if req.UserId == "" {
+		return &authv1.UpdateUserProfileResponse{
+			Success: false,
+			Message: "User ID is required",
+		}, nil
+	}
+
+	update := &dto.UserUpdateRequest{}
+	if req.FullName != "" {
+		update.FullName = &req.FullName
+	}
+	if req.Locale != "" {
+		update.Locale = &req.Locale
+	}
+	if req.Email != "" {
+		current, err := h.userService.GetMe(ctx, req.UserId)
+		if err == nil && !strings.EqualFold(current.Email, req.Email) {
+			err = domain.ErrEmailChange
+		}
+		if err != nil {
+			return updateProfileFailure(err), nil
+		}
+	}
+
+	user, err := h.userService.UpdateProfile(ctx, req.UserId, update)
+	if err != nil {
+		return updateProfileFailure(err), nil
+	}
 
 	return &authv1.UpdateUserProfileResponse{
 		Success: true,
 		Message: "User profile updated successfully",
 		User: &authv1.User{
-			Id:       req.UserId,
-			Username: "username",
-			Email:    req.Email,
-			FullName: req.FullName,
+			Id:              user.ID,
+			Username:        user.Username,
+			Email:           user.Email,
+			FullName:        user.FullName,
+			TwoFaEnabled:    user.TwoFAEnabled,
+			SmsTwoFaEnabled: user.SMSTwoFAEnabled,
+			PhoneNumber:     maskedPhone(user.PhoneNumber),
+			Locale:          user.Locale,
+			CreatedAt:       user.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:       user.UpdatedAt.Format(time.RFC3339),
 		},
 	}, nil
 }
+
+func updateProfileFailure(err error) *authv1.UpdateUserProfileResponse {
+	if derr, ok := err.(*domain.DomainError); ok {
+		return &authv1.UpdateUserProfileResponse{
+			Success: false,
+			Message: derr.Message,
+		}
+	}
+	return &authv1.UpdateUserProfileResponse{
+		Success: false,
+		Message: "Internal server error",
+	}
+}
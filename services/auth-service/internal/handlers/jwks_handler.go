@@ -3,6 +3,7 @@ package handlers
 import (
 	"auth-service/internal/utils/jwt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,7 +19,7 @@ func NewJWKSHandler(jwtService jwt.JWTService) *JWKSHandler {
 }
 
 func (h *JWKSHandler) GetJWKS(c *gin.Context) {
-	jwks, err := h.jwtService.GetJWKS()
+	doc, err := h.jwtService.GetJWKSDocument()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Unable to retrieve JWKS",
@@ -26,7 +27,34 @@ func (h *JWKSHandler) GetJWKS(c *gin.Context) {
 		return
 	}
 
-	c.Header("Content-Type", "application/json")
 	c.Header("Cache-Control", "public, max-age=3600")
-	c.JSON(http.StatusOK, jwks)
+	c.Header("ETag", doc.ETag)
+	c.Header("Last-Modified", doc.LastModified.Format(http.TimeFormat))
+
+	if notModified(c.Request, doc) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", doc.Body)
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since only
+// when no entity tag was sent (RFC 9110 section 13.2.2).
+func notModified(r *http.Request, doc *jwt.JWKSDocument) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == doc.ETag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !doc.LastModified.After(t)
+	}
+	return false
 }
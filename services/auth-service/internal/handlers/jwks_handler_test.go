@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"auth-service/internal/utils/jwt"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeJWKSService struct {
+	jwt.JWTService
+	doc *jwt.JWKSDocument
+	err error
+}
+
+func (f *fakeJWKSService) GetJWKSDocument() (*jwt.JWKSDocument, error) {
+	return f.doc, f.err
+}
+
+func serveJWKS(svc jwt.JWTService, header http.Header) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/.well-known/jwks.json", NewJWKSHandler(svc).GetJWKS)
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	req.Header = header
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetJWKSConditional(t *testing.T) {
+	modified := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	doc := &jwt.JWKSDocument{Body: []byte(`{"keys":[]}`), ETag: `"abc"`, LastModified: modified}
+	svc := &fakeJWKSService{doc: doc}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"unconditional", http.Header{}, http.StatusOK},
+		{"matching ETag", http.Header{"If-None-Match": {`"abc"`}}, http.StatusNotModified},
+		{"weak matching ETag in a list", http.Header{"If-None-Match": {`"old", W/"abc"`}}, http.StatusNotModified},
+		{"wildcard", http.Header{"If-None-Match": {"*"}}, http.StatusNotModified},
+		{"stale ETag", http.Header{"If-None-Match": {`"old"`}}, http.StatusOK},
+		{"not modified since", http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}}, http.StatusNotModified},
+		{"later since", http.Header{"If-Modified-Since": {modified.Add(time.Hour).Format(http.TimeFormat)}}, http.StatusNotModified},
+		{"modified since", http.Header{"If-Modified-Since": {modified.Add(-time.Second).Format(http.TimeFormat)}}, http.StatusOK},
+		{"bad date", http.Header{"If-Modified-Since": {"yesterday"}}, http.StatusOK},
+		// A stale ETag wins over a fresh date.
+		{"stale ETag with a fresh date", http.Header{"If-None-Match": {`"old"`}, "If-Modified-Since": {modified.Format(http.TimeFormat)}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := serveJWKS(svc, tt.header)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, w.Code, tt.want)
+		}
+		if got := w.Header().Get("ETag"); got != doc.ETag {
+			t.Errorf("%s: ETag = %q; want %q", tt.name, got, doc.ETag)
+		}
+		if got := w.Header().Get("Last-Modified"); got != modified.Format(http.TimeFormat) {
+			t.Errorf("%s: Last-Modified = %q", tt.name, got)
+		}
+		switch {
+		case w.Code == http.StatusOK && w.Body.String() != string(doc.Body):
+			t.Errorf("%s: body = %s; want the document", tt.name, w.Body)
+		case w.Code == http.StatusNotModified && w.Body.Len() != 0:
+			t.Errorf("%s: 304 with a body", tt.name)
+		}
+	}
+}
+
+func TestGetJWKSUnavailable(t *testing.T) {
+	w := serveJWKS(&fakeJWKSService{err: errors.New("JWKS file not loaded")}, http.Header{})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d; want 500", w.Code)
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Error("error response carries an ETag")
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"auth-service/internal/utils/jwt"
+	"context"
+	"crypto/subtle"
+	authv1 "music-player/api/proto/auth/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// adminTokenMetadataKey carries the token authorizing signing key administration.
+const adminTokenMetadataKey = "x-admin-token"
+
+func (h *AuthGRPCHandler) ListSigningKeys(ctx context.Context, req *authv1.ListSigningKeysRequest) (*authv1.ListSigningKeysResponse, error) {
+	if err := h.authorizeKeyAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return &authv1.ListSigningKeysResponse{
+		Keys: toProtoSigningKeys(h.jwtCfg.Keys.Status()),
+	}, nil
+}
+
+// ReloadSigningKeys re-reads the keys directory. An invalid directory leaves
+// the current key set in place and is reported in the response.
+func (h *AuthGRPCHandler) ReloadSigningKeys(ctx context.Context, req *authv1.ReloadSigningKeysRequest) (*authv1.ReloadSigningKeysResponse, error) {
+	if err := h.authorizeKeyAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if !h.jwtCfg.Keys.Managed() {
+		return nil, status.Error(codes.FailedPrecondition, "JWT_KEYS_DIR is not configured")
+	}
+
+	if err := h.jwtCfg.Keys.Reload(); err != nil {
+		return &authv1.ReloadSigningKeysResponse{
+			Success: false,
+			Message: err.Error(),
+			Keys:    toProtoSigningKeys(h.jwtCfg.Keys.Status()),
+		}, nil
+	}
+	return &authv1.ReloadSigningKeysResponse{
+		Success: true,
+		Message: "Signing keys reloaded",
+		Keys:    toProtoSigningKeys(h.jwtCfg.Keys.Status()),
+	}, nil
+}
+
+func (h *AuthGRPCHandler) authorizeKeyAdmin(ctx context.Context) error {
+	if h.jwtCfg.KeysAdminToken == "" {
+		return status.Error(codes.PermissionDenied, "signing key administration is disabled")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens := md.Get(adminTokenMetadataKey)
+	if len(tokens) == 0 || subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(h.jwtCfg.KeysAdminToken)) != 1 {
+		return status.Error(codes.PermissionDenied, "invalid admin token")
+	}
+	return nil
+}
+
+func toProtoSigningKeys(keys []jwt.KeyStatus) []*authv1.SigningKey {
+	out := make([]*authv1.SigningKey, 0, len(keys))
+	for _, k := range keys {
+		pk := &authv1.SigningKey{
+			Kid:     k.KID,
+			State:   string(k.State),
+			Signing: k.Signing,
+		}
+		if !k.ActivateAt.IsZero() {
+			pk.ActivateAt = k.ActivateAt.Unix()
+		}
+		if !k.RetireAt.IsZero() {
+			pk.RetireAt = k.RetireAt.Unix()
+		}
+		out = append(out, pk)
+	}
+	return out
+}
@@ -5,6 +5,7 @@ import (
 	"auth-service/internal/services"
 	"auth-service/internal/utils"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -54,13 +55,14 @@ func (h *TwoFAHandler) Enable2FA(c *gin.Context) {
 func (h *TwoFAHandler) Verify2FA(c *gin.Context) {
 	userID := c.Param("id")
 	var req struct {
-		Code string `json:"code"`
+		Code   string `json:"code"`
+		Method string `json:"method"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
 		utils.Fail(c, http.StatusBadRequest, "INVALID_CODE", "Invalid code")
 		return
 	}
-	if err := h.service.Verify2FA(c.Request.Context(), userID, req.Code); err != nil {
+	if err := h.service.Verify2FA(c.Request.Context(), userID, req.Method, req.Code); err != nil {
 		if domainErr, ok := err.(*domain.DomainError); ok {
 			utils.Fail(c, domainErr.Status, domainErr.Code, domainErr.Message)
 		} else {
@@ -74,13 +76,14 @@ func (h *TwoFAHandler) Verify2FA(c *gin.Context) {
 func (h *TwoFAHandler) Disable2FA(c *gin.Context) {
 	userID := c.Param("id")
 	var req struct {
-		Code string `json:"code"`
+		Code   string `json:"code"`
+		Method string `json:"method"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
 		utils.Fail(c, http.StatusBadRequest, "INVALID_CODE", "Invalid code")
 		return
 	}
-	if err := h.service.Disable2FA(c.Request.Context(), userID, req.Code); err != nil {
+	if err := h.service.Disable2FA(c.Request.Context(), userID, req.Method, req.Code); err != nil {
 		if domainErr, ok := err.(*domain.DomainError); ok {
 			utils.Fail(c, domainErr.Status, domainErr.Code, domainErr.Message)
 		} else {
@@ -90,3 +93,54 @@ func (h *TwoFAHandler) Disable2FA(c *gin.Context) {
 	}
 	utils.Success(c, http.StatusOK, gin.H{"message": "2FA disabled"})
 }
+
+func (h *TwoFAHandler) SetupSMS2FA(c *gin.Context) {
+	userID := c.Param("id")
+	var req struct {
+		PhoneNumber string `json:"phone_number"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.PhoneNumber == "" {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_PHONE_NUMBER", "Phone number is required")
+		return
+	}
+	sent, err := h.service.SetupSMS2FA(c.Request.Context(), userID, req.PhoneNumber)
+	if err != nil {
+		h.failSMS(c, err, "SETUP_SMS_2FA_FAILED")
+		return
+	}
+	utils.Success(c, http.StatusOK, gin.H{"phone_number": sent.PhoneNumber, "expires_in": int64(sent.ExpiresIn / time.Second)})
+}
+
+func (h *TwoFAHandler) EnableSMS2FA(c *gin.Context) {
+	userID := c.Param("id")
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_CODE", "Invalid code")
+		return
+	}
+	if err := h.service.EnableSMS2FA(c.Request.Context(), userID, req.Code); err != nil {
+		h.failSMS(c, err, "ENABLE_SMS_2FA_FAILED")
+		return
+	}
+	utils.Success(c, http.StatusOK, gin.H{"message": "SMS 2FA enabled"})
+}
+
+func (h *TwoFAHandler) SendSMS2FACode(c *gin.Context) {
+	userID := c.Param("id")
+	sent, err := h.service.SendSMS2FACode(c.Request.Context(), userID)
+	if err != nil {
+		h.failSMS(c, err, "SEND_2FA_CODE_FAILED")
+		return
+	}
+	utils.Success(c, http.StatusOK, gin.H{"phone_number": sent.PhoneNumber, "expires_in": int64(sent.ExpiresIn / time.Second)})
+}
+
+func (h *TwoFAHandler) failSMS(c *gin.Context, err error, code string) {
+	if derr, ok := err.(*domain.DomainError); ok {
+		utils.Fail(c, derr.Status, derr.Code, derr.Message)
+		return
+	}
+	utils.Fail(c, http.StatusInternalServerError, code, "Internal server error")
+}
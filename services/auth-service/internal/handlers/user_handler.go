@@ -40,6 +40,7 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 			Username:  user.Username,
 			Email:     user.Email,
 			FullName:  user.FullName,
+			Locale:    user.Locale,
 			CreatedAt: user.CreatedAt.Format(time.RFC3339),
 		})
 	}
@@ -64,6 +65,7 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 		Username:  user.Username,
 		Email:     user.Email,
 		FullName:  user.FullName,
+		Locale:    user.Locale,
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
 	}
 	utils.Success(c, http.StatusOK, resp)
@@ -86,12 +88,49 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 	}
 
 	resp := dto.UserResponse{
-		ID:           user.ID,
-		Username:     user.Username,
-		Email:        user.Email,
-		FullName:     user.FullName,
-		TwoFAEnabled: user.TwoFAEnabled,
-		CreatedAt:    user.CreatedAt.Format(time.RFC3339),
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		FullName:        user.FullName,
+		TwoFAEnabled:    user.TwoFAEnabled,
+		SMSTwoFAEnabled: user.SMSTwoFAEnabled,
+		Locale:          user.Locale,
+		CreatedAt:       user.CreatedAt.Format(time.RFC3339),
+	}
+	utils.Success(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	userID, ok := middleware.MustGetUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	user, err := h.service.UpdateProfile(c.Request.Context(), userID, &req)
+	if err != nil {
+		if derr, ok := err.(*domain.DomainError); ok {
+			utils.Fail(c, derr.Status, derr.Code, derr.Message)
+			return
+		}
+		utils.Fail(c, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	resp := dto.UserResponse{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		FullName:        user.FullName,
+		TwoFAEnabled:    user.TwoFAEnabled,
+		SMSTwoFAEnabled: user.SMSTwoFAEnabled,
+		Locale:          user.Locale,
+		CreatedAt:       user.CreatedAt.Format(time.RFC3339),
 	}
 	utils.Success(c, http.StatusOK, resp)
 }
@@ -118,6 +157,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		Username:  createdUser.Username,
 		Email:     createdUser.Email,
 		FullName:  createdUser.FullName,
+		Locale:    createdUser.Locale,
 		CreatedAt: createdUser.CreatedAt.Format(time.RFC3339),
 	}
 	utils.Success(c, http.StatusCreated, resp)
@@ -155,12 +195,14 @@ func (h *UserHandler) Login(c *gin.Context) {
 
 	resp := dto.UserLoginResponse{
 		User: dto.UserResponse{
-			ID:           user.ID,
-			Username:     user.Username,
-			Email:        user.Email,
-			FullName:     user.FullName,
-			TwoFAEnabled: user.TwoFAEnabled,
-			CreatedAt:    user.CreatedAt.Format(time.RFC3339),
+			ID:              user.ID,
+			Username:        user.Username,
+			Email:           user.Email,
+			FullName:        user.FullName,
+			TwoFAEnabled:    user.TwoFAEnabled,
+			SMSTwoFAEnabled: user.SMSTwoFAEnabled,
+			Locale:          user.Locale,
+			CreatedAt:       user.CreatedAt.Format(time.RFC3339),
 		},
 		AccessToken: accessToken,
 	}
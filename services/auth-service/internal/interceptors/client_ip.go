@@ -0,0 +1,55 @@
+package interceptors
+
+import (
+	"context"
+	"music-player/api/clientip"
+	"net/netip"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ClientIPUnary accepts the client address forwarded by the gateway only with
+// a valid signature under secret and stores it in the context for
+// clientip.FromContext. Without a secret the metadata is ignored, since any
+// caller could set it.
+func ClientIPUnary(secret []byte) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withClientIP(ctx, info.FullMethod, secret), req)
+	}
+}
+
+// ClientIPStream is the streaming counterpart of ClientIPUnary.
+func ClientIPStream(secret []byte) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withClientIP(ss.Context(), info.FullMethod, secret)
+		return handler(srv, wrapStream(ss, ctx))
+	}
+}
+
+func withClientIP(ctx context.Context, method string, secret []byte) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(secret) == 0 {
+		return ctx
+	}
+	ips := md.Get(clientip.MetadataKey)
+	if len(ips) != 1 {
+		return ctx
+	}
+	ip := ips[0]
+	if _, err := netip.ParseAddr(ip); err != nil {
+		lg.WarnContext(ctx, "Ignoring malformed client IP metadata", "method", method)
+		return ctx
+	}
+
+	sig := ""
+	if sigs := md.Get(clientip.SignatureMetadataKey); len(sigs) == 1 {
+		sig = sigs[0]
+	}
+	if err := clientip.Verify(secret, ip, method, sig, time.Now()); err != nil {
+		lg.WarnContext(ctx, "Ignoring unverified client IP metadata", "method", method, "error", err)
+		return ctx
+	}
+	return clientip.NewContext(ctx, ip)
+}
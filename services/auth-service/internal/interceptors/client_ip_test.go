@@ -0,0 +1,38 @@
+package interceptors
+
+import (
+	"context"
+	"music-player/api/clientip"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestWithClientIP(t *testing.T) {
+	secret := []byte("secret")
+	const method = "/auth.AuthService/Login"
+	valid := clientip.Sign(secret, "192.0.2.1", method, time.Now())
+
+	tests := []struct {
+		name   string
+		secret []byte
+		md     metadata.MD
+		want   string
+	}{
+		{"signed", secret, metadata.Pairs(clientip.MetadataKey, "192.0.2.1", clientip.SignatureMetadataKey, valid), "192.0.2.1"},
+		{"unsigned", secret, metadata.Pairs(clientip.MetadataKey, "192.0.2.1"), ""},
+		{"bad signature", secret, metadata.Pairs(clientip.MetadataKey, "192.0.2.2", clientip.SignatureMetadataKey, valid), ""},
+		{"no secret ignores metadata", nil, metadata.Pairs(clientip.MetadataKey, "192.0.2.1", clientip.SignatureMetadataKey, valid), ""},
+		{"malformed address", secret, metadata.Pairs(clientip.MetadataKey, "not-an-ip"), ""},
+		{"repeated address", secret, metadata.Pairs(clientip.MetadataKey, "192.0.2.1", clientip.MetadataKey, "192.0.2.2"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			if got := clientip.FromContext(withClientIP(ctx, method, tt.secret)); got != tt.want {
+				t.Errorf("client IP = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package interceptors
+
+import (
+	"auth-service/internal/metrics"
+	"context"
+	"music-player/api/logger"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+var lg = logger.For("interceptors")
+
+// DefaultDeadline is applied to incoming RPCs whose caller did not set a deadline.
+const DefaultDeadline = 10 * time.Second
+
+// ServerOptions returns the interceptor chain installed on the gRPC server.
+// Order matters: the request ID is resolved first so every later stage can
+// log it, and recovery sits inside logging so a panic is logged as Internal.
+// The OpenTelemetry stats handler runs before every interceptor and continues
+// the trace carried in the gateway's traceparent metadata.
+func ServerOptions(defaultDeadline time.Duration, clientIPSecret []byte) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			RequestIDUnary(),
+			ClientIPUnary(clientIPSecret),
+			LoggingUnary(),
+			metrics.UnaryServerInterceptor(),
+			RecoveryUnary(),
+			DeadlineUnary(defaultDeadline),
+		),
+		grpc.ChainStreamInterceptor(
+			RequestIDStream(),
+			ClientIPStream(clientIPSecret),
+			LoggingStream(),
+			metrics.StreamServerInterceptor(),
+			RecoveryStream(),
+			DeadlineStream(defaultDeadline),
+		),
+	}
+}
+
+// wrappedStream overrides the context of a grpc.ServerStream.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
+func wrapStream(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &wrappedStream{ServerStream: ss, ctx: ctx}
+}
@@ -4,13 +4,15 @@ import (
 	"auth-service/configs"
 	"context"
 	"fmt"
-	"log"
-	"os"
+	"music-player/api/logger"
+	"music-player/api/metrics"
 	"time"
 
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
+var lg = logger.For("kafka.consumer")
+
 type Consumer struct {
 	cl *kgo.Client
 }
@@ -24,9 +26,10 @@ func NewConsumer(cfg *configs.KafkaConfig) (*Consumer, error) {
 		kgo.BlockRebalanceOnPoll(),
 		kgo.SessionTimeout(45 * time.Second),
 		kgo.HeartbeatInterval(3 * time.Second),
+		kgo.WithHooks(metrics.KafkaHooks{}),
 	}
 	if cfg.Debug {
-		opts = append(opts, kgo.WithLogger(kgo.BasicLogger(os.Stderr, kgo.LogLevelInfo, nil)))
+		opts = append(opts, kgo.WithLogger(logger.Kgo("kafka.client")))
 	}
 
 	client, err := kgo.NewClient(opts...)
@@ -42,11 +45,16 @@ func NewConsumer(cfg *configs.KafkaConfig) (*Consumer, error) {
 		return nil, fmt.Errorf("[ERROR] Failed to connect to Kafka brokers: %w", err)
 	}
 
-	log.Printf("[INFO] Kafka consumer connected to %v (group: %s)", cfg.Brokers, cfg.GroupID)
+	lg.Info("Kafka consumer connected", "brokers", cfg.Brokers, "group", cfg.GroupID)
 	return &Consumer{cl: client}, nil
 }
 
+// Ping checks connectivity to the Kafka brokers
+func (c *Consumer) Ping(ctx context.Context) error {
+	return c.cl.Ping(ctx)
+}
+
 func (c *Consumer) Close() {
 	c.cl.Close()
-	log.Println("[INFO] Kafka consumer connection closed")
+	lg.Info("Kafka consumer connection closed")
 }
@@ -15,6 +15,9 @@ type Envelope struct {
 	Metadata  *Metadata       `json:"metadata,omitempty"`
 	TraceID   string          `json:"trace_id,omitempty"`
 	SpanID    string          `json:"span_id,omitempty"`
+	// TraceFlags is the producer's W3C trace-flags byte in hex ("01" when
+	// sampled), so consumers keep its sampling decision.
+	TraceFlags string `json:"trace_flags,omitempty"`
 }
 
 type Metadata struct {
@@ -46,3 +49,16 @@ func (p Priority) IsValid() bool {
 func (p Priority) String() string {
 	return string(p)
 }
+
+// SetTrace records the producer's span context; see tracing.StampEnvelope.
+func (e *Envelope) SetTrace(traceID, spanID, flags string) {
+	e.TraceID, e.SpanID, e.TraceFlags = traceID, spanID, flags
+}
+
+// Trace returns the span context stamped by the producer, if any.
+func (e *Envelope) Trace() (traceID, spanID, flags string) {
+	if e == nil {
+		return "", "", ""
+	}
+	return e.TraceID, e.SpanID, e.TraceFlags
+}
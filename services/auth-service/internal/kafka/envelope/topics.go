@@ -17,6 +17,9 @@ const (
 	TopicUserLoggedOut      Topic = "user.logged_out"
 	TopicUserProfileUpdated Topic = "user.profile_updated"
 	TopicUserDeleted        Topic = "user.deleted"
+
+	// TopicSMSSend asks notification-service to text a number.
+	TopicSMSSend Topic = "notification.sms.send"
 )
 
 // External Event Topics - Inbound (Auth Service Consumes)
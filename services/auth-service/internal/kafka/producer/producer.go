@@ -4,12 +4,15 @@ import (
 	"auth-service/configs"
 	"context"
 	"fmt"
-	"log"
+	"music-player/api/logger"
+	"music-player/api/tracing"
 	"time"
 
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
+var lg = logger.For("kafka.producer")
+
 type Producer struct {
 	cl *kgo.Client
 }
@@ -35,7 +38,7 @@ func NewProducerWithProfile(cfg *configs.KafkaConfig, profile ProducerProfile) (
 		return nil, fmt.Errorf("[ERROR] Failed to connect to Kafka brokers: %w", err)
 	}
 
-	log.Printf("[INFO] Kafka producer connected to %v (profile=%s)", cfg.Brokers, profile)
+	lg.Info("Kafka producer connected", "brokers", cfg.Brokers, "profile", string(profile))
 	return &Producer{cl: client}, nil
 }
 
@@ -58,19 +61,20 @@ func (p *Producer) Publish(ctx context.Context, topic string, key string, messag
 
 	var produceErr error
 
+	ctx, span := tracing.StartProducerSpan(ctx, record)
 	p.cl.Produce(ctx, record, func(r *kgo.Record, err error) {
+		tracing.EndProducerSpan(span, r, err)
 		if err != nil {
 			produceErr = fmt.Errorf("failed to produce message: %w", err)
-			log.Printf("❌ [KAFKA] Failed to publish to topic '%s': %v", topic, err)
+			lg.ErrorContext(ctx, "Kafka publish failed", "topic", topic, "error", err)
 			return
 		}
 
-		log.Printf("✅ [KAFKA] Message published successfully")
-		log.Printf("   Topic: %s", r.Topic)
-		log.Printf("   Partition: %d", r.Partition)
-		log.Printf("   Offset: %d", r.Offset)
-		log.Printf("   Key: %s", string(r.Key))
-		log.Printf("   Size: %d bytes", len(r.Value))
+		lg.DebugContext(ctx, "Kafka message published",
+			"topic", r.Topic,
+			"partition", r.Partition,
+			"offset", r.Offset,
+		)
 	})
 
 	if err := p.cl.Flush(ctx); err != nil {
@@ -91,21 +95,33 @@ func (p *Producer) PublishAsync(ctx context.Context, topic string, key string, m
 		},
 	}
 
+	// The span is parented on ctx but the record is produced on a background
+	// context so it is not aborted when the request that triggered it ends.
+	_, span := tracing.StartProducerSpan(ctx, record)
 	bgCtx := context.Background()
 	p.cl.Produce(bgCtx, record, func(r *kgo.Record, err error) {
+		tracing.EndProducerSpan(span, r, err)
 		if err != nil {
-			log.Printf("❌ [KAFKA] Async publish failed to topic '%s': %v", topic, err)
+			lg.Error("Kafka async publish failed", "topic", topic, "error", err)
 			return
 		}
-		log.Printf("✅ [KAFKA] Async message published to %s (partition=%d, offset=%d)",
-			r.Topic, r.Partition, r.Offset)
+		lg.Debug("Kafka async message published",
+			"topic", r.Topic,
+			"partition", r.Partition,
+			"offset", r.Offset,
+		)
 	})
 }
 
+// Ping checks connectivity to the Kafka brokers
+func (p *Producer) Ping(ctx context.Context) error {
+	return p.cl.Ping(ctx)
+}
+
 func (p *Producer) Close() {
 	if p.cl != nil {
-		log.Println("[INFO] Closing Kafka producer...")
+		lg.Info("Closing Kafka producer")
 		p.cl.Close()
-		log.Println("[INFO] Kafka producer connection closed")
+		lg.Info("Kafka producer connection closed")
 	}
 }
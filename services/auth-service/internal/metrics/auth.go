@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var (
+	loginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Login attempts by result.",
+	}, []string{"result"})
+
+	twoFAVerificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_2fa_verifications_total",
+		Help: "2FA code verifications by result.",
+	}, []string{"result"})
+
+	tokenRefreshesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_refreshes_total",
+		Help: "Refresh token exchanges by result.",
+	}, []string{"result"})
+
+	sessionRevocationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "auth_session_revocations_total",
+		Help: "Sessions revoked through logout or administrative revocation.",
+	})
+)
+
+// Result maps an error to the success/failure label value.
+func Result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+func ObserveLogin(err error) {
+	loginsTotal.WithLabelValues(Result(err)).Inc()
+}
+
+func ObserveTwoFAVerification(err error) {
+	twoFAVerificationsTotal.WithLabelValues(Result(err)).Inc()
+}
+
+func ObserveTokenRefresh(err error) {
+	tokenRefreshesTotal.WithLabelValues(Result(err)).Inc()
+}
+
+func ObserveSessionRevocation() {
+	sessionRevocationsTotal.Inc()
+}
@@ -5,6 +5,8 @@ import (
 	customjwt "auth-service/internal/utils/jwt"
 	redisutil "auth-service/internal/utils/redis"
 	"context"
+	"music-player/api/logger"
+	"music-player/api/session"
 	"net/http"
 	"time"
 
@@ -63,7 +65,7 @@ func (mw *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		if !mw.validateSession(c) {
+		if !mw.validateSession(c, claims) {
 			c.Abort()
 			return
 		}
@@ -77,31 +79,27 @@ func (mw *AuthMiddleware) setUserContext(c *gin.Context, claims *customjwt.Acces
 	c.Set(ContextKeyUserID, claims.Subject)
 	c.Set(ContextKeyUserJTI, claims.SID)
 	c.Set(ContextKeyUserData, claims)
+	c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), claims.Subject))
 }
 
-func (mw *AuthMiddleware) validateSession(c *gin.Context) bool {
-	refreshToken, err := GetRefreshTokenFromCookie(c)
-	if err != nil {
-		utils.Fail(c, http.StatusUnauthorized, "SESSION_INVALID", "Session expired - please login again")
-		return false
-	}
-
-	refreshClaims, err := mw.jwtService.VerifyAccessToken(refreshToken)
-	if err != nil {
-		utils.Fail(c, http.StatusUnauthorized, "SESSION_INVALID", "Invalid session - please login again")
-		return false
-	}
-
-	redisKey := "auth:session:" + refreshClaims.ID
+// validateSession checks the session named by the access token: it must be
+// active and still on the token's access version.
+func (mw *AuthMiddleware) validateSession(c *gin.Context, claims *customjwt.AccessClaims) bool {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	var sessionData map[string]interface{}
-	err = mw.redisUtil.GetJSON(ctx, redisKey, &sessionData)
-	if err != nil {
-		utils.Fail(c, http.StatusUnauthorized, "SESSION_REVOKED", "Session has been revoked - please login again")
+	if err := session.Validate(ctx, mw.redisUtil, sessionToken(claims), time.Now()); err != nil {
+		code, message := session.ErrorCode(err)
+		utils.Fail(c, http.StatusUnauthorized, code, message)
 		return false
 	}
-
 	return true
 }
+
+func sessionToken(claims *customjwt.AccessClaims) session.Token {
+	tok := session.Token{SID: claims.SID, AV: claims.AV}
+	if claims.ExpiresAt != nil {
+		tok.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return tok
+}
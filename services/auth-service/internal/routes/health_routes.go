@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"auth-service/internal/handlers"
+	"music-player/api/logger"
+	"music-player/api/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterHealthRoutes registers liveness and readiness probes at the root of the router.
+func RegisterHealthRoutes(r *gin.Engine, healthHandler *handlers.HealthHandler) {
+	r.GET("/livez", healthHandler.Livez)
+	r.GET("/readyz", healthHandler.Readyz)
+}
+
+// RegisterMetricsRoutes exposes Prometheus metrics at /metrics.
+func RegisterMetricsRoutes(r *gin.Engine) {
+	r.GET("/metrics", metrics.Handler())
+}
+
+// RegisterLogLevelRoutes exposes runtime log level control at /admin/log-levels
+// when an admin token is configured.
+func RegisterLogLevelRoutes(r *gin.Engine, logLevelHandler *logger.LevelHandler) {
+	if !logLevelHandler.Enabled() {
+		return
+	}
+	admin := r.Group("/admin", logLevelHandler.Authorize)
+	admin.GET("/log-levels", logLevelHandler.List)
+	admin.PUT("/log-levels", logLevelHandler.Set)
+}
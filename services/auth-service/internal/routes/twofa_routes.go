@@ -15,5 +15,8 @@ func RegisterTwoFARoutes(rg *gin.RouterGroup, handler *handlers.TwoFAHandler, au
 		user.POST("/enable", handler.Enable2FA)
 		user.POST("/verify", handler.Verify2FA)
 		user.POST("/disable", handler.Disable2FA)
+		user.POST("/sms/setup", handler.SetupSMS2FA)
+		user.POST("/sms/enable", handler.EnableSMS2FA)
+		user.POST("/sms/send", handler.SendSMS2FACode)
 	}
 }
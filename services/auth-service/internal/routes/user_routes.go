@@ -21,6 +21,7 @@ func RegisterUserRoutes(r *gin.RouterGroup, userHandler *handlers.UserHandler, a
 			protectedGroup.GET("/users", userHandler.GetAllUsers)
 			protectedGroup.GET("/users/:id", userHandler.GetUserByID)
 			protectedGroup.GET("/me", userHandler.GetMe)
+			protectedGroup.PATCH("/me", userHandler.UpdateMe)
 		}
 	}
 }
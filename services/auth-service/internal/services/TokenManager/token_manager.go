@@ -1,14 +1,27 @@
 package tokenmanager
 
 import (
+	"auth-service/internal/metrics"
 	"auth-service/internal/utils/jwt"
 	redisutil "auth-service/internal/utils/redis"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"music-player/api/logger"
+	"music-player/api/session"
+	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
+	goredis "github.com/redis/go-redis/v9"
 )
 
+var lg = logger.For("services")
+
 type CtxKey string
 
 const (
@@ -28,19 +41,28 @@ type SessionInfo struct {
 	RTRotatedAt time.Time `json:"rt_rotated_at"`
 }
 
+// opaqueRefreshPrefix marks refresh tokens that are random handles instead of
+// JWTs: "rt_<sid>_<secret>". Only a hash of the secret is kept in the session.
+const opaqueRefreshPrefix = "rt_"
+
 type TokenManager interface {
 	IssueInitialTokens(ctx context.Context, userID string) (string, string, error)
+	ParseRefreshToken(ctx context.Context, token string) (*jwt.RefreshClaims, error)
 	RefreshToken(ctx context.Context, claims *jwt.RefreshClaims) (string, string, error)
 	RevokeSession(ctx context.Context, sid string) error
 }
 
 type tokenManager struct {
-	jwtService jwt.JWTService
-	redisUtil  *redisutil.RedisUtil
+	jwtService    jwt.JWTService
+	redisUtil     *redisutil.RedisUtil
+	opaqueRefresh bool
 }
 
-func NewTokenManager(jwtService jwt.JWTService, redisUtil *redisutil.RedisUtil) TokenManager {
-	return &tokenManager{jwtService: jwtService, redisUtil: redisUtil}
+// NewTokenManager issues JWT refresh tokens, or opaque handles when
+// opaqueRefresh is set. Both formats are accepted on refresh either way, so
+// the setting can be switched without logging users out.
+func NewTokenManager(jwtService jwt.JWTService, redisUtil *redisutil.RedisUtil, opaqueRefresh bool) TokenManager {
+	return &tokenManager{jwtService: jwtService, redisUtil: redisUtil, opaqueRefresh: opaqueRefresh}
 }
 
 func getStringFromContext(ctx context.Context, key CtxKey) string {
@@ -56,7 +78,6 @@ func (tm *tokenManager) IssueInitialTokens(ctx context.Context, userID string) (
 	userAgent := getStringFromContext(ctx, CtxKeyUserAgent)
 
 	sid := ulid.Make().String()
-	jti := ulid.Make().String()
 	const avInit uint64 = 1
 
 	accessToken, _, err := tm.jwtService.SignAccessToken(userID, sid, avInit)
@@ -64,92 +85,179 @@ func (tm *tokenManager) IssueInitialTokens(ctx context.Context, userID string) (
 		return "", "", err
 	}
 
-	refreshToken, _, err := tm.jwtService.SignRefreshToken(userID, sid, jti)
+	refreshToken, jti, err := tm.issueRefreshToken(userID, sid)
 	if err != nil {
 		return "", "", err
 	}
 
-	session := SessionInfo{
+	sess := SessionInfo{
 		UserID:      userID,
 		AV:          avInit,
 		IP:          ip,
 		UserAgent:   userAgent,
 		CreatedAt:   time.Now().UTC(),
-		Status:      "active",
+		Status:      session.StatusActive,
 		RTCurrent:   jti,
 		RTPrev:      "",
 		RTRotatedAt: time.Now().UTC(),
 	}
 
-	key := "auth:session:" + sid
+	key := session.Key(sid)
 	refreshTTL := tm.jwtService.GetRefreshTTL()
-	err = tm.redisUtil.SetJSON(ctx, key, session, refreshTTL)
+	err = tm.redisUtil.SetJSON(ctx, key, sess, refreshTTL)
 	if err != nil {
 		return "", "", err
 	}
 	return accessToken, refreshToken, nil
 }
 
+// RefreshToken rotates the session's refresh token. The presented token must
+// be the session's current one; presenting the previous one means it was
+// copied, so the session is revoked. The check and the rotation run as one
+// WATCHed transaction, so two concurrent refreshes cannot both rotate.
 func (tm *tokenManager) RefreshToken(ctx context.Context, claims *jwt.RefreshClaims) (string, string, error) {
 	ip := getStringFromContext(ctx, CtxKeyIP)
 	userAgent := getStringFromContext(ctx, CtxKeyUserAgent)
 
-	key := "auth:session:" + claims.SID
-	var sess SessionInfo
-	if err := tm.redisUtil.GetJSON(ctx, key, &sess); err != nil {
+	var accessToken, refreshToken string
+	var av uint64
+	reused := false
+	err := redisutil.UpdateJSON(ctx, tm.redisUtil, session.Key(claims.SID), func(sess *SessionInfo, ttl time.Duration) (time.Duration, error) {
+		if sess.Status != session.StatusActive {
+			return 0, jwt.ErrSessionRevoked
+		}
+		switch {
+		case claims.JTI != "" && subtle.ConstantTimeCompare([]byte(claims.JTI), []byte(sess.RTCurrent)) == 1:
+		case claims.JTI != "" && subtle.ConstantTimeCompare([]byte(claims.JTI), []byte(sess.RTPrev)) == 1:
+			reused = true
+			return revoke(sess, ttl), nil
+		default:
+			return 0, jwt.ErrTokenInvalid
+		}
+
+		sess.AV++
+		var err error
+		accessToken, _, err = tm.jwtService.SignAccessToken(claims.UserID, claims.SID, sess.AV)
+		if err != nil {
+			return 0, err
+		}
+		var newJTI string
+		refreshToken, newJTI, err = tm.issueRefreshToken(claims.UserID, claims.SID)
+		if err != nil {
+			return 0, err
+		}
+
+		sess.RTPrev = sess.RTCurrent
+		sess.RTCurrent = newJTI
+		sess.RTRotatedAt = time.Now().UTC()
+		sess.IP = ip
+		sess.UserAgent = userAgent
+		av = sess.AV
+		return tm.jwtService.GetRefreshTTL(), nil
+	})
+	if errors.Is(err, goredis.Nil) {
 		return "", "", jwt.ErrSessionNotFound
 	}
+	if err != nil {
+		return "", "", err
+	}
 
-	if sess.Status != "active" {
-		return "", "", jwt.ErrSessionRevoked
+	if reused {
+		lg.WarnContext(ctx, "Refresh token reused, session revoked", "sid", claims.SID, "user_id", claims.UserID)
+		tm.publishSessionEvent(ctx, session.Event{SID: claims.SID, Reason: session.EventRevoked})
+		metrics.ObserveSessionRevocation()
+		return "", "", jwt.ErrRefreshTokenReused
 	}
 
-	newJTI := ulid.Make().String()
-	sess.AV++
+	tm.publishSessionEvent(ctx, session.Event{SID: claims.SID, Reason: session.EventRotated, AV: av})
+	return accessToken, refreshToken, nil
+}
 
-	accessToken, _, err := tm.jwtService.SignAccessToken(claims.UserID, claims.SID, sess.AV)
-	if err != nil {
-		return "", "", err
+// ParseRefreshToken verifies a refresh token of either format. Opaque handles
+// are resolved against the session; the returned claims carry the handle hash
+// as JTI, which RefreshToken checks against the session like a JWT's JTI.
+func (tm *tokenManager) ParseRefreshToken(ctx context.Context, token string) (*jwt.RefreshClaims, error) {
+	if !strings.HasPrefix(token, opaqueRefreshPrefix) {
+		return tm.jwtService.VerifyRefreshToken(token)
 	}
 
-	refreshToken, _, err := tm.jwtService.SignRefreshToken(claims.UserID, claims.SID, newJTI)
-	if err != nil {
-		return "", "", err
+	sid, secret, ok := strings.Cut(strings.TrimPrefix(token, opaqueRefreshPrefix), "_")
+	if !ok || sid == "" || secret == "" {
+		return nil, jwt.ErrTokenInvalid
+	}
+
+	var sess SessionInfo
+	if err := tm.redisUtil.GetJSON(ctx, session.Key(sid), &sess); err != nil {
+		return nil, jwt.ErrSessionNotFound
+	}
+	if sess.Status != session.StatusActive {
+		return nil, jwt.ErrSessionRevoked
 	}
 
-	prev := sess.RTCurrent
-	sess.RTPrev = prev
-	sess.RTCurrent = newJTI
-	sess.RTRotatedAt = time.Now().UTC()
-	sess.IP = ip
-	sess.UserAgent = userAgent
+	return &jwt.RefreshClaims{UserID: sess.UserID, SID: sid, JTI: hashRefreshSecret(secret)}, nil
+}
+
+// issueRefreshToken returns the refresh token for a session and the
+// identifier stored as RTCurrent: the JTI for JWTs, the secret's hash for
+// opaque handles.
+func (tm *tokenManager) issueRefreshToken(userID, sid string) (string, string, error) {
+	if !tm.opaqueRefresh {
+		jti := ulid.Make().String()
+		token, _, err := tm.jwtService.SignRefreshToken(userID, sid, jti)
+		return token, jti, err
+	}
 
-	if err := tm.redisUtil.SetJSON(ctx, key, sess, tm.jwtService.GetRefreshTTL()); err != nil {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
 		return "", "", err
 	}
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+	return opaqueRefreshPrefix + sid + "_" + secret, hashRefreshSecret(secret), nil
+}
 
-	return accessToken, refreshToken, nil
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
 }
 
 func (tm *tokenManager) RevokeSession(ctx context.Context, sid string) error {
-	key := "auth:session:" + sid
-	var sess SessionInfo
-	if err := tm.redisUtil.GetJSON(ctx, key, &sess); err != nil {
+	err := redisutil.UpdateJSON(ctx, tm.redisUtil, session.Key(sid), func(sess *SessionInfo, ttl time.Duration) (time.Duration, error) {
+		if sess.Status != session.StatusActive {
+			return 0, jwt.ErrSessionRevoked
+		}
+		return revoke(sess, ttl), nil
+	})
+	if errors.Is(err, goredis.Nil) {
 		return jwt.ErrSessionNotFound
 	}
-	if sess.Status != "active" {
-		return jwt.ErrSessionRevoked
+	if err != nil {
+		return err
 	}
-	sess.Status = "revoked"
-	sess.RTCurrent = ""
-	sess.RTPrev = ""
+	tm.publishSessionEvent(ctx, session.Event{SID: sid, Reason: session.EventRevoked})
+	metrics.ObserveSessionRevocation()
+	return nil
+}
 
-	const tombstone = 24 * time.Hour
+// revokeTombstone bounds how long a revoked session is kept so gateways and
+// replayed refresh tokens still see it as revoked rather than missing.
+const revokeTombstone = 24 * time.Hour
 
-	rem := tm.redisUtil.PTTL(ctx, key)
-	if rem <= 0 || rem > tombstone {
-		rem = tombstone
+// revoke marks sess revoked and returns the TTL to keep it for, given the
+// key's remaining TTL.
+func revoke(sess *SessionInfo, ttl time.Duration) time.Duration {
+	sess.Status = session.StatusRevoked
+	sess.RTCurrent = ""
+	sess.RTPrev = ""
+	if ttl <= 0 || ttl > revokeTombstone {
+		ttl = revokeTombstone
 	}
+	return ttl
+}
 
-	return tm.redisUtil.SetJSON(ctx, key, sess, rem)
+// publishSessionEvent is best effort: the session in Redis is already
+// updated and gateway caches expire on their own within their short TTL.
+func (tm *tokenManager) publishSessionEvent(ctx context.Context, event session.Event) {
+	if err := tm.redisUtil.PublishJSON(ctx, session.EventsChannel, event); err != nil {
+		lg.WarnContext(ctx, "Failed to publish session event", "sid", event.SID, "reason", event.Reason, "error", err)
+	}
 }
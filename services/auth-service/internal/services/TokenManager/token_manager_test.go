@@ -0,0 +1,195 @@
+package tokenmanager
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"music-player/api/session"
+	"sync"
+	"testing"
+	"time"
+
+	"auth-service/internal/utils/jwt"
+	redisutil "auth-service/internal/utils/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestManager(t *testing.T, opaque bool) (*tokenManager, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshKeys, err := jwt.NewRefreshKeyRing([]jwt.RefreshKey{{KID: "r1", Secret: []byte("test-refresh-secret")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtService := jwt.NewJWTService(&jwt.JWTConfig{
+		Keys:        jwt.NewStaticKeyRing("k1", priv),
+		AccessTTL:   15 * time.Minute,
+		RefreshKeys: refreshKeys,
+		RefreshTTL:  24 * time.Hour,
+	})
+	return &tokenManager{jwtService: jwtService, redisUtil: redisutil.NewRedisUtil(client), opaqueRefresh: opaque}, mr
+}
+
+func (tm *tokenManager) testSession(t *testing.T, sid string) SessionInfo {
+	t.Helper()
+	var sess SessionInfo
+	if err := tm.redisUtil.GetJSON(context.Background(), session.Key(sid), &sess); err != nil {
+		t.Fatalf("session %s: %v", sid, err)
+	}
+	return sess
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	for _, opaque := range []bool{false, true} {
+		name := "jwt"
+		if opaque {
+			name = "opaque"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			tm, _ := newTestManager(t, opaque)
+
+			_, rt1, err := tm.IssueInitialTokens(ctx, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims1, err := tm.ParseRefreshToken(ctx, rt1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, rt2, err := tm.RefreshToken(ctx, claims1)
+			if err != nil {
+				t.Fatalf("first refresh: %v", err)
+			}
+			sess := tm.testSession(t, claims1.SID)
+			if sess.AV != 2 || sess.RTPrev != claims1.JTI || sess.RTCurrent == claims1.JTI {
+				t.Fatalf("session after rotation = %+v", sess)
+			}
+
+			claims2, err := tm.ParseRefreshToken(ctx, rt2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := tm.RefreshToken(ctx, claims2); err != nil {
+				t.Fatalf("second refresh: %v", err)
+			}
+
+			// rt2 is now the previous token: replaying it revokes the session.
+			if _, _, err := tm.RefreshToken(ctx, claims2); !errors.Is(err, jwt.ErrRefreshTokenReused) {
+				t.Fatalf("replayed previous token: err = %v, want ErrRefreshTokenReused", err)
+			}
+			if sess := tm.testSession(t, claims1.SID); sess.Status != session.StatusRevoked || sess.RTCurrent != "" {
+				t.Fatalf("session after reuse = %+v, want revoked", sess)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenRejectsUnknownJTI(t *testing.T) {
+	ctx := context.Background()
+	tm, _ := newTestManager(t, false)
+
+	_, rt, err := tm.IssueInitialTokens(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tm.ParseRefreshToken(ctx, rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := *claims
+	forged.JTI = "not-the-current-jti"
+	if _, _, err := tm.RefreshToken(ctx, &forged); !errors.Is(err, jwt.ErrTokenInvalid) {
+		t.Fatalf("err = %v, want ErrTokenInvalid", err)
+	}
+	if sess := tm.testSession(t, claims.SID); sess.Status != session.StatusActive || sess.AV != 1 {
+		t.Fatalf("session changed by a rejected refresh: %+v", sess)
+	}
+
+	missing := *claims
+	missing.SID = "missing"
+	if _, _, err := tm.RefreshToken(ctx, &missing); !errors.Is(err, jwt.ErrSessionNotFound) {
+		t.Fatalf("err = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestRefreshTokenConcurrent(t *testing.T) {
+	ctx := context.Background()
+	tm, _ := newTestManager(t, false)
+
+	_, rt, err := tm.IssueInitialTokens(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tm.ParseRefreshToken(ctx, rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 8
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := tm.RefreshToken(ctx, claims); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("%d concurrent refreshes with the same token succeeded, want 1", succeeded)
+	}
+	if sess := tm.testSession(t, claims.SID); sess.Status != session.StatusRevoked {
+		t.Fatalf("session after replay race = %+v, want revoked", sess)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	tm, mr := newTestManager(t, false)
+
+	_, rt, err := tm.IssueInitialTokens(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tm.ParseRefreshToken(ctx, rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tm.RevokeSession(ctx, claims.SID); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL(session.Key(claims.SID)); ttl <= 0 || ttl > revokeTombstone {
+		t.Errorf("tombstone TTL = %s, want (0, %s]", ttl, revokeTombstone)
+	}
+	if err := tm.RevokeSession(ctx, claims.SID); !errors.Is(err, jwt.ErrSessionRevoked) {
+		t.Errorf("second revoke: err = %v, want ErrSessionRevoked", err)
+	}
+	if _, _, err := tm.RefreshToken(ctx, claims); !errors.Is(err, jwt.ErrSessionRevoked) {
+		t.Errorf("refresh after revoke: err = %v, want ErrSessionRevoked", err)
+	}
+	if err := tm.RevokeSession(ctx, "missing"); !errors.Is(err, jwt.ErrSessionNotFound) {
+		t.Errorf("revoke missing: err = %v, want ErrSessionNotFound", err)
+	}
+}
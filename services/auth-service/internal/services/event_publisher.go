@@ -1,17 +1,26 @@
 package services
 
 import (
+	"auth-service/configs"
 	"auth-service/internal/domain"
 	"auth-service/internal/kafka/envelope"
 	"auth-service/internal/kafka/producer"
 	"context"
-	"log"
+	"music-player/api/logger"
+	"music-player/api/tracing"
 	"time"
+
+	"music-player/api/phone"
 )
 
+var lg = logger.For("services")
+
 // EventPublisher handles publishing domain events to Kafka
 type EventPublisher interface {
 	PublishUserRegistered(ctx context.Context, user *domain.User) error
+	// PublishSMSCode asks notification-service to text code to number with
+	// template, one of its SMS templates.
+	PublishSMSCode(ctx context.Context, user *domain.User, number, template, code string, ttl time.Duration) error
 	// Future events:
 	// PublishUserUpdated(ctx context.Context, user *domain.User) error
 	// PublishUserDeleted(ctx context.Context, userID string) error
@@ -20,12 +29,15 @@ type EventPublisher interface {
 
 type kafkaEventPublisher struct {
 	producer *producer.Producer
+	// environment is APP_ENV, stamped on every event's metadata.
+	environment string
 }
 
 // NewEventPublisher creates a new event publisher
-func NewEventPublisher(producer *producer.Producer) EventPublisher {
+func NewEventPublisher(producer *producer.Producer, appCfg *configs.AppConfig) EventPublisher {
 	return &kafkaEventPublisher{
-		producer: producer,
+		producer:    producer,
+		environment: appCfg.Env,
 	}
 }
 
@@ -37,6 +49,7 @@ func (p *kafkaEventPublisher) PublishUserRegistered(ctx context.Context, user *d
 		"email":      user.Email,
 		"username":   user.Username,
 		"full_name":  user.FullName,
+		"locale":     user.Locale,
 		"created_at": user.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -53,13 +66,15 @@ func (p *kafkaEventPublisher) PublishUserRegistered(ctx context.Context, user *d
 	// Add metadata
 	env.Metadata = &envelope.Metadata{
 		UserID:      user.ID,
-		Environment: "production",
+		Environment: p.environment,
 		CustomFields: map[string]string{
 			"event_type": "user_lifecycle",
 			"action":     "registration",
 		},
 	}
 
+	tracing.StampEnvelope(ctx, env)
+
 	// Validate envelope
 	if err := env.Validate(); err != nil {
 		return err
@@ -76,10 +91,62 @@ func (p *kafkaEventPublisher) PublishUserRegistered(ctx context.Context, user *d
 	key := user.ID
 
 	if err := p.producer.Publish(ctx, topic, key, messageBytes); err != nil {
-		log.Printf("[WARN] Failed to publish user.registered event: %v", err)
+		lg.WarnContext(ctx, "Failed to publish user.registered event", "error", err)
+		return err
+	}
+
+	lg.InfoContext(ctx, "Published user.registered event", "user_id", user.ID)
+	return nil
+}
+
+// SMS templates of notification-service.
+const (
+	SMSTemplateLoginCode         = "login_code"
+	SMSTemplatePhoneVerification = "phone_verification"
+)
+
+// PublishSMSCode publishes notification.sms.send. The code travels in the
+// event: notification-service needs it for the text.
+func (p *kafkaEventPublisher) PublishSMSCode(ctx context.Context, user *domain.User, number, template, code string, ttl time.Duration) error {
+	env, err := envelope.NewEnvelope(
+		"auth-service",
+		envelope.PriorityCritical,
+		map[string]interface{}{
+			"to":       number,
+			"user_id":  user.ID,
+			"template": template,
+			"locale":   user.Locale,
+			"category": "security",
+			"data": map[string]interface{}{
+				"code":    code,
+				"minutes": int(ttl.Minutes()),
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	env.Metadata = &envelope.Metadata{
+		UserID:      user.ID,
+		Environment: p.environment,
+		CustomFields: map[string]string{
+			"event_type": "two_factor",
+			"action":     template,
+		},
+	}
+	tracing.StampEnvelope(ctx, env)
+	if err := env.Validate(); err != nil {
+		return err
+	}
+	messageBytes, err := env.Marshal()
+	if err != nil {
 		return err
 	}
 
-	log.Printf("[INFO] Published user.registered event for user %s", user.ID)
+	if err := p.producer.Publish(ctx, envelope.TopicSMSSend.String(), user.ID, messageBytes); err != nil {
+		lg.WarnContext(ctx, "Failed to publish notification.sms.send event", "error", err)
+		return err
+	}
+	lg.InfoContext(ctx, "Published notification.sms.send event", "user_id", user.ID, "template", template, "to", phone.Mask(number))
 	return nil
 }
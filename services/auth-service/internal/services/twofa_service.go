@@ -1,33 +1,67 @@
 package services
 
 import (
+	"auth-service/configs"
 	"auth-service/internal/domain"
+	"auth-service/internal/metrics"
 	"auth-service/internal/repositories"
 	redisutil "auth-service/internal/utils/redis"
 	"auth-service/internal/utils/twofa"
 	"context"
+	"crypto/rand"
+	"errors"
 	"time"
 )
 
 type TwoFAService interface {
 	Setup2FA(ctx context.Context, userID string) (*twofa.SetupResult, error)
 	Enable2FA(ctx context.Context, userID, code string) error
-	Verify2FA(ctx context.Context, userID, code string) error
-	Disable2FA(ctx context.Context, userID string, code string) error
+	// Verify2FA and Disable2FA check code against method, a
+	// domain.TwoFAMethod*; "" is TOTP.
+	Verify2FA(ctx context.Context, userID, method, code string) error
+	Disable2FA(ctx context.Context, userID, method, code string) error
+
+	// SetupSMS2FA texts a code to phoneNumber, which EnableSMS2FA checks
+	// before making the number the user's second factor.
+	SetupSMS2FA(ctx context.Context, userID, phoneNumber string) (*SMSCodeSent, error)
+	EnableSMS2FA(ctx context.Context, userID, code string) error
+	// SendSMS2FACode texts a sign-in code to the user's number, for
+	// Verify2FA with the SMS method.
+	SendSMS2FACode(ctx context.Context, userID string) (*SMSCodeSent, error)
 }
 
 type twoFAService struct {
 	twoFAUtil *twofa.TwoFAUtil
 	userRepo  repositories.UserRepository
 	redisUtil *redisutil.RedisUtil
+	publisher EventPublisher
+	cfg       *configs.TwoFAConfig
+	// codeSecret keys the hashes of texted codes.
+	codeSecret []byte
 }
 
-func NewTwoFAService(userRepo repositories.UserRepository, twoFAUtil *twofa.TwoFAUtil, redisUtil *redisutil.RedisUtil) TwoFAService {
-	return &twoFAService{
-		twoFAUtil: twoFAUtil,
-		userRepo:  userRepo,
-		redisUtil: redisUtil,
+// NewTwoFAService fails without SMS_CODE_SECRET outside development. In
+// development a random secret is used, so codes do not outlive a restart.
+func NewTwoFAService(userRepo repositories.UserRepository, twoFAUtil *twofa.TwoFAUtil, redisUtil *redisutil.RedisUtil, publisher EventPublisher, cfg *configs.TwoFAConfig) (TwoFAService, error) {
+	secret := cfg.SMSCodeSecret
+	if len(secret) == 0 {
+		if !cfg.Development {
+			return nil, errors.New("twofa: SMS_CODE_SECRET is required outside development")
+		}
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		lg.Warn("SMS_CODE_SECRET is not set, using a random one: texted codes do not survive a restart")
 	}
+	return &twoFAService{
+		twoFAUtil:  twoFAUtil,
+		userRepo:   userRepo,
+		redisUtil:  redisUtil,
+		publisher:  publisher,
+		cfg:        cfg,
+		codeSecret: secret,
+	}, nil
 }
 
 func (s *twoFAService) Setup2FA(ctx context.Context, userID string) (*twofa.SetupResult, error) {
@@ -88,11 +122,23 @@ func (s *twoFAService) Enable2FA(ctx context.Context, userID, code string) error
 	return nil
 }
 
-func (s *twoFAService) Verify2FA(ctx context.Context, userID, code string) error {
+func (s *twoFAService) Verify2FA(ctx context.Context, userID, method, code string) (err error) {
+	defer func() { metrics.ObserveTwoFAVerification(err) }()
+
+	if method, err = domain.ParseTwoFAMethod(method); err != nil {
+		return err
+	}
 	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil || user == nil {
 		return domain.ErrUserNotFound
 	}
+	if method == domain.TwoFAMethodSMS {
+		if !user.SMSTwoFAEnabled {
+			return domain.ErrTwoFANotAvailable
+		}
+		_, err = s.checkSMSCode(ctx, userID, smsLoginKey(userID), code, domain.ErrInvalidTwoFACode)
+		return err
+	}
 	if !user.TwoFAEnabled || user.TwoFASecret == "" {
 		return domain.ErrTwoFANotAvailable
 	}
@@ -102,11 +148,18 @@ func (s *twoFAService) Verify2FA(ctx context.Context, userID, code string) error
 	return nil
 }
 
-func (s *twoFAService) Disable2FA(ctx context.Context, userID string, code string) error {
+func (s *twoFAService) Disable2FA(ctx context.Context, userID, method, code string) error {
+	method, err := domain.ParseTwoFAMethod(method)
+	if err != nil {
+		return err
+	}
 	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil || user == nil {
 		return domain.ErrUserNotFound
 	}
+	if method == domain.TwoFAMethodSMS {
+		return s.disableSMS2FA(ctx, user, code)
+	}
 	if !user.TwoFAEnabled || user.TwoFASecret == "" {
 		return domain.ErrTwoFANotAvailable
 	}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/utils/twofa"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"music-player/api/phone"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SMSCodeSent tells the client where a code went and how long it is valid.
+// The code is queued for notification-service, not yet delivered: it may
+// still drop the text, e.g. when the number reached its SMS_RATE_LIMIT, and
+// reports that on notification.sms.failed only.
+type SMSCodeSent struct {
+	// PhoneNumber is masked, e.g. "+84******678".
+	PhoneNumber string
+	ExpiresIn   time.Duration
+}
+
+// smsCode is a texted code waiting to be entered. Only its hash is kept.
+type smsCode struct {
+	PhoneNumber string `json:"phone_number"`
+	Hash        string `json:"hash"`
+}
+
+func smsSetupKey(userID string) string { return "2fa:sms:setup:" + userID }
+func smsLoginKey(userID string) string { return "2fa:sms:login:" + userID }
+
+// smsCooldownKey is the resend cooldown of the codes stored under key, so a
+// setup code does not hold back a sign-in code and the other way round.
+func smsCooldownKey(key string) string { return key + ":cooldown" }
+
+func (s *twoFAService) SetupSMS2FA(ctx context.Context, userID, phoneNumber string) (*SMSCodeSent, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	if user.SMSTwoFAEnabled {
+		return nil, domain.ErrTwoFAEnabled
+	}
+	number, err := phone.Normalize(phoneNumber)
+	if err != nil {
+		return nil, domain.ErrInvalidPhoneNumber
+	}
+	return s.sendSMSCode(ctx, user, number, smsSetupKey(userID), SMSTemplatePhoneVerification)
+}
+
+func (s *twoFAService) EnableSMS2FA(ctx context.Context, userID, code string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+	if user.SMSTwoFAEnabled {
+		return domain.ErrTwoFAEnabled
+	}
+	setup, err := s.checkSMSCode(ctx, userID, smsSetupKey(userID), code, domain.ErrTwoFASetupExpired)
+	if err != nil {
+		return err
+	}
+	user.PhoneNumber = setup.PhoneNumber
+	user.SMSTwoFAEnabled = true
+	_, err = s.userRepo.Update(ctx, user)
+	return err
+}
+
+func (s *twoFAService) SendSMS2FACode(ctx context.Context, userID string) (*SMSCodeSent, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	if !user.SMSTwoFAEnabled || user.PhoneNumber == "" {
+		return nil, domain.ErrTwoFANotAvailable
+	}
+	return s.sendSMSCode(ctx, user, user.PhoneNumber, smsLoginKey(userID), SMSTemplateLoginCode)
+}
+
+// disableSMS2FA turns SMS codes off and forgets the number, once the user
+// entered a code texted by SendSMS2FACode.
+func (s *twoFAService) disableSMS2FA(ctx context.Context, user *domain.User, code string) error {
+	if !user.SMSTwoFAEnabled {
+		return domain.ErrTwoFANotAvailable
+	}
+	if _, err := s.checkSMSCode(ctx, user.ID, smsLoginKey(user.ID), code, domain.ErrInvalidTwoFACode); err != nil {
+		return err
+	}
+	user.SMSTwoFAEnabled = false
+	user.PhoneNumber = ""
+	_, err := s.userRepo.Update(ctx, user)
+	return err
+}
+
+// sendSMSCode stores a new code under key, replacing any earlier one, and
+// asks notification-service to text it. A user gets at most one code per
+// resend interval for each purpose.
+func (s *twoFAService) sendSMSCode(ctx context.Context, user *domain.User, number, key, template string) (*SMSCodeSent, error) {
+	ok, err := s.redisUtil.SetNX(ctx, smsCooldownKey(key), "1", s.cfg.SMSResendInterval)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.ErrTwoFACodeCooldown
+	}
+	code, err := twofa.GenerateCode(twofa.SMSCodeDigits)
+	if err == nil {
+		err = s.redisUtil.SetJSON(ctx, key, smsCode{PhoneNumber: number, Hash: twofa.HashCode(s.codeSecret, user.ID, code)}, s.cfg.SMSCodeTTL)
+	}
+	if err == nil {
+		_ = s.redisUtil.Delete(ctx, key+":attempts")
+		err = s.publisher.PublishSMSCode(ctx, user, number, template, code, s.cfg.SMSCodeTTL)
+	}
+	if err != nil {
+		// Nothing was texted: let the user ask again at once.
+		_ = s.redisUtil.Delete(ctx, smsCooldownKey(key))
+		return nil, err
+	}
+	return &SMSCodeSent{PhoneNumber: phone.Mask(number), ExpiresIn: s.cfg.SMSCodeTTL}, nil
+}
+
+// checkSMSCodeScript counts an attempt at the code stored under KEYS[1] and
+// consumes it when its hash is ARGV[1], in one step: two requests with the
+// right code cannot both pass. The counter KEYS[2] expires after ARGV[2]
+// milliseconds. At ARGV[3] attempts the code is dropped. It returns
+// {smsCodeMissing}, {smsCodeWrong} or {smsCodeOK, stored JSON}. Comparing
+// HMACs in Lua without constant time is safe: the secret is never exposed.
+var checkSMSCodeScript = redis.NewScript(`
+local stored = redis.call('GET', KEYS[1])
+if not stored then
+	return {0}
+end
+local attempts = redis.call('INCR', KEYS[2])
+if attempts == 1 then
+	redis.call('PEXPIRE', KEYS[2], ARGV[2])
+end
+local max = tonumber(ARGV[3])
+if attempts <= max and cjson.decode(stored).hash == ARGV[1] then
+	redis.call('DEL', KEYS[1], KEYS[2])
+	return {2, stored}
+end
+if attempts >= max then
+	redis.call('DEL', KEYS[1], KEYS[2])
+end
+return {1}
+`)
+
+// Results of checkSMSCodeScript.
+const (
+	smsCodeMissing = 0
+	smsCodeWrong   = 1
+	smsCodeOK      = 2
+)
+
+// checkSMSCode checks code against the one stored under key and consumes it
+// on success. missing is returned when no code is waiting. After the
+// maximum number of wrong guesses the code is dropped.
+func (s *twoFAService) checkSMSCode(ctx context.Context, userID, key, code string, missing error) (*smsCode, error) {
+	res, err := s.redisUtil.RunScript(ctx, checkSMSCodeScript,
+		[]string{key, key + ":attempts"},
+		twofa.HashCode(s.codeSecret, userID, code), s.cfg.SMSCodeTTL.Milliseconds(), s.cfg.SMSMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	reply, _ := res.([]interface{})
+	switch {
+	case len(reply) == 1 && reply[0] == int64(smsCodeMissing):
+		return nil, missing
+	case len(reply) == 1 && reply[0] == int64(smsCodeWrong):
+		return nil, domain.ErrInvalidTwoFACode
+	case len(reply) == 2 && reply[0] == int64(smsCodeOK):
+		stored, _ := reply[1].(string)
+		var sc smsCode
+		if err := json.Unmarshal([]byte(stored), &sc); err != nil {
+			return nil, err
+		}
+		return &sc, nil
+	}
+	return nil, fmt.Errorf("twofa: unexpected SMS code check reply %v", res)
+}
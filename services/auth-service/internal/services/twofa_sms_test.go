@@ -0,0 +1,216 @@
+package services
+
+import (
+	"auth-service/configs"
+	"auth-service/internal/domain"
+	redisutil "auth-service/internal/utils/redis"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// fakePublisher keeps the last code texted instead of publishing it.
+type fakePublisher struct {
+	EventPublisher
+	code string
+	err  error
+}
+
+func (p *fakePublisher) PublishSMSCode(ctx context.Context, user *domain.User, number, template, code string, ttl time.Duration) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.code = code
+	return nil
+}
+
+func newTestTwoFAService(t *testing.T) (*twoFAService, *fakePublisher) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	publisher := &fakePublisher{}
+	return &twoFAService{
+		redisUtil: redisutil.NewRedisUtil(client),
+		publisher: publisher,
+		cfg: &configs.TwoFAConfig{
+			SMSCodeTTL:        5 * time.Minute,
+			SMSResendInterval: time.Minute,
+			SMSMaxAttempts:    3,
+		},
+		codeSecret: []byte("test-code-secret"),
+	}, publisher
+}
+
+var testUser = &domain.User{BaseModel: domain.BaseModel{ID: "u1"}, Locale: "en"}
+
+// sendTestCode texts a new code under key and returns it.
+func sendTestCode(t *testing.T, s *twoFAService, p *fakePublisher, key string) string {
+	t.Helper()
+	_ = s.redisUtil.Delete(context.Background(), smsCooldownKey(key))
+	if _, err := s.sendSMSCode(context.Background(), testUser, "+84912345678", key, SMSTemplateLoginCode); err != nil {
+		t.Fatal(err)
+	}
+	return p.code
+}
+
+// wrongCode is a code other than code.
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestCheckSMSCodeConsumedOnSuccess(t *testing.T) {
+	ctx := context.Background()
+	s, p := newTestTwoFAService(t)
+	key := smsLoginKey(testUser.ID)
+	code := sendTestCode(t, s, p, key)
+
+	got, err := s.checkSMSCode(ctx, testUser.ID, key, code, domain.ErrTwoFASetupExpired)
+	if err != nil {
+		t.Fatalf("checkSMSCode = %v; want success", err)
+	}
+	if got.PhoneNumber != "+84912345678" {
+		t.Errorf("PhoneNumber = %q; want the number the code went to", got.PhoneNumber)
+	}
+	if _, err := s.checkSMSCode(ctx, testUser.ID, key, code, domain.ErrTwoFASetupExpired); !errors.Is(err, domain.ErrTwoFASetupExpired) {
+		t.Errorf("reused code = %v; want the missing error", err)
+	}
+}
+
+func TestCheckSMSCodeConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	s, p := newTestTwoFAService(t)
+	key := smsLoginKey(testUser.ID)
+	code := sendTestCode(t, s, p, key)
+
+	var passed atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.checkSMSCode(ctx, testUser.ID, key, code, domain.ErrTwoFASetupExpired); err == nil {
+				passed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := passed.Load(); n != 1 {
+		t.Errorf("the code passed %d concurrent checks; want 1", n)
+	}
+}
+
+func TestCheckSMSCodeAttempts(t *testing.T) {
+	ctx := context.Background()
+	s, p := newTestTwoFAService(t)
+	key := smsLoginKey(testUser.ID)
+	code := sendTestCode(t, s, p, key)
+
+	// Wrong guesses below the maximum keep the code.
+	for i := 1; i < s.cfg.SMSMaxAttempts; i++ {
+		if _, err := s.checkSMSCode(ctx, testUser.ID, key, wrongCode(code), domain.ErrTwoFASetupExpired); !errors.Is(err, domain.ErrInvalidTwoFACode) {
+			t.Fatalf("wrong guess %d = %v; want ErrInvalidTwoFACode", i, err)
+		}
+	}
+	if ttl := s.redisUtil.PTTL(ctx, key+":attempts"); ttl <= 0 || ttl > s.cfg.SMSCodeTTL {
+		t.Errorf("attempt counter TTL = %s; want at most %s", ttl, s.cfg.SMSCodeTTL)
+	}
+	if _, err := s.checkSMSCode(ctx, testUser.ID, key, code, domain.ErrTwoFASetupExpired); err != nil {
+		t.Fatalf("right code on the last attempt = %v; want success", err)
+	}
+
+	// A new code starts counting again.
+	code = sendTestCode(t, s, p, key)
+	if _, err := s.checkSMSCode(ctx, testUser.ID, key, wrongCode(code), domain.ErrTwoFASetupExpired); !errors.Is(err, domain.ErrInvalidTwoFACode) {
+		t.Fatalf("wrong guess = %v; want ErrInvalidTwoFACode", err)
+	}
+	if _, err := s.checkSMSCode(ctx, testUser.ID, key, code, domain.ErrTwoFASetupExpired); err != nil {
+		t.Errorf("right code after a new one was sent = %v; want success", err)
+	}
+}
+
+func TestCheckSMSCodeLockout(t *testing.T) {
+	ctx := context.Background()
+	s, p := newTestTwoFAService(t)
+	key := smsLoginKey(testUser.ID)
+	code := sendTestCode(t, s, p, key)
+
+	for i := 1; i <= s.cfg.SMSMaxAttempts; i++ {
+		if _, err := s.checkSMSCode(ctx, testUser.ID, key, wrongCode(code), domain.ErrTwoFASetupExpired); !errors.Is(err, domain.ErrInvalidTwoFACode) {
+			t.Fatalf("wrong guess %d = %v; want ErrInvalidTwoFACode", i, err)
+		}
+	}
+	// The last wrong guess dropped the code: the right one no longer works.
+	if _, err := s.checkSMSCode(ctx, testUser.ID, key, code, domain.ErrTwoFASetupExpired); !errors.Is(err, domain.ErrTwoFASetupExpired) {
+		t.Errorf("right code after the lockout = %v; want the missing error", err)
+	}
+	if _, err := s.redisUtil.Get(ctx, key+":attempts"); err == nil {
+		t.Error("the attempt counter outlived the code")
+	}
+}
+
+func TestCheckSMSCodeOtherSecret(t *testing.T) {
+	ctx := context.Background()
+	s, p := newTestTwoFAService(t)
+	key := smsLoginKey(testUser.ID)
+	code := sendTestCode(t, s, p, key)
+
+	s.codeSecret = []byte("another-secret")
+	if _, err := s.checkSMSCode(ctx, testUser.ID, key, code, domain.ErrTwoFASetupExpired); !errors.Is(err, domain.ErrInvalidTwoFACode) {
+		t.Errorf("code checked under another secret = %v; want ErrInvalidTwoFACode", err)
+	}
+}
+
+func TestSendSMSCodeCooldown(t *testing.T) {
+	ctx := context.Background()
+	s, p := newTestTwoFAService(t)
+	send := func(key string) error {
+		_, err := s.sendSMSCode(ctx, testUser, "+84912345678", key, SMSTemplateLoginCode)
+		return err
+	}
+
+	if err := send(smsSetupKey(testUser.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(smsSetupKey(testUser.ID)); !errors.Is(err, domain.ErrTwoFACodeCooldown) {
+		t.Errorf("second setup code = %v; want ErrTwoFACodeCooldown", err)
+	}
+	// A setup code does not hold back a sign-in code.
+	if err := send(smsLoginKey(testUser.ID)); err != nil {
+		t.Errorf("sign-in code after a setup code = %v; want success", err)
+	}
+
+	// When nothing was texted the user may ask again at once.
+	p.err = errors.New("kafka down")
+	key := smsLoginKey("u2")
+	if _, err := s.sendSMSCode(ctx, &domain.User{BaseModel: domain.BaseModel{ID: "u2"}}, "+84912345678", key, SMSTemplateLoginCode); err == nil {
+		t.Fatal("sendSMSCode succeeded; want the publish error")
+	}
+	p.err = nil
+	if _, err := s.sendSMSCode(ctx, &domain.User{BaseModel: domain.BaseModel{ID: "u2"}}, "+84912345678", key, SMSTemplateLoginCode); err != nil {
+		t.Errorf("code after a failed publish = %v; want success", err)
+	}
+}
+
+func TestNewTwoFAServiceRequiresSecret(t *testing.T) {
+	if _, err := NewTwoFAService(nil, nil, nil, nil, &configs.TwoFAConfig{}); err == nil {
+		t.Error("NewTwoFAService accepted an empty SMS_CODE_SECRET outside development")
+	}
+	s, err := NewTwoFAService(nil, nil, nil, nil, &configs.TwoFAConfig{Development: true})
+	if err != nil {
+		t.Fatalf("NewTwoFAService in development = %v", err)
+	}
+	if len(s.(*twoFAService).codeSecret) == 0 {
+		t.Error("no secret in development")
+	}
+}
@@ -3,11 +3,13 @@ package services
 import (
 	"auth-service/internal/domain"
 	"auth-service/internal/dto"
+	"auth-service/internal/metrics"
 	repo "auth-service/internal/repositories"
 	tokenmanager "auth-service/internal/services/TokenManager"
 	"auth-service/internal/utils/jwt"
 	"context"
-	"log"
+	"errors"
+	"music-player/api/locale"
 	"time"
 )
 
@@ -16,6 +18,7 @@ type UserService interface {
 	GetUserByID(ctx context.Context, userID string) (*domain.User, error)
 	GetMe(ctx context.Context, userID string) (*domain.User, error)
 	Register(ctx context.Context, req *dto.UserCreateRequest) (*domain.User, error)
+	UpdateProfile(ctx context.Context, userID string, req *dto.UserUpdateRequest) (*domain.User, error)
 	Login(ctx context.Context, req *dto.UserLoginRequest) (*domain.User, string, string, error)
 	RefreshToken(ctx context.Context, token string) (string, string, error)
 	Logout(ctx context.Context, sid string) error
@@ -89,6 +92,7 @@ func (s *userService) Register(ctx context.Context, req *dto.UserCreateRequest)
 		Email:    req.Email,
 		Password: req.Password,
 		FullName: req.FullName,
+		Locale:   locale.Match(req.Locale, domain.SupportedLocales...),
 	}
 
 	createdUser, err := s.userRepo.Create(ctx, user)
@@ -98,13 +102,37 @@ func (s *userService) Register(ctx context.Context, req *dto.UserCreateRequest)
 
 	// Publish user registered event (non-blocking, only log warning on failure)
 	if err := s.eventPublisher.PublishUserRegistered(ctx, createdUser); err != nil {
-		log.Printf("[WARN] Failed to publish user.registered event: %v", err)
+		lg.WarnContext(ctx, "Failed to publish user.registered event", "error", err)
 	}
 
 	return createdUser, nil
 }
 
-func (s *userService) Login(ctx context.Context, req *dto.UserLoginRequest) (*domain.User, string, string, error) {
+func (s *userService) UpdateProfile(ctx context.Context, userID string, req *dto.UserUpdateRequest) (*domain.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	if req.FullName != nil {
+		user.FullName = *req.FullName
+	}
+	if req.Locale != nil {
+		matched := locale.Match(*req.Locale, domain.SupportedLocales...)
+		if matched == "" {
+			return nil, domain.ErrUnsupportedLocale
+		}
+		user.Locale = matched
+	}
+	return s.userRepo.Update(ctx, user)
+}
+
+func (s *userService) Login(ctx context.Context, req *dto.UserLoginRequest) (_ *domain.User, _ string, _ string, err error) {
+	defer func() { metrics.ObserveLogin(err) }()
+
 	existingUser, err := s.userRepo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, "", "", err
@@ -131,8 +159,10 @@ func (s *userService) Login(ctx context.Context, req *dto.UserLoginRequest) (*do
 	return updatedUser, accessToken, refreshToken, nil
 }
 
-func (s *userService) RefreshToken(ctx context.Context, token string) (string, string, error) {
-	claims, err := s.jwtService.VerifyRefreshToken(token)
+func (s *userService) RefreshToken(ctx context.Context, token string) (_ string, _ string, err error) {
+	defer func() { metrics.ObserveTokenRefresh(err) }()
+
+	claims, err := s.tokenManager.ParseRefreshToken(ctx, token)
 	if err != nil {
 		return "", "", err
 	}
@@ -144,6 +174,12 @@ func (s *userService) RefreshToken(ctx context.Context, token string) (string, s
 	return newAccessToken, newRefreshToken, nil
 }
 
+// Logout revokes the session. A session that is already revoked counts as
+// logged out, so the gateway can retry the call safely.
 func (s *userService) Logout(ctx context.Context, sid string) error {
-	return s.tokenManager.RevokeSession(ctx, sid)
+	err := s.tokenManager.RevokeSession(ctx, sid)
+	if errors.Is(err, jwt.ErrSessionRevoked) {
+		return nil
+	}
+	return err
 }
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tokenmanager "auth-service/internal/services/TokenManager"
+	"auth-service/internal/utils/jwt"
+)
+
+type fakeTokenManager struct {
+	tokenmanager.TokenManager
+	revokeErr error
+}
+
+func (f *fakeTokenManager) RevokeSession(ctx context.Context, sid string) error {
+	return f.revokeErr
+}
+
+func TestLogout(t *testing.T) {
+	boom := errors.New("redis down")
+	tests := []struct {
+		name      string
+		revokeErr error
+		want      error
+	}{
+		{"active session", nil, nil},
+		{"already revoked, e.g. a retried call", jwt.ErrSessionRevoked, nil},
+		{"unknown session", jwt.ErrSessionNotFound, jwt.ErrSessionNotFound},
+		{"storage error", boom, boom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &userService{tokenManager: &fakeTokenManager{revokeErr: tt.revokeErr}}
+			if err := s.Logout(context.Background(), "sid"); !errors.Is(err, tt.want) {
+				t.Errorf("Logout = %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,28 +1,40 @@
 package jwt
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/x509"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type JWTConfig struct {
-	AccessPrivateKey ed25519.PrivateKey
-	AccessKID        string
-	AccessTTL        time.Duration
-	RefreshSecret string
+	// Keys holds the access token signing keys. It is backed by JWT_KEYS_DIR
+	// when set, otherwise by the single JWT_ACCESS_PRIVATE_KEY_FILE key.
+	Keys      *KeyRing
+	AccessTTL time.Duration
+
+	// RefreshKeys signs refresh tokens. RefreshOpaque switches new refresh
+	// tokens to random handles that only exist in the Redis session.
+	RefreshKeys   *RefreshKeyRing
 	RefreshTTL    time.Duration
+	RefreshOpaque bool
 
-	// JWKS for verification (test purposes)
+	// JWKS for verification of legacy keys when JWT_KEYS_DIR is not set
 	JWKSFile string
+	// JWKSCache holds JWKSFile parsed in memory; nil when the ring is
+	// managed or no file is configured.
+	JWKSCache *JWKSCache
+
+	// KeysAdminToken authorizes the ListSigningKeys/ReloadSigningKeys RPCs.
+	KeysAdminToken string
 }
 
 type JWKS struct {
@@ -38,9 +50,9 @@ type JWK struct {
 }
 
 func LoadJWTConfig() (*JWTConfig, error) {
-	refreshSecret := viper.GetString("JWT_REFRESH_SECRET")
-	if refreshSecret == "" {
-		return nil, ErrInvalidJWTConfig
+	refreshKeys, err := loadRefreshKeys()
+	if err != nil {
+		return nil, err
 	}
 
 	// Load TTL values
@@ -50,13 +62,65 @@ func LoadJWTConfig() (*JWTConfig, error) {
 		return nil, ErrInvalidJWTConfig
 	}
 
-	// Load access token EdDSA
+	keys, err := loadKeyRing()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &JWTConfig{
+		Keys:           keys,
+		AccessTTL:      accessTTL,
+		RefreshKeys:    refreshKeys,
+		RefreshTTL:     refreshTTL,
+		RefreshOpaque:  viper.GetBool("JWT_REFRESH_OPAQUE"),
+		JWKSFile:       viper.GetString("JWT_JWKS_FILE"),
+		KeysAdminToken: viper.GetString("JWT_KEYS_ADMIN_TOKEN"),
+	}
+	if !keys.Managed() && cfg.JWKSFile != "" {
+		cfg.JWKSCache = NewJWKSCache(cfg.JWKSFile)
+	}
+	return cfg, nil
+}
+
+// loadRefreshKeys combines JWT_REFRESH_KEYS with the optional legacy
+// JWT_REFRESH_SECRET. The legacy secret, if set, is the oldest key and keeps
+// verifying tokens issued without a kid until JWT_REFRESH_SECRET_RETIRED is
+// set, after which it can be removed.
+func loadRefreshKeys() (*RefreshKeyRing, error) {
+	keys, err := parseRefreshKeys(viper.GetString("JWT_REFRESH_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	if secret := viper.GetString("JWT_REFRESH_SECRET"); secret != "" {
+		legacy := RefreshKey{Secret: []byte(secret), Retired: viper.GetBool("JWT_REFRESH_SECRET_RETIRED")}
+		keys = append([]RefreshKey{legacy}, keys...)
+	}
+	if len(keys) == 0 {
+		return nil, ErrInvalidJWTConfig
+	}
+
+	ring, err := NewRefreshKeyRing(keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh keys: %w", err)
+	}
+	return ring, nil
+}
+
+// loadKeyRing prefers the managed keys directory and falls back to the single
+// key configured by JWT_ACCESS_PRIVATE_KEY_FILE and JWT_ACCESS_KID.
+func loadKeyRing() (*KeyRing, error) {
+	if keysDir := viper.GetString("JWT_KEYS_DIR"); keysDir != "" {
+		keys, err := NewKeyRing(keysDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keys from %s: %w", keysDir, err)
+		}
+		return keys, nil
+	}
+
 	accessPrivateKeyFile := viper.GetString("JWT_ACCESS_PRIVATE_KEY_FILE")
 	accessKID := viper.GetString("JWT_ACCESS_KID")
-	jwksFile := viper.GetString("JWT_JWKS_FILE")
-
 	if accessPrivateKeyFile == "" || accessKID == "" {
-		return nil, fmt.Errorf("JWT_ACCESS_PRIVATE_KEY_FILE and JWT_ACCESS_KID must be set")
+		return nil, fmt.Errorf("JWT_KEYS_DIR or JWT_ACCESS_PRIVATE_KEY_FILE and JWT_ACCESS_KID must be set")
 	}
 
 	// Load EdDSA private key for access token signing
@@ -64,15 +128,7 @@ func LoadJWTConfig() (*JWTConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load EdDSA private key from %s: %w", accessPrivateKeyFile, err)
 	}
-
-	return &JWTConfig{
-		AccessPrivateKey: accessPrivateKey,
-		AccessKID:        accessKID,
-		AccessTTL:        accessTTL,
-		RefreshSecret:    refreshSecret,
-		RefreshTTL:       refreshTTL,
-		JWKSFile:         jwksFile,
-	}, nil
+	return NewStaticKeyRing(accessKID, accessPrivateKey), nil
 }
 
 // loadEd25519PrivateKey loads an Ed25519 private key from PEM file
@@ -100,37 +156,45 @@ func loadEd25519PrivateKey(filepath string) (ed25519.PrivateKey, error) {
 	return ed25519Key, nil
 }
 
-func (cfg *JWTConfig) GetPublicKeyFromJWKS(kid string) (ed25519.PublicKey, error) {
-	if cfg.JWKSFile == "" {
-		return nil, fmt.Errorf("JWKS file not configured")
+// PublicKey resolves the verification key for kid. Managed rings are
+// authoritative; the legacy setup also accepts keys listed in the JWKS file
+// so tokens signed before a manual rotation stay valid.
+func (cfg *JWTConfig) PublicKey(kid string) (ed25519.PublicKey, error) {
+	if key, err := cfg.Keys.PublicKey(kid); err == nil {
+		return key, nil
 	}
-
-	jwksData, err := os.ReadFile(cfg.JWKSFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	if cfg.Keys.Managed() {
+		return nil, ErrKeyNotFound
 	}
+	return cfg.GetPublicKeyFromJWKS(kid)
+}
 
-	var jwks JWKS
-	if err := json.Unmarshal(jwksData, &jwks); err != nil {
-		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+func (cfg *JWTConfig) GetPublicKeyFromJWKS(kid string) (ed25519.PublicKey, error) {
+	if cfg.JWKSCache == nil {
+		return nil, fmt.Errorf("JWKS file not configured")
 	}
+	return cfg.JWKSCache.PublicKey(kid)
+}
 
-	for _, key := range jwks.Keys {
-		if key.Kid == kid && key.Kty == "OKP" && key.Crv == "Ed25519" {
-			pubKeyBytes, err := decodeBase64URL(key.X)
-			if err != nil {
-				return nil, fmt.Errorf("failed to decode public key: %w", err)
-			}
-
-			if len(pubKeyBytes) != ed25519.PublicKeySize {
-				return nil, fmt.Errorf("invalid Ed25519 public key size")
-			}
-
-			return ed25519.PublicKey(pubKeyBytes), nil
-		}
+// Watch keeps the key ring and the JWKS cache in sync with their files until
+// ctx is cancelled.
+func (cfg *JWTConfig) Watch(ctx context.Context, interval time.Duration) {
+	var wg sync.WaitGroup
+	if cfg.Keys.Managed() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.Keys.Watch(ctx, interval)
+		}()
 	}
-
-	return nil, fmt.Errorf("key with KID %s not found in JWKS", kid)
+	if cfg.JWKSCache != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.JWKSCache.Watch(ctx, interval)
+		}()
+	}
+	wg.Wait()
 }
 
 // decodeBase64URL decodes base64url without padding
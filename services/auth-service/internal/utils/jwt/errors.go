@@ -10,4 +10,7 @@ var (
 	ErrInvalidJWTConfig        = errors.New("jwt: invalid JWT config in environment")
 	ErrSessionNotFound         = errors.New("jwt: session not found")
 	ErrSessionRevoked          = errors.New("jwt: session revoked")
+	ErrRefreshTokenReused      = errors.New("jwt: refresh token reused")
+	ErrNoSigningKey            = errors.New("jwt: no active signing key")
+	ErrKeyNotFound             = errors.New("jwt: key not found")
 )
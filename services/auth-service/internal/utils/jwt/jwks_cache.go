@@ -0,0 +1,131 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JWKSDocument is a serialized JWKS with the validators used to answer
+// conditional requests on /.well-known/jwks.json.
+type JWKSDocument struct {
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+}
+
+func newJWKSDocument(jwks *JWKS, lastModified time.Time) (*JWKSDocument, error) {
+	body, err := json.Marshal(jwks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JWKS: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	return &JWKSDocument{
+		Body:         body,
+		ETag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		LastModified: lastModified.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// JWKSCache keeps the parsed JWKS file in memory, keyed by KID. It replaces
+// reading and decoding the file on every verification in the legacy
+// single-key setup. A reload that fails keeps the previous key set.
+type JWKSCache struct {
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]ed25519.PublicKey
+	doc  *JWKSDocument
+}
+
+// NewJWKSCache loads path. A missing or invalid file is logged rather than
+// returned so the service can start and pick the file up once it appears.
+func NewJWKSCache(path string) *JWKSCache {
+	c := &JWKSCache{path: path, keys: map[string]ed25519.PublicKey{}}
+	if err := c.Reload(); err != nil {
+		lg.Warn("JWKS file not loaded", "path", path, "error", err)
+	}
+	return c
+}
+
+// Reload re-reads and parses the JWKS file.
+func (c *JWKSCache) Reload() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	fi, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat JWKS file: %w", err)
+	}
+
+	var jwks JWKS
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		if key.Kty != "OKP" || key.Crv != "Ed25519" {
+			continue
+		}
+		pubKeyBytes, err := decodeBase64URL(key.X)
+		if err != nil {
+			return fmt.Errorf("failed to decode public key %s: %w", key.Kid, err)
+		}
+		if len(pubKeyBytes) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid Ed25519 public key size for %s", key.Kid)
+		}
+		keys[key.Kid] = ed25519.PublicKey(pubKeyBytes)
+	}
+
+	doc, err := newJWKSDocument(&jwks, fi.ModTime())
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.doc = doc
+	c.mu.Unlock()
+	return nil
+}
+
+// PublicKey returns the key for kid.
+func (c *JWKSCache) PublicKey(kid string) (ed25519.PublicKey, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	key, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("key with KID %s not found in JWKS", kid)
+	}
+	return key, nil
+}
+
+// Document returns the serialized JWKS.
+func (c *JWKSCache) Document() (*JWKSDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.doc == nil {
+		return nil, fmt.Errorf("JWKS file %s not loaded", c.path)
+	}
+	return c.doc, nil
+}
+
+// Watch reloads the cache when the JWKS file changes until ctx is cancelled.
+func (c *JWKSCache) Watch(ctx context.Context, interval time.Duration) {
+	watchDir(ctx, filepath.Dir(c.path), c.path, interval, func(reason string) {
+		if err := c.Reload(); err != nil {
+			lg.Error("JWKS reload failed, keeping previous key set", "reason", reason, "error", err)
+			return
+		}
+		lg.Info("JWKS file reloaded", "reason", reason)
+	}, func() {})
+}
@@ -0,0 +1,123 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeJWKS writes a JWKS file holding one new key under kid and returns the
+// key.
+func writeJWKS(t *testing.T, path, kid string, modTime time.Time) ed25519.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(JWKS{Keys: []JWK{{Kty: "OKP", Crv: "Ed25519", Use: "sig", Kid: kid, X: base64.RawURLEncoding.EncodeToString(pub)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+func TestJWKSCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	modTime := time.Date(2025, 11, 1, 12, 0, 0, 500, time.UTC)
+	pub := writeJWKS(t, path, "k1", modTime)
+
+	c := NewJWKSCache(path)
+	got, err := c.PublicKey("k1")
+	if err != nil || !got.Equal(pub) {
+		t.Fatalf("PublicKey(k1) = %x, %v; want %x", got, err, pub)
+	}
+	if _, err := c.PublicKey("k2"); err == nil {
+		t.Error("PublicKey(k2) found a key that is not in the file")
+	}
+	doc, err := c.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.LastModified.Equal(modTime.Truncate(time.Second)) {
+		t.Errorf("LastModified = %s; want the file's mtime in seconds, %s", doc.LastModified, modTime.Truncate(time.Second))
+	}
+
+	// Reloading the same file keeps the validators.
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := c.Document(); same.ETag != doc.ETag || !same.LastModified.Equal(doc.LastModified) {
+		t.Errorf("document after reloading the same file = %+v; want %+v", same, doc)
+	}
+
+	writeJWKS(t, path, "k2", modTime.Add(time.Hour))
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if next, _ := c.Document(); next.ETag == doc.ETag || !next.LastModified.After(doc.LastModified) {
+		t.Errorf("reloaded document = %+v; want a new ETag and Last-Modified", next)
+	}
+}
+
+func TestJWKSCacheKeepsPreviousSetOnBadReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	pub := writeJWKS(t, path, "k1", time.Now())
+	c := NewJWKSCache(path)
+	doc, err := c.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data string // "" removes the file
+	}{
+		{"not json", `{"keys":`},
+		{"bad base64", `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k2","x":"!!"}]}`},
+		{"short key", `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k2","x":"AAAA"}]}`},
+		{"removed", ""},
+	}
+	for _, tt := range tests {
+		if tt.data == "" {
+			os.Remove(path)
+		} else if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Reload(); err == nil {
+			t.Errorf("%s: Reload succeeded; want an error", tt.name)
+		}
+		if got, err := c.PublicKey("k1"); err != nil || !got.Equal(pub) {
+			t.Errorf("%s: PublicKey(k1) = %v; want the previous key", tt.name, err)
+		}
+		if got, _ := c.Document(); got != doc {
+			t.Errorf("%s: Document changed; want the previous one served", tt.name)
+		}
+	}
+}
+
+func TestJWKSCacheMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	c := NewJWKSCache(path)
+	if _, err := c.Document(); err == nil {
+		t.Error("Document without a file succeeded")
+	}
+
+	// The file is picked up once it appears.
+	writeJWKS(t, path, "k1", time.Now())
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Document(); err != nil {
+		t.Errorf("Document after the file appeared = %v", err)
+	}
+}
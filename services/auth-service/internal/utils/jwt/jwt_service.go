@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
@@ -20,6 +19,7 @@ type JWTService interface {
 	GetAccessTTL() time.Duration
 	GetRefreshTTL() time.Duration
 	GetJWKS() (*JWKS, error)
+	GetJWKSDocument() (*JWKSDocument, error)
 }
 
 type jwtService struct {
@@ -46,10 +46,15 @@ func (j *jwtService) SignAccessToken(userID, sid string, av uint64) (string, tim
 		},
 	}
 
+	signer, err := j.cfg.Keys.Signer()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
-	token.Header["kid"] = j.cfg.AccessKID
+	token.Header["kid"] = signer.KID
 
-	signed, err := token.SignedString(j.cfg.AccessPrivateKey)
+	signed, err := token.SignedString(signer.PrivateKey)
 	return signed, exp, err
 }
 
@@ -68,8 +73,13 @@ func (j *jwtService) SignRefreshToken(userID string, sid string, jti string) (st
 		},
 	}
 
+	signer := j.cfg.RefreshKeys.Signer()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(j.cfg.RefreshSecret))
+	if signer.KID != "" {
+		token.Header["kid"] = signer.KID
+	}
+
+	signed, err := token.SignedString(signer.Secret)
 	return signed, exp, err
 }
 
@@ -91,7 +101,7 @@ func (j *jwtService) VerifyAccessToken(tokenStr string) (*AccessClaims, error) {
 			return nil, ErrTokenInvalid
 		}
 
-		publicKey, err := j.cfg.GetPublicKeyFromJWKS(kid)
+		publicKey, err := j.cfg.PublicKey(kid)
 		if err != nil {
 			return nil, ErrTokenInvalid
 		}
@@ -120,7 +130,14 @@ func (j *jwtService) VerifyRefreshToken(tokenStr string) (*RefreshClaims, error)
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrUnexpectedSigningMethod
 		}
-		return []byte(j.cfg.RefreshSecret), nil
+
+		// Tokens without a kid predate the key ring and map to the legacy secret.
+		kid, _ := token.Header["kid"].(string)
+		secret, err := j.cfg.RefreshKeys.Secret(kid)
+		if err != nil {
+			return nil, ErrTokenInvalid
+		}
+		return secret, nil
 	}
 
 	token, err := jwt.ParseWithClaims(
@@ -169,20 +186,26 @@ func (j *jwtService) ExtractTokenFromHeader(authHeader string) (string, error) {
 	return token, nil
 }
 
+// GetJWKS publishes every non-retired key of a managed ring. The legacy
+// setup serves the JWKS file, or just the configured key without one.
 func (j *jwtService) GetJWKS() (*JWKS, error) {
-	if j.cfg.JWKSFile == "" {
-		return nil, fmt.Errorf("JWKS file not configured")
-	}
-
-	jwksData, err := os.ReadFile(j.cfg.JWKSFile)
+	doc, err := j.GetJWKSDocument()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+		return nil, err
 	}
 
 	var jwks JWKS
-	if err := json.Unmarshal(jwksData, &jwks); err != nil {
+	if err := json.Unmarshal(doc.Body, &jwks); err != nil {
 		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
 	}
-
 	return &jwks, nil
 }
+
+// GetJWKSDocument returns the same key set as GetJWKS, serialized and with
+// ETag/Last-Modified validators, from memory.
+func (j *jwtService) GetJWKSDocument() (*JWKSDocument, error) {
+	if j.cfg.JWKSCache != nil {
+		return j.cfg.JWKSCache.Document()
+	}
+	return j.cfg.Keys.Document()
+}
@@ -0,0 +1,341 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyState is the lifecycle state of an access token signing key.
+//
+//	next     published in the JWKS so verifiers can fetch it ahead of time;
+//	         becomes the signer once its activate_at passes
+//	active   signs new tokens (the most recently activated one wins)
+//	retiring no longer signs but is still published and accepted
+//	retired  neither published nor accepted
+type KeyState string
+
+const (
+	KeyStateNext     KeyState = "next"
+	KeyStateActive   KeyState = "active"
+	KeyStateRetiring KeyState = "retiring"
+	KeyStateRetired  KeyState = "retired"
+)
+
+func (s KeyState) IsValid() bool {
+	switch s {
+	case KeyStateNext, KeyStateActive, KeyStateRetiring, KeyStateRetired:
+		return true
+	default:
+		return false
+	}
+}
+
+// ManifestFile is the file in the keys directory listing every key and its state.
+const ManifestFile = "keys.json"
+
+// KeyManifest is the on-disk description of the key directory, e.g.
+//
+//	{"keys": [
+//	  {"kid": "20251001T000000Z-ab12c", "file": "ed25519-20251001T000000Z-ab12c.pem", "state": "active"},
+//	  {"kid": "20251101T000000Z-cd34e", "file": "ed25519-20251101T000000Z-cd34e.pem", "state": "next",
+//	   "activate_at": "2025-11-02T00:00:00Z"}
+//	]}
+type KeyManifest struct {
+	Keys []KeyManifestEntry `json:"keys"`
+}
+
+type KeyManifestEntry struct {
+	KID        string     `json:"kid"`
+	File       string     `json:"file"`
+	State      KeyState   `json:"state"`
+	ActivateAt *time.Time `json:"activate_at,omitempty"`
+	RetireAt   *time.Time `json:"retire_at,omitempty"`
+}
+
+// SigningKey is a loaded Ed25519 key pair with its declared schedule.
+type SigningKey struct {
+	KID        string
+	State      KeyState
+	ActivateAt time.Time
+	RetireAt   time.Time
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+// KeyStatus is the effective state of a key at a point in time.
+type KeyStatus struct {
+	KID        string
+	State      KeyState
+	ActivateAt time.Time
+	RetireAt   time.Time
+	Signing    bool
+}
+
+// KeyRing holds the access token signing keys. States are evaluated against
+// the clock on every call, so a "next" key takes over signing when its
+// activate_at passes without a reload. Reload swaps the whole set atomically
+// and keeps the previous one if the directory is invalid.
+type KeyRing struct {
+	dir string
+	now func() time.Time
+
+	mu       sync.RWMutex
+	keys     []*SigningKey
+	loadedAt time.Time
+
+	docMu sync.Mutex
+	doc   *JWKSDocument
+}
+
+// NewKeyRing loads the keys described by dir/keys.json.
+func NewKeyRing(dir string) (*KeyRing, error) {
+	r := &KeyRing{dir: dir, now: time.Now}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewStaticKeyRing wraps a single always-active key. It backs the legacy
+// JWT_ACCESS_PRIVATE_KEY_FILE/JWT_ACCESS_KID configuration.
+func NewStaticKeyRing(kid string, privateKey ed25519.PrivateKey) *KeyRing {
+	return &KeyRing{
+		now: time.Now,
+		keys: []*SigningKey{{
+			KID:        kid,
+			State:      KeyStateActive,
+			PrivateKey: privateKey,
+			PublicKey:  privateKey.Public().(ed25519.PublicKey),
+		}},
+		loadedAt: time.Now(),
+	}
+}
+
+// Managed reports whether the ring is backed by a keys directory.
+func (r *KeyRing) Managed() bool {
+	return r.dir != ""
+}
+
+// Dir returns the keys directory, or "" for a static ring.
+func (r *KeyRing) Dir() string {
+	return r.dir
+}
+
+// Reload re-reads the manifest and key files.
+func (r *KeyRing) Reload() error {
+	if r.dir == "" {
+		return nil
+	}
+	keys, err := loadKeyDir(r.dir)
+	if err != nil {
+		return err
+	}
+	if _, _, err := evaluate(keys, r.now()); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.loadedAt = r.now()
+	r.mu.Unlock()
+	return nil
+}
+
+// LoadedAt returns when the key set was last (re)loaded.
+func (r *KeyRing) LoadedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loadedAt
+}
+
+// Signer returns the key new access tokens are signed with.
+func (r *KeyRing) Signer() (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, signer, err := evaluate(r.keys, r.now())
+	return signer, err
+}
+
+// PublicKey returns the verification key for kid unless it is retired.
+func (r *KeyRing) PublicKey(kid string) (ed25519.PublicKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	states, _, _ := evaluate(r.keys, r.now())
+	for _, k := range r.keys {
+		if k.KID == kid && states[kid] != KeyStateRetired {
+			return k.PublicKey, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Status returns the effective state of every key, signer first.
+func (r *KeyRing) Status() []KeyStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.statusLocked()
+}
+
+// statusLocked is Status for callers holding r.mu.
+func (r *KeyRing) statusLocked() []KeyStatus {
+	states, signer, _ := evaluate(r.keys, r.now())
+	out := make([]KeyStatus, 0, len(r.keys))
+	for _, k := range r.keys {
+		out = append(out, KeyStatus{
+			KID:        k.KID,
+			State:      states[k.KID],
+			ActivateAt: k.ActivateAt,
+			RetireAt:   k.RetireAt,
+			Signing:    signer != nil && signer.KID == k.KID,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Signing != out[j].Signing {
+			return out[i].Signing
+		}
+		return out[i].ActivateAt.After(out[j].ActivateAt)
+	})
+	return out
+}
+
+// JWKS returns every non-retired public key, signer first. States and keys
+// are read under one lock so a concurrent Reload cannot drop a listed kid.
+func (r *KeyRing) JWKS() *JWKS {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	status := r.statusLocked()
+	byKID := make(map[string]*SigningKey, len(r.keys))
+	for _, k := range r.keys {
+		byKID[k.KID] = k
+	}
+
+	jwks := &JWKS{Keys: make([]JWK, 0, len(status))}
+	for _, s := range status {
+		if s.State == KeyStateRetired {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			Use: "sig",
+			Kid: s.KID,
+			X:   base64.RawURLEncoding.EncodeToString(byKID[s.KID].PublicKey),
+		})
+	}
+	return jwks
+}
+
+// Document returns the serialized JWKS. The document is rebuilt on every call
+// because key states depend on the clock, but LastModified only moves when
+// the published key set actually changes.
+func (r *KeyRing) Document() (*JWKSDocument, error) {
+	doc, err := newJWKSDocument(r.JWKS(), r.now())
+	if err != nil {
+		return nil, err
+	}
+
+	r.docMu.Lock()
+	defer r.docMu.Unlock()
+	if r.doc != nil && r.doc.ETag == doc.ETag {
+		return r.doc, nil
+	}
+	r.doc = doc
+	return doc, nil
+}
+
+// evaluate computes effective states at now. Keys past retire_at are retired;
+// active keys and next keys past activate_at compete for signing, the most
+// recently activated one wins and the others are reported as retiring.
+func evaluate(keys []*SigningKey, now time.Time) (map[string]KeyState, *SigningKey, error) {
+	states := make(map[string]KeyState, len(keys))
+	var signer *SigningKey
+	var candidates []*SigningKey
+
+	for _, k := range keys {
+		state := k.State
+		if state != KeyStateRetired && !k.RetireAt.IsZero() && !now.Before(k.RetireAt) {
+			state = KeyStateRetired
+		}
+		if state == KeyStateNext && !k.ActivateAt.IsZero() && !now.Before(k.ActivateAt) {
+			state = KeyStateActive
+		}
+		states[k.KID] = state
+		if state == KeyStateActive {
+			candidates = append(candidates, k)
+		}
+	}
+
+	for _, k := range candidates {
+		if signer == nil || !k.ActivateAt.Before(signer.ActivateAt) {
+			signer = k
+		}
+	}
+	for _, k := range candidates {
+		if k != signer {
+			states[k.KID] = KeyStateRetiring
+		}
+	}
+
+	if signer == nil {
+		return states, nil, ErrNoSigningKey
+	}
+	return states, signer, nil
+}
+
+func loadKeyDir(dir string) ([]*SigningKey, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key manifest: %w", err)
+	}
+
+	var manifest KeyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse key manifest: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(manifest.Keys))
+	keys := make([]*SigningKey, 0, len(manifest.Keys))
+	for _, e := range manifest.Keys {
+		if e.KID == "" || e.File == "" {
+			return nil, fmt.Errorf("key manifest entry needs kid and file")
+		}
+		if _, dup := seen[e.KID]; dup {
+			return nil, fmt.Errorf("duplicate kid %s in key manifest", e.KID)
+		}
+		seen[e.KID] = struct{}{}
+		if !e.State.IsValid() {
+			return nil, fmt.Errorf("key %s has invalid state %q", e.KID, e.State)
+		}
+
+		path := e.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		privateKey, err := loadEd25519PrivateKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", e.KID, err)
+		}
+
+		k := &SigningKey{
+			KID:        e.KID,
+			State:      e.State,
+			PrivateKey: privateKey,
+			PublicKey:  privateKey.Public().(ed25519.PublicKey),
+		}
+		if e.ActivateAt != nil {
+			k.ActivateAt = e.ActivateAt.UTC()
+		}
+		if e.RetireAt != nil {
+			k.RetireAt = e.RetireAt.UTC()
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
@@ -0,0 +1,309 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		keys       []*SigningKey
+		wantSigner string
+		wantStates map[string]KeyState
+		wantErr    error
+	}{
+		{
+			name:       "single active key signs",
+			keys:       []*SigningKey{{KID: "a", State: KeyStateActive}},
+			wantSigner: "a",
+			wantStates: map[string]KeyState{"a": KeyStateActive},
+		},
+		{
+			name: "next key before activate_at is only published",
+			keys: []*SigningKey{
+				{KID: "a", State: KeyStateActive},
+				{KID: "b", State: KeyStateNext, ActivateAt: after},
+			},
+			wantSigner: "a",
+			wantStates: map[string]KeyState{"a": KeyStateActive, "b": KeyStateNext},
+		},
+		{
+			name: "next key takes over at activate_at",
+			keys: []*SigningKey{
+				{KID: "a", State: KeyStateActive, ActivateAt: before},
+				{KID: "b", State: KeyStateNext, ActivateAt: now},
+			},
+			wantSigner: "b",
+			wantStates: map[string]KeyState{"a": KeyStateRetiring, "b": KeyStateActive},
+		},
+		{
+			name: "next key without activate_at never activates",
+			keys: []*SigningKey{
+				{KID: "a", State: KeyStateActive},
+				{KID: "b", State: KeyStateNext},
+			},
+			wantSigner: "a",
+			wantStates: map[string]KeyState{"a": KeyStateActive, "b": KeyStateNext},
+		},
+		{
+			name: "most recently activated active key wins",
+			keys: []*SigningKey{
+				{KID: "new", State: KeyStateActive, ActivateAt: now},
+				{KID: "old", State: KeyStateActive, ActivateAt: before},
+			},
+			wantSigner: "new",
+			wantStates: map[string]KeyState{"new": KeyStateActive, "old": KeyStateRetiring},
+		},
+		{
+			name: "key is retired at retire_at",
+			keys: []*SigningKey{
+				{KID: "a", State: KeyStateRetiring, RetireAt: now},
+				{KID: "b", State: KeyStateActive},
+			},
+			wantSigner: "b",
+			wantStates: map[string]KeyState{"a": KeyStateRetired, "b": KeyStateActive},
+		},
+		{
+			name: "retiring key before retire_at is still accepted",
+			keys: []*SigningKey{
+				{KID: "a", State: KeyStateRetiring, RetireAt: after},
+				{KID: "b", State: KeyStateActive},
+			},
+			wantSigner: "b",
+			wantStates: map[string]KeyState{"a": KeyStateRetiring, "b": KeyStateActive},
+		},
+		{
+			name: "retire_at wins over activate_at",
+			keys: []*SigningKey{
+				{KID: "a", State: KeyStateActive},
+				{KID: "b", State: KeyStateNext, ActivateAt: before, RetireAt: now},
+			},
+			wantSigner: "a",
+			wantStates: map[string]KeyState{"a": KeyStateActive, "b": KeyStateRetired},
+		},
+		{
+			name:       "active key past retire_at leaves no signer",
+			keys:       []*SigningKey{{KID: "a", State: KeyStateActive, RetireAt: before}},
+			wantStates: map[string]KeyState{"a": KeyStateRetired},
+			wantErr:    ErrNoSigningKey,
+		},
+		{
+			name:       "no keys",
+			wantStates: map[string]KeyState{},
+			wantErr:    ErrNoSigningKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states, signer, err := evaluate(tt.keys, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			gotSigner := ""
+			if signer != nil {
+				gotSigner = signer.KID
+			}
+			if gotSigner != tt.wantSigner {
+				t.Errorf("signer = %q, want %q", gotSigner, tt.wantSigner)
+			}
+			if len(states) != len(tt.wantStates) {
+				t.Errorf("states = %v, want %v", states, tt.wantStates)
+			}
+			for kid, want := range tt.wantStates {
+				if states[kid] != want {
+					t.Errorf("state[%s] = %s, want %s", kid, states[kid], want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyRingFollowsClock(t *testing.T) {
+	now := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	activateAt := now.Add(time.Hour)
+	retireAt := now.Add(2 * time.Hour)
+
+	r := &KeyRing{
+		now: func() time.Time { return now },
+		keys: []*SigningKey{
+			newTestKey(t, "old", KeyStateActive, time.Time{}, retireAt),
+			newTestKey(t, "new", KeyStateNext, activateAt, time.Time{}),
+		},
+	}
+
+	signerAt := func(at time.Time) string {
+		now = at
+		k, err := r.Signer()
+		if err != nil {
+			t.Fatalf("Signer at %s: %v", at, err)
+		}
+		return k.KID
+	}
+
+	if got := signerAt(now); got != "old" {
+		t.Errorf("signer before activate_at = %s, want old", got)
+	}
+	if got := len(r.JWKS().Keys); got != 2 {
+		t.Errorf("published keys before activate_at = %d, want 2", got)
+	}
+
+	if got := signerAt(activateAt); got != "new" {
+		t.Errorf("signer at activate_at = %s, want new", got)
+	}
+	if _, err := r.PublicKey("old"); err != nil {
+		t.Errorf("retiring key rejected: %v", err)
+	}
+
+	now = retireAt
+	if _, err := r.PublicKey("old"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("PublicKey(old) after retire_at = %v, want ErrKeyNotFound", err)
+	}
+	jwks := r.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "new" {
+		t.Errorf("JWKS after retire_at = %+v, want only new", jwks.Keys)
+	}
+}
+
+func newTestKey(t *testing.T, kid string, state KeyState, activateAt, retireAt time.Time) *SigningKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SigningKey{
+		KID:        kid,
+		State:      state,
+		ActivateAt: activateAt,
+		RetireAt:   retireAt,
+		PrivateKey: priv,
+		PublicKey:  pub,
+	}
+}
+
+// writeKeyDir writes a key file for each kid and a manifest listing them as
+// active, and returns the manifest.
+func writeKeyDir(t *testing.T, dir string, kids ...string) []byte {
+	t.Helper()
+	manifest := KeyManifest{}
+	for _, kid := range kids {
+		_, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := kid + ".pem"
+		if err := os.WriteFile(filepath.Join(dir, file), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		manifest.Keys = append(manifest.Keys, KeyManifestEntry{KID: kid, File: file, State: KeyStateActive})
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeManifest(t, dir, data)
+	return data
+}
+
+// writeManifest replaces the manifest by a rename, so a concurrent Reload
+// never reads it half-written.
+func writeManifest(t *testing.T, dir string, data []byte) {
+	tmp := filepath.Join(dir, ManifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, ManifestFile)); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestKeyRingJWKSDuringReload swaps between two key sets that share no kid
+// while JWKS is built. Run with -race.
+func TestKeyRingJWKSDuringReload(t *testing.T) {
+	dir := t.TempDir()
+	setB := writeKeyDir(t, dir, "b1")
+	setA := writeKeyDir(t, dir, "a1", "a2")
+
+	r, err := NewKeyRing(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if i%2 == 0 {
+				writeManifest(t, dir, setB)
+			} else {
+				writeManifest(t, dir, setA)
+			}
+			if err := r.Reload(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for range 2000 {
+		for _, k := range r.JWKS().Keys {
+			if k.X == "" {
+				t.Fatalf("key %s published without its public key", k.Kid)
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestKeyRingKeepsPreviousSetOnBadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	writeKeyDir(t, dir, "a1", "a2")
+	r, err := NewKeyRing(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := r.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a2.pem"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Fatal("Reload of a bad key file succeeded")
+	}
+	got, err := r.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ETag != doc.ETag || string(got.Body) != string(doc.Body) {
+		t.Errorf("document after a failed reload = %s; want the previous %s", got.Body, doc.Body)
+	}
+}
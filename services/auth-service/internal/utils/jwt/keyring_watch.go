@@ -0,0 +1,40 @@
+package jwt
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+)
+
+// Watch reloads the ring when files in the keys directory change and, every
+// interval, logs signing key switches caused by the schedule. It returns when
+// ctx is cancelled and is a no-op for a static ring.
+func (r *KeyRing) Watch(ctx context.Context, interval time.Duration) {
+	if !r.Managed() {
+		return
+	}
+
+	lastSigner := r.signerKID()
+	watchDir(ctx, r.dir, filepath.Join(r.dir, ManifestFile), interval, r.reloadAndLog, func() {
+		if kid := r.signerKID(); kid != lastSigner {
+			lg.Info("Signing key switched", "from_kid", lastSigner, "to_kid", kid)
+			lastSigner = kid
+		}
+	})
+}
+
+func (r *KeyRing) reloadAndLog(reason string) {
+	if err := r.Reload(); err != nil {
+		lg.Error("Key reload failed, keeping previous key set", "reason", reason, "error", err)
+		return
+	}
+	lg.Info("Signing keys reloaded", "reason", reason, "keys", len(r.Status()))
+}
+
+func (r *KeyRing) signerKID() string {
+	k, err := r.Signer()
+	if err != nil {
+		return ""
+	}
+	return k.KID
+}
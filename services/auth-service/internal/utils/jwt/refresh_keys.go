@@ -0,0 +1,88 @@
+package jwt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RefreshKey is an HMAC secret for refresh tokens. The legacy
+// JWT_REFRESH_SECRET has an empty KID and signs tokens without a kid header.
+type RefreshKey struct {
+	KID     string
+	Secret  []byte
+	Retired bool
+}
+
+// RefreshKeyRing holds the refresh token secrets, oldest first. The newest
+// non-retired key signs; every non-retired key verifies, so a new secret can
+// be rolled out without logging users out and the old one retired once the
+// refresh TTL has passed.
+type RefreshKeyRing struct {
+	keys []RefreshKey
+}
+
+// NewRefreshKeyRing validates keys, ordered oldest first.
+func NewRefreshKeyRing(keys []RefreshKey) (*RefreshKeyRing, error) {
+	seen := make(map[string]bool, len(keys))
+	active := 0
+	for _, k := range keys {
+		if len(k.Secret) == 0 {
+			return nil, fmt.Errorf("refresh key %q has an empty secret", k.KID)
+		}
+		if seen[k.KID] {
+			return nil, fmt.Errorf("duplicate refresh key %q", k.KID)
+		}
+		seen[k.KID] = true
+		if !k.Retired {
+			active++
+		}
+	}
+	if active == 0 {
+		return nil, ErrNoSigningKey
+	}
+	return &RefreshKeyRing{keys: keys}, nil
+}
+
+// Signer returns the newest non-retired key.
+func (r *RefreshKeyRing) Signer() RefreshKey {
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if !r.keys[i].Retired {
+			return r.keys[i]
+		}
+	}
+	// NewRefreshKeyRing guarantees a non-retired key.
+	panic("jwt: refresh key ring without signer")
+}
+
+// Secret returns the secret for kid, or ErrKeyNotFound if it is unknown or
+// retired.
+func (r *RefreshKeyRing) Secret(kid string) ([]byte, error) {
+	for _, k := range r.keys {
+		if k.KID == kid && !k.Retired {
+			return k.Secret, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// parseRefreshKeys parses JWT_REFRESH_KEYS: comma-separated
+// "kid:secret[:retired]" entries, oldest first. The kid ends at the first
+// colon, so secrets may contain colons but not commas, and a secret cannot
+// itself end in ":retired".
+func parseRefreshKeys(spec string) ([]RefreshKey, error) {
+	var keys []RefreshKey
+	for i, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, secret, ok := strings.Cut(entry, ":")
+		secret, retired := strings.CutSuffix(secret, ":retired")
+		if !ok || kid == "" || secret == "" {
+			// Never echo the entry, it may be a bare secret.
+			return nil, fmt.Errorf("invalid refresh key entry #%d, want kid:secret[:retired]", i+1)
+		}
+		keys = append(keys, RefreshKey{KID: kid, Secret: []byte(secret), Retired: retired})
+	}
+	return keys, nil
+}
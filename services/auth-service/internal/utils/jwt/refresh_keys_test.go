@@ -0,0 +1,38 @@
+package jwt
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseRefreshKeys(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []RefreshKey
+		wantErr bool
+	}{
+		{"r1:s1", []RefreshKey{{KID: "r1", Secret: []byte("s1")}}, false},
+		{" r1:s1:retired , r2:s2 ", []RefreshKey{{KID: "r1", Secret: []byte("s1"), Retired: true}, {KID: "r2", Secret: []byte("s2")}}, false},
+		// Only the first colon ends the kid.
+		{"r1:a:b:c", []RefreshKey{{KID: "r1", Secret: []byte("a:b:c")}}, false},
+		{"r1:a:b:retired", []RefreshKey{{KID: "r1", Secret: []byte("a:b"), Retired: true}}, false},
+		{"r1:s1,,", []RefreshKey{{KID: "r1", Secret: []byte("s1")}}, false},
+		{"", nil, false},
+		{"bare-secret", nil, true},
+		{":s1", nil, true},
+		{"r1:", nil, true},
+		{"r1::retired", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRefreshKeys(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRefreshKeys(%q) error = %v; want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !slices.EqualFunc(got, tt.want, func(a, b RefreshKey) bool {
+			return a.KID == b.KID && string(a.Secret) == string(b.Secret) && a.Retired == b.Retired
+		}) {
+			t.Errorf("parseRefreshKeys(%q) = %+v; want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"context"
+	"music-player/api/logger"
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var lg = logger.For("jwt.keys")
+
+// reloadDebounce coalesces the burst of events an editor or a rotation
+// script produces when rewriting several files.
+const reloadDebounce = 500 * time.Millisecond
+
+// watchDir calls onChange when files in dir change. Every interval it also
+// stats statPath and calls onChange if its modification time moved, so
+// changes are picked up on filesystems where fsnotify is unavailable (e.g.
+// some network mounts), then calls onTick. It returns when ctx is cancelled.
+func watchDir(ctx context.Context, dir, statPath string, interval time.Duration, onChange func(reason string), onTick func()) {
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(dir)
+	}
+	if err != nil {
+		lg.Warn("Directory watch unavailable, falling back to polling", "dir", dir, "error", err)
+	} else {
+		defer watcher.Close()
+		events, errs = watcher.Events, watcher.Errors
+	}
+
+	lastMod := modTime(statPath)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				debounce = time.After(reloadDebounce)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			lg.Warn("Directory watch error", "dir", dir, "error", err)
+		case <-debounce:
+			debounce = nil
+			onChange("file change")
+			lastMod = modTime(statPath)
+		case <-ticker.C:
+			if mod := modTime(statPath); !mod.Equal(lastMod) {
+				lastMod = mod
+				onChange("modified")
+			}
+		}
+		onTick()
+	}
+}
+
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
@@ -3,11 +3,20 @@ package redisutil
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrConflict is returned by UpdateJSON when the key kept changing under
+// concurrent writers.
+var ErrConflict = errors.New("redis: concurrent update")
+
+// updateRetries bounds how often UpdateJSON re-reads a key that changed
+// between its read and its write.
+const updateRetries = 5
+
 type RedisUtil struct {
 	client *redis.Client
 }
@@ -50,6 +59,70 @@ func (r *RedisUtil) GetJSON(ctx context.Context, key string, dest interface{}) e
 	return json.Unmarshal(data, dest)
 }
 
+// UpdateJSON reads the JSON value at key, passes it and the key's remaining
+// TTL to update and writes the result back with the TTL update returns. The key is WATCHed, so a write by
+// someone else in between makes the transaction retry against the new value
+// instead of overwriting it. An error from update aborts without writing;
+// redis.Nil is returned when the key does not exist.
+func UpdateJSON[T any](ctx context.Context, r *RedisUtil, key string, update func(value *T, ttl time.Duration) (time.Duration, error)) error {
+	for i := 0; i < updateRetries; i++ {
+		err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, key).Bytes()
+			if err != nil {
+				return err
+			}
+			var value T
+			if err := json.Unmarshal(data, &value); err != nil {
+				return err
+			}
+			ttl, err := tx.PTTL(ctx, key).Result()
+			if err != nil {
+				return err
+			}
+			ttl, err = update(&value, max(ttl, 0))
+			if err != nil {
+				return err
+			}
+			out, err := json.Marshal(&value)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, out, ttl)
+				return nil
+			})
+			return err
+		}, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return ErrConflict
+}
+
+// SetNX stores value with a TTL unless key exists, and reports whether it
+// did.
+func (r *RedisUtil) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+// Incr increments the counter at key and returns its value. A new counter
+// expires after ttl.
+func (r *RedisUtil) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// RunScript runs a Lua script atomically, by SHA when Redis has it cached.
+func (r *RedisUtil) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return script.Run(ctx, r.client, keys, args...).Result()
+}
+
 func (r *RedisUtil) PTTL(ctx context.Context, key string) time.Duration {
 	ttl, err := r.client.PTTL(ctx, key).Result()
 	if err != nil {
@@ -60,3 +133,12 @@ func (r *RedisUtil) PTTL(ctx context.Context, key string) time.Duration {
 	}
 	return ttl
 }
+
+// PublishJSON marshals a value to JSON and publishes it on a pub/sub channel.
+func (r *RedisUtil) PublishJSON(ctx context.Context, channel string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, channel, data).Err()
+}
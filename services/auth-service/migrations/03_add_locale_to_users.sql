@@ -0,0 +1,8 @@
+-- +goose Up
+-- Thêm trường locale cho bảng users để gửi thông báo theo ngôn ngữ của người dùng (vi, en)
+ALTER TABLE users
+ADD COLUMN locale VARCHAR(16) NOT NULL DEFAULT 'vi';
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN locale;
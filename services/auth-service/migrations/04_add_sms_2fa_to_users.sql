@@ -0,0 +1,10 @@
+-- +goose Up
+-- Thêm số điện thoại (định dạng E.164) và cờ sms_two_fa_enabled để gửi mã đăng nhập qua SMS thay cho TOTP
+ALTER TABLE users
+ADD COLUMN phone_number VARCHAR(16),
+ADD COLUMN sms_two_fa_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN phone_number,
+DROP COLUMN sms_two_fa_enabled;
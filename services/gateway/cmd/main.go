@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"gateway/configs"
-	"log"
+	"gateway/internal/routes"
+	"log/slog"
+	"music-player/api/logger"
+	"music-player/api/tracing"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,10 +19,23 @@ import (
 func main() {
 	appCfg := configs.LoadAppConfig()
 	redisCfg := configs.LoadRedisConfig()
+	logCfg := logger.LoadConfig()
+	securityCfg := configs.LoadSecurityConfig()
+	realtimeCfg := configs.LoadRealtimeConfig()
+	tracingCfg := tracing.LoadConfig("gateway")
 
-	app, err := InitializeApp(appCfg, redisCfg)
+	if err := logger.Init("gateway", logCfg.Level, logCfg.Levels); err != nil {
+		fatal("Failed to initialize logger", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), tracingCfg)
+	if err != nil {
+		fatal("Failed to initialize tracing", err)
+	}
+
+	app, err := InitializeApp(appCfg, redisCfg, realtimeCfg, logCfg, securityCfg)
 	if err != nil {
-		log.Fatalf("[FATAL] Failed to initialize app: %v", err)
+		fatal("Failed to initialize app", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -36,34 +52,84 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		log.Printf("[INFO] HTTP server starting in %s mode on port %s", appCfg.Env, appCfg.Port)
+		slog.Info("HTTP server starting", "env", appCfg.Env, "port", appCfg.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[ERROR] HTTP server error: %v", err)
+			slog.Error("HTTP server error", "error", err)
 			cancel()
 		}
 	}()
 
+	// Metrics are scraped on a separate listener that is not published
+	var metricsSrv *http.Server
+	if appCfg.MetricsAddr != "" {
+		metricsSrv = &http.Server{
+			Addr:              appCfg.MetricsAddr,
+			Handler:           routes.MetricsHandler(),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("Metrics server starting", "addr", appCfg.MetricsAddr)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("Metrics server error", "error", err)
+			}
+		}()
+	}
+
+	// Keep the JWKS fresh ahead of expiry and key rotations
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.JWKSClient.Run(ctx)
+	}()
+
+	// Drop cached sessions as soon as auth-service revokes or rotates them
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.SessionCache.Listen(ctx, app.RedisUtil)
+	}()
+
+	// Push user and session events to realtime connections; on shutdown
+	// they are told to reconnect to another replica
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.Hub.Listen(ctx)
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	select {
 	case <-quit:
-		log.Println("[INFO] Shutting down gracefully...")
+		slog.Info("Shutting down gracefully")
 	case <-ctx.Done():
-		log.Println("[INFO] Context cancelled, shutting down...")
+		slog.Info("Context cancelled, shutting down")
 	}
 
 	cancel()
 
-	log.Println("[INFO] Cleaning up resources...")
+	slog.Info("Cleaning up resources")
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("[ERROR] HTTP server forced to shutdown: %v", err)
+		slog.Error("HTTP server forced to shutdown", "error", err)
 	} else {
-		log.Println("[INFO] HTTP server stopped successfully")
+		slog.Info("HTTP server stopped successfully")
+	}
+
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Metrics server forced to shutdown", "error", err)
+		}
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
 	}
 
 	done := make(chan struct{})
@@ -74,10 +140,16 @@ func main() {
 
 	select {
 	case <-done:
-		log.Println("[INFO] All services stopped successfully")
+		slog.Info("All services stopped successfully")
 	case <-time.After(5 * time.Second):
-		log.Println("[WARN] Timeout waiting for services to stop")
+		slog.Warn("Timeout waiting for services to stop")
 	}
 
-	log.Println("[INFO] Application shutdown complete")
+	slog.Info("Application shutdown complete")
+}
+
+// fatal logs err and exits. slog has no Fatal level.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
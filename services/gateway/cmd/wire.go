@@ -8,11 +8,19 @@ import (
 	"gateway/configs"
 	"gateway/internal/handlers"
 	"gateway/internal/middleware"
+	"gateway/internal/proxy"
+	"gateway/internal/realtime"
 	"gateway/internal/routes"
+	"gateway/internal/utils"
 	"gateway/internal/utils/jwt"
+	"music-player/api/health"
+	"music-player/api/logger"
+	"music-player/api/metrics"
+	"music-player/api/tracing"
+	"time"
 
-	"gateway/internal/redis"
 	redisutil "gateway/internal/utils/redis"
+	"music-player/api/redisclient"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
@@ -26,30 +34,45 @@ type App struct {
 	TwoFAHandler   handlers.TwoFAHandler
 	UserHandler    handlers.UserHandler
 	AuthMiddleware *middleware.AuthMiddleware
+	JWKSClient     *jwt.JWKSClient
+	SessionCache   *middleware.SessionCache
+	RedisUtil      *redisutil.RedisUtil
+	Hub            *realtime.Hub
 }
 
-func InitializeApp(appCfg *configs.AppConfig, redisCfg *configs.RedisConfig) (*App, error) {
+func InitializeApp(appCfg *configs.AppConfig, redisCfg *redisclient.Config, realtimeCfg *configs.RealtimeConfig, logCfg *logger.Config, securityCfg *configs.SecurityConfig) (*App, error) {
 	wire.Build(
 		// Infrastructure
-		redis.NewRedisClient,
+		redisclient.New,
 
 		// Handlers
 		handlers.NewAuthHandler,
 		handlers.NewTwoFAHandler,
 		handlers.NewUserHandler,
+		handlers.NewHealthHandler,
+		provideLogLevelHandler,
 
 		// JWT utilities and middleware
 		provideJWKSClient,
 		jwt.NewJWTVerifier,
+		provideSessionCache,
+		provideCookies,
+		provideRateLimiter,
 		middleware.NewAuthMiddleware,
 
+		// Realtime
+		realtime.NewHub,
+		realtime.NewHandler,
+
 		// Router and App
 		provideRouter,
 		provideGRPCClients,
+		provideNotificationBackend,
 		provideApp,
 
 		// Utilities
 		provideRedisUtil,
+		provideHealthRegistry,
 	)
 	return nil, nil
 }
@@ -61,6 +84,10 @@ func provideApp(
 	twoFAHandler handlers.TwoFAHandler,
 	userHandler handlers.UserHandler,
 	authMiddleware *middleware.AuthMiddleware,
+	jwksClient *jwt.JWKSClient,
+	sessionCache *middleware.SessionCache,
+	redisUtil *redisutil.RedisUtil,
+	hub *realtime.Hub,
 ) *App {
 	return &App{
 		Router:         router,
@@ -69,6 +96,10 @@ func provideApp(
 		TwoFAHandler:   twoFAHandler,
 		UserHandler:    userHandler,
 		AuthMiddleware: authMiddleware,
+		JWKSClient:     jwksClient,
+		SessionCache:   sessionCache,
+		RedisUtil:      redisUtil,
+		Hub:            hub,
 	}
 }
 
@@ -76,25 +107,79 @@ func provideRouter(
 	authHandler *handlers.AuthHandler,
 	twoFAHandler handlers.TwoFAHandler,
 	userHandler handlers.UserHandler,
+	healthHandler *handlers.HealthHandler,
+	logLevelHandler *logger.LevelHandler,
 	authMiddleware *middleware.AuthMiddleware,
+	cookies *middleware.Cookies,
+	limiter *middleware.RateLimiter,
+	securityCfg *configs.SecurityConfig,
+	notificationService *proxy.Backend,
+	realtimeHandler *realtime.Handler,
 ) *gin.Engine {
-	r := gin.Default()
-
-	routes.SetupAuthRoutes(r, authHandler, twoFAHandler, userHandler, authMiddleware)
+	r := gin.New()
+	// Client addresses come from the ClientIP middleware; keep gin's own
+	// c.ClientIP() from trusting forwarding headers.
+	_ = r.SetTrustedProxies(nil)
+	r.Use(gin.Recovery())
+	r.Use(middleware.RequestID())
+	r.Use(middleware.ClientIP(utils.NewClientIPResolver(
+		securityCfg.ClientIP.TrustedProxies,
+		securityCfg.ClientIP.Header == configs.ClientIPHeaderForwarded,
+	)))
+	// Realtime streams would get spans as long as the connection and record
+	// the access token sent in the query string
+	r.Use(tracing.GinMiddleware("gateway", "/api/v1/realtime/sse", "/api/v1/realtime/ws"))
+	r.Use(logger.GinMiddleware())
+	r.Use(metrics.HTTPMiddleware())
+	r.Use(middleware.CORSMiddleware(securityCfg.CORS))
+
+	routes.RegisterHealthRoutes(r, healthHandler)
+	routes.RegisterLogLevelRoutes(r, logLevelHandler)
+
+	routes.SetupAuthRoutes(r, authHandler, twoFAHandler, userHandler, authMiddleware, cookies, limiter)
+	routes.SetupNotificationRoutes(r, notificationService, authMiddleware, limiter)
+	routes.SetupRealtimeRoutes(r, realtimeHandler, authMiddleware, limiter)
 
 	return r
 }
 
-func provideGRPCClients(appCfg *configs.AppConfig) (*configs.GRPCClients, error) {
+func provideLogLevelHandler(logCfg *logger.Config) *logger.LevelHandler {
+	return logger.NewLevelHandler(logCfg.AdminToken)
+}
+
+func provideGRPCClients(appCfg *configs.AppConfig, securityCfg *configs.SecurityConfig) (*configs.GRPCClients, error) {
 	ctx := context.Background()
 
-	return configs.NewGRPCClients(ctx, appCfg.AuthServiceAddr)
+	return configs.NewGRPCClients(ctx, appCfg, securityCfg.ClientIP.SigningSecret)
+}
+
+func provideNotificationBackend(appCfg *configs.AppConfig) (*proxy.Backend, error) {
+	return proxy.NewBackend("notification_service", appCfg.NotificationServiceURL, appCfg.InternalAPIToken, appCfg.NotificationTimeout)
 }
 
 func provideJWKSClient(appCfg *configs.AppConfig) *jwt.JWKSClient {
 	return jwt.NewJWKSClient(appCfg.AuthServiceHTTPURL)
 }
 
+func provideCookies(securityCfg *configs.SecurityConfig) *middleware.Cookies {
+	return middleware.NewCookies(securityCfg.Cookie)
+}
+
+func provideRateLimiter(redisUtil *redisutil.RedisUtil, securityCfg *configs.SecurityConfig) *middleware.RateLimiter {
+	return middleware.NewRateLimiter(redisUtil, securityCfg.RateLimit)
+}
+
+func provideSessionCache(appCfg *configs.AppConfig) *middleware.SessionCache {
+	return middleware.NewSessionCache(appCfg.AuthCacheTTL, appCfg.AuthCacheMaxEntries)
+}
+
 func provideRedisUtil(redisClient *goredis.Client) *redisutil.RedisUtil {
 	return redisutil.NewRedisUtil(redisClient)
 }
+
+func provideHealthRegistry(redisClient *goredis.Client, grpcClients *configs.GRPCClients) *health.Registry {
+	registry := health.NewRegistry(2 * time.Second)
+	registry.Register("redis", health.RedisCheck(redisClient))
+	registry.Register("auth_service", health.PingCheck(grpcClients))
+	return registry
+}
@@ -11,31 +11,51 @@ import (
 	"gateway/configs"
 	"gateway/internal/handlers"
 	"gateway/internal/middleware"
-	"gateway/internal/redis"
+	"gateway/internal/proxy"
+	"gateway/internal/realtime"
 	"gateway/internal/routes"
+	"gateway/internal/utils"
 	"gateway/internal/utils/jwt"
 	"gateway/internal/utils/redis"
 	"github.com/gin-gonic/gin"
 	redis2 "github.com/redis/go-redis/v9"
+	"music-player/api/health"
+	"music-player/api/logger"
+	"music-player/api/metrics"
+	"music-player/api/redisclient"
+	"music-player/api/tracing"
+	"time"
 )
 
 // Injectors from wire.go:
 
-func InitializeApp(appCfg *configs.AppConfig, redisCfg *configs.RedisConfig) (*App, error) {
-	grpcClients, err := provideGRPCClients(appCfg)
+func InitializeApp(appCfg *configs.AppConfig, redisCfg *redisclient.Config, realtimeCfg *configs.RealtimeConfig, logCfg *logger.Config, securityCfg *configs.SecurityConfig) (*App, error) {
+	grpcClients, err := provideGRPCClients(appCfg, securityCfg)
 	if err != nil {
 		return nil, err
 	}
-	authHandler := handlers.NewAuthHandler(grpcClients)
+	cookies := provideCookies(securityCfg)
+	authHandler := handlers.NewAuthHandler(grpcClients, cookies)
 	twoFAHandler := handlers.NewTwoFAHandler(grpcClients)
 	userHandler := handlers.NewUserHandler(grpcClients)
+	client := redisclient.New(redisCfg)
+	registry := provideHealthRegistry(client, grpcClients)
+	healthHandler := handlers.NewHealthHandler(registry)
+	logLevelHandler := provideLogLevelHandler(logCfg)
 	jwksClient := provideJWKSClient(appCfg)
 	jwtVerifier := jwt.NewJWTVerifier(jwksClient)
-	client := redis.NewRedisClient(redisCfg)
 	redisUtil := provideRedisUtil(client)
-	authMiddleware := middleware.NewAuthMiddleware(jwtVerifier, redisUtil)
-	engine := provideRouter(authHandler, twoFAHandler, userHandler, authMiddleware)
-	app := provideApp(engine, grpcClients, authHandler, twoFAHandler, userHandler, authMiddleware)
+	sessionCache := provideSessionCache(appCfg)
+	authMiddleware := middleware.NewAuthMiddleware(jwtVerifier, redisUtil, sessionCache)
+	rateLimiter := provideRateLimiter(redisUtil, securityCfg)
+	backend, err := provideNotificationBackend(appCfg)
+	if err != nil {
+		return nil, err
+	}
+	hub := realtime.NewHub(redisUtil, realtimeCfg)
+	handler := realtime.NewHandler(hub, securityCfg)
+	engine := provideRouter(authHandler, twoFAHandler, userHandler, healthHandler, logLevelHandler, authMiddleware, cookies, rateLimiter, securityCfg, backend, handler)
+	app := provideApp(engine, grpcClients, authHandler, twoFAHandler, userHandler, authMiddleware, jwksClient, sessionCache, redisUtil, hub)
 	return app, nil
 }
 
@@ -48,6 +68,10 @@ type App struct {
 	TwoFAHandler   handlers.TwoFAHandler
 	UserHandler    handlers.UserHandler
 	AuthMiddleware *middleware.AuthMiddleware
+	JWKSClient     *jwt.JWKSClient
+	SessionCache   *middleware.SessionCache
+	RedisUtil      *redisutil.RedisUtil
+	Hub            *realtime.Hub
 }
 
 func provideApp(
@@ -57,6 +81,10 @@ func provideApp(
 	twoFAHandler handlers.TwoFAHandler,
 	userHandler handlers.UserHandler,
 	authMiddleware *middleware.AuthMiddleware,
+	jwksClient *jwt.JWKSClient,
+	sessionCache *middleware.SessionCache,
+	redisUtil *redisutil.RedisUtil,
+	hub *realtime.Hub,
 ) *App {
 	return &App{
 		Router:         router,
@@ -65,6 +93,10 @@ func provideApp(
 		TwoFAHandler:   twoFAHandler,
 		UserHandler:    userHandler,
 		AuthMiddleware: authMiddleware,
+		JWKSClient:     jwksClient,
+		SessionCache:   sessionCache,
+		RedisUtil:      redisUtil,
+		Hub:            hub,
 	}
 }
 
@@ -72,24 +104,76 @@ func provideRouter(
 	authHandler *handlers.AuthHandler,
 	twoFAHandler handlers.TwoFAHandler,
 	userHandler handlers.UserHandler,
+	healthHandler *handlers.HealthHandler,
+	logLevelHandler *logger.LevelHandler,
 	authMiddleware *middleware.AuthMiddleware,
+	cookies *middleware.Cookies,
+	limiter *middleware.RateLimiter,
+	securityCfg *configs.SecurityConfig,
+	notificationService *proxy.Backend,
+	realtimeHandler *realtime.Handler,
 ) *gin.Engine {
-	r := gin.Default()
-	routes.SetupAuthRoutes(r, authHandler, twoFAHandler, userHandler, authMiddleware)
+	r := gin.New()
+
+	_ = r.SetTrustedProxies(nil)
+	r.Use(gin.Recovery())
+	r.Use(middleware.RequestID())
+	r.Use(middleware.ClientIP(utils.NewClientIPResolver(
+		securityCfg.ClientIP.TrustedProxies,
+		securityCfg.ClientIP.Header == configs.ClientIPHeaderForwarded,
+	)))
+	// Realtime streams would get spans as long as the connection and record
+	// the access token sent in the query string
+	r.Use(tracing.GinMiddleware("gateway", "/api/v1/realtime/sse", "/api/v1/realtime/ws"))
+	r.Use(logger.GinMiddleware())
+	r.Use(metrics.HTTPMiddleware())
+	r.Use(middleware.CORSMiddleware(securityCfg.CORS))
+	routes.RegisterHealthRoutes(r, healthHandler)
+	routes.RegisterLogLevelRoutes(r, logLevelHandler)
+	routes.SetupAuthRoutes(r, authHandler, twoFAHandler, userHandler, authMiddleware, cookies, limiter)
+	routes.SetupNotificationRoutes(r, notificationService, authMiddleware, limiter)
+	routes.SetupRealtimeRoutes(r, realtimeHandler, authMiddleware, limiter)
 
 	return r
 }
 
-func provideGRPCClients(appCfg *configs.AppConfig) (*configs.GRPCClients, error) {
+func provideLogLevelHandler(logCfg *logger.Config) *logger.LevelHandler {
+	return logger.NewLevelHandler(logCfg.AdminToken)
+}
+
+func provideGRPCClients(appCfg *configs.AppConfig, securityCfg *configs.SecurityConfig) (*configs.GRPCClients, error) {
 	ctx := context.Background()
 
-	return configs.NewGRPCClients(ctx, appCfg.AuthServiceAddr)
+	return configs.NewGRPCClients(ctx, appCfg, securityCfg.ClientIP.SigningSecret)
+}
+
+func provideNotificationBackend(appCfg *configs.AppConfig) (*proxy.Backend, error) {
+	return proxy.NewBackend("notification_service", appCfg.NotificationServiceURL, appCfg.InternalAPIToken, appCfg.NotificationTimeout)
 }
 
 func provideJWKSClient(appCfg *configs.AppConfig) *jwt.JWKSClient {
 	return jwt.NewJWKSClient(appCfg.AuthServiceHTTPURL)
 }
 
+func provideCookies(securityCfg *configs.SecurityConfig) *middleware.Cookies {
+	return middleware.NewCookies(securityCfg.Cookie)
+}
+
+func provideRateLimiter(redisUtil *redisutil.RedisUtil, securityCfg *configs.SecurityConfig) *middleware.RateLimiter {
+	return middleware.NewRateLimiter(redisUtil, securityCfg.RateLimit)
+}
+
+func provideSessionCache(appCfg *configs.AppConfig) *middleware.SessionCache {
+	return middleware.NewSessionCache(appCfg.AuthCacheTTL, appCfg.AuthCacheMaxEntries)
+}
+
 func provideRedisUtil(redisClient *redis2.Client) *redisutil.RedisUtil {
 	return redisutil.NewRedisUtil(redisClient)
 }
+
+func provideHealthRegistry(redisClient *redis2.Client, grpcClients *configs.GRPCClients) *health.Registry {
+	registry := health.NewRegistry(2 * time.Second)
+	registry.Register("redis", health.RedisCheck(redisClient))
+	registry.Register("auth_service", health.PingCheck(grpcClients))
+	return registry
+}
@@ -1,7 +1,8 @@
 package configs
 
 import (
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -11,6 +12,30 @@ type AppConfig struct {
 	Env                string
 	AuthServiceAddr    string // gRPC address
 	AuthServiceHTTPURL string // HTTP URL for REST API
+
+	// MetricsAddr is the internal listener serving /metrics, kept off the
+	// public router so per-route traffic and dependency latency are not
+	// exposed to clients. Empty disables it.
+	MetricsAddr string
+
+	// AuthCacheTTL bounds how long verified tokens and session snapshots are
+	// reused by the auth middleware; 0 disables the cache.
+	AuthCacheTTL        time.Duration
+	AuthCacheMaxEntries int
+
+	// AuthBreakerFailures consecutive failed calls open the circuit to
+	// auth-service for AuthBreakerCooldown; 0 disables the breaker.
+	AuthBreakerFailures int
+	AuthBreakerCooldown time.Duration
+	// AuthHedgeDelay is how long a hedged read waits before a second
+	// attempt is sent; 0 disables hedging.
+	AuthHedgeDelay time.Duration
+
+	// NotificationServiceURL is the HTTP address REST calls under
+	// /api/v1/notifications are forwarded to, with InternalAPIToken.
+	NotificationServiceURL string
+	NotificationTimeout    time.Duration
+	InternalAPIToken       string
 }
 
 func LoadAppConfig() *AppConfig {
@@ -19,14 +44,39 @@ func LoadAppConfig() *AppConfig {
 
 	err := viper.ReadInConfig()
 	if err != nil {
-		log.Printf("[INFO] No .env file found or error reading config: %v", err)
+		slog.Info("No .env file found or error reading config", "error", err)
 	}
 
+	viper.SetDefault("METRICS_ADDR", ":9090")
+	viper.SetDefault("AUTH_CACHE_TTL", "5s")
+	viper.SetDefault("AUTH_CACHE_MAX_ENTRIES", 10000)
+	viper.SetDefault("AUTH_BREAKER_FAILURES", 5)
+	viper.SetDefault("AUTH_BREAKER_COOLDOWN", "10s")
+	viper.SetDefault("AUTH_HEDGE_DELAY", "150ms")
+	viper.SetDefault("NOTIFICATION_SERVICE_URL", "http://localhost:8082")
+	viper.SetDefault("NOTIFICATION_TIMEOUT", "10s")
+
 	cfg := &AppConfig{
 		Port:               viper.GetString("APP_PORT"),
+		MetricsAddr:        viper.GetString("METRICS_ADDR"),
 		Env:                viper.GetString("APP_ENV"),
 		AuthServiceAddr:    viper.GetString("AUTH_SERVICE_ADDR"),     // gRPC: localhost:8081
 		AuthServiceHTTPURL: viper.GetString("AUTH_SERVICE_HTTP_URL"), // HTTP: http://localhost:8080
+
+		AuthCacheTTL:        viper.GetDuration("AUTH_CACHE_TTL"),
+		AuthCacheMaxEntries: viper.GetInt("AUTH_CACHE_MAX_ENTRIES"),
+
+		AuthBreakerFailures: viper.GetInt("AUTH_BREAKER_FAILURES"),
+		AuthBreakerCooldown: viper.GetDuration("AUTH_BREAKER_COOLDOWN"),
+		AuthHedgeDelay:      viper.GetDuration("AUTH_HEDGE_DELAY"),
+
+		NotificationServiceURL: viper.GetString("NOTIFICATION_SERVICE_URL"),
+		NotificationTimeout:    viper.GetDuration("NOTIFICATION_TIMEOUT"),
+		InternalAPIToken:       viper.GetString("INTERNAL_API_TOKEN"),
+	}
+
+	if cfg.InternalAPIToken == "" {
+		slog.Warn("INTERNAL_API_TOKEN is not set: notification-service rejects forwarded requests")
 	}
 
 	return cfg
@@ -0,0 +1,80 @@
+package configs
+
+import (
+	"log/slog"
+	"net/netip"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Headers the trusted proxies may use to report the client address.
+const (
+	ClientIPHeaderXFF       = "x-forwarded-for"
+	ClientIPHeaderForwarded = "forwarded"
+)
+
+// ClientIPConfig controls how the end-user address is derived from a request.
+// Forwarding headers are only read when the direct peer is in TrustedProxies,
+// and only the one named by Header: a proxy that appends to one header
+// passes the other through untouched, so honouring both would let clients
+// pick their own address. SigningSecret is shared with the backend services,
+// which only accept an address the gateway signed.
+type ClientIPConfig struct {
+	TrustedProxies []netip.Prefix
+	Header         string
+	SigningSecret  []byte
+}
+
+func loadClientIPConfig() ClientIPConfig {
+	viper.SetDefault("CLIENT_IP_HEADER", ClientIPHeaderXFF)
+
+	header := strings.ToLower(strings.TrimSpace(viper.GetString("CLIENT_IP_HEADER")))
+	if header != ClientIPHeaderXFF && header != ClientIPHeaderForwarded {
+		slog.Warn("Unknown CLIENT_IP_HEADER, using X-Forwarded-For", "value", header)
+		header = ClientIPHeaderXFF
+	}
+
+	cfg := ClientIPConfig{
+		TrustedProxies: parseTrustedProxies(viper.GetString("TRUSTED_PROXIES")),
+		Header:         header,
+		SigningSecret:  []byte(viper.GetString("CLIENT_IP_SIGNING_SECRET")),
+	}
+	if len(cfg.SigningSecret) == 0 {
+		slog.Warn("CLIENT_IP_SIGNING_SECRET is not set, auth-service will ignore forwarded client IPs")
+	}
+	return cfg
+}
+
+// parseTrustedProxies parses a comma-separated list of CIDRs or bare
+// addresses. Invalid entries are skipped.
+func parseTrustedProxies(spec string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				slog.Warn("Ignoring invalid TRUSTED_PROXIES entry", "value", entry, "error", err)
+				continue
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			slog.Warn("Ignoring invalid TRUSTED_PROXIES entry", "value", entry, "error", err)
+			continue
+		}
+		if prefix.Addr().Is4In6() {
+			// ::ffff:a.b.c.d/n; peers are compared in their unmapped form.
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), max(prefix.Bits()-96, 0))
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
@@ -2,32 +2,63 @@ package configs
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"gateway/internal/interceptors"
 	authv1 "music-player/api/proto/auth/v1"
+	"sort"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 )
 
 type GRPCClients struct {
 	AuthClient authv1.AuthServiceClient
+	authHealth healthpb.HealthClient
 	authConn   *grpc.ClientConn
 }
 
-func NewGRPCClients(ctx context.Context, authEndpoint string) (*GRPCClients, error) {
-	const svcCfg = `{
-	  "loadBalancingPolicy": "round_robin",
-	  "methodConfig": [{
-	    "name": [{"service": "auth.v1.AuthService"}],
-	    "timeout": "3s"
-	  }]
-	}`
-
-	authConn, err := grpc.DialContext(
-		ctx,
-		authEndpoint,
+// authMethodPolicies sets the timeout of every auth-service RPC and marks the
+// ones that may be sent more than once. Login, RefreshToken and the 2FA calls
+// are not retried: a replay could open a second session, rotate a refresh
+// token twice, consume a one-time code or text a second one.
+var authMethodPolicies = interceptors.MethodPolicies{
+	authv1.AuthService_Login_FullMethodName:             {Timeout: 5 * time.Second},
+	authv1.AuthService_Register_FullMethodName:          {Timeout: 10 * time.Second},
+	authv1.AuthService_Logout_FullMethodName:            {Timeout: 3 * time.Second, Idempotent: true},
+	authv1.AuthService_RefreshToken_FullMethodName:      {Timeout: 5 * time.Second},
+	authv1.AuthService_ValidateToken_FullMethodName:     {Timeout: 2 * time.Second, Idempotent: true, Hedge: true},
+	authv1.AuthService_RevokeToken_FullMethodName:       {Timeout: 3 * time.Second, Idempotent: true},
+	authv1.AuthService_SetupTwoFA_FullMethodName:        {Timeout: 5 * time.Second},
+	authv1.AuthService_EnableTwoFA_FullMethodName:       {Timeout: 5 * time.Second},
+	authv1.AuthService_DisableTwoFA_FullMethodName:      {Timeout: 5 * time.Second},
+	authv1.AuthService_VerifyTwoFA_FullMethodName:       {Timeout: 5 * time.Second},
+	authv1.AuthService_SetupSMSTwoFA_FullMethodName:     {Timeout: 5 * time.Second},
+	authv1.AuthService_EnableSMSTwoFA_FullMethodName:    {Timeout: 5 * time.Second},
+	authv1.AuthService_SendTwoFACode_FullMethodName:     {Timeout: 5 * time.Second},
+	authv1.AuthService_GetUserProfile_FullMethodName:    {Timeout: 2 * time.Second, Idempotent: true, Hedge: true},
+	authv1.AuthService_UpdateUserProfile_FullMethodName: {Timeout: 5 * time.Second},
+	authv1.AuthService_ListSigningKeys_FullMethodName:   {Timeout: 3 * time.Second, Idempotent: true},
+	authv1.AuthService_ReloadSigningKeys_FullMethodName: {Timeout: 10 * time.Second, Idempotent: true},
+}
+
+// NewGRPCClients dials the backend services. Calls to auth-service go through
+// a circuit breaker and are bounded, retried and hedged per
+// authMethodPolicies. clientIPSecret signs the client address forwarded with
+// every call; see interceptors.ClientIPUnary.
+func NewGRPCClients(ctx context.Context, appCfg *AppConfig, clientIPSecret []byte) (*GRPCClients, error) {
+	svcCfg, err := serviceConfig(authMethodPolicies)
+	if err != nil {
+		return nil, err
+	}
+	breaker := interceptors.NewCircuitBreaker("auth-service", appCfg.AuthBreakerFailures, appCfg.AuthBreakerCooldown)
+
+	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithBlock(),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
@@ -45,17 +76,42 @@ func NewGRPCClients(ctx context.Context, authEndpoint string) (*GRPCClients, err
 			},
 		}),
 		grpc.WithDefaultServiceConfig(svcCfg),
-	)
+	}
+	dialOpts = append(dialOpts, interceptors.DialOptions(interceptors.DefaultDeadline, authMethodPolicies, clientIPSecret)...)
+	// Innermost: the breaker sees one outcome per call, after hedging and
+	// the retries driven by the service config.
+	dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(
+		breaker.UnaryClientInterceptor(),
+		interceptors.HedgeUnary(authMethodPolicies, appCfg.AuthHedgeDelay),
+	))
+
+	authConn, err := grpc.DialContext(ctx, appCfg.AuthServiceAddr, dialOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &GRPCClients{
 		AuthClient: authv1.NewAuthServiceClient(authConn),
+		authHealth: healthpb.NewHealthClient(authConn),
 		authConn:   authConn,
 	}, nil
 }
 
+// Ping queries the grpc.health.v1 service of auth-service and fails unless
+// the auth service reports SERVING.
+func (c *GRPCClients) Ping(ctx context.Context) error {
+	resp, err := c.authHealth.Check(ctx, &healthpb.HealthCheckRequest{
+		Service: authv1.AuthService_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("auth service status: %s", resp.GetStatus())
+	}
+	return nil
+}
+
 // Close closes all gRPC connections
 func (c *GRPCClients) Close() error {
 	if c.authConn != nil {
@@ -63,3 +119,68 @@ func (c *GRPCClients) Close() error {
 	}
 	return nil
 }
+
+// Retry policy for idempotent RPCs. Only UNAVAILABLE is retried: the call did
+// not reach a healthy backend. Throttling stops retries once more than a
+// tenth of recent calls failed, so an outage is not amplified.
+const (
+	retryMaxAttempts    = 3
+	retryInitialBackoff = "0.1s"
+	retryMaxBackoff     = "1s"
+)
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+type methodConfig struct {
+	Name        []methodName `json:"name"`
+	RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// serviceConfig builds the gRPC service config enabling retries for the
+// idempotent methods in policies. Timeouts are applied by
+// interceptors.DeadlineUnary instead, which also covers methods without a
+// policy.
+func serviceConfig(policies interceptors.MethodPolicies) (string, error) {
+	var retried []methodName
+	for fullMethod, p := range policies {
+		if !p.Idempotent {
+			continue
+		}
+		service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+		if !ok {
+			return "", fmt.Errorf("invalid method name %q", fullMethod)
+		}
+		retried = append(retried, methodName{Service: service, Method: method})
+	}
+	sort.Slice(retried, func(i, j int) bool { return retried[i].Method < retried[j].Method })
+
+	cfg := map[string]any{
+		"loadBalancingPolicy": "round_robin",
+		"retryThrottling":     map[string]any{"maxTokens": 10, "tokenRatio": 0.1},
+	}
+	if len(retried) > 0 {
+		cfg["methodConfig"] = []methodConfig{{
+			Name: retried,
+			RetryPolicy: &retryPolicy{
+				MaxAttempts:          retryMaxAttempts,
+				InitialBackoff:       retryInitialBackoff,
+				MaxBackoff:           retryMaxBackoff,
+				BackoffMultiplier:    2,
+				RetryableStatusCodes: []string{"UNAVAILABLE"},
+			},
+		}}
+	}
+	b, err := json.Marshal(cfg)
+	return string(b), err
+}
@@ -0,0 +1,90 @@
+package configs
+
+import (
+	"encoding/json"
+	"gateway/internal/interceptors"
+	"slices"
+	"testing"
+	"time"
+
+	authv1 "music-player/api/proto/auth/v1"
+)
+
+// TestAuthMethodPolicies keeps authMethodPolicies in step with the service:
+// a method without a policy gets the default deadline and is never retried.
+func TestAuthMethodPolicies(t *testing.T) {
+	desc := authv1.AuthService_ServiceDesc
+	for _, m := range desc.Methods {
+		name := "/" + desc.ServiceName + "/" + m.MethodName
+		p, ok := authMethodPolicies[name]
+		if !ok {
+			t.Errorf("%s has no policy", name)
+			continue
+		}
+		if p.Timeout <= 0 {
+			t.Errorf("%s has no timeout", name)
+		}
+		if p.Hedge && !p.Idempotent {
+			t.Errorf("%s is hedged but not idempotent", name)
+		}
+	}
+	if len(authMethodPolicies) != len(desc.Methods) {
+		t.Errorf("%d policies for %d methods; remove the ones of deleted methods", len(authMethodPolicies), len(desc.Methods))
+	}
+}
+
+func TestServiceConfigRetriesIdempotentMethods(t *testing.T) {
+	raw, err := serviceConfig(interceptors.MethodPolicies{
+		"/pkg.Svc/Read":  {Timeout: time.Second, Idempotent: true},
+		"/pkg.Svc/Write": {Timeout: time.Second},
+		"/pkg.Svc/Get":   {Timeout: time.Second, Idempotent: true, Hedge: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		LoadBalancingPolicy string         `json:"loadBalancingPolicy"`
+		RetryThrottling     map[string]any `json:"retryThrottling"`
+		MethodConfig        []methodConfig `json:"methodConfig"`
+	}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatalf("service config is not JSON: %v\n%s", err, raw)
+	}
+	if cfg.LoadBalancingPolicy != "round_robin" || cfg.RetryThrottling == nil {
+		t.Errorf("config = %s; want round_robin with retry throttling", raw)
+	}
+	if len(cfg.MethodConfig) != 1 {
+		t.Fatalf("methodConfig = %+v; want one entry", cfg.MethodConfig)
+	}
+
+	mc := cfg.MethodConfig[0]
+	want := []methodName{{Service: "pkg.Svc", Method: "Get"}, {Service: "pkg.Svc", Method: "Read"}}
+	if !slices.Equal(mc.Name, want) {
+		t.Errorf("retried methods = %+v; want %+v", mc.Name, want)
+	}
+	rp := mc.RetryPolicy
+	if rp == nil || rp.MaxAttempts != retryMaxAttempts || !slices.Equal(rp.RetryableStatusCodes, []string{"UNAVAILABLE"}) {
+		t.Errorf("retry policy = %+v; want %d attempts on UNAVAILABLE only", rp, retryMaxAttempts)
+	}
+}
+
+func TestServiceConfigWithoutIdempotentMethods(t *testing.T) {
+	raw, err := serviceConfig(interceptors.MethodPolicies{"/pkg.Svc/Write": {Timeout: time.Second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg["methodConfig"]; ok {
+		t.Errorf("config = %s; want no methodConfig", raw)
+	}
+}
+
+func TestServiceConfigInvalidMethod(t *testing.T) {
+	if _, err := serviceConfig(interceptors.MethodPolicies{"Read": {Idempotent: true}}); err == nil {
+		t.Error("serviceConfig accepted a method name without a service")
+	}
+}
@@ -0,0 +1,96 @@
+package configs
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Rate limit route groups.
+const (
+	RateLimitLogin     = "login"
+	RateLimitRegister  = "register"
+	RateLimitTwoFA     = "2fa_verify"
+	RateLimitTwoFASMS  = "2fa_sms"
+	RateLimitRefresh   = "refresh"
+	RateLimitGeneral   = "general"
+	rateLimitEnvPrefix = "RATE_LIMIT_"
+)
+
+// RateLimitPolicy allows Requests per Window with bursts of up to Requests.
+type RateLimitPolicy struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Rate returns the refill rate in requests per second.
+func (p RateLimitPolicy) Rate() float64 {
+	return float64(p.Requests) / p.Window.Seconds()
+}
+
+func (p RateLimitPolicy) String() string {
+	return fmt.Sprintf("%d/%s", p.Requests, p.Window)
+}
+
+// RateLimitConfig holds a policy per route group. FailOpen lets requests
+// through when Redis cannot be reached.
+type RateLimitConfig struct {
+	Enabled  bool
+	FailOpen bool
+	Policies map[string]RateLimitPolicy
+}
+
+var defaultRateLimits = map[string]string{
+	RateLimitLogin:    "10/1m",
+	RateLimitRegister: "5/1h",
+	RateLimitTwoFA:    "5/5m",
+	RateLimitTwoFASMS: "3/10m",
+	RateLimitRefresh:  "30/1m",
+	RateLimitGeneral:  "300/1m",
+}
+
+func loadRateLimitConfig() RateLimitConfig {
+	viper.SetDefault("RATE_LIMIT_ENABLED", true)
+	viper.SetDefault("RATE_LIMIT_FAIL_OPEN", true)
+
+	cfg := RateLimitConfig{
+		Enabled:  viper.GetBool("RATE_LIMIT_ENABLED"),
+		FailOpen: viper.GetBool("RATE_LIMIT_FAIL_OPEN"),
+		Policies: make(map[string]RateLimitPolicy, len(defaultRateLimits)),
+	}
+	for group, def := range defaultRateLimits {
+		env := rateLimitEnvPrefix + strings.ToUpper(group)
+		spec := viper.GetString(env)
+		if spec == "" {
+			spec = def
+		}
+		policy, err := parseRateLimitPolicy(spec)
+		if err != nil {
+			slog.Warn("Invalid rate limit, using default", "env", env, "value", spec, "default", def, "error", err)
+			policy, _ = parseRateLimitPolicy(def)
+		}
+		cfg.Policies[group] = policy
+	}
+	return cfg
+}
+
+// parseRateLimitPolicy parses "<requests>/<window>", e.g. "10/1m".
+func parseRateLimitPolicy(spec string) (RateLimitPolicy, error) {
+	reqs, window, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	if !ok {
+		return RateLimitPolicy{}, fmt.Errorf("want <requests>/<window>")
+	}
+	n, err := strconv.Atoi(reqs)
+	if err != nil || n <= 0 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid request count %q", reqs)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid window %q", window)
+	}
+	return RateLimitPolicy{Requests: n, Window: d}, nil
+}
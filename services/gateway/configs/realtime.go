@@ -0,0 +1,33 @@
+package configs
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// RealtimeConfig configures the WebSocket and Server-Sent Events endpoints.
+type RealtimeConfig struct {
+	// HeartbeatInterval is how often idle connections are pinged. A
+	// WebSocket client that does not answer within two intervals is
+	// dropped.
+	HeartbeatInterval time.Duration
+	// MaxConnectionsPerUser caps the connections of a user on each gateway
+	// replica.
+	MaxConnectionsPerUser int
+	// ReplayLimit is the most events replayed to a reconnecting client;
+	// beyond it the client is told to reload its state.
+	ReplayLimit int64
+}
+
+func LoadRealtimeConfig() *RealtimeConfig {
+	viper.SetDefault("REALTIME_HEARTBEAT_INTERVAL", "25s")
+	viper.SetDefault("REALTIME_MAX_CONNECTIONS_PER_USER", 5)
+	viper.SetDefault("REALTIME_REPLAY_LIMIT", 100)
+
+	return &RealtimeConfig{
+		HeartbeatInterval:     viper.GetDuration("REALTIME_HEARTBEAT_INTERVAL"),
+		MaxConnectionsPerUser: viper.GetInt("REALTIME_MAX_CONNECTIONS_PER_USER"),
+		ReplayLimit:           viper.GetInt64("REALTIME_REPLAY_LIMIT"),
+	}
+}
@@ -0,0 +1,102 @@
+package configs
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// CookiePolicy describes how the refresh token and CSRF cookies are set.
+// With HostPrefix the cookie names get the "__Host-" prefix, which browsers
+// only accept with Secure, Path=/ and no Domain, so those are forced.
+type CookiePolicy struct {
+	Secure     bool
+	SameSite   http.SameSite
+	Domain     string
+	Path       string
+	HostPrefix bool
+	// MaxAge is used for the refresh cookie when auth-service does not
+	// report the refresh token lifetime.
+	MaxAge time.Duration
+}
+
+// CORSConfig allow-lists the browser origins that may call the API with
+// credentials.
+type CORSConfig struct {
+	AllowedOrigins []string
+	MaxAge         time.Duration
+}
+
+type SecurityConfig struct {
+	Cookie    CookiePolicy
+	CORS      CORSConfig
+	RateLimit RateLimitConfig
+	ClientIP  ClientIPConfig
+}
+
+func LoadSecurityConfig() *SecurityConfig {
+	viper.SetDefault("COOKIE_SECURE", true)
+	viper.SetDefault("COOKIE_SAMESITE", "strict")
+	viper.SetDefault("COOKIE_PATH", "/api/v1/auth")
+	viper.SetDefault("COOKIE_MAX_AGE", "168h")
+	viper.SetDefault("CORS_MAX_AGE", "10m")
+
+	cookie := CookiePolicy{
+		Secure:     viper.GetBool("COOKIE_SECURE"),
+		SameSite:   parseSameSite(viper.GetString("COOKIE_SAMESITE")),
+		Domain:     viper.GetString("COOKIE_DOMAIN"),
+		Path:       viper.GetString("COOKIE_PATH"),
+		HostPrefix: viper.GetBool("COOKIE_HOST_PREFIX"),
+		MaxAge:     viper.GetDuration("COOKIE_MAX_AGE"),
+	}
+	cookie.normalize()
+
+	var origins []string
+	for _, origin := range strings.Split(viper.GetString("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return &SecurityConfig{
+		Cookie: cookie,
+		CORS: CORSConfig{
+			AllowedOrigins: origins,
+			MaxAge:         viper.GetDuration("CORS_MAX_AGE"),
+		},
+		RateLimit: loadRateLimitConfig(),
+		ClientIP:  loadClientIPConfig(),
+	}
+}
+
+// normalize forces the attributes browsers require: Secure, Path=/ and no
+// Domain for "__Host-" cookies, and Secure for SameSite=None.
+func (p *CookiePolicy) normalize() {
+	if p.HostPrefix {
+		if !p.Secure || p.Domain != "" || p.Path != "/" {
+			slog.Warn("COOKIE_HOST_PREFIX forces Secure, Path=/ and no Domain")
+		}
+		p.Secure, p.Domain, p.Path = true, "", "/"
+	}
+	if p.SameSite == http.SameSiteNoneMode && !p.Secure {
+		slog.Warn("COOKIE_SAMESITE=none requires Secure cookies, enabling COOKIE_SECURE")
+		p.Secure = true
+	}
+}
+
+func parseSameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	case "strict":
+		return http.SameSiteStrictMode
+	default:
+		slog.Warn("Unknown COOKIE_SAMESITE, using strict", "value", s)
+		return http.SameSiteStrictMode
+	}
+}
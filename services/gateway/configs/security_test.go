@@ -0,0 +1,57 @@
+package configs
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCookiePolicyNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   CookiePolicy
+		want CookiePolicy
+	}{
+		{
+			"unchanged",
+			CookiePolicy{Secure: false, SameSite: http.SameSiteStrictMode, Domain: "example.com", Path: "/api/v1/auth"},
+			CookiePolicy{Secure: false, SameSite: http.SameSiteStrictMode, Domain: "example.com", Path: "/api/v1/auth"},
+		},
+		{
+			"host prefix forces Secure, Path=/ and no Domain",
+			CookiePolicy{HostPrefix: true, SameSite: http.SameSiteLaxMode, Domain: "example.com", Path: "/api/v1/auth"},
+			CookiePolicy{HostPrefix: true, SameSite: http.SameSiteLaxMode, Secure: true, Path: "/"},
+		},
+		{
+			"SameSite=None forces Secure",
+			CookiePolicy{SameSite: http.SameSiteNoneMode, Path: "/api/v1/auth"},
+			CookiePolicy{SameSite: http.SameSiteNoneMode, Secure: true, Path: "/api/v1/auth"},
+		},
+		{
+			"lax stays insecure when asked",
+			CookiePolicy{SameSite: http.SameSiteLaxMode, Path: "/"},
+			CookiePolicy{SameSite: http.SameSiteLaxMode, Path: "/"},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.in
+		got.normalize()
+		if got != tt.want {
+			t.Errorf("%s: normalize = %+v; want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	tests := map[string]http.SameSite{
+		"lax":    http.SameSiteLaxMode,
+		"None":   http.SameSiteNoneMode,
+		"STRICT": http.SameSiteStrictMode,
+		"":       http.SameSiteStrictMode,
+		"bogus":  http.SameSiteStrictMode,
+	}
+	for in, want := range tests {
+		if got := parseSameSite(in); got != want {
+			t.Errorf("parseSameSite(%q) = %v; want %v", in, got, want)
+		}
+	}
+}
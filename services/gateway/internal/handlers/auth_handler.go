@@ -1,12 +1,11 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
-	"time"
 
 	"gateway/configs"
 	"gateway/internal/dto"
+	"gateway/internal/middleware"
 	"gateway/internal/utils"
 	authv1 "music-player/api/proto/auth/v1"
 
@@ -16,11 +15,13 @@ import (
 
 type AuthHandler struct {
 	grpcClients *configs.GRPCClients
+	cookies     *middleware.Cookies
 }
 
-func NewAuthHandler(grpcClients *configs.GRPCClients) *AuthHandler {
+func NewAuthHandler(grpcClients *configs.GRPCClients, cookies *middleware.Cookies) *AuthHandler {
 	return &AuthHandler{
 		grpcClients: grpcClients,
+		cookies:     cookies,
 	}
 }
 
@@ -31,27 +32,18 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	clientIP := utils.GetClientIP(c)
-	userAgent := c.GetHeader("User-Agent")
-
 	md := metadata.Pairs(
-		"x-client-ip", clientIP,
-		"x-user-agent", userAgent,
-		"x-real-ip", c.GetHeader("X-Real-IP"),
-		"x-forwarded-for", c.GetHeader("X-Forwarded-For"),
+		"x-user-agent", c.GetHeader("User-Agent"),
 	)
 	ctx := metadata.NewOutgoingContext(c.Request.Context(), md)
 
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	resp, err := h.grpcClients.AuthClient.Login(ctx, &authv1.LoginRequest{
 		Email:    req.Email,
 		Password: req.Password,
 	})
 
 	if err != nil {
-		utils.Fail(c, http.StatusInternalServerError, "AUTH_SERVICE_UNAVAILABLE", err.Error())
+		failRPC(c, err, "LOGIN_FAILED")
 		return
 	}
 
@@ -63,28 +55,27 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie(
-		"refresh_token",
-		resp.RefreshToken,
-		int(resp.ExpiresIn)*24*7,
-		"/",
-		"",
-		false,
-		true,
-	)
+	csrfToken, err := h.cookies.SetSession(c, resp.RefreshToken, resp.RefreshExpiresIn)
+	if err != nil {
+		utils.Fail(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to issue CSRF token")
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
 		"message":     resp.Message,
 		"accessToken": resp.AccessToken,
 		"expiresIn":   resp.ExpiresIn,
+		"csrfToken":   csrfToken,
 		"user": gin.H{
-			"id":           resp.User.Id,
-			"username":     resp.User.Username,
-			"email":        resp.User.Email,
-			"fullName":     resp.User.FullName,
-			"twoFaEnabled": resp.User.TwoFaEnabled,
-			"createdAt":    resp.User.CreatedAt,
+			"id":              resp.User.Id,
+			"username":        resp.User.Username,
+			"email":           resp.User.Email,
+			"fullName":        resp.User.FullName,
+			"twoFaEnabled":    resp.User.TwoFaEnabled,
+			"smsTwoFaEnabled": resp.User.SmsTwoFaEnabled,
+			"phoneNumber":     resp.User.PhoneNumber,
+			"createdAt":       resp.User.CreatedAt,
 		},
 	})
 }
@@ -95,6 +86,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required,min=6,max=64"`
 		FullName string `json:"fullName,omitempty"`
+		// Locale defaults to the Accept-Language header.
+		Locale string `json:"locale,omitempty" binding:"omitempty,max=64"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -102,22 +95,22 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
-	defer cancel()
+	if req.Locale == "" {
+		req.Locale = c.GetHeader("Accept-Language")
+	}
+
+	ctx := c.Request.Context()
 
 	resp, err := h.grpcClients.AuthClient.Register(ctx, &authv1.RegisterRequest{
 		Username: req.Username,
 		Email:    req.Email,
 		Password: req.Password,
 		FullName: req.FullName,
+		Locale:   req.Locale,
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Authentication service unavailable",
-			"error":   err.Error(),
-		})
+		failRPC(c, err, "REGISTRATION_FAILED")
 		return
 	}
 
@@ -153,19 +146,14 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 	}
 	token := authHeader[len(bearerPrefix):]
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	resp, err := h.grpcClients.AuthClient.ValidateToken(ctx, &authv1.ValidateTokenRequest{
 		AccessToken: token,
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Authentication service unavailable",
-			"error":   err.Error(),
-		})
+		failRPC(c, err, "VALIDATE_TOKEN_FAILED")
 		return
 	}
 
@@ -191,7 +179,7 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 }
 
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	refreshToken, err := c.Cookie("refresh_token")
+	refreshToken, err := h.cookies.RefreshToken(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
@@ -200,29 +188,16 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	clientIP := utils.GetClientIP(c)
-	userAgent := c.GetHeader("User-Agent")
-
 	md := metadata.Pairs(
-		"x-client-ip", clientIP,
-		"x-user-agent", userAgent,
-		"x-real-ip", c.GetHeader("X-Real-IP"),
-		"x-forwarded-for", c.GetHeader("X-Forwarded-For"),
+		"x-user-agent", c.GetHeader("User-Agent"),
 		"refresh_token", refreshToken,
 	)
 	ctx := metadata.NewOutgoingContext(c.Request.Context(), md)
 
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	resp, err := h.grpcClients.AuthClient.RefreshToken(ctx, &authv1.RefreshTokenRequest{})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Authentication service unavailable",
-			"error":   err.Error(),
-		})
+		failRPC(c, err, "REFRESH_FAILED")
 		return
 	}
 
@@ -234,21 +209,18 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie(
-		"refresh_token",
-		resp.RefreshToken,
-		int(resp.ExpiresIn)*24*7,
-		"/",
-		"",
-		false,
-		true,
-	)
+	csrfToken, err := h.cookies.SetSession(c, resp.RefreshToken, resp.RefreshExpiresIn)
+	if err != nil {
+		utils.Fail(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to issue CSRF token")
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
 		"message":     resp.Message,
 		"accessToken": resp.AccessToken,
 		"expiresIn":   resp.ExpiresIn,
+		"csrfToken":   csrfToken,
 	})
 }
 
@@ -259,15 +231,14 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	resp, err := h.grpcClients.AuthClient.Logout(ctx, &authv1.LogoutRequest{
 		Sid: sid.(string),
 	})
 
 	if err != nil {
-		utils.Fail(c, http.StatusInternalServerError, "Authentication Service Unavailable", err.Error())
+		failRPC(c, err, "LOGOUT_FAILED")
 		return
 	}
 
@@ -276,15 +247,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie(
-		"refresh_token",
-		"",
-		-1,
-		"/",
-		"",
-		false,
-		true,
-	)
+	h.cookies.ClearSession(c)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"music-player/api/health"
+	"music-player/api/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var healthLog = logger.For("health")
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{registry: registry}
+}
+
+// Livez reports that the process is running. It never probes dependencies so
+// a slow database does not get the container restarted.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusUp})
+}
+
+// Readyz probes every registered dependency and returns 503 if any is down.
+// The report names each dependency with its status and latency; the errors
+// of failing probes are only logged, for operators.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.registry.Check(c.Request.Context())
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+		for name, result := range report.Checks {
+			if result.Status != health.StatusUp {
+				healthLog.WarnContext(c.Request.Context(), "Readiness probe failed", "dependency", name, "latency", result.Latency, "error", result.Error)
+			}
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
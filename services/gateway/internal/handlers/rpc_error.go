@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gateway/internal/utils"
+	"music-player/api/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusClientClosedRequest is logged when the client went away before the
+// backend answered; nothing is sent since nobody is listening.
+const statusClientClosedRequest = 499
+
+var rpcLog = logger.For("rpc")
+
+// failRPC answers a failed auth-service call. Unavailable, including calls
+// rejected by the open circuit breaker, becomes 503 and an exhausted deadline
+// 504; other errors keep the handler-specific code. Internal and Unknown
+// errors may carry backend details, so they are logged and answered with a
+// fixed message.
+func failRPC(c *gin.Context, err error, code string) {
+	if errors.Is(c.Request.Context().Err(), context.Canceled) {
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
+
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.Unavailable:
+		c.Header("Retry-After", "1")
+		utils.Fail(c, http.StatusServiceUnavailable, "AUTH_SERVICE_UNAVAILABLE", "Authentication service unavailable")
+	case codes.DeadlineExceeded:
+		utils.Fail(c, http.StatusGatewayTimeout, "AUTH_SERVICE_TIMEOUT", "Authentication service timed out")
+	case codes.InvalidArgument:
+		utils.Fail(c, http.StatusBadRequest, code, st.Message())
+	case codes.NotFound:
+		utils.Fail(c, http.StatusNotFound, code, st.Message())
+	case codes.Unauthenticated:
+		utils.Fail(c, http.StatusUnauthorized, code, st.Message())
+	case codes.PermissionDenied:
+		utils.Fail(c, http.StatusForbidden, code, st.Message())
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		rpcLog.ErrorContext(c.Request.Context(), "Auth service call failed", "code", st.Code().String(), "error", st.Message())
+		utils.Fail(c, http.StatusInternalServerError, code, "Internal server error")
+	default:
+		utils.Fail(c, http.StatusInternalServerError, code, st.Message())
+	}
+}
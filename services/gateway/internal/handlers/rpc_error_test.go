@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFailRPC(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantBody string
+		hideBody string
+	}{
+		{"unavailable", status.Error(codes.Unavailable, "dial tcp 10.0.0.5:50051"), http.StatusServiceUnavailable, "AUTH_SERVICE_UNAVAILABLE", "10.0.0.5"},
+		{"deadline", status.Error(codes.DeadlineExceeded, "slow"), http.StatusGatewayTimeout, "AUTH_SERVICE_TIMEOUT", ""},
+		{"invalid argument", status.Error(codes.InvalidArgument, "email is required"), http.StatusBadRequest, "email is required", ""},
+		{"not found", status.Error(codes.NotFound, "user not found"), http.StatusNotFound, "user not found", ""},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "bad token"), http.StatusUnauthorized, "bad token", ""},
+		{"permission denied", status.Error(codes.PermissionDenied, "admin only"), http.StatusForbidden, "admin only", ""},
+		{"internal", status.Error(codes.Internal, "pq: relation users does not exist"), http.StatusInternalServerError, "Internal server error", "pq:"},
+		{"unknown", status.Error(codes.Unknown, "panic: nil map"), http.StatusInternalServerError, "Internal server error", "panic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/", nil)
+
+			failRPC(c, tt.err, "TEST_FAILED")
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d; want %d", w.Code, tt.wantCode)
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body = %s; want it to contain %q", body, tt.wantBody)
+			}
+			if tt.hideBody != "" && strings.Contains(body, tt.hideBody) {
+				t.Errorf("body = %s; leaks %q", body, tt.hideBody)
+			}
+		})
+	}
+}
+
+func TestFailRPCClientGone(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Request = httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+
+	failRPC(c, status.Error(codes.Canceled, "context canceled"), "TEST_FAILED")
+
+	if c.Writer.Status() != statusClientClosedRequest || w.Body.Len() != 0 {
+		t.Errorf("status = %d, body = %q; want %d and no body", c.Writer.Status(), w.Body.String(), statusClientClosedRequest)
+	}
+}
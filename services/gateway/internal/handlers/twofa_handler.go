@@ -1,12 +1,10 @@
 package handlers
 
 import (
-	"context"
 	"gateway/configs"
 	"gateway/internal/utils"
 	authv1 "music-player/api/proto/auth/v1"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +14,9 @@ type TwoFAHandler interface {
 	Enable2FA(c *gin.Context)
 	Verify2FA(c *gin.Context)
 	Disable2FA(c *gin.Context)
+	SetupSMS2FA(c *gin.Context)
+	EnableSMS2FA(c *gin.Context)
+	SendSMS2FACode(c *gin.Context)
 }
 
 // TwoFAHandler handles 2FA-related HTTP requests
@@ -37,15 +38,14 @@ func (h *twoFAHandler) Setup2FA(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	resp, err := h.grpcClients.AuthClient.SetupTwoFA(ctx, &authv1.SetupTwoFARequest{
 		UserId: userID.(string),
 	})
 
 	if err != nil {
-		utils.Fail(c, http.StatusInternalServerError, "SETUP_2FA_FAILED", err.Error())
+		failRPC(c, err, "SETUP_2FA_FAILED")
 		return
 	}
 
@@ -75,15 +75,14 @@ func (h *twoFAHandler) Enable2FA(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	resp, err := h.grpcClients.AuthClient.EnableTwoFA(ctx, &authv1.EnableTwoFARequest{
 		UserId: userId.(string),
 		Code:   req.Code,
 	})
 	if err != nil {
-		utils.Fail(c, http.StatusInternalServerError, "ENABLE_2FA_FAILED", err.Error())
+		failRPC(c, err, "ENABLE_2FA_FAILED")
 		return
 	}
 
@@ -107,21 +106,23 @@ func (h *twoFAHandler) Verify2FA(c *gin.Context) {
 
 	var req struct {
 		Code string `json:"code" binding:"required"`
+		// Method is "totp" (default) or "sms".
+		Method string `json:"method" binding:"omitempty,oneof=totp sms"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "Code is required")
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "Code is required and method must be totp or sms")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	resp, err := h.grpcClients.AuthClient.VerifyTwoFA(ctx, &authv1.VerifyTwoFARequest{
 		UserId: userId.(string),
 		Code:   req.Code,
+		Method: req.Method,
 	})
 	if err != nil {
-		utils.Fail(c, http.StatusInternalServerError, "VERIFY_2FA_FAILED", err.Error())
+		failRPC(c, err, "VERIFY_2FA_FAILED")
 		return
 	}
 
@@ -145,21 +146,123 @@ func (h *twoFAHandler) Disable2FA(c *gin.Context) {
 
 	var req struct {
 		Code string `json:"code" binding:"required"`
+		// Method is "totp" (default) or "sms".
+		Method string `json:"method" binding:"omitempty,oneof=totp sms"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "Code is required")
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "Code is required and method must be totp or sms")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	resp, err := h.grpcClients.AuthClient.DisableTwoFA(ctx, &authv1.DisableTwoFARequest{
 		UserId: userId.(string),
 		Code:   req.Code,
+		Method: req.Method,
+	})
+	if err != nil {
+		failRPC(c, err, "DISABLE_2FA_FAILED")
+		return
+	}
+
+	if !resp.Success {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": resp.Message,
+		})
+		return
+	}
+
+	utils.Success(c, http.StatusOK, resp)
+}
+
+// SetupSMS2FA texts a code to the phone number in the body; EnableSMS2FA
+// checks it.
+func (h *twoFAHandler) SetupSMS2FA(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Fail(c, http.StatusUnauthorized, "UNAUTHORIZED", "User ID not found in context")
+		return
+	}
+
+	var req struct {
+		PhoneNumber string `json:"phoneNumber" binding:"required,max=32"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "Phone number is required")
+		return
+	}
+
+	resp, err := h.grpcClients.AuthClient.SetupSMSTwoFA(c.Request.Context(), &authv1.SetupSMSTwoFARequest{
+		UserId:      userId.(string),
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil {
+		failRPC(c, err, "SETUP_SMS_2FA_FAILED")
+		return
+	}
+
+	if !resp.Success {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": resp.Message,
+		})
+		return
+	}
+
+	utils.Success(c, http.StatusOK, resp)
+}
+
+func (h *twoFAHandler) EnableSMS2FA(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Fail(c, http.StatusUnauthorized, "UNAUTHORIZED", "User ID not found in context")
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "Code is required")
+		return
+	}
+
+	resp, err := h.grpcClients.AuthClient.EnableSMSTwoFA(c.Request.Context(), &authv1.EnableSMSTwoFARequest{
+		UserId: userId.(string),
+		Code:   req.Code,
+	})
+	if err != nil {
+		failRPC(c, err, "ENABLE_SMS_2FA_FAILED")
+		return
+	}
+
+	if !resp.Success {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": resp.Message,
+		})
+		return
+	}
+
+	utils.Success(c, http.StatusOK, resp)
+}
+
+// SendSMS2FACode texts a sign-in code to the user's number, to pass to
+// /2fa/verify with method "sms".
+func (h *twoFAHandler) SendSMS2FACode(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Fail(c, http.StatusUnauthorized, "UNAUTHORIZED", "User ID not found in context")
+		return
+	}
+
+	resp, err := h.grpcClients.AuthClient.SendTwoFACode(c.Request.Context(), &authv1.SendTwoFACodeRequest{
+		UserId: userId.(string),
 	})
 	if err != nil {
-		utils.Fail(c, http.StatusInternalServerError, "DISABLE_2FA_FAILED", err.Error())
+		failRPC(c, err, "SEND_2FA_CODE_FAILED")
 		return
 	}
 
@@ -1,11 +1,10 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
-	"time"
 
 	"gateway/configs"
+	"gateway/internal/utils"
 	authv1 "music-player/api/proto/auth/v1"
 
 	"github.com/gin-gonic/gin"
@@ -13,6 +12,7 @@ import (
 
 type UserHandler interface {
 	GetUserProfile(c *gin.Context)
+	UpdateUserProfile(c *gin.Context)
 }
 type userHandler struct {
 	grpcClients *configs.GRPCClients
@@ -31,14 +31,46 @@ func (h *userHandler) GetUserProfile(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	resp, err := h.grpcClients.AuthClient.GetUserProfile(ctx, &authv1.GetUserProfileRequest{
+	resp, err := h.grpcClients.AuthClient.GetUserProfile(c.Request.Context(), &authv1.GetUserProfileRequest{
 		UserId: userID.(string),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user profile"})
+		failRPC(c, err, "GET_PROFILE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateUserProfile changes the fields present in the body. locale is "vi"
+// or "en" and selects the language of the user's notifications.
+func (h *userHandler) UpdateUserProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req struct {
+		FullName string `json:"fullName" binding:"omitempty,max=64"`
+		Locale   string `json:"locale" binding:"omitempty,max=16"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	resp, err := h.grpcClients.AuthClient.UpdateUserProfile(c.Request.Context(), &authv1.UpdateUserProfileRequest{
+		UserId:   userID.(string),
+		FullName: req.FullName,
+		Locale:   req.Locale,
+	})
+	if err != nil {
+		failRPC(c, err, "UPDATE_PROFILE_FAILED")
+		return
+	}
+	if !resp.Success {
+		utils.Fail(c, http.StatusBadRequest, "UPDATE_PROFILE_FAILED", resp.Message)
 		return
 	}
 
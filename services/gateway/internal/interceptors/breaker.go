@@ -0,0 +1,150 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"gateway/internal/metrics"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops calls to a backend after consecutive failures so that
+// requests fail fast instead of each waiting out its deadline. After the
+// cooldown one probe call is let through: success closes the circuit, failure
+// opens it for another cooldown. Only failures that point at the backend
+// count; errors the backend returns on purpose (NotFound, InvalidArgument...)
+// and calls cancelled by the caller do not.
+type CircuitBreaker struct {
+	target    string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker returns a breaker that opens after threshold consecutive
+// failures. It returns nil, which lets every call through, when threshold is
+// not positive.
+func NewCircuitBreaker(target string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	metrics.SetCircuitBreakerState(target, int(breakerClosed))
+	return &CircuitBreaker{
+		target:    target,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// UnaryClientInterceptor rejects calls with codes.Unavailable while the
+// circuit is open.
+func (b *CircuitBreaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if b == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		probe, err := b.allow()
+		if err != nil {
+			metrics.ObserveCircuitBreakerRejection(b.target)
+			return err
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		b.record(ctx, probe, err)
+		return err
+	}
+}
+
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false, b.openError()
+		}
+		b.setState(breakerHalfOpen)
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return false, b.openError()
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+func (b *CircuitBreaker) record(ctx context.Context, probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// The caller went away; this says nothing about the backend.
+		return
+	}
+	if !isBackendFailure(err) {
+		b.failures = 0
+		if b.state != breakerClosed {
+			b.setState(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+		b.openedAt = b.now()
+		b.setState(breakerOpen)
+	}
+}
+
+func (b *CircuitBreaker) setState(s breakerState) {
+	lg.Info("Circuit breaker state changed", "target", b.target, "from", b.state.String(), "to", s.String(), "failures", b.failures)
+	b.state = s
+	metrics.SetCircuitBreakerState(b.target, int(s))
+}
+
+func (b *CircuitBreaker) openError() error {
+	return status.Errorf(codes.Unavailable, "%s circuit breaker is open", b.target)
+}
+
+func isBackendFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
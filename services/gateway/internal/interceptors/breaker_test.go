@@ -0,0 +1,152 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)}
+	b := NewCircuitBreaker("test", threshold, cooldown)
+	b.now = clock.now
+	return b, clock
+}
+
+// call runs one call through the breaker whose backend answers with err and
+// reports whether the backend was reached.
+func call(b *CircuitBreaker, err error) (reached bool, got error) {
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		reached = true
+		return err
+	}
+	got = b.UnaryClientInterceptor()(context.Background(), "/test/Method", nil, nil, nil, invoker)
+	return reached, got
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b, _ := newTestBreaker(3, time.Second)
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	for i := 0; i < 3; i++ {
+		if reached, _ := call(b, unavailable); !reached {
+			t.Fatalf("call %d rejected before the threshold", i+1)
+		}
+	}
+	reached, err := call(b, nil)
+	if reached {
+		t.Fatal("open breaker let a call through")
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("rejection code = %v; want Unavailable", status.Code(err))
+	}
+}
+
+func TestCircuitBreakerIgnoresNonBackendErrors(t *testing.T) {
+	b, _ := newTestBreaker(2, time.Second)
+
+	for _, err := range []error{
+		status.Error(codes.NotFound, ""),
+		status.Error(codes.InvalidArgument, ""),
+		status.Error(codes.Unauthenticated, ""),
+		status.Error(codes.PermissionDenied, ""),
+	} {
+		call(b, err)
+		call(b, err)
+	}
+	if reached, _ := call(b, nil); !reached {
+		t.Fatal("breaker opened on errors returned on purpose")
+	}
+}
+
+func TestCircuitBreakerSuccessResetsCount(t *testing.T) {
+	b, _ := newTestBreaker(2, time.Second)
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	call(b, unavailable)
+	call(b, nil)
+	call(b, unavailable)
+	if reached, _ := call(b, nil); !reached {
+		t.Fatal("failures separated by a success opened the breaker")
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	b, clock := newTestBreaker(1, time.Second)
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	call(b, unavailable)
+	clock.advance(999 * time.Millisecond)
+	if reached, _ := call(b, nil); reached {
+		t.Fatal("call let through during the cooldown")
+	}
+
+	// A failed probe opens the circuit for another cooldown.
+	clock.advance(time.Millisecond)
+	if reached, _ := call(b, unavailable); !reached {
+		t.Fatal("probe rejected after the cooldown")
+	}
+	if reached, _ := call(b, nil); reached {
+		t.Fatal("call let through after a failed probe")
+	}
+
+	// A successful probe closes it.
+	clock.advance(time.Second)
+	if reached, _ := call(b, nil); !reached {
+		t.Fatal("probe rejected after the second cooldown")
+	}
+	for i := 0; i < 3; i++ {
+		if reached, _ := call(b, nil); !reached {
+			t.Fatal("closed breaker rejected a call")
+		}
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	b, clock := newTestBreaker(1, time.Second)
+	call(b, status.Error(codes.Unavailable, "down"))
+	clock.advance(time.Second)
+
+	probe, err := b.allow()
+	if !probe || err != nil {
+		t.Fatalf("allow = %v, %v; want probe", probe, err)
+	}
+	if _, err := b.allow(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("second allow while probing = %v; want Unavailable", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresCancelledCalls(t *testing.T) {
+	b, _ := newTestBreaker(1, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.Unavailable, "")
+	}
+	b.UnaryClientInterceptor()(ctx, "/test/Method", nil, nil, nil, invoker)
+	if reached, _ := call(b, nil); !reached {
+		t.Fatal("a call cancelled by the caller opened the breaker")
+	}
+}
+
+func TestNilCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker("test", 0, time.Second)
+	if b != nil {
+		t.Fatal("NewCircuitBreaker with threshold 0 returned a breaker")
+	}
+	for i := 0; i < 3; i++ {
+		if reached, _ := call(b, status.Error(codes.Unavailable, "down")); !reached {
+			t.Fatal("nil breaker rejected a call")
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package interceptors
+
+import (
+	"context"
+	"gateway/internal/metrics"
+	"log/slog"
+	"music-player/api/clientip"
+	"music-player/api/logger"
+	"music-player/api/requestid"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var lg = logger.For("interceptors")
+
+// DefaultDeadline is applied to outgoing RPCs whose context has no deadline.
+const DefaultDeadline = 5 * time.Second
+
+// DialOptions returns the client interceptor chain installed on every
+// connection in GRPCClients. It mirrors the server chain in auth-service;
+// streams get no default deadline since they are expected to be long-lived.
+// The OpenTelemetry stats handler injects the W3C traceparent of the caller's
+// span into outgoing metadata. Unary calls get the timeout of their entry in
+// policies, or defaultDeadline.
+func DialOptions(defaultDeadline time.Duration, policies MethodPolicies, clientIPSecret []byte) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(
+			RequestIDUnary(),
+			ClientIPUnary(clientIPSecret),
+			LoggingUnary(),
+			metrics.UnaryClientInterceptor(),
+			DeadlineUnary(defaultDeadline, policies),
+		),
+		grpc.WithChainStreamInterceptor(
+			RequestIDStream(),
+			ClientIPStream(clientIPSecret),
+			LoggingStream(),
+		),
+	}
+}
+
+// RequestIDUnary forwards the request ID of the inbound HTTP request as gRPC metadata.
+func RequestIDUnary() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// RequestIDStream is the streaming counterpart of RequestIDUnary.
+func RequestIDStream() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+// ClientIPUnary forwards the client address resolved by the ClientIP HTTP
+// middleware, signed with secret so the backend can tell it came from the
+// gateway. Without a secret the address is sent unsigned.
+func ClientIPUnary(secret []byte) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withClientIP(ctx, method, secret), method, req, reply, cc, opts...)
+	}
+}
+
+// ClientIPStream is the streaming counterpart of ClientIPUnary.
+func ClientIPStream(secret []byte) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withClientIP(ctx, method, secret), desc, cc, method, opts...)
+	}
+}
+
+// LoggingUnary writes one log line per outgoing RPC with method, status and duration.
+func LoggingUnary() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		logCall(ctx, method, start, err)
+		return err
+	}
+}
+
+// LoggingStream logs stream establishment; per-message logging is left to callers.
+func LoggingStream() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		logCall(ctx, method, start, err)
+		return cs, err
+	}
+}
+
+// DeadlineUnary bounds every call by the timeout of its method policy, or by
+// defaultDeadline. A shorter deadline already on the context is kept, so the
+// caller's own cancellation still propagates.
+func DeadlineUnary(defaultDeadline time.Duration, policies MethodPolicies) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if timeout := policies.timeout(method, defaultDeadline); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, requestid.MetadataKey, id)
+}
+
+func withClientIP(ctx context.Context, method string, secret []byte) context.Context {
+	ip := clientip.FromContext(ctx)
+	if ip == "" {
+		return ctx
+	}
+	if len(secret) == 0 {
+		return metadata.AppendToOutgoingContext(ctx, clientip.MetadataKey, ip)
+	}
+	return metadata.AppendToOutgoingContext(ctx,
+		clientip.MetadataKey, ip,
+		clientip.SignatureMetadataKey, clientip.Sign(secret, ip, method, time.Now()),
+	)
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	lg.Log(ctx, level, "gRPC client request",
+		slog.String("method", method),
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
@@ -0,0 +1,84 @@
+package interceptors
+
+import (
+	"context"
+	"gateway/internal/metrics"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// MethodPolicy describes how one RPC is called. Idempotent RPCs are retried
+// when the backend is unavailable (see the service config built in configs);
+// Hedge additionally sends a second attempt when the first is slow, and only
+// makes sense for idempotent reads.
+type MethodPolicy struct {
+	Timeout    time.Duration
+	Idempotent bool
+	Hedge      bool
+}
+
+// MethodPolicies maps full method names ("/pkg.Service/Method") to their
+// policy. Methods without an entry get the default deadline and no retries.
+type MethodPolicies map[string]MethodPolicy
+
+func (p MethodPolicies) timeout(method string, fallback time.Duration) time.Duration {
+	if mp, ok := p[method]; ok && mp.Timeout > 0 {
+		return mp.Timeout
+	}
+	return fallback
+}
+
+// HedgeUnary sends a second attempt of a hedged RPC when the first has not
+// completed after delay, and returns whichever succeeds first. The slower
+// attempt is cancelled. A delay of 0 disables hedging.
+func HedgeUnary(policies MethodPolicies, delay time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		out, ok := reply.(proto.Message)
+		if delay <= 0 || !policies[method].Hedge || !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			reply proto.Message
+			err   error
+		}
+		results := make(chan result, 2)
+		// Each attempt decodes into its own message; the winner is copied
+		// into reply once, after which the loser no longer touches it.
+		attempt := func() {
+			r := out.ProtoReflect().New().Interface()
+			go func() {
+				results <- result{reply: r, err: invoker(ctx, method, req, r, cc, opts...)}
+			}()
+		}
+
+		attempt()
+		pending := 1
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		var err error
+		for pending > 0 {
+			select {
+			case <-timer.C:
+				metrics.ObserveHedge(method)
+				attempt()
+				pending++
+			case r := <-results:
+				pending--
+				if r.err == nil {
+					proto.Reset(out)
+					proto.Merge(out, r.reply)
+					return nil
+				}
+				err = r.err
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,116 @@
+package interceptors
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const hedgedMethod = "/test/Hedged"
+
+var hedgePolicies = MethodPolicies{
+	hedgedMethod:  {Timeout: time.Second, Idempotent: true, Hedge: true},
+	"/test/Plain": {Timeout: time.Second, Idempotent: true},
+}
+
+func TestMethodPoliciesTimeout(t *testing.T) {
+	if got := hedgePolicies.timeout(hedgedMethod, 5*time.Second); got != time.Second {
+		t.Errorf("timeout(policy) = %v; want 1s", got)
+	}
+	if got := hedgePolicies.timeout("/test/Unknown", 5*time.Second); got != 5*time.Second {
+		t.Errorf("timeout(no policy) = %v; want fallback 5s", got)
+	}
+}
+
+func TestHedgeUnarySecondAttemptWins(t *testing.T) {
+	var attempts atomic.Int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if attempts.Add(1) == 1 {
+			// The first attempt hangs until the winner cancels it.
+			<-ctx.Done()
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		reply.(*wrapperspb.StringValue).Value = "second"
+		return nil
+	}
+
+	reply := &wrapperspb.StringValue{}
+	err := HedgeUnary(hedgePolicies, 10*time.Millisecond)(context.Background(), hedgedMethod, nil, reply, nil, invoker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != "second" {
+		t.Errorf("reply = %q; want the second attempt's", reply.Value)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("attempts = %d; want 2", n)
+	}
+}
+
+func TestHedgeUnaryFastFirstAttempt(t *testing.T) {
+	var attempts atomic.Int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		attempts.Add(1)
+		reply.(*wrapperspb.StringValue).Value = "first"
+		return nil
+	}
+
+	reply := &wrapperspb.StringValue{}
+	if err := HedgeUnary(hedgePolicies, time.Second)(context.Background(), hedgedMethod, nil, reply, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != "first" || attempts.Load() != 1 {
+		t.Errorf("reply = %q after %d attempts; want first after 1", reply.Value, attempts.Load())
+	}
+}
+
+func TestHedgeUnaryBothFail(t *testing.T) {
+	var attempts atomic.Int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if attempts.Add(1) == 1 {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return status.Error(codes.Unavailable, "down")
+	}
+
+	err := HedgeUnary(hedgePolicies, 10*time.Millisecond)(context.Background(), hedgedMethod, nil, &wrapperspb.StringValue{}, nil, invoker)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("err = %v; want Unavailable", err)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("attempts = %d; want 2", n)
+	}
+}
+
+func TestHedgeUnarySkipsUnhedgedMethods(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		method string
+		delay  time.Duration
+	}{
+		{"no hedge policy", "/test/Plain", 10 * time.Millisecond},
+		{"no policy", "/test/Unknown", 10 * time.Millisecond},
+		{"hedging disabled", hedgedMethod, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				attempts.Add(1)
+				time.Sleep(30 * time.Millisecond)
+				return nil
+			}
+			if err := HedgeUnary(hedgePolicies, tt.delay)(context.Background(), tt.method, nil, &wrapperspb.StringValue{}, nil, invoker); err != nil {
+				t.Fatal(err)
+			}
+			if n := attempts.Load(); n != 1 {
+				t.Errorf("attempts = %d; want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var authCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_auth_cache_lookups_total",
+	Help: "Auth middleware cache lookups by cache (token, session) and result (hit, miss).",
+}, []string{"cache", "result"})
+
+// ObserveAuthCache records a lookup in the auth middleware caches.
+func ObserveAuthCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	authCacheLookups.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,16 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var rateLimitDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_rate_limit_decisions_total",
+	Help: "Rate limiter decisions by route group and result (allowed, limited, error).",
+}, []string{"group", "result"})
+
+// ObserveRateLimit records a rate limiter decision.
+func ObserveRateLimit(group, result string) {
+	rateLimitDecisions.WithLabelValues(group, result).Inc()
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	realtimeConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_realtime_connections",
+		Help: "Open realtime connections by transport (sse, websocket).",
+	}, []string{"transport"})
+
+	realtimeEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_realtime_events_total",
+		Help: "Events written to realtime connections by type; replayed events included.",
+	}, []string{"type"})
+
+	realtimeDisconnectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_realtime_disconnects_total",
+		Help: "Realtime connections closed by reason (client_closed, session_revoked, token_expired, token_rotated, session_invalid, slow_consumer, shutdown, write_error).",
+	}, []string{"reason"})
+)
+
+// ObserveRealtimeConnection adds delta (1 or -1) to the open connections.
+func ObserveRealtimeConnection(transport string, delta float64) {
+	realtimeConnections.WithLabelValues(transport).Add(delta)
+}
+
+func ObserveRealtimeEvent(eventType string) {
+	realtimeEventsTotal.WithLabelValues(eventType).Inc()
+}
+
+func ObserveRealtimeDisconnect(reason string) {
+	realtimeDisconnectsTotal.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	circuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_circuit_breaker_state",
+		Help: "Circuit breaker state by target: 0 closed, 1 half-open, 2 open.",
+	}, []string{"target"})
+
+	circuitBreakerRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_circuit_breaker_rejections_total",
+		Help: "Calls rejected without being sent because the circuit was open, by target.",
+	}, []string{"target"})
+
+	grpcClientHedges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_grpc_client_hedged_total",
+		Help: "Hedged attempts sent because the first attempt was slow, by method.",
+	}, []string{"method"})
+)
+
+// SetCircuitBreakerState records the current state of a circuit breaker.
+func SetCircuitBreakerState(target string, state int) {
+	circuitBreakerState.WithLabelValues(target).Set(float64(state))
+}
+
+// ObserveCircuitBreakerRejection records a call rejected by an open circuit.
+func ObserveCircuitBreakerRejection(target string) {
+	circuitBreakerRejections.WithLabelValues(target).Inc()
+}
+
+// ObserveHedge records a hedged attempt of an RPC.
+func ObserveHedge(method string) {
+	grpcClientHedges.WithLabelValues(method).Inc()
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"gateway/internal/utils"
 	"gateway/internal/utils/jwt"
+	"music-player/api/logger"
+	"music-player/api/session"
 	"net/http"
 	"time"
 
@@ -21,12 +23,14 @@ const (
 type AuthMiddleware struct {
 	jwtVerifier jwt.JWTVerifier
 	redisUtil   *redisutil.RedisUtil
+	cache       *SessionCache
 }
 
-func NewAuthMiddleware(jwtVerifier jwt.JWTVerifier, redisUtil *redisutil.RedisUtil) *AuthMiddleware {
+func NewAuthMiddleware(jwtVerifier jwt.JWTVerifier, redisUtil *redisutil.RedisUtil, cache *SessionCache) *AuthMiddleware {
 	return &AuthMiddleware{
 		jwtVerifier: jwtVerifier,
 		redisUtil:   redisUtil,
+		cache:       cache,
 	}
 }
 
@@ -39,78 +43,129 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		m.authenticate(c, token)
+	})
+}
 
-		claims, err := m.jwtVerifier.VerifyToken(token)
-		if err != nil {
-			var errorCode string
-			var errorMessage string
-
-			switch err {
-			case jwt.ErrTokenExpired:
-				errorCode = "TOKEN_EXPIRED"
-				errorMessage = "Token has expired"
-			case jwt.ErrTokenInvalid:
-				errorCode = "TOKEN_INVALID"
-				errorMessage = "Token is invalid"
-			case jwt.ErrMissingKID:
-				errorCode = "MISSING_KID"
-				errorMessage = "Token missing key ID"
-			case jwt.ErrUnexpectedSigningMethod:
-				errorCode = "INVALID_SIGNING_METHOD"
-				errorMessage = "Invalid token signing method"
-			default:
-				errorCode = "TOKEN_VERIFICATION_FAILED"
-				errorMessage = "Token verification failed"
-			}
-
-			utils.Fail(c, http.StatusUnauthorized, errorCode, errorMessage)
-			c.Abort()
+// RequireStreamAuth is RequireAuth for the endpoints browsers open without
+// custom headers, EventSource and WebSocket: instead of the access token,
+// the request may carry a stream ticket in the ticket query parameter.
+func (m *AuthMiddleware) RequireStreamAuth() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if token, err := m.jwtVerifier.ExtractTokenFromHeader(c.GetHeader("Authorization")); err == nil {
+			m.authenticate(c, token)
 			return
 		}
-
-		if claims == nil {
-			utils.Fail(c, http.StatusUnauthorized, "INVALID_CLAIMS", "Token claims are invalid")
+		ticket := c.Query(StreamTicketQueryParam)
+		if ticket == "" {
+			utils.Fail(c, http.StatusUnauthorized, "MISSING_TOKEN", "Authorization token or stream ticket is required")
 			c.Abort()
 			return
 		}
-		if !m.validateSession(c, claims) {
-			utils.Fail(c, http.StatusUnauthorized, "SESSION_INVALID", "User session is invalid or has expired")
+		claims, err := m.redeemStreamTicket(c.Request.Context(), ticket)
+		if err != nil {
+			utils.Fail(c, http.StatusUnauthorized, "TICKET_INVALID", "Stream ticket is invalid or was already used")
 			c.Abort()
 			return
 		}
+		m.authorize(c, claims)
+	})
+}
+
+// authenticate verifies token, then authorizes its claims.
+func (m *AuthMiddleware) authenticate(c *gin.Context, token string) {
+	claims, err := m.verifyToken(token)
+	if err != nil {
+		var errorCode string
+		var errorMessage string
+
+		switch err {
+		case jwt.ErrTokenExpired:
+			errorCode = "TOKEN_EXPIRED"
+			errorMessage = "Token has expired"
+		case jwt.ErrTokenInvalid:
+			errorCode = "TOKEN_INVALID"
+			errorMessage = "Token is invalid"
+		case jwt.ErrMissingKID:
+			errorCode = "MISSING_KID"
+			errorMessage = "Token missing key ID"
+		case jwt.ErrUnexpectedSigningMethod:
+			errorCode = "INVALID_SIGNING_METHOD"
+			errorMessage = "Invalid token signing method"
+		default:
+			errorCode = "TOKEN_VERIFICATION_FAILED"
+			errorMessage = "Token verification failed"
+		}
 
-		m.setUserContext(c, claims)
+		utils.Fail(c, http.StatusUnauthorized, errorCode, errorMessage)
+		c.Abort()
+		return
+	}
 
-		c.Next()
-	})
+	if claims == nil {
+		utils.Fail(c, http.StatusUnauthorized, "INVALID_CLAIMS", "Token claims are invalid")
+		c.Abort()
+		return
+	}
+	m.authorize(c, claims)
+}
+
+// authorize checks the session of verified claims, and stores the user in
+// the context before running the next handlers.
+func (m *AuthMiddleware) authorize(c *gin.Context, claims *jwt.AccessClaims) {
+	if err := m.validateSession(c, claims); err != nil {
+		code, message := session.ErrorCode(err)
+		utils.Fail(c, http.StatusUnauthorized, code, message)
+		c.Abort()
+		return
+	}
+
+	m.setUserContext(c, claims)
+
+	c.Next()
 }
 
 func (m *AuthMiddleware) setUserContext(c *gin.Context, claims *jwt.AccessClaims) {
 	c.Set(ContextKeyUserID, claims.Subject)
 	c.Set(ContextKeyUserData, claims)
 	c.Set(ContextUserSID, claims.SID)
+	c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), claims.Subject))
 }
 
-func (m *AuthMiddleware) validateSession(c *gin.Context, claims *jwt.AccessClaims) bool {
-	redisKey := "auth:session:" + claims.SID
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
-	defer cancel()
-
-	var sess struct {
-		Status string `json:"status"`
-		AV     uint64 `json:"av"`
+// verifyToken verifies the token signature, reusing recently verified
+// claims from the cache.
+func (m *AuthMiddleware) verifyToken(token string) (*jwt.AccessClaims, error) {
+	if claims, ok := m.cache.Claims(token); ok {
+		return claims, nil
 	}
-	if err := m.redisUtil.GetJSON(ctx, redisKey, &sess); err != nil {
-		return false
+	claims, err := m.jwtVerifier.VerifyToken(token)
+	if err != nil {
+		return nil, err
 	}
-	if sess.Status != "active" {
-		return false
-	}
-	if sess.AV != claims.AV {
-		return false
+	m.cache.StoreClaims(token, claims)
+	return claims, nil
+}
+
+// validateSession checks the session named by the access token, using the
+// cached snapshot when there is one.
+func (m *AuthMiddleware) validateSession(c *gin.Context, claims *jwt.AccessClaims) error {
+	snap, gen, ok := m.cache.Session(claims.SID)
+	if !ok {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+		defer cancel()
+
+		var err error
+		if snap, err = session.Load(ctx, m.redisUtil, claims.SID); err != nil {
+			return err
+		}
+		m.cache.StoreSession(claims.SID, snap, gen)
 	}
 
-	return true
+	tok := session.Token{SID: claims.SID, AV: claims.AV}
+	if claims.ExpiresAt != nil {
+		tok.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return session.Check(snap, tok, time.Now())
 }
 
 func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
@@ -127,7 +182,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := m.jwtVerifier.VerifyToken(token)
+		claims, err := m.verifyToken(token)
 		if err != nil {
 			c.Next()
 			return
@@ -158,19 +213,3 @@ func GetUserClaims(c *gin.Context) (*jwt.AccessClaims, bool) {
 	userClaims, ok := claims.(*jwt.AccessClaims)
 	return userClaims, ok
 }
-
-func CORSMiddleware() gin.HandlerFunc {
-	return gin.HandlerFunc(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
-		}
-
-		c.Next()
-	})
-}
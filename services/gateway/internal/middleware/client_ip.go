@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"gateway/internal/utils"
+	"music-player/api/clientip"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIP resolves the client address once per request and stores it in the
+// request context, where utils.GetClientIP and the gRPC client interceptors
+// read it.
+func ClientIP(resolver *utils.ClientIPResolver) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		ip := resolver.Resolve(c.Request)
+		c.Request = c.Request.WithContext(clientip.NewContext(c.Request.Context(), ip))
+		c.Next()
+	})
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitSubject(t *testing.T) {
+	tests := []struct {
+		name   string
+		by     RateLimitKey
+		userID string
+		want   string
+	}{
+		{"ip ignores user", KeyByIP, "u1", "ip:192.0.2.1"},
+		{"user verified", KeyByUser, "u1", "user:u1"},
+		{"user missing falls back to ip", KeyByUser, "", "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/v1/health", nil)
+			c.Request.RemoteAddr = "192.0.2.1:4321"
+			// Unverified credentials must never pick the bucket.
+			c.Request.Header.Set("Authorization", "Bearer made-up")
+			c.Request.Header.Set("X-API-Key", "made-up")
+			if tt.userID != "" {
+				c.Set("user_id", tt.userID)
+			}
+			if got := rateLimitSubject(c, tt.by); got != tt.want {
+				t.Errorf("rateLimitSubject = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
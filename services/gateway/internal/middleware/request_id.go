@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"music-player/api/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContextKeyRequestID = "request_id"
+
+// RequestID reuses the client's X-Request-ID when it passes requestid.Valid or
+// generates a new one, echoes it in the response and stores it in the request
+// context so gRPC client interceptors can forward it to backend services.
+func RequestID() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Set(ContextKeyRequestID, id)
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+
+		c.Next()
+	})
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"gateway/configs"
+	"gateway/internal/utils"
+	"music-player/api/requestid"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	refreshCookieName = "refresh_token"
+	csrfCookieName    = "csrf_token"
+	hostCookiePrefix  = "__Host-"
+
+	// CSRFHeader carries the double-submitted CSRF token.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// Cookies sets the refresh token and CSRF cookies according to the
+// configured policy. The refresh cookie is HttpOnly and scoped to the auth
+// routes; the CSRF cookie is readable by scripts so it can be echoed in
+// CSRFHeader.
+type Cookies struct {
+	policy configs.CookiePolicy
+}
+
+func NewCookies(policy configs.CookiePolicy) *Cookies {
+	return &Cookies{policy: policy}
+}
+
+func (k *Cookies) name(base string) string {
+	if k.policy.HostPrefix {
+		return hostCookiePrefix + base
+	}
+	return base
+}
+
+func (k *Cookies) set(c *gin.Context, name, value, path string, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     k.name(name),
+		Value:    value,
+		Path:     path,
+		Domain:   k.policy.Domain,
+		MaxAge:   maxAge,
+		Secure:   k.policy.Secure,
+		HttpOnly: httpOnly,
+		SameSite: k.policy.SameSite,
+	})
+}
+
+// RefreshToken returns the refresh token cookie value.
+func (k *Cookies) RefreshToken(c *gin.Context) (string, error) {
+	return c.Cookie(k.name(refreshCookieName))
+}
+
+// SetSession stores the refresh token and issues a new CSRF token, which is
+// returned so it can also be sent in the response body. maxAgeSeconds is
+// the refresh token lifetime; 0 falls back to the configured MaxAge.
+func (k *Cookies) SetSession(c *gin.Context, refreshToken string, maxAgeSeconds int64) (string, error) {
+	maxAge := int(maxAgeSeconds)
+	if maxAge <= 0 {
+		maxAge = int(k.policy.MaxAge.Seconds())
+	}
+
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	k.set(c, refreshCookieName, refreshToken, k.policy.Path, maxAge, true)
+	// The CSRF cookie must be visible to pages outside the auth path.
+	k.set(c, csrfCookieName, csrfToken, "/", maxAge, false)
+	return csrfToken, nil
+}
+
+// ClearSession expires both cookies.
+func (k *Cookies) ClearSession(c *gin.Context) {
+	k.set(c, refreshCookieName, "", k.policy.Path, -1, true)
+	k.set(c, csrfCookieName, "", "/", -1, false)
+}
+
+// RequireCSRF enforces the double-submit check on cookie-authenticated
+// endpoints: CSRFHeader must match the CSRF cookie. A cross-site attacker
+// can make the browser send the cookie but cannot read it to set the header.
+// Safe methods pass, as they must not change state.
+func (k *Cookies) RequireCSRF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		cookie, err := c.Cookie(k.name(csrfCookieName))
+		header := c.GetHeader(CSRFHeader)
+		if err != nil || cookie == "" || header == "" ||
+			subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) != 1 {
+			utils.Fail(c, http.StatusForbidden, "CSRF_TOKEN_INVALID", "Missing or invalid CSRF token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func newCSRFToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CORSMiddleware answers CORS requests from allow-listed origins only.
+// Credentials are allowed, so the matching origin is echoed instead of "*".
+// Requests from other origins get no CORS headers and preflights are
+// rejected.
+func CORSMiddleware(cfg configs.CORSConfig) gin.HandlerFunc {
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	allowHeaders := strings.Join([]string{
+		"Content-Type", "Authorization", "Accept", "Cache-Control",
+		"X-Requested-With", CSRFHeader, requestid.Header,
+	}, ", ")
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Origin")
+		if !slices.Contains(cfg.AllowedOrigins, origin) {
+			if c.Request.Method == http.MethodOptions {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Expose-Headers", requestid.Header)
+
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	})
+}
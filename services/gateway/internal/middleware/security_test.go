@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"gateway/configs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		hostPrefix bool
+		method     string
+		cookie     string // name=value
+		header     string
+		want       int
+	}{
+		{"match", false, http.MethodPost, "csrf_token=abc", "abc", http.StatusOK},
+		{"mismatch", false, http.MethodPost, "csrf_token=abc", "abd", http.StatusForbidden},
+		{"missing header", false, http.MethodPost, "csrf_token=abc", "", http.StatusForbidden},
+		{"missing cookie", false, http.MethodPost, "", "abc", http.StatusForbidden},
+		{"empty cookie and header", false, http.MethodPost, "csrf_token=", "", http.StatusForbidden},
+		{"delete", false, http.MethodDelete, "csrf_token=abc", "abd", http.StatusForbidden},
+		{"get", false, http.MethodGet, "", "", http.StatusOK},
+		{"head", false, http.MethodHead, "", "", http.StatusOK},
+		{"options", false, http.MethodOptions, "", "", http.StatusOK},
+		{"host prefix", true, http.MethodPost, "__Host-csrf_token=abc", "abc", http.StatusOK},
+		// Without the prefix the cookie could have been set by a subdomain.
+		{"unprefixed cookie under host prefix", true, http.MethodPost, "csrf_token=abc", "abc", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		k := NewCookies(configs.CookiePolicy{HostPrefix: tt.hostPrefix})
+		r := gin.New()
+		r.Handle(tt.method, "/refresh", k.RequireCSRF(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		req := httptest.NewRequest(tt.method, "/refresh", nil)
+		if tt.cookie != "" {
+			req.Header.Set("Cookie", tt.cookie)
+		}
+		if tt.header != "" {
+			req.Header.Set(CSRFHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORSMiddleware(configs.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, MaxAge: 10 * time.Minute}))
+	r.Any("/api", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		want       int
+		wantOrigin string
+	}{
+		{"allowed", http.MethodGet, "https://app.example.com", http.StatusOK, "https://app.example.com"},
+		{"allowed preflight", http.MethodOptions, "https://app.example.com", http.StatusNoContent, "https://app.example.com"},
+		// The browser blocks the response; the request itself is served.
+		{"unknown origin", http.MethodGet, "https://evil.example.com", http.StatusOK, ""},
+		{"unknown origin preflight", http.MethodOptions, "https://evil.example.com", http.StatusForbidden, ""},
+		{"prefix of an allowed origin", http.MethodOptions, "https://app.example.com.evil.com", http.StatusForbidden, ""},
+		{"other scheme", http.MethodOptions, "http://app.example.com", http.StatusForbidden, ""},
+		{"same origin", http.MethodGet, "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		h := w.Header()
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, w.Code, tt.want)
+		}
+		if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+			t.Errorf("%s: Allow-Origin = %q; want %q", tt.name, got, tt.wantOrigin)
+		}
+		if allowed := tt.wantOrigin != ""; (h.Get("Access-Control-Allow-Credentials") == "true") != allowed {
+			t.Errorf("%s: Allow-Credentials = %q", tt.name, h.Get("Access-Control-Allow-Credentials"))
+		}
+		if tt.origin != "" && h.Get("Vary") != "Origin" {
+			t.Errorf("%s: Vary = %q; want Origin", tt.name, h.Get("Vary"))
+		}
+		preflight := tt.method == http.MethodOptions && tt.wantOrigin != ""
+		if preflight && (h.Get("Access-Control-Max-Age") != "600" || h.Get("Access-Control-Allow-Headers") == "") {
+			t.Errorf("%s: preflight headers = %v", tt.name, h)
+		}
+	}
+}
+
+func TestSetSession(t *testing.T) {
+	tests := []struct {
+		name                  string
+		policy                configs.CookiePolicy
+		wantRefresh, wantCSRF string
+		wantPath              string
+		wantDomain            string
+		wantSecure            bool
+		wantSameSite          http.SameSite
+	}{
+		{
+			name:        "plain",
+			policy:      configs.CookiePolicy{Path: "/api/v1/auth", Domain: "example.com", SameSite: http.SameSiteStrictMode, MaxAge: time.Hour},
+			wantRefresh: "refresh_token", wantCSRF: "csrf_token",
+			wantPath: "/api/v1/auth", wantDomain: "example.com", wantSameSite: http.SameSiteStrictMode,
+		},
+		{
+			name:        "host prefix",
+			policy:      configs.CookiePolicy{Secure: true, Path: "/", HostPrefix: true, SameSite: http.SameSiteNoneMode, MaxAge: time.Hour},
+			wantRefresh: "__Host-refresh_token", wantCSRF: "__Host-csrf_token",
+			wantPath: "/", wantSecure: true, wantSameSite: http.SameSiteNoneMode,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+
+		token, err := NewCookies(tt.policy).SetSession(c, "rt", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cookies := map[string]*http.Cookie{}
+		for _, ck := range w.Result().Cookies() {
+			cookies[ck.Name] = ck
+		}
+		refresh, csrf := cookies[tt.wantRefresh], cookies[tt.wantCSRF]
+		if refresh == nil || csrf == nil {
+			t.Fatalf("%s: cookies = %v; want %s and %s", tt.name, w.Result().Cookies(), tt.wantRefresh, tt.wantCSRF)
+		}
+		if refresh.Value != "rt" || !refresh.HttpOnly || refresh.Path != tt.wantPath || refresh.Domain != tt.wantDomain {
+			t.Errorf("%s: refresh cookie = %+v", tt.name, refresh)
+		}
+		// The CSRF cookie is read by scripts on any page.
+		if csrf.Value != token || token == "" || csrf.HttpOnly || csrf.Path != "/" {
+			t.Errorf("%s: CSRF cookie = %+v; want the returned token %q", tt.name, csrf, token)
+		}
+		for _, ck := range []*http.Cookie{refresh, csrf} {
+			if ck.Secure != tt.wantSecure || ck.SameSite != tt.wantSameSite || ck.MaxAge != 3600 {
+				t.Errorf("%s: %s Secure=%v SameSite=%v MaxAge=%d", tt.name, ck.Name, ck.Secure, ck.SameSite, ck.MaxAge)
+			}
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"gateway/internal/metrics"
+	"gateway/internal/utils/jwt"
+	"music-player/api/logger"
+	"music-player/api/session"
+	"slices"
+	"sync"
+	"time"
+
+	redisutil "gateway/internal/utils/redis"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var lg = logger.For("middleware")
+
+type tokenEntry struct {
+	claims    *jwt.AccessClaims
+	expiresAt time.Time
+}
+
+type sessionEntry struct {
+	snapshot  session.Snapshot
+	expiresAt time.Time
+}
+
+// SessionCache is a bounded in-process cache of verified access tokens and
+// session snapshots used by AuthMiddleware. Entries live for a short TTL;
+// Listen drops session snapshots as soon as auth-service announces a
+// revocation or rotation, and flushes them all whenever the subscription is
+// (re)established because messages may have been missed in between. A nil
+// *SessionCache disables caching.
+type SessionCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu       sync.Mutex
+	tokens   map[[sha256.Size]byte]tokenEntry
+	sessions map[string]sessionEntry
+	// gen counts invalidations so a snapshot read from Redis before an
+	// invalidation is not stored after it.
+	gen uint64
+}
+
+// NewSessionCache returns nil when ttl or maxEntries is not positive.
+func NewSessionCache(ttl time.Duration, maxEntries int) *SessionCache {
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil
+	}
+	return &SessionCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		tokens:     make(map[[sha256.Size]byte]tokenEntry),
+		sessions:   make(map[string]sessionEntry),
+	}
+}
+
+// Claims returns the cached claims of a previously verified token.
+func (c *SessionCache) Claims(token string) (*jwt.AccessClaims, bool) {
+	if c == nil {
+		return nil, false
+	}
+	key := sha256.Sum256([]byte(token))
+
+	c.mu.Lock()
+	entry, ok := c.tokens[key]
+	if ok && !c.now().Before(entry.expiresAt) {
+		delete(c.tokens, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	metrics.ObserveAuthCache("token", ok)
+	return entry.claims, ok
+}
+
+// StoreClaims caches verified claims, never beyond the token's own expiry.
+func (c *SessionCache) StoreClaims(token string, claims *jwt.AccessClaims) {
+	if c == nil {
+		return
+	}
+	expiresAt := c.now().Add(c.ttl)
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	evict(c.tokens, c.maxEntries, c.now(), func(e tokenEntry) time.Time { return e.expiresAt })
+	c.tokens[sha256.Sum256([]byte(token))] = tokenEntry{claims: claims, expiresAt: expiresAt}
+}
+
+// Session returns the cached snapshot of sid. On a miss, gen must be passed
+// to StoreSession along with the snapshot loaded from Redis.
+func (c *SessionCache) Session(sid string) (_ session.Snapshot, gen uint64, ok bool) {
+	if c == nil {
+		return session.Snapshot{}, 0, false
+	}
+
+	c.mu.Lock()
+	entry, ok := c.sessions[sid]
+	if ok && !c.now().Before(entry.expiresAt) {
+		delete(c.sessions, sid)
+		ok = false
+	}
+	gen = c.gen
+	c.mu.Unlock()
+
+	metrics.ObserveAuthCache("session", ok)
+	return entry.snapshot, gen, ok
+}
+
+// StoreSession caches a session snapshot loaded while the cache was at gen.
+// It is dropped if an invalidation happened since.
+func (c *SessionCache) StoreSession(sid string, snapshot session.Snapshot, gen uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	evict(c.sessions, c.maxEntries, c.now(), func(e sessionEntry) time.Time { return e.expiresAt })
+	c.sessions[sid] = sessionEntry{snapshot: snapshot, expiresAt: c.now().Add(c.ttl)}
+}
+
+// Invalidate drops the snapshot of sid.
+func (c *SessionCache) Invalidate(sid string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.sessions, sid)
+	c.gen++
+	c.mu.Unlock()
+}
+
+// Flush drops every session snapshot.
+func (c *SessionCache) Flush() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	clear(c.sessions)
+	c.gen++
+	c.mu.Unlock()
+}
+
+// Listen applies session events from Redis until ctx is cancelled.
+func (c *SessionCache) Listen(ctx context.Context, redisUtil *redisutil.RedisUtil) {
+	if c == nil {
+		return
+	}
+
+	pubsub := redisUtil.Subscribe(ctx, session.EventsChannel)
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// go-redis reconnects on the next Receive; until the subscription
+			// is confirmed again, events may be lost.
+			c.Flush()
+			lg.WarnContext(ctx, "Session events subscription error", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			c.Flush()
+			lg.InfoContext(ctx, "Subscribed to session events", "channel", m.Channel)
+		case *redis.Message:
+			var event session.Event
+			if err := json.Unmarshal([]byte(m.Payload), &event); err != nil || event.SID == "" {
+				lg.WarnContext(ctx, "Ignoring malformed session event", "error", err)
+				continue
+			}
+			c.Invalidate(event.SID)
+			lg.DebugContext(ctx, "Session cache entry invalidated", "sid", event.SID, "reason", event.Reason)
+		}
+	}
+}
+
+// evict makes room in a full map: it removes expired entries and, if the map
+// is still full, the tenth closest to expiry. Dropping a batch keeps the sort
+// off the per-insert path, and the entries evicted are the ones that would
+// have been refetched soonest anyway.
+func evict[K comparable, V any](m map[K]V, maxEntries int, now time.Time, expiresAt func(V) time.Time) {
+	if len(m) < maxEntries {
+		return
+	}
+	for k, v := range m {
+		if !now.Before(expiresAt(v)) {
+			delete(m, k)
+		}
+	}
+	if len(m) < maxEntries {
+		return
+	}
+
+	type candidate struct {
+		key       K
+		expiresAt time.Time
+	}
+	candidates := make([]candidate, 0, len(m))
+	for k, v := range m {
+		candidates = append(candidates, candidate{key: k, expiresAt: expiresAt(v)})
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return a.expiresAt.Compare(b.expiresAt)
+	})
+	for _, c := range candidates[:max(len(m)/10, 1)] {
+		delete(m, c.key)
+	}
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"fmt"
+	"music-player/api/session"
+	"testing"
+	"time"
+
+	"gateway/internal/utils/jwt"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestCache(ttl time.Duration, maxEntries int) (*SessionCache, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)}
+	c := NewSessionCache(ttl, maxEntries)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestSessionCacheDisabled(t *testing.T) {
+	for _, c := range []*SessionCache{NewSessionCache(0, 10), NewSessionCache(time.Second, 0)} {
+		if c != nil {
+			t.Fatal("NewSessionCache with zero ttl or size returned a cache")
+		}
+		// A nil cache is usable and never hits.
+		c.StoreClaims("tok", &jwt.AccessClaims{})
+		if _, ok := c.Claims("tok"); ok {
+			t.Fatal("nil cache hit")
+		}
+		c.Invalidate("sid")
+		c.Flush()
+	}
+}
+
+func TestSessionCacheClaimsTTL(t *testing.T) {
+	c, clock := newTestCache(5*time.Second, 10)
+
+	c.StoreClaims("tok", &jwt.AccessClaims{SID: "s1"})
+	if claims, ok := c.Claims("tok"); !ok || claims.SID != "s1" {
+		t.Fatalf("Claims = %+v, %v; want hit", claims, ok)
+	}
+	clock.advance(5 * time.Second)
+	if _, ok := c.Claims("tok"); ok {
+		t.Fatal("claims served past the cache TTL")
+	}
+}
+
+func TestSessionCacheClaimsCappedAtTokenExpiry(t *testing.T) {
+	c, clock := newTestCache(time.Minute, 10)
+
+	claims := &jwt.AccessClaims{SID: "s1"}
+	claims.ExpiresAt = gojwt.NewNumericDate(clock.now().Add(2 * time.Second))
+	c.StoreClaims("tok", claims)
+
+	clock.advance(time.Second)
+	if _, ok := c.Claims("tok"); !ok {
+		t.Fatal("claims missing before token expiry")
+	}
+	clock.advance(time.Second)
+	if _, ok := c.Claims("tok"); ok {
+		t.Fatal("claims served past the token's own expiry")
+	}
+}
+
+func TestSessionCacheInvalidation(t *testing.T) {
+	c, clock := newTestCache(5*time.Second, 10)
+	active := session.Snapshot{Status: session.StatusActive, AV: 1}
+
+	_, gen, ok := c.Session("s1")
+	if ok {
+		t.Fatal("hit on empty cache")
+	}
+	c.StoreSession("s1", active, gen)
+	if got, _, ok := c.Session("s1"); !ok || got != active {
+		t.Fatalf("Session = %+v, %v; want %+v", got, ok, active)
+	}
+
+	c.Invalidate("s1")
+	if _, _, ok := c.Session("s1"); ok {
+		t.Fatal("snapshot served after Invalidate")
+	}
+
+	// A snapshot read before an invalidation must not be stored after it.
+	_, gen, _ = c.Session("s2")
+	c.Invalidate("s2")
+	c.StoreSession("s2", active, gen)
+	if _, _, ok := c.Session("s2"); ok {
+		t.Fatal("stale snapshot stored after a concurrent invalidation")
+	}
+
+	_, gen, _ = c.Session("s3")
+	c.StoreSession("s3", active, gen)
+	c.Flush()
+	if _, _, ok := c.Session("s3"); ok {
+		t.Fatal("snapshot served after Flush")
+	}
+
+	_, gen, _ = c.Session("s4")
+	c.StoreSession("s4", active, gen)
+	clock.advance(5 * time.Second)
+	if _, _, ok := c.Session("s4"); ok {
+		t.Fatal("snapshot served past the cache TTL")
+	}
+}
+
+func TestEvictByExpiry(t *testing.T) {
+	now := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := func(v time.Time) time.Time { return v }
+
+	// Full map with nothing expired: the entries closest to expiry go.
+	m := make(map[string]time.Time)
+	for i := 0; i < 20; i++ {
+		m[fmt.Sprintf("k%02d", i)] = now.Add(time.Duration(i+1) * time.Second)
+	}
+	evict(m, 20, now, expiresAt)
+	if len(m) != 18 {
+		t.Fatalf("len after evict = %d, want 18", len(m))
+	}
+	for _, k := range []string{"k00", "k01"} {
+		if _, ok := m[k]; ok {
+			t.Errorf("%s (closest to expiry) was kept", k)
+		}
+	}
+
+	// Expired entries are removed first; nothing else goes if that frees room.
+	m = map[string]time.Time{
+		"expired": now.Add(-time.Second),
+		"a":       now.Add(time.Second),
+		"b":       now.Add(2 * time.Second),
+	}
+	evict(m, 3, now, expiresAt)
+	if _, ok := m["expired"]; ok || len(m) != 2 {
+		t.Fatalf("map after evict = %v, want a and b", m)
+	}
+
+	// Below capacity nothing is touched, even expired entries.
+	m = map[string]time.Time{"expired": now.Add(-time.Second)}
+	evict(m, 2, now, expiresAt)
+	if len(m) != 1 {
+		t.Fatalf("evict below capacity removed entries: %v", m)
+	}
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"music-player/api/session"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gateway/internal/utils/jwt"
+	redisutil "gateway/internal/utils/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeVerifier accepts the tokens it maps to claims.
+type fakeVerifier map[string]*jwt.AccessClaims
+
+func (v fakeVerifier) VerifyToken(token string) (*jwt.AccessClaims, error) {
+	if claims, ok := v[token]; ok {
+		return claims, nil
+	}
+	return nil, jwt.ErrTokenInvalid
+}
+
+func (v fakeVerifier) ExtractTokenFromHeader(header string) (string, error) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", errors.New("missing bearer token")
+	}
+	return token, nil
+}
+
+func newStreamTestRouter(t *testing.T) (*gin.Engine, *miniredis.Miniredis) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	claims := &jwt.AccessClaims{
+		SID: "s1",
+		AV:  1,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			Subject:   "u1",
+			ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	setSession(t, mr, "s1", session.StatusActive)
+
+	m := NewAuthMiddleware(fakeVerifier{"access-token": claims}, redisutil.NewRedisUtil(rdb), nil)
+	r := gin.New()
+	r.POST("/ticket", m.RequireAuth(), m.IssueStreamTicket)
+	r.GET("/stream", m.RequireStreamAuth(), func(c *gin.Context) {
+		userID, _ := GetUserID(c)
+		c.String(http.StatusOK, userID)
+	})
+	return r, mr
+}
+
+func setSession(t *testing.T, mr *miniredis.Miniredis, sid, status string) {
+	t.Helper()
+	b, _ := json.Marshal(session.Snapshot{Status: status, AV: 1})
+	if err := mr.Set(session.Key(sid), string(b)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func issueTicket(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/ticket", nil)
+	req.Header.Set("Authorization", "Bearer access-token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("issue ticket: status = %d (%s)", w.Code, w.Body)
+	}
+	var resp struct {
+		Data struct {
+			Ticket    string `json:"ticket"`
+			ExpiresIn int    `json:"expiresIn"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.Ticket == "" {
+		t.Fatalf("issue ticket: body = %s", w.Body)
+	}
+	if resp.Data.ExpiresIn != int(StreamTicketTTL.Seconds()) {
+		t.Errorf("expiresIn = %d; want %v", resp.Data.ExpiresIn, StreamTicketTTL)
+	}
+	return resp.Data.Ticket
+}
+
+func openStream(r *gin.Engine, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream"+query, nil))
+	return w
+}
+
+func TestStreamTicketOpensOneConnection(t *testing.T) {
+	r, _ := newStreamTestRouter(t)
+	ticket := issueTicket(t, r)
+
+	w := openStream(r, "?ticket="+ticket)
+	if w.Code != http.StatusOK || w.Body.String() != "u1" {
+		t.Fatalf("first use: status = %d, body = %s; want 200 for u1", w.Code, w.Body)
+	}
+	w = openStream(r, "?ticket="+ticket)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "TICKET_INVALID") {
+		t.Errorf("second use: status = %d, body = %s; want TICKET_INVALID", w.Code, w.Body)
+	}
+}
+
+func TestStreamTicketExpires(t *testing.T) {
+	r, mr := newStreamTestRouter(t)
+	ticket := issueTicket(t, r)
+
+	mr.FastForward(StreamTicketTTL)
+	if w := openStream(r, "?ticket="+ticket); w.Code != http.StatusUnauthorized {
+		t.Errorf("expired ticket: status = %d; want 401", w.Code)
+	}
+}
+
+func TestStreamTicketChecksSession(t *testing.T) {
+	r, mr := newStreamTestRouter(t)
+	ticket := issueTicket(t, r)
+
+	// The session revoked after the ticket was issued is checked when the
+	// ticket is used.
+	setSession(t, mr, "s1", session.StatusRevoked)
+	if w := openStream(r, "?ticket="+ticket); w.Code != http.StatusUnauthorized {
+		t.Errorf("ticket of a revoked session: status = %d; want 401", w.Code)
+	}
+}
+
+func TestRequireStreamAuthCredentials(t *testing.T) {
+	r, _ := newStreamTestRouter(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		header   string
+		wantCode int
+	}{
+		{"bearer header", "", "Bearer access-token", http.StatusOK},
+		{"nothing", "", "", http.StatusUnauthorized},
+		{"unknown ticket", "?ticket=made-up", "", http.StatusUnauthorized},
+		// Access tokens are not accepted in the URL.
+		{"access token in query", "?access_token=access-token", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stream"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d; want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Package proxy forwards REST requests to backend services that serve HTTP
+// rather than gRPC.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/internal/utils"
+	"music-player/api/logger"
+	"music-player/api/requestid"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+var lg = logger.For("proxy")
+
+// Headers set on forwarded requests. Backends trust them because of the
+// internal token, so values sent by the client are dropped.
+const (
+	HeaderInternalToken = "X-Internal-Token"
+	HeaderUserID        = "X-User-ID"
+)
+
+// statusClientClosedRequest is logged when the client went away before the
+// backend answered.
+const statusClientClosedRequest = 499
+
+// Backend is an HTTP service behind the gateway.
+type Backend struct {
+	name   string
+	target *url.URL
+	token  string
+	proxy  *httputil.ReverseProxy
+}
+
+// forward is what a Forward handler passes to the shared reverse proxy
+// through the request context.
+type forward struct {
+	c       *gin.Context
+	path    string
+	rawPath string
+	userID  string
+}
+
+type forwardKey struct{}
+
+// NewBackend returns a backend at baseURL. token is sent as
+// X-Internal-Token; timeout bounds the wait for response headers.
+func NewBackend(name, baseURL, token string, timeout time.Duration) (*Backend, error) {
+	target, err := url.Parse(baseURL)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("proxy: invalid %s URL %q", name, baseURL)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	b := &Backend{name: name, target: target, token: token}
+	b.proxy = &httputil.ReverseProxy{
+		Transport: transport,
+		Rewrite:   b.rewrite,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			b.fail(r.Context().Value(forwardKey{}).(*forward).c, err)
+		},
+	}
+	return b, nil
+}
+
+// Forward returns a handler that sends the request to path on the backend,
+// with its query string and body. Segments of path written :name are
+// replaced by the route parameter of that name, as in
+// Forward("/api/v1/notifications/:id/read"). The user set by the auth
+// middleware, if any, is passed as X-User-ID.
+func (b *Backend) Forward(path string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f := &forward{c: c, userID: c.GetString("user_id")}
+		f.path, f.rawPath = expandPath(path, c.Params)
+		req := c.Request.WithContext(context.WithValue(c.Request.Context(), forwardKey{}, f))
+		b.proxy.ServeHTTP(c.Writer, req)
+	}
+}
+
+func (b *Backend) rewrite(pr *httputil.ProxyRequest) {
+	f := pr.In.Context().Value(forwardKey{}).(*forward)
+	pr.Out.URL.Scheme = b.target.Scheme
+	pr.Out.URL.Host = b.target.Host
+	base := strings.TrimRight(b.target.Path, "/")
+	pr.Out.URL.Path = base + f.path
+	pr.Out.URL.RawPath = ""
+	if f.rawPath != f.path {
+		pr.Out.URL.RawPath = base + f.rawPath
+	}
+	pr.Out.Host = b.target.Host
+
+	// The backend authenticates the gateway, not the client.
+	for _, h := range []string{"Authorization", "Cookie", HeaderInternalToken, HeaderUserID} {
+		pr.Out.Header.Del(h)
+	}
+	pr.Out.Header.Set(HeaderInternalToken, b.token)
+	if f.userID != "" {
+		pr.Out.Header.Set(HeaderUserID, f.userID)
+	}
+	if id := requestid.FromContext(pr.In.Context()); id != "" {
+		pr.Out.Header.Set(requestid.Header, id)
+	}
+	otel.GetTextMapPropagator().Inject(pr.In.Context(), propagation.HeaderCarrier(pr.Out.Header))
+}
+
+// expandPath replaces the :name segments of path with params, and returns
+// the path and its escaped form.
+func expandPath(path string, params gin.Params) (string, string) {
+	if !strings.Contains(path, ":") {
+		return path, path
+	}
+	segments := strings.Split(path, "/")
+	escaped := make([]string, len(segments))
+	for i, seg := range segments {
+		escaped[i] = seg
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			value := params.ByName(name)
+			segments[i] = value
+			escaped[i] = url.PathEscape(value)
+		}
+	}
+	return strings.Join(segments, "/"), strings.Join(escaped, "/")
+}
+
+func (b *Backend) fail(c *gin.Context, err error) {
+	ctx := c.Request.Context()
+	if errors.Is(ctx.Err(), context.Canceled) {
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
+	code := strings.ToUpper(b.name) + "_UNAVAILABLE"
+	lg.WarnContext(ctx, "Backend request failed", "backend", b.name, "path", c.FullPath(), "error", err)
+
+	var netErr interface{ Timeout() bool }
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		utils.Fail(c, http.StatusGatewayTimeout, strings.ToUpper(b.name)+"_TIMEOUT", "Upstream service timed out")
+		return
+	}
+	c.Header("Retry-After", "1")
+	utils.Fail(c, http.StatusServiceUnavailable, code, "Upstream service unavailable")
+}
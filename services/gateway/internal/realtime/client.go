@@ -0,0 +1,232 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"gateway/internal/metrics"
+	"gateway/internal/utils/jwt"
+	"music-player/api/session"
+	"sync"
+	"time"
+
+	apirealtime "music-player/api/realtime"
+)
+
+// sendBuffer is how many events a connection may fall behind before it is
+// closed as a slow consumer.
+const sendBuffer = 64
+
+// message is an event as written to a connection. ID is set on user events
+// only, which clients pass back to resume after it.
+type message struct {
+	ID   string          `json:"id,omitempty"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+func reconnect(reason string) message {
+	data, _ := json.Marshal(map[string]string{"reason": reason})
+	return message{Type: EventReconnect, Data: data}
+}
+
+func revoked() message {
+	data, _ := json.Marshal(map[string]string{"reason": session.EventRevoked})
+	return message{Type: EventSessionRevoked, Data: data}
+}
+
+// client is an open connection of a user.
+type client struct {
+	userID    string
+	token     session.Token
+	transport string
+
+	send    chan message
+	catchUp chan struct{}
+	done    chan struct{}
+
+	closeOnce sync.Once
+	// final is written to the connection before it is closed for reason.
+	// Both are set once, before done is closed.
+	final  message
+	reason string
+}
+
+func newClient(claims *jwt.AccessClaims, transport string) *client {
+	tok := session.Token{SID: claims.SID, AV: claims.AV}
+	if claims.ExpiresAt != nil {
+		tok.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return &client{
+		userID:    claims.Subject,
+		token:     tok,
+		transport: transport,
+		send:      make(chan message, sendBuffer),
+		catchUp:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// enqueue queues m without blocking and reports false if the buffer is
+// full.
+func (c *client) enqueue(m message) bool {
+	select {
+	case c.send <- m:
+		return true
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *client) requestCatchUp() {
+	select {
+	case c.catchUp <- struct{}{}:
+	default:
+	}
+}
+
+// close ends the connection after final is written. Only the first call has
+// an effect.
+func (c *client) close(final message, reason string) {
+	c.closeOnce.Do(func() {
+		c.final = final
+		c.reason = reason
+		close(c.done)
+	})
+}
+
+// transport writes messages to one connection.
+type transport interface {
+	write(m message) error
+	ping() error
+	// closed is closed once the client has gone away.
+	closed() <-chan struct{}
+	// close ends the connection for reason.
+	close(reason string)
+}
+
+// writer writes to a transport, skipping user events at or before the last
+// one written: an event can arrive both live and in a replay.
+type writer struct {
+	t    transport
+	last string
+}
+
+func (w *writer) write(m message) error {
+	if m.ID != "" && w.last != "" && !apirealtime.After(m.ID, w.last) {
+		return nil
+	}
+	if err := w.t.write(m); err != nil {
+		return err
+	}
+	if m.ID != "" {
+		w.last = m.ID
+	}
+	metrics.ObserveRealtimeEvent(m.Type)
+	return nil
+}
+
+// serve runs the connection of c until it ends. lastEventID, if set, is the
+// last event the client received on a previous connection.
+func (h *Hub) serve(ctx context.Context, c *client, t transport, lastEventID string) {
+	defer h.unregister(c)
+	metrics.ObserveRealtimeConnection(c.transport, 1)
+	defer metrics.ObserveRealtimeConnection(c.transport, -1)
+	lg.DebugContext(ctx, "Realtime connection opened", "transport", c.transport, "sid", c.token.SID, "last_event_id", lastEventID)
+
+	reason := h.run(ctx, c, t, lastEventID)
+	t.close(reason)
+
+	metrics.ObserveRealtimeDisconnect(reason)
+	lg.DebugContext(ctx, "Realtime connection closed", "transport", c.transport, "sid", c.token.SID, "reason", reason)
+}
+
+func (h *Hub) run(ctx context.Context, c *client, t transport, lastEventID string) string {
+	w := &writer{t: t, last: lastEventID}
+	if lastEventID != "" {
+		if err := h.replay(ctx, c, w); err != nil {
+			return reasonWriteError
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	var expired <-chan time.Time
+	if !c.token.ExpiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(c.token.ExpiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case m := <-c.send:
+			if err := w.write(m); err != nil {
+				return reasonWriteError
+			}
+		case <-c.catchUp:
+			if err := h.replay(ctx, c, w); err != nil {
+				return reasonWriteError
+			}
+		case <-heartbeat.C:
+			if err := t.ping(); err != nil {
+				return reasonWriteError
+			}
+		case <-expired:
+			_ = w.write(reconnect(ReasonTokenExpired))
+			return ReasonTokenExpired
+		case <-c.done:
+			_ = w.write(c.final)
+			return c.reason
+		case <-t.closed():
+			return reasonClientClosed
+		}
+	}
+}
+
+// replay writes the events of the user's stream after the last one written,
+// oldest first. When some may be missing, because the stream was trimmed or
+// expired or holds more than the replay limit, EventResync comes first and
+// only the newest events follow. Only write errors are returned.
+func (h *Hub) replay(ctx context.Context, c *client, w *writer) error {
+	after := w.last
+	if _, _, ok := apirealtime.ParseID(after); !ok {
+		return w.write(message{Type: EventResync})
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	key := apirealtime.StreamKey(c.userID)
+	entries, err := h.redisUtil.StreamRevRange(ctx, key, "+", "("+after, h.replayLimit+1)
+	if err != nil {
+		lg.WarnContext(ctx, "Failed to read realtime stream", "error", err)
+		return w.write(message{Type: EventResync})
+	}
+	gap := int64(len(entries)) > h.replayLimit
+	if gap {
+		entries = entries[:h.replayLimit]
+	} else {
+		// The stream still holding the last event written, or an older
+		// one, means nothing after it was trimmed.
+		first, err := h.redisUtil.StreamFirst(ctx, key)
+		gap = err != nil || first == nil || apirealtime.After(first.ID, after)
+	}
+
+	if gap {
+		if err := w.write(message{Type: EventResync}); err != nil {
+			return err
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		typ, _ := entries[i].Values[apirealtime.FieldType].(string)
+		data, _ := entries[i].Values[apirealtime.FieldData].(string)
+		if typ == "" || !json.Valid([]byte(data)) {
+			continue
+		}
+		if err := w.write(message{ID: entries[i].ID, Type: typ, Data: json.RawMessage(data)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package realtime
+
+import (
+	"gateway/configs"
+	"gateway/internal/middleware"
+	"gateway/internal/utils"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Handler serves the realtime endpoints. They run after
+// AuthMiddleware.RequireStreamAuth, and a connection lasts until its access
+// token expires: the client then gets EventReconnect and reconnects with a
+// fresh token.
+type Handler struct {
+	hub      *Hub
+	upgrader websocket.Upgrader
+}
+
+func NewHandler(hub *Hub, securityCfg *configs.SecurityConfig) *Handler {
+	origins := securityCfg.CORS.AllowedOrigins
+	return &Handler{
+		hub: hub,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 4096,
+			// Browsers always send Origin; other clients do not have
+			// cookies to ride on, so only browser origins are checked.
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || slices.Contains(origins, origin)
+			},
+		},
+	}
+}
+
+// SSE streams events as Server-Sent Events.
+func (h *Handler) SSE(c *gin.Context) {
+	cl, ok := h.connect(c, transportSSE)
+	if !ok {
+		return
+	}
+	t := newSSETransport(c.Writer, c.Request)
+	if err := t.start(); err != nil {
+		h.hub.unregister(cl)
+		return
+	}
+	h.hub.serve(c.Request.Context(), cl, t, lastEventID(c))
+}
+
+// WebSocket upgrades the request and streams events as JSON text frames.
+func (h *Handler) WebSocket(c *gin.Context) {
+	cl, ok := h.connect(c, transportWebSocket)
+	if !ok {
+		return
+	}
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered with an HTTP error.
+		h.hub.unregister(cl)
+		lg.WarnContext(c.Request.Context(), "WebSocket upgrade failed", "error", err)
+		return
+	}
+	h.hub.serve(c.Request.Context(), cl, newWSTransport(conn, h.hub.heartbeat), lastEventID(c))
+}
+
+// connect registers a connection for the authenticated user.
+func (h *Handler) connect(c *gin.Context, transport string) (*client, bool) {
+	claims, ok := middleware.GetUserClaims(c)
+	if !ok {
+		utils.Fail(c, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		return nil, false
+	}
+	cl := newClient(claims, transport)
+	if err := h.hub.register(cl); err != nil {
+		utils.Fail(c, http.StatusTooManyRequests, "TOO_MANY_CONNECTIONS", "Too many open realtime connections")
+		return nil, false
+	}
+	return cl, true
+}
+
+// lastEventID is the last event the client received. EventSource sends it
+// as the Last-Event-ID header when it reconnects; WebSocket clients, and
+// EventSource on a fresh page, pass it as the lastEventId query parameter.
+func lastEventID(c *gin.Context) string {
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Query("lastEventId")
+}
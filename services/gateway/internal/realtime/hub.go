@@ -0,0 +1,259 @@
+// Package realtime pushes events to clients over WebSocket and Server-Sent
+// Events. User events come from notification-service through Redis (see
+// music-player/api/realtime), and session events from auth-service, so a
+// client whose session is revoked is logged out at once.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gateway/configs"
+	"music-player/api/logger"
+	"music-player/api/session"
+	"sync"
+	"time"
+
+	apirealtime "music-player/api/realtime"
+
+	redisutil "gateway/internal/utils/redis"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var lg = logger.For("realtime")
+
+// Events the gateway sends on its own. They have no ID and are not replayed.
+const (
+	// EventSessionRevoked tells the client its session was revoked: it must
+	// log out. The connection is closed after it.
+	EventSessionRevoked = "session.revoked"
+	// EventReconnect asks the client to reconnect, after refreshing its
+	// access token if the reason is token_expired or token_rotated. The
+	// connection is closed after it.
+	EventReconnect = "reconnect"
+	// EventResync tells the client that events may have been missed, so it
+	// should reload its state, such as the inbox and unread count.
+	EventResync = "resync"
+)
+
+// Reasons a connection is closed, sent with EventReconnect and counted in
+// gateway_realtime_disconnects_total.
+const (
+	ReasonSessionRevoked = "session_revoked"
+	ReasonSessionInvalid = "session_invalid"
+	ReasonTokenExpired   = "token_expired"
+	ReasonTokenRotated   = "token_rotated"
+	ReasonSlowConsumer   = "slow_consumer"
+	ReasonShutdown       = "shutdown"
+	reasonClientClosed   = "client_closed"
+	reasonWriteError     = "write_error"
+)
+
+const (
+	// redisTimeout bounds the Redis calls made for one connection.
+	redisTimeout     = 5 * time.Second
+	defaultHeartbeat = 25 * time.Second
+)
+
+var errTooManyConnections = errors.New("realtime: too many connections")
+
+// Hub tracks the connections open on this replica and routes events to
+// them.
+type Hub struct {
+	redisUtil   *redisutil.RedisUtil
+	maxPerUser  int
+	replayLimit int64
+	heartbeat   time.Duration
+
+	mu       sync.Mutex
+	users    map[string]map[*client]struct{}
+	sessions map[string]map[*client]struct{}
+}
+
+func NewHub(redisUtil *redisutil.RedisUtil, cfg *configs.RealtimeConfig) *Hub {
+	heartbeat := cfg.HeartbeatInterval
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+	return &Hub{
+		redisUtil:   redisUtil,
+		maxPerUser:  cfg.MaxConnectionsPerUser,
+		replayLimit: max(cfg.ReplayLimit, 1),
+		heartbeat:   heartbeat,
+		users:       make(map[string]map[*client]struct{}),
+		sessions:    make(map[string]map[*client]struct{}),
+	}
+}
+
+func (h *Hub) register(c *client) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.maxPerUser > 0 && len(h.users[c.userID]) >= h.maxPerUser {
+		return errTooManyConnections
+	}
+	add(h.users, c.userID, c)
+	add(h.sessions, c.token.SID, c)
+	return nil
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	remove(h.users, c.userID, c)
+	remove(h.sessions, c.token.SID, c)
+}
+
+// Listen delivers the events announced on Redis until ctx is cancelled, then
+// closes every connection.
+func (h *Hub) Listen(ctx context.Context) {
+	defer h.closeAll(reconnect(ReasonShutdown), ReasonShutdown)
+
+	pubsub := h.redisUtil.Subscribe(ctx, apirealtime.EventsChannel, session.EventsChannel)
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// go-redis reconnects on the next Receive; events published
+			// until then are recovered when the subscription is confirmed.
+			lg.WarnContext(ctx, "Realtime events subscription error", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			lg.InfoContext(ctx, "Subscribed to realtime events", "channel", m.Channel)
+			// Events missed while unsubscribed are in the streams, and
+			// session changes in the sessions themselves.
+			switch m.Channel {
+			case apirealtime.EventsChannel:
+				h.catchUpAll()
+			case session.EventsChannel:
+				go h.recheckSessions(ctx)
+			}
+		case *redis.Message:
+			switch m.Channel {
+			case apirealtime.EventsChannel:
+				h.deliver(ctx, m.Payload)
+			case session.EventsChannel:
+				h.sessionEvent(ctx, m.Payload)
+			}
+		}
+	}
+}
+
+// deliver queues a user event on each of the user's connections. A
+// connection too far behind is closed; the client replays what it missed
+// when it reconnects.
+func (h *Hub) deliver(ctx context.Context, payload string) {
+	var event apirealtime.Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil || event.UserID == "" || event.Type == "" {
+		lg.WarnContext(ctx, "Ignoring malformed realtime event", "error", err)
+		return
+	}
+	m := message{ID: event.ID, Type: event.Type, Data: event.Data}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.users[event.UserID] {
+		if !c.enqueue(m) {
+			c.close(reconnect(ReasonSlowConsumer), ReasonSlowConsumer)
+		}
+	}
+}
+
+// sessionEvent closes the connections opened with the tokens a session
+// event invalidates.
+func (h *Hub) sessionEvent(ctx context.Context, payload string) {
+	var event session.Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil || event.SID == "" {
+		lg.WarnContext(ctx, "Ignoring malformed session event", "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.sessions[event.SID] {
+		switch event.Reason {
+		case session.EventRevoked:
+			c.close(revoked(), ReasonSessionRevoked)
+		case session.EventRotated:
+			// Connections opened after the refresh use the new version.
+			if c.token.AV != event.AV {
+				c.close(reconnect(ReasonTokenRotated), ReasonTokenRotated)
+			}
+		}
+	}
+}
+
+// recheckSessions validates the session of every connection, for the
+// session events that may have been missed.
+func (h *Hub) recheckSessions(ctx context.Context) {
+	for _, c := range h.clients() {
+		checkCtx, cancel := context.WithTimeout(ctx, redisTimeout)
+		err := session.Validate(checkCtx, h.redisUtil, c.token, time.Now())
+		cancel()
+		switch {
+		case err == nil:
+		case errors.Is(err, session.ErrRevoked):
+			c.close(revoked(), ReasonSessionRevoked)
+		case errors.Is(err, session.ErrRotated):
+			c.close(reconnect(ReasonTokenRotated), ReasonTokenRotated)
+		case errors.Is(err, session.ErrExpired):
+			c.close(reconnect(ReasonTokenExpired), ReasonTokenExpired)
+		default:
+			// The session could not be read; the client finds out whether
+			// it still exists when it reconnects.
+			c.close(reconnect(ReasonSessionInvalid), ReasonSessionInvalid)
+		}
+	}
+}
+
+// catchUpAll asks every connection to replay its stream from the last
+// event it received.
+func (h *Hub) catchUpAll() {
+	for _, c := range h.clients() {
+		c.requestCatchUp()
+	}
+}
+
+func (h *Hub) closeAll(final message, reason string) {
+	for _, c := range h.clients() {
+		c.close(final, reason)
+	}
+}
+
+func (h *Hub) clients() []*client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var all []*client
+	for _, cs := range h.users {
+		for c := range cs {
+			all = append(all, c)
+		}
+	}
+	return all
+}
+
+func add(m map[string]map[*client]struct{}, key string, c *client) {
+	if m[key] == nil {
+		m[key] = make(map[*client]struct{})
+	}
+	m[key][c] = struct{}{}
+}
+
+func remove(m map[string]map[*client]struct{}, key string, c *client) {
+	delete(m[key], c)
+	if len(m[key]) == 0 {
+		delete(m, key)
+	}
+}
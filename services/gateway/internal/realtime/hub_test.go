@@ -0,0 +1,353 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gateway/configs"
+	"sync"
+	"testing"
+	"time"
+
+	apirealtime "music-player/api/realtime"
+
+	"gateway/internal/utils/jwt"
+	redisutil "gateway/internal/utils/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeTransport keeps what is written to it.
+type fakeTransport struct {
+	mu       sync.Mutex
+	messages []message
+	reason   string
+	gone     chan struct{}
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{gone: make(chan struct{})}
+}
+
+func (t *fakeTransport) write(m message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(t.messages, m)
+	return nil
+}
+
+func (t *fakeTransport) ping() error             { return nil }
+func (t *fakeTransport) closed() <-chan struct{} { return t.gone }
+
+func (t *fakeTransport) close(reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reason = reason
+}
+
+func (t *fakeTransport) written() []message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]message(nil), t.messages...)
+}
+
+// summary lists the messages as "type" or "type@id".
+func summary(messages []message) string {
+	s := ""
+	for i, m := range messages {
+		if i > 0 {
+			s += " "
+		}
+		s += m.Type
+		if m.ID != "" {
+			s += "@" + m.ID
+		}
+	}
+	return s
+}
+
+func newTestHub(t *testing.T, cfg *configs.RealtimeConfig) (*Hub, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewHub(redisutil.NewRedisUtil(rdb), cfg), rdb
+}
+
+func newTestClient(userID, sid string) *client {
+	return newClient(&jwt.AccessClaims{
+		SID: sid,
+		AV:  1,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}, transportSSE)
+}
+
+func eventPayload(t *testing.T, userID, id string) string {
+	t.Helper()
+	b, err := json.Marshal(apirealtime.Event{ID: id, UserID: userID, Type: apirealtime.EventNotificationCreated, Data: json.RawMessage(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// addEntries appends entries with the given IDs to userID's stream.
+func addEntries(t *testing.T, rdb *redis.Client, userID string, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		err := rdb.XAdd(context.Background(), &redis.XAddArgs{
+			Stream: apirealtime.StreamKey(userID),
+			ID:     id,
+			Values: map[string]any{apirealtime.FieldType: apirealtime.EventNotificationCreated, apirealtime.FieldData: `{}`},
+		}).Err()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHubDeliverFansOutToUser(t *testing.T) {
+	hub, _ := newTestHub(t, &configs.RealtimeConfig{})
+	phone, laptop, other := newTestClient("u1", "s1"), newTestClient("u1", "s2"), newTestClient("u2", "s3")
+	for _, c := range []*client{phone, laptop, other} {
+		if err := hub.register(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hub.deliver(context.Background(), eventPayload(t, "u1", "1-0"))
+
+	for name, c := range map[string]*client{"phone": phone, "laptop": laptop} {
+		select {
+		case m := <-c.send:
+			if m.ID != "1-0" || m.Type != apirealtime.EventNotificationCreated {
+				t.Errorf("%s got %+v", name, m)
+			}
+		default:
+			t.Errorf("%s got nothing", name)
+		}
+	}
+	if len(other.send) != 0 {
+		t.Error("another user's connection got the event")
+	}
+
+	// Malformed events are dropped.
+	hub.deliver(context.Background(), `{"type":"x"}`)
+	hub.deliver(context.Background(), `not json`)
+	if len(phone.send) != 0 {
+		t.Error("a malformed event was delivered")
+	}
+}
+
+func TestHubClosesSlowConsumer(t *testing.T) {
+	hub, _ := newTestHub(t, &configs.RealtimeConfig{})
+	slow, fast := newTestClient("u1", "s1"), newTestClient("u1", "s2")
+	hub.register(slow)
+	hub.register(fast)
+
+	for i := range sendBuffer {
+		hub.deliver(context.Background(), eventPayload(t, "u1", fmt.Sprintf("1-%d", i)))
+		<-fast.send
+	}
+	select {
+	case <-slow.done:
+		t.Fatal("closed with room left in its buffer")
+	default:
+	}
+
+	hub.deliver(context.Background(), eventPayload(t, "u1", "2-0"))
+	select {
+	case <-slow.done:
+	default:
+		t.Fatal("a full buffer did not close the connection")
+	}
+	if slow.reason != ReasonSlowConsumer || slow.final.Type != EventReconnect {
+		t.Errorf("closed with %s after %s; want %s after %s", slow.reason, slow.final.Type, ReasonSlowConsumer, EventReconnect)
+	}
+	select {
+	case <-fast.done:
+		t.Error("the connection keeping up was closed too")
+	default:
+	}
+}
+
+func TestHubMaxConnectionsPerUser(t *testing.T) {
+	hub, _ := newTestHub(t, &configs.RealtimeConfig{MaxConnectionsPerUser: 1})
+	first := newTestClient("u1", "s1")
+	if err := hub.register(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := hub.register(newTestClient("u1", "s2")); err != errTooManyConnections {
+		t.Errorf("register over the limit = %v; want errTooManyConnections", err)
+	}
+	if err := hub.register(newTestClient("u2", "s3")); err != nil {
+		t.Errorf("register of another user = %v", err)
+	}
+	hub.unregister(first)
+	if err := hub.register(newTestClient("u1", "s2")); err != nil {
+		t.Errorf("register after unregister = %v", err)
+	}
+}
+
+func TestHubSessionEvent(t *testing.T) {
+	hub, _ := newTestHub(t, &configs.RealtimeConfig{})
+	revokedClient, rotatedOld, rotatedNew, other := newTestClient("u1", "s1"), newTestClient("u1", "s2"), newTestClient("u1", "s2"), newTestClient("u1", "s3")
+	rotatedNew.token.AV = 2
+	for _, c := range []*client{revokedClient, rotatedOld, rotatedNew, other} {
+		hub.register(c)
+	}
+
+	hub.sessionEvent(context.Background(), `{"sid":"s1","reason":"revoked"}`)
+	hub.sessionEvent(context.Background(), `{"sid":"s2","reason":"rotated","av":2}`)
+
+	tests := []struct {
+		name   string
+		c      *client
+		reason string
+	}{
+		{"revoked", revokedClient, ReasonSessionRevoked},
+		{"rotated before the refresh", rotatedOld, ReasonTokenRotated},
+		{"opened after the refresh", rotatedNew, ""},
+		{"other session", other, ""},
+	}
+	for _, tt := range tests {
+		if tt.c.reason != tt.reason {
+			t.Errorf("%s: closed for %q; want %q", tt.name, tt.c.reason, tt.reason)
+		}
+	}
+	if revokedClient.final.Type != EventSessionRevoked {
+		t.Errorf("revoked connection gets %s; want %s", revokedClient.final.Type, EventSessionRevoked)
+	}
+}
+
+func TestHubReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		last    string
+		limit   int64
+		want    string
+	}{
+		{
+			name:    "events after the last one",
+			entries: []string{"1-0", "2-0", "3-0"},
+			last:    "1-0",
+			limit:   10,
+			want:    "notification.created@2-0 notification.created@3-0",
+		},
+		{
+			name:    "nothing missed",
+			entries: []string{"1-0", "2-0"},
+			last:    "2-0",
+			limit:   10,
+			want:    "",
+		},
+		{
+			name:    "stream trimmed past the last event",
+			entries: []string{"2-0", "3-0"},
+			last:    "1-5",
+			limit:   10,
+			want:    "resync notification.created@2-0 notification.created@3-0",
+		},
+		{
+			name:    "more than the replay limit",
+			entries: []string{"1-0", "2-0", "3-0", "4-0", "5-0"},
+			last:    "1-0",
+			limit:   2,
+			want:    "resync notification.created@4-0 notification.created@5-0",
+		},
+		{
+			name:  "stream expired",
+			last:  "1-0",
+			limit: 10,
+			want:  "resync",
+		},
+		{
+			name:    "invalid last event ID",
+			entries: []string{"1-0"},
+			last:    "bogus",
+			limit:   10,
+			want:    "resync",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, rdb := newTestHub(t, &configs.RealtimeConfig{ReplayLimit: tt.limit})
+			addEntries(t, rdb, "u1", tt.entries...)
+			tr := newFakeTransport()
+
+			if err := hub.replay(context.Background(), newTestClient("u1", "s1"), &writer{t: tr, last: tt.last}); err != nil {
+				t.Fatal(err)
+			}
+			if got := summary(tr.written()); got != tt.want {
+				t.Errorf("replay wrote %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterSkipsEventsAlreadyWritten(t *testing.T) {
+	tr := newFakeTransport()
+	w := &writer{t: tr, last: "2-0"}
+	for _, m := range []message{
+		{ID: "1-0", Type: "a"},
+		{ID: "2-0", Type: "b"},
+		{ID: "3-0", Type: "c"},
+		{Type: EventResync},
+		{ID: "3-0", Type: "c"},
+	} {
+		if err := w.write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := summary(tr.written()), "c@3-0 resync"; got != want {
+		t.Errorf("wrote %q; want %q", got, want)
+	}
+}
+
+func TestServeReplaysThenStreams(t *testing.T) {
+	hub, rdb := newTestHub(t, &configs.RealtimeConfig{ReplayLimit: 10})
+	addEntries(t, rdb, "u1", "1-0", "2-0")
+	c := newTestClient("u1", "s1")
+	if err := hub.register(c); err != nil {
+		t.Fatal(err)
+	}
+	tr := newFakeTransport()
+
+	done := make(chan struct{})
+	go func() {
+		hub.serve(context.Background(), c, tr, "1-0")
+		close(done)
+	}()
+
+	// A live event that was also replayed is written once.
+	hub.deliver(context.Background(), eventPayload(t, "u1", "2-0"))
+	hub.deliver(context.Background(), eventPayload(t, "u1", "3-0"))
+	deadline := time.After(5 * time.Second)
+	for len(tr.written()) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("wrote %q", summary(tr.written()))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	c.close(reconnect(ReasonShutdown), ReasonShutdown)
+	<-done
+
+	if got, want := summary(tr.written()), "notification.created@2-0 notification.created@3-0 reconnect"; got != want {
+		t.Errorf("wrote %q; want %q", got, want)
+	}
+	if tr.reason != ReasonShutdown {
+		t.Errorf("transport closed for %q; want %q", tr.reason, ReasonShutdown)
+	}
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if len(hub.users) != 0 || len(hub.sessions) != 0 {
+		t.Error("the connection is still registered after serve returned")
+	}
+}
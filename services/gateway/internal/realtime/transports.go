@@ -0,0 +1,146 @@
+package realtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	transportSSE       = "sse"
+	transportWebSocket = "websocket"
+
+	// writeTimeout bounds each write, so a stalled client is dropped
+	// instead of blocking its connection for good.
+	writeTimeout = 10 * time.Second
+	// sseRetry is the reconnection delay suggested to EventSource clients.
+	sseRetry = 3 * time.Second
+	// wsReadLimit caps the messages clients send, which are ignored.
+	wsReadLimit = 4096
+)
+
+// sseTransport writes text/event-stream. User events carry an id: line, so
+// an EventSource that reconnects sends the last one as Last-Event-ID.
+type sseTransport struct {
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+	done <-chan struct{}
+}
+
+func newSSETransport(w http.ResponseWriter, r *http.Request) *sseTransport {
+	return &sseTransport{w: w, rc: http.NewResponseController(w), done: r.Context().Done()}
+}
+
+// start sends the response headers and the retry delay.
+func (t *sseTransport) start() error {
+	h := t.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// Keep nginx-style proxies from buffering the stream.
+	h.Set("X-Accel-Buffering", "no")
+	t.w.WriteHeader(http.StatusOK)
+	return t.send("retry: " + strconv.FormatInt(sseRetry.Milliseconds(), 10) + "\n\n")
+}
+
+func (t *sseTransport) write(m message) error {
+	var b strings.Builder
+	if m.ID != "" {
+		b.WriteString("id: " + m.ID + "\n")
+	}
+	b.WriteString("event: " + m.Type + "\n")
+	data := string(m.Data)
+	if data == "" {
+		data = "{}"
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return t.send(b.String())
+}
+
+// ping writes a comment line, which EventSource ignores.
+func (t *sseTransport) ping() error {
+	return t.send(": ping\n\n")
+}
+
+func (t *sseTransport) send(s string) error {
+	// Not every ResponseWriter supports deadlines; writes then block until
+	// the server notices the client is gone.
+	_ = t.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := t.w.Write([]byte(s)); err != nil {
+		return err
+	}
+	return t.rc.Flush()
+}
+
+func (t *sseTransport) closed() <-chan struct{} {
+	return t.done
+}
+
+// close does nothing: the response ends when the handler returns.
+func (t *sseTransport) close(string) {}
+
+// wsTransport writes each message as a JSON text frame. Frames clients send
+// are read only to process pings, pongs and close.
+type wsTransport struct {
+	conn *websocket.Conn
+	done chan struct{}
+}
+
+func newWSTransport(conn *websocket.Conn, heartbeat time.Duration) *wsTransport {
+	t := &wsTransport{conn: conn, done: make(chan struct{})}
+	// A client that misses two heartbeats is gone.
+	timeout := 2 * heartbeat
+	conn.SetReadLimit(wsReadLimit)
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+	go t.read()
+	return t
+}
+
+func (t *wsTransport) read() {
+	defer close(t.done)
+	for {
+		if _, _, err := t.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+func (t *wsTransport) write(m message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_ = t.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return t.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+func (t *wsTransport) ping() error {
+	return t.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+}
+
+func (t *wsTransport) closed() <-chan struct{} {
+	return t.done
+}
+
+// close sends a close frame with the reason, unless the client is already
+// gone, and releases the connection.
+func (t *wsTransport) close(reason string) {
+	if reason != reasonClientClosed && reason != reasonWriteError {
+		code := websocket.CloseNormalClosure
+		if reason == ReasonShutdown {
+			code = websocket.CloseGoingAway
+		}
+		_ = t.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	}
+	_ = t.conn.Close()
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"gateway/internal/handlers"
+	"music-player/api/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RegisterHealthRoutes registers liveness and readiness probes at the root of the router.
+func RegisterHealthRoutes(r *gin.Engine, healthHandler *handlers.HealthHandler) {
+	r.GET("/livez", healthHandler.Livez)
+	r.GET("/readyz", healthHandler.Readyz)
+}
+
+// MetricsHandler serves Prometheus metrics at /metrics. It is mounted on the
+// internal listener (METRICS_ADDR), never on the public router.
+func MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// RegisterLogLevelRoutes exposes runtime log level control at /admin/log-levels
+// when an admin token is configured.
+func RegisterLogLevelRoutes(r *gin.Engine, logLevelHandler *logger.LevelHandler) {
+	if !logLevelHandler.Enabled() {
+		return
+	}
+	admin := r.Group("/admin", logLevelHandler.Authorize)
+	admin.GET("/log-levels", logLevelHandler.List)
+	admin.PUT("/log-levels", logLevelHandler.Set)
+}
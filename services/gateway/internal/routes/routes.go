@@ -1,8 +1,11 @@
 package routes
 
 import (
+	"gateway/configs"
 	"gateway/internal/handlers"
 	"gateway/internal/middleware"
+	"gateway/internal/proxy"
+	"gateway/internal/realtime"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,42 +17,125 @@ func SetupAuthRoutes(
 	twoFAHandler handlers.TwoFAHandler,
 	userHandler handlers.UserHandler,
 	authMiddleware *middleware.AuthMiddleware,
+	cookies *middleware.Cookies,
+	limiter *middleware.RateLimiter,
 ) {
+	// The general limit is keyed by client IP on public routes and by user
+	// on protected ones, where it runs after RequireAuth has verified the
+	// token.
 	api := router.Group("/api/v1")
 
-	api.GET("/health", func(c *gin.Context) {
+	api.GET("/health", limiter.Limit(configs.RateLimitGeneral, middleware.KeyByIP), func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "service": "gateway"})
 	})
 
 	auth := api.Group("/auth")
 	{
-		auth.POST("/login", authHandler.Login)
-		auth.POST("/register", authHandler.Register)
-		auth.POST("/refresh", authHandler.RefreshToken)
+		public := auth.Group("")
+		public.Use(limiter.Limit(configs.RateLimitGeneral, middleware.KeyByIP))
+		public.POST("/login", limiter.Limit(configs.RateLimitLogin, middleware.KeyByIP), authHandler.Login)
+		public.POST("/register", limiter.Limit(configs.RateLimitRegister, middleware.KeyByIP), authHandler.Register)
+		// Authenticated by the refresh token cookie, so CSRF protected
+		public.POST("/refresh", limiter.Limit(configs.RateLimitRefresh, middleware.KeyByIP), cookies.RequireCSRF(), authHandler.RefreshToken)
 
 		// Protected auth routes
 		authProtected := auth.Group("")
-		authProtected.Use(authMiddleware.RequireAuth())
+		authProtected.Use(authMiddleware.RequireAuth(), limiter.Limit(configs.RateLimitGeneral, middleware.KeyByUser))
 		{
-			authProtected.POST("/logout", authHandler.Logout)
+			authProtected.POST("/logout", cookies.RequireCSRF(), authHandler.Logout)
 			authProtected.GET("/validate", authHandler.ValidateToken)
 		}
 	}
 
 	// Two-Factor Authentication routes (all protected)
 	twoFA := api.Group("/2fa")
-	twoFA.Use(authMiddleware.RequireAuth())
+	twoFA.Use(authMiddleware.RequireAuth(), limiter.Limit(configs.RateLimitGeneral, middleware.KeyByUser))
 	{
 		twoFA.POST("/setup", twoFAHandler.Setup2FA)
 		twoFA.POST("/enable", twoFAHandler.Enable2FA)
-		twoFA.POST("/verify", twoFAHandler.Verify2FA)
+		twoFA.POST("/verify", limiter.Limit(configs.RateLimitTwoFA, middleware.KeyByUser), twoFAHandler.Verify2FA)
 		twoFA.POST("/disable", twoFAHandler.Disable2FA)
+		// Each text costs money: sending codes has its own, stricter limit.
+		twoFA.POST("/sms/setup", limiter.Limit(configs.RateLimitTwoFASMS, middleware.KeyByUser), twoFAHandler.SetupSMS2FA)
+		twoFA.POST("/sms/enable", limiter.Limit(configs.RateLimitTwoFA, middleware.KeyByUser), twoFAHandler.EnableSMS2FA)
+		twoFA.POST("/sms/send", limiter.Limit(configs.RateLimitTwoFASMS, middleware.KeyByUser), twoFAHandler.SendSMS2FACode)
 	}
 
 	// User management routes (all protected)
 	users := api.Group("/users")
-	users.Use(authMiddleware.RequireAuth())
+	users.Use(authMiddleware.RequireAuth(), limiter.Limit(configs.RateLimitGeneral, middleware.KeyByUser))
 	{
 		users.GET("", userHandler.GetUserProfile)
+		users.PATCH("", userHandler.UpdateUserProfile)
+	}
+}
+
+// SetupNotificationRoutes forwards /api/v1/notifications to
+// notification-service: the inbox and preferences of the signed-in user, and
+// the unsubscribe links of emails.
+func SetupNotificationRoutes(
+	router *gin.Engine,
+	notificationService *proxy.Backend,
+	authMiddleware *middleware.AuthMiddleware,
+	limiter *middleware.RateLimiter,
+) {
+	notifications := router.Group("/api/v1/notifications")
+	requireUser := []gin.HandlerFunc{authMiddleware.RequireAuth(), limiter.Limit(configs.RateLimitGeneral, middleware.KeyByUser)}
+
+	// Unsubscribe links are opened from emails, without a session: the
+	// signed token in the query string is checked by notification-service.
+	unsubscribe := notifications.Group("/unsubscribe")
+	unsubscribe.Use(limiter.Limit(configs.RateLimitGeneral, middleware.KeyByIP))
+	unsubscribe.GET("", notificationService.Forward("/api/v1/unsubscribe"))
+	unsubscribe.POST("", notificationService.Forward("/api/v1/unsubscribe"))
+
+	preferences := notifications.Group("/preferences")
+	preferences.Use(requireUser...)
+	{
+		preferences.GET("", notificationService.Forward("/api/v1/preferences"))
+		preferences.PATCH("", notificationService.Forward("/api/v1/preferences"))
+	}
+
+	inbox := notifications.Group("")
+	inbox.Use(requireUser...)
+	{
+		inbox.GET("", notificationService.Forward("/api/v1/notifications"))
+		inbox.GET("/unread-count", notificationService.Forward("/api/v1/notifications/unread-count"))
+		inbox.POST("/read-all", notificationService.Forward("/api/v1/notifications/read-all"))
+		inbox.POST("/:id/read", notificationService.Forward("/api/v1/notifications/:id/read"))
+		inbox.POST("/:id/archive", notificationService.Forward("/api/v1/notifications/:id/archive"))
+	}
+
+	push := notifications.Group("/push")
+	push.Use(requireUser...)
+	{
+		push.GET("/vapid-public-key", notificationService.Forward("/api/v1/push/vapid-public-key"))
+		push.GET("/subscriptions", notificationService.Forward("/api/v1/push/subscriptions"))
+		push.POST("/subscriptions", notificationService.Forward("/api/v1/push/subscriptions"))
+		push.DELETE("/subscriptions", notificationService.Forward("/api/v1/push/subscriptions"))
+	}
+}
+
+// SetupRealtimeRoutes sets up the WebSocket and Server-Sent Events streams.
+// Browsers cannot set headers on either, so they first exchange their access
+// token for a one-time ticket and pass that in the query string.
+func SetupRealtimeRoutes(
+	router *gin.Engine,
+	realtimeHandler *realtime.Handler,
+	authMiddleware *middleware.AuthMiddleware,
+	limiter *middleware.RateLimiter,
+) {
+	router.POST("/api/v1/realtime/ticket",
+		authMiddleware.RequireAuth(),
+		limiter.Limit(configs.RateLimitGeneral, middleware.KeyByUser),
+		authMiddleware.IssueStreamTicket,
+	)
+
+	stream := router.Group("/api/v1/realtime")
+	stream.Use(authMiddleware.RequireStreamAuth())
+	stream.Use(limiter.Limit(configs.RateLimitGeneral, middleware.KeyByUser))
+	{
+		stream.GET("/sse", realtimeHandler.SSE)
+		stream.GET("/ws", realtimeHandler.WebSocket)
 	}
 }
@@ -1,28 +1,175 @@
 package utils
 
 import (
+	"music-player/api/clientip"
+	"net"
+	"net/http"
+	"net/netip"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// GetClientIP returns the client address resolved by the ClientIP
+// middleware, or the direct peer address on routes it does not cover.
 func GetClientIP(c *gin.Context) string {
-	if xff := c.GetHeader("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		return strings.TrimSpace(ips[0])
+	if ip := clientip.FromContext(c.Request.Context()); ip != "" {
+		return ip
 	}
+	if addr, ok := peerAddr(c.Request.RemoteAddr); ok {
+		return addr.String()
+	}
+	return c.Request.RemoteAddr
+}
+
+// ClientIPResolver derives the client address from the direct peer and, when
+// the peer is a trusted proxy, from the forwarding header the proxies write.
+// The header is walked right to left, the order in which proxies append to
+// it, and the first hop that is not a trusted proxy is the client. Anything
+// left of it was supplied by that client and is ignored.
+type ClientIPResolver struct {
+	trusted   []netip.Prefix
+	forwarded bool
+}
+
+// NewClientIPResolver reads the RFC 7239 Forwarded header when forwarded is
+// set and X-Forwarded-For otherwise.
+func NewClientIPResolver(trusted []netip.Prefix, forwarded bool) *ClientIPResolver {
+	return &ClientIPResolver{trusted: trusted, forwarded: forwarded}
+}
+
+// Resolve returns the client address of r.
+func (p *ClientIPResolver) Resolve(r *http.Request) string {
+	client, ok := peerAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	var hops []string
+	if p.isTrusted(client) {
+		if p.forwarded {
+			hops = forwardedFor(r.Header.Values("Forwarded"))
+		} else {
+			hops = splitList(r.Header.Values("X-Forwarded-For"))
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0 && p.isTrusted(client); i-- {
+		hop, ok := parseNode(hops[i])
+		if !ok {
+			// "unknown", an obfuscated identifier or garbage: the chain
+			// cannot be followed further, keep the last proxy we trust.
+			break
+		}
+		client = hop
+	}
+	return client.String()
+}
+
+func (p *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range p.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
 
-	if xri := c.GetHeader("X-Real-IP"); xri != "" {
-		return xri
+func peerAddr(remoteAddr string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
 	}
+	return parseAddr(host)
+}
+
+// parseNode parses a forwarding hop: an address with an optional port, IPv6
+// optionally in brackets.
+func parseNode(node string) (netip.Addr, bool) {
+	node = strings.TrimSpace(node)
+	if addr, ok := parseAddr(node); ok {
+		return addr, true
+	}
+	if ap, err := netip.ParseAddrPort(node); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	if strings.HasPrefix(node, "[") && strings.HasSuffix(node, "]") {
+		return parseAddr(node[1 : len(node)-1])
+	}
+	return netip.Addr{}, false
+}
+
+func parseAddr(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func splitList(values []string) []string {
+	var items []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
 
-	if cfip := c.GetHeader("CF-Connecting-IP"); cfip != "" {
-		return cfip
+// forwardedFor returns the for= node of each element of RFC 7239 Forwarded
+// headers, in order. An element without for= yields "" so it still counts
+// as a hop.
+func forwardedFor(values []string) []string {
+	var nodes []string
+	for _, v := range values {
+		for _, element := range splitQuoted(v, ',') {
+			node := ""
+			for _, pair := range splitQuoted(element, ';') {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(strings.TrimSpace(name), "for") {
+					node = unquote(strings.TrimSpace(value))
+					break
+				}
+			}
+			nodes = append(nodes, node)
+		}
 	}
+	return nodes
+}
 
-	if xf := c.GetHeader("X-Forwarded"); xf != "" {
-		return strings.TrimSpace(strings.Split(xf, ",")[0])
+// splitQuoted splits s on sep outside of quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
 	}
+	return append(parts, s[start:])
+}
 
-	return c.ClientIP()
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
 }
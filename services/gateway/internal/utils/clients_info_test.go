@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestSplitQuoted(t *testing.T) {
+	tests := []struct {
+		in   string
+		sep  byte
+		want []string
+	}{
+		{"a,b", ',', []string{"a", "b"}},
+		{"a", ',', []string{"a"}},
+		{"", ',', []string{""}},
+		{`for="a,b",for=c`, ',', []string{`for="a,b"`, "for=c"}},
+		{`for="a\",b";proto=https`, ';', []string{`for="a\",b"`, "proto=https"}},
+		{"a;;b", ';', []string{"a", "", "b"}},
+	}
+	for _, tt := range tests {
+		if got := splitQuoted(tt.in, tt.sep); !slices.Equal(got, tt.want) {
+			t.Errorf("splitQuoted(%q, %q) = %q; want %q", tt.in, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`192.0.2.1`, `192.0.2.1`},
+		{`"[2001:db8::1]:443"`, `[2001:db8::1]:443`},
+		{`"a\"b"`, `a"b`},
+		{`"a\\b"`, `a\b`},
+		{`""`, ``},
+		{`"`, `"`},
+		{`"open`, `"open`},
+	}
+	for _, tt := range tests {
+		if got := unquote(tt.in); got != tt.want {
+			t.Errorf("unquote(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseNode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"192.0.2.1", "192.0.2.1"},
+		{" 192.0.2.1 ", "192.0.2.1"},
+		{"192.0.2.1:8080", "192.0.2.1"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"[2001:db8::1]", "2001:db8::1"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"::ffff:192.0.2.1", "192.0.2.1"},
+		{"fe80::1%eth0", ""},
+		{"unknown", ""},
+		{"_hidden", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		addr, ok := parseNode(tt.in)
+		got := ""
+		if ok {
+			got = addr.String()
+		}
+		if got != tt.want {
+			t.Errorf("parseNode(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClientIPResolver(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name      string
+		forwarded bool
+		peer      string
+		header    string
+		want      string
+	}{
+		{"untrusted peer ignores header", false, "198.51.100.7:1000", "192.0.2.1", "198.51.100.7"},
+		{"xff single hop", false, "10.0.0.1:1000", "192.0.2.1", "192.0.2.1"},
+		{"xff spoofed left entry ignored", false, "10.0.0.1:1000", "203.0.113.9, 192.0.2.1", "192.0.2.1"},
+		{"xff through trusted proxies", false, "10.0.0.1:1000", "192.0.2.1, 10.0.0.2", "192.0.2.1"},
+		{"xff unknown hop stops walk", false, "10.0.0.1:1000", "192.0.2.1, unknown", "10.0.0.1"},
+		{"xff empty", false, "10.0.0.1:1000", "", "10.0.0.1"},
+		{"forwarded", true, "10.0.0.1:1000", `for=192.0.2.1;proto=https`, "192.0.2.1"},
+		{"forwarded quoted ipv6", true, "10.0.0.1:1000", `for="[2001:db8::1]:443", for=10.0.0.2`, "2001:db8::1"},
+		{"forwarded element without for", true, "10.0.0.1:1000", `for=192.0.2.1, proto=https`, "10.0.0.1"},
+		{"forwarded obfuscated", true, "10.0.0.1:1000", `for=_hidden`, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.peer
+			if tt.header != "" {
+				if tt.forwarded {
+					r.Header.Set("Forwarded", tt.header)
+				} else {
+					r.Header.Set("X-Forwarded-For", tt.header)
+				}
+			}
+			got := NewClientIPResolver(trusted, tt.forwarded).Resolve(r)
+			if got != tt.want {
+				t.Errorf("Resolve = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
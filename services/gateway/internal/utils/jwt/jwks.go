@@ -1,11 +1,42 @@
 package jwt
 
 import (
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
+	"music-player/api/logger"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var lg = logger.For("jwks")
+
+const (
+	// defaultJWKSTTL applies when the response has no usable max-age.
+	defaultJWKSTTL = 1 * time.Hour
+	// minJWKSTTL and maxJWKSTTL clamp the advertised max-age.
+	minJWKSTTL = 30 * time.Second
+	maxJWKSTTL = 24 * time.Hour
+	// defaultJWKSStaleIfError is how long the last good set is served while
+	// auth-service is unreachable, unless stale-if-error says otherwise.
+	defaultJWKSStaleIfError = 24 * time.Hour
+	// kidMissRefetchInterval rate limits refetches triggered by unknown KIDs
+	// so tokens with made-up KIDs cannot hammer auth-service.
+	kidMissRefetchInterval = 10 * time.Second
+	// staleRefetchInterval spaces the background refetches started while a
+	// stale set is being served, so an outage does not turn every request
+	// into a fetch.
+	staleRefetchInterval = 5 * time.Second
+	// refreshRetryMin and refreshRetryMax bound the background retry backoff.
+	refreshRetryMin = 1 * time.Second
+	refreshRetryMax = 1 * time.Minute
 )
 
 // JWKS represents JSON Web Key Set structure
@@ -22,18 +53,31 @@ type JWK struct {
 	X   string `json:"x"`   // X coordinate (base64url encoded)
 }
 
-// JWKSClient handles fetching and caching JWKS from auth service
+// JWKSClient handles fetching and caching JWKS from auth service. Run keeps
+// the set fresh in the background according to Cache-Control; lookups only
+// fetch inline when the cache is empty, or (rate limited) when a token
+// carries an unknown KID. An expired set within its stale-if-error window is
+// served at once while a refetch runs in the background, so requests never
+// wait on an unreachable auth-service. Concurrent fetches are deduplicated.
 type JWKSClient struct {
 	authServiceURL string
 	httpClient     *http.Client
 	cache          *jwksCache
+	group          singleflight.Group
+	refreshing     atomic.Bool
+	now            func() time.Time
 }
 
 type jwksCache struct {
-	data      *JWKS
-	lastFetch time.Time
-	ttl       time.Duration
-	mutex     sync.RWMutex
+	mutex          sync.RWMutex
+	data           *JWKS
+	keys           map[string]ed25519.PublicKey
+	etag           string
+	lastFetch      time.Time
+	expiresAt      time.Time
+	staleIfError   time.Duration
+	lastMissFetch  time.Time
+	lastStaleFetch time.Time
 }
 
 // NewJWKSClient creates a new JWKS client
@@ -43,68 +87,109 @@ func NewJWKSClient(authServiceURL string) *JWKSClient {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		cache: &jwksCache{
-			ttl: 1 * time.Hour, // Cache for 1 hour
-		},
+		cache: &jwksCache{},
+		now:   time.Now,
 	}
 }
 
-// GetJWKS fetches JWKS from auth service with caching
+// Run refreshes the key set shortly before it expires until ctx is
+// cancelled. Failed refreshes are retried with exponential backoff while the
+// previous set stays in use.
+func (c *JWKSClient) Run(ctx context.Context) {
+	retry := refreshRetryMin
+	for {
+		var wait time.Duration
+		if err := c.refresh(ctx); err != nil {
+			lg.WarnContext(ctx, "JWKS refresh failed", "error", err, "retry_in", retry)
+			wait = retry
+			retry = min(retry*2, refreshRetryMax)
+		} else {
+			retry = refreshRetryMin
+			wait = c.refreshIn()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// refreshIn returns the delay until the next proactive refresh, at 80% of
+// the remaining lifetime so the set never expires under normal operation.
+func (c *JWKSClient) refreshIn() time.Duration {
+	c.cache.mutex.RLock()
+	remaining := c.cache.expiresAt.Sub(c.now())
+	c.cache.mutex.RUnlock()
+	return max(remaining*4/5, minJWKSTTL)
+}
+
+// GetJWKS returns the cached key set. A missing set is fetched inline; an
+// expired one is served as-is while it is within its stale-if-error window
+// and refetched in the background.
 func (c *JWKSClient) GetJWKS() (*JWKS, error) {
-	// Check cache first
 	c.cache.mutex.RLock()
-	if c.cache.data != nil && time.Since(c.cache.lastFetch) < c.cache.ttl {
-		cached := c.cache.data
-		c.cache.mutex.RUnlock()
-		return cached, nil
-	}
+	data, fresh := c.cache.data, c.now().Before(c.cache.expiresAt)
 	c.cache.mutex.RUnlock()
+	if data != nil && fresh {
+		return data, nil
+	}
 
-	// Cache miss or expired, fetch from auth service
-	jwks, err := c.fetchJWKS()
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrJWKSFetchFailed, err)
+	if stale := c.staleData(); stale != nil {
+		c.refreshInBackground()
+		return stale, nil
 	}
 
-	// Update cache
-	c.cache.mutex.Lock()
-	c.cache.data = jwks
-	c.cache.lastFetch = time.Now()
-	c.cache.mutex.Unlock()
+	if err := c.refresh(context.Background()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWKSFetchFailed, err)
+	}
 
-	return jwks, nil
+	c.cache.mutex.RLock()
+	defer c.cache.mutex.RUnlock()
+	return c.cache.data, nil
 }
 
-// fetchJWKS performs the actual HTTP request to get JWKS
-func (c *JWKSClient) fetchJWKS() (*JWKS, error) {
-	url := fmt.Sprintf("%s/.well-known/jwks.json", c.authServiceURL)
-
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+// GetKey returns the parsed public key for kid. An unknown KID triggers an
+// immediate refetch, at most once per kidMissRefetchInterval, so keys
+// published by a rotation are picked up without waiting for expiry.
+func (c *JWKSClient) GetKey(kid string) (ed25519.PublicKey, error) {
+	if _, err := c.GetJWKS(); err != nil {
+		return nil, err
+	}
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	c.cache.mutex.Lock()
+	allowed := c.now().Sub(c.cache.lastMissFetch) >= kidMissRefetchInterval
+	if allowed {
+		c.cache.lastMissFetch = c.now()
 	}
+	c.cache.mutex.Unlock()
 
-	var jwks JWKS
-	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
-		return nil, fmt.Errorf("failed to decode JWKS response: %w", err)
+	if allowed {
+		if err := c.refresh(context.Background()); err != nil {
+			lg.Warn("JWKS refetch for unknown kid failed", "kid", kid, "error", err)
+		} else if key, ok := c.lookup(kid); ok {
+			return key, nil
+		}
 	}
 
-	return &jwks, nil
+	return nil, fmt.Errorf("%w: kid=%s", ErrKeyNotFound, kid)
 }
 
 // GetKeyByKID finds a specific key by its Key ID
 func (c *JWKSClient) GetKeyByKID(kid string) (*JWK, error) {
-	jwks, err := c.GetJWKS()
-	if err != nil {
+	if _, err := c.GetKey(kid); err != nil {
 		return nil, err
 	}
 
-	for _, key := range jwks.Keys {
+	c.cache.mutex.RLock()
+	defer c.cache.mutex.RUnlock()
+	for _, key := range c.cache.data.Keys {
 		if key.Kid == kid {
 			return &key, nil
 		}
@@ -116,6 +201,171 @@ func (c *JWKSClient) GetKeyByKID(kid string) (*JWK, error) {
 // InvalidateCache forces a fresh fetch on next request
 func (c *JWKSClient) InvalidateCache() {
 	c.cache.mutex.Lock()
-	c.cache.data = nil
+	c.cache.expiresAt = time.Time{}
+	c.cache.etag = ""
+	c.cache.mutex.Unlock()
+}
+
+func (c *JWKSClient) lookup(kid string) (ed25519.PublicKey, bool) {
+	c.cache.mutex.RLock()
+	defer c.cache.mutex.RUnlock()
+	key, ok := c.cache.keys[kid]
+	return key, ok
+}
+
+// staleData returns the last good set while it is within its stale-if-error
+// window, or nil.
+func (c *JWKSClient) staleData() *JWKS {
+	c.cache.mutex.RLock()
+	defer c.cache.mutex.RUnlock()
+	if c.cache.data == nil || c.now().Sub(c.cache.lastFetch) > c.cache.staleIfError {
+		return nil
+	}
+	return c.cache.data
+}
+
+// refreshInBackground starts a refetch without waiting for it, unless one is
+// already running or the last one started less than staleRefetchInterval ago.
+func (c *JWKSClient) refreshInBackground() {
+	c.cache.mutex.Lock()
+	due := c.now().Sub(c.cache.lastStaleFetch) >= staleRefetchInterval
+	if due {
+		c.cache.lastStaleFetch = c.now()
+	}
 	c.cache.mutex.Unlock()
+	if !due || !c.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer c.refreshing.Store(false)
+		if err := c.refresh(context.Background()); err != nil {
+			lg.Warn("Serving stale JWKS, refetch failed", "error", err)
+		}
+	}()
+}
+
+// refresh fetches the key set, sharing a single in-flight request between
+// all concurrent callers.
+func (c *JWKSClient) refresh(ctx context.Context) error {
+	_, err, _ := c.group.Do("jwks", func() (interface{}, error) {
+		return nil, c.fetchJWKS(ctx)
+	})
+	return err
+}
+
+// fetchJWKS performs a conditional request for the key set and stores the
+// result together with its parsed keys and Cache-Control lifetime.
+func (c *JWKSClient) fetchJWKS(ctx context.Context) error {
+	url := fmt.Sprintf("%s/.well-known/jwks.json", c.authServiceURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	c.cache.mutex.RLock()
+	if c.cache.etag != "" && c.cache.data != nil {
+		req.Header.Set("If-None-Match", c.cache.etag)
+	}
+	c.cache.mutex.RUnlock()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	ttl, staleIfError := parseCacheControl(resp.Header.Get("Cache-Control"))
+	now := c.now()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		c.cache.mutex.Lock()
+		c.cache.lastFetch = now
+		c.cache.expiresAt = now.Add(ttl)
+		c.cache.staleIfError = staleIfError
+		c.cache.mutex.Unlock()
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var jwks JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("failed to decode JWKS response: %w", err)
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		key, err := parseEd25519JWK(jwk)
+		if err != nil {
+			lg.Warn("Skipping unusable JWK", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: no usable Ed25519 keys", ErrInvalidKeyFormat)
+	}
+
+	c.cache.mutex.Lock()
+	added := 0
+	for kid := range keys {
+		if _, ok := c.cache.keys[kid]; !ok {
+			added++
+		}
+	}
+	c.cache.data = &jwks
+	c.cache.keys = keys
+	c.cache.etag = resp.Header.Get("ETag")
+	c.cache.lastFetch = now
+	c.cache.expiresAt = now.Add(ttl)
+	c.cache.staleIfError = staleIfError
+	c.cache.mutex.Unlock()
+
+	if added > 0 {
+		lg.Info("JWKS updated", "keys", len(keys), "new_keys", added, "ttl", ttl)
+	}
+	return nil
+}
+
+// parseEd25519JWK decodes an OKP/Ed25519 JWK into a public key.
+func parseEd25519JWK(jwk JWK) (ed25519.PublicKey, error) {
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" {
+		return nil, fmt.Errorf("%w: type=%s, curve=%s", ErrInvalidKeyFormat, jwk.Kty, jwk.Crv)
+	}
+
+	pubKeyBytes, err := decodeBase64URL(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
+	}
+
+	if len(pubKeyBytes) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid Ed25519 public key size: %d", ErrInvalidKeyFormat, len(pubKeyBytes))
+	}
+
+	return ed25519.PublicKey(pubKeyBytes), nil
+}
+
+// parseCacheControl extracts the cache lifetime and stale-if-error window.
+// no-cache and no-store make the set expire as soon as allowed.
+func parseCacheControl(header string) (ttl, staleIfError time.Duration) {
+	ttl, staleIfError = defaultJWKSTTL, defaultJWKSStaleIfError
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "max-age":
+			if secs, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && secs >= 0 {
+				ttl = time.Duration(secs) * time.Second
+			}
+		case "stale-if-error":
+			if secs, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && secs >= 0 {
+				staleIfError = time.Duration(secs) * time.Second
+			}
+		case "no-cache", "no-store":
+			ttl = 0
+		}
+	}
+	return min(max(ttl, minJWKSTTL), maxJWKSTTL), staleIfError
 }
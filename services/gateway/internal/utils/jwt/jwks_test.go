@@ -0,0 +1,119 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetKeyServesStaleWithoutBlocking(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		hang  atomic.Bool
+		calls atomic.Int32
+	)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if hang.Load() {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60, stale-if-error=3600")
+		_ = json.NewEncoder(w).Encode(JWKS{Keys: []JWK{{
+			Kty: "OKP", Crv: "Ed25519", Use: "sig", Kid: "k1",
+			X: base64.RawURLEncoding.EncodeToString(pub),
+		}}})
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	now := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	c := NewJWKSClient(srv.URL)
+	c.now = func() time.Time { return now }
+
+	if _, err := c.GetKey("k1"); err != nil {
+		t.Fatalf("initial GetKey: %v", err)
+	}
+
+	// Expire the set and make auth-service hang.
+	hang.Store(true)
+	now = now.Add(2 * time.Minute)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetKey("k1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("GetKey with stale set: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetKey blocked on the refetch instead of serving the stale set")
+	}
+
+	// Within staleRefetchInterval no further background fetch is started.
+	if _, err := c.GetKey("k1"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2 (initial + one background refetch)", got)
+	}
+}
+
+func TestGetKeyPastStaleWindowFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	c := NewJWKSClient(srv.URL)
+	c.now = func() time.Time { return now }
+	c.cache.data = &JWKS{}
+	c.cache.lastFetch = now.Add(-2 * time.Hour)
+	c.cache.staleIfError = time.Hour
+
+	if _, err := c.GetJWKS(); err == nil {
+		t.Fatal("GetJWKS past the stale-if-error window succeeded")
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	tests := []struct {
+		header    string
+		ttl       time.Duration
+		staleIfEr time.Duration
+	}{
+		{"", defaultJWKSTTL, defaultJWKSStaleIfError},
+		{"max-age=300", 5 * time.Minute, defaultJWKSStaleIfError},
+		{"public, max-age=300, stale-if-error=600", 5 * time.Minute, 10 * time.Minute},
+		{"max-age=1", minJWKSTTL, defaultJWKSStaleIfError},
+		{"max-age=999999", maxJWKSTTL, defaultJWKSStaleIfError},
+		{"no-store", minJWKSTTL, defaultJWKSStaleIfError},
+	}
+	for _, tt := range tests {
+		ttl, stale := parseCacheControl(tt.header)
+		if ttl != tt.ttl || stale != tt.staleIfEr {
+			t.Errorf("parseCacheControl(%q) = %s, %s; want %s, %s", tt.header, ttl, stale, tt.ttl, tt.staleIfEr)
+		}
+	}
+}
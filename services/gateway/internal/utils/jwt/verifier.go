@@ -65,25 +65,7 @@ func (v *jwtVerifier) VerifyToken(tokenStr string) (*AccessClaims, error) {
 }
 
 func (v *jwtVerifier) getPublicKeyFromJWKS(kid string) (ed25519.PublicKey, error) {
-	jwk, err := v.jwksClient.GetKeyByKID(kid)
-	if err != nil {
-		return nil, err
-	}
-
-	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" {
-		return nil, fmt.Errorf("%w: type=%s, curve=%s", ErrInvalidKeyFormat, jwk.Kty, jwk.Crv)
-	}
-
-	pubKeyBytes, err := decodeBase64URL(jwk.X)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode public key: %w", err)
-	}
-
-	if len(pubKeyBytes) != ed25519.PublicKeySize {
-		return nil, fmt.Errorf("%w: invalid Ed25519 public key size: %d", ErrInvalidKeyFormat, len(pubKeyBytes))
-	}
-
-	return ed25519.PublicKey(pubKeyBytes), nil
+	return v.jwksClient.GetKey(kid)
 }
 
 func (v *jwtVerifier) ExtractTokenFromHeader(authHeader string) (string, error) {
@@ -3,6 +3,8 @@ package redisutil
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -27,6 +29,12 @@ func (r *RedisUtil) Get(ctx context.Context, key string) (string, error) {
 	return r.client.Get(ctx, key).Result()
 }
 
+// GetDel retrieves a string value and removes its key, so that only one
+// caller gets it.
+func (r *RedisUtil) GetDel(ctx context.Context, key string) (string, error) {
+	return r.client.GetDel(ctx, key).Result()
+}
+
 // Delete removes a key from Redis.
 func (r *RedisUtil) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
@@ -49,3 +57,110 @@ func (r *RedisUtil) GetJSON(ctx context.Context, key string, dest interface{}) e
 	}
 	return json.Unmarshal(data, dest)
 }
+
+// Subscribe subscribes to pub/sub channels. The caller must close the
+// returned PubSub.
+func (r *RedisUtil) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return r.client.Subscribe(ctx, channels...)
+}
+
+// StreamRevRange returns up to count entries of stream key between stop and
+// start, newest first. Bounds take the XREVRANGE syntax: "+" and "-" for
+// the ends, and a "(" prefix to exclude an ID.
+func (r *RedisUtil) StreamRevRange(ctx context.Context, key, start, stop string, count int64) ([]redis.XMessage, error) {
+	return r.client.XRevRangeN(ctx, key, start, stop, count).Result()
+}
+
+// StreamFirst returns the oldest entry of stream key, or nil if the stream
+// is empty or does not exist.
+func (r *RedisUtil) StreamFirst(ctx context.Context, key string) (*redis.XMessage, error) {
+	msgs, err := r.client.XRangeN(ctx, key, "-", "+", 1).Result()
+	if err != nil || len(msgs) == 0 {
+		return nil, err
+	}
+	return &msgs[0], nil
+}
+
+// tokenBucketScript refills a bucket of burst tokens at rate tokens per
+// second and takes cost tokens if available, using the Redis clock so every
+// gateway instance shares one view. Returns allowed, remaining tokens, the
+// seconds until cost tokens are available and the seconds until the bucket
+// is full again.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= cost then
+  tokens = tokens - cost
+  allowed = 1
+else
+  retry_after = (cost - tokens) / rate
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+
+return {allowed, math.floor(tokens), tostring(retry_after), tostring((burst - tokens) / rate)}
+`)
+
+// RateLimitResult is the outcome of a TakeToken call.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// TakeToken atomically takes cost tokens from the token bucket at key, which
+// holds up to burst tokens refilled at rate tokens per second.
+func (r *RedisUtil) TakeToken(ctx context.Context, key string, rate float64, burst, cost int) (RateLimitResult, error) {
+	res, err := tokenBucketScript.Run(ctx, r.client, []string{key}, rate, burst, cost).Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(res) != 4 {
+		return RateLimitResult{}, fmt.Errorf("unexpected token bucket reply: %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	remaining, _ := res[1].(int64)
+	retryAfter, err := parseSeconds(res[2])
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	resetAfter, err := parseSeconds(res[3])
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return RateLimitResult{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: retryAfter,
+		ResetAfter: resetAfter,
+	}, nil
+}
+
+func parseSeconds(v interface{}) (time.Duration, error) {
+	s, _ := v.(string)
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid token bucket duration %q: %w", s, err)
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
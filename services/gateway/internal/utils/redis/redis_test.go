@@ -0,0 +1,111 @@
+package redisutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*RedisUtil, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC))
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisUtil(client), mr
+}
+
+func TestTakeTokenBurstThenReject(t *testing.T) {
+	r, _ := newTestRedis(t)
+	ctx := context.Background()
+
+	// 3 tokens refilled at 1 per second.
+	for i := 2; i >= 0; i-- {
+		res, err := r.TakeToken(ctx, "rl:test", 1, 3, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed || res.Remaining != i {
+			t.Fatalf("take %d = %+v; want allowed with %d remaining", 3-i, res, i)
+		}
+	}
+
+	res, err := r.TakeToken(ctx, "rl:test", 1, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Allowed {
+		t.Fatalf("take past burst = %+v; want rejected", res)
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v; want 1s", res.RetryAfter)
+	}
+	if res.ResetAfter != 3*time.Second {
+		t.Errorf("ResetAfter = %v; want 3s", res.ResetAfter)
+	}
+}
+
+func TestTakeTokenRefillsWithRedisClock(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+	start := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.TakeToken(ctx, "rl:test", 2, 2, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if res, _ := r.TakeToken(ctx, "rl:test", 2, 2, 1); res.Allowed {
+		t.Fatal("empty bucket allowed a request")
+	}
+
+	// Half a second refills one token at 2 per second.
+	mr.SetTime(start.Add(500 * time.Millisecond))
+	res, err := r.TakeToken(ctx, "rl:test", 2, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after refill = %+v; want allowed with 0 remaining", res)
+	}
+
+	// A long pause never fills the bucket past burst.
+	mr.SetTime(start.Add(time.Hour))
+	res, err = r.TakeToken(ctx, "rl:test", 2, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("after long pause = %+v; want allowed with 1 remaining", res)
+	}
+}
+
+func TestTakeTokenKeysAreIndependent(t *testing.T) {
+	r, _ := newTestRedis(t)
+	ctx := context.Background()
+
+	if res, _ := r.TakeToken(ctx, "rl:a", 1, 1, 1); !res.Allowed {
+		t.Fatal("first take on rl:a rejected")
+	}
+	if res, _ := r.TakeToken(ctx, "rl:a", 1, 1, 1); res.Allowed {
+		t.Fatal("second take on rl:a allowed")
+	}
+	if res, _ := r.TakeToken(ctx, "rl:b", 1, 1, 1); !res.Allowed {
+		t.Fatal("rl:b shares a bucket with rl:a")
+	}
+}
+
+func TestTakeTokenSetsExpiry(t *testing.T) {
+	r, mr := newTestRedis(t)
+
+	if _, err := r.TakeToken(context.Background(), "rl:test", 1, 10, 1); err != nil {
+		t.Fatal(err)
+	}
+	// Time to refill the whole bucket plus a second of slack.
+	if ttl := mr.TTL("rl:test"); ttl != 11*time.Second {
+		t.Errorf("TTL = %v; want 11s", ttl)
+	}
+}
@@ -0,0 +1,109 @@
+// Command dlq inspects, replays and purges the notification dead-letter
+// topic. It reads the same KAFKA_* environment as the service.
+//
+//	dlq list   [-partition N] [-offset N] [-limit N]
+//	dlq replay [-partition N] [-offset N]
+//	dlq purge  (-partition N [-offset N] | -all) -yes
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"music-player/api/logger"
+	"notification/configs"
+	"notification/internal/kafka/dlq"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	partition := fs.Int("partition", -1, "only this partition")
+	offset := fs.Int64("offset", -1, "only this offset (requires -partition); purge deletes up to it")
+	limit := fs.Int("limit", 50, "records to list")
+	all := fs.Bool("all", false, "purge every partition")
+	yes := fs.Bool("yes", false, "confirm purge")
+	timeout := fs.Duration("timeout", time.Minute, "overall timeout")
+	_ = fs.Parse(os.Args[2:])
+
+	sel := dlq.Selection{All: *all}
+	if *partition >= 0 {
+		p := int32(*partition)
+		sel.Partition = &p
+	}
+	if *offset >= 0 {
+		sel.Offset = offset
+	}
+
+	// The logger shares stdout with the result; keep it to warnings.
+	logger.SetLevel("", slog.LevelWarn)
+	// LoadAppConfig reads .env and the environment for the other loaders.
+	configs.LoadAppConfig()
+	kafkaCfg := configs.LoadKafkaConfig()
+
+	manager, err := dlq.NewManager(kafkaCfg)
+	if err != nil {
+		fail(err)
+	}
+	defer manager.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	switch cmd {
+	case "list":
+		msgs, partitions, err := manager.List(ctx, sel, *limit)
+		if err != nil {
+			fail(err)
+		}
+		printJSON(map[string]any{"topic": manager.Topic(), "partitions": partitions, "messages": msgs})
+	case "replay":
+		n, err := manager.Replay(ctx, sel)
+		if err != nil {
+			fail(err)
+		}
+		printJSON(map[string]any{"replayed": n})
+	case "purge":
+		if !*yes {
+			fmt.Fprintln(os.Stderr, "purge deletes records for good, pass -yes to confirm")
+			os.Exit(2)
+		}
+		n, err := manager.Purge(ctx, sel)
+		if err != nil {
+			fail(err)
+		}
+		printJSON(map[string]any{"purged": n})
+	default:
+		usage()
+	}
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "dlq:", err)
+	os.Exit(1)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq list|replay|purge [-partition N] [-offset N] [-limit N] [-all] [-yes]")
+	os.Exit(2)
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"music-player/api/logger"
+	"music-player/api/tracing"
 	"net/http"
 	"notification/configs"
 	"os"
@@ -11,15 +13,36 @@ import (
 	"sync"
 	"syscall"
 	"time"
+	// Quiet hours are computed in each user's timezone, which must not
+	// depend on the image having tzdata installed.
+	_ "time/tzdata"
 )
 
 func main() {
 	appCfg := configs.LoadAppConfig()
 	kafkaCfg := configs.LoadKafkaConfig()
+	redisCfg := configs.LoadRedisConfig()
+	dbCfg := configs.LoadDBConfig()
+	emailCfg := configs.LoadEmailConfig()
+	prefsCfg := configs.LoadPreferencesConfig()
+	realtimeCfg := configs.LoadRealtimeConfig()
+	pushCfg := configs.LoadPushConfig()
+	smsCfg := configs.LoadSMSConfig()
+	logCfg := logger.LoadConfig()
+	tracingCfg := tracing.LoadConfig("notification-service")
+
+	if err := logger.Init("notification-service", logCfg.Level, logCfg.Levels); err != nil {
+		fatal("Failed to initialize logger", err)
+	}
 
-	app, err := InitializeApp(appCfg, kafkaCfg)
+	shutdownTracing, err := tracing.Init(context.Background(), tracingCfg)
 	if err != nil {
-		log.Fatalf("[FATAL] Failed to initialize app: %v", err)
+		fatal("Failed to initialize tracing", err)
+	}
+
+	app, err := InitializeApp(appCfg, kafkaCfg, redisCfg, dbCfg, emailCfg, prefsCfg, realtimeCfg, pushCfg, smsCfg, logCfg)
+	if err != nil {
+		fatal("Failed to initialize app", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -36,44 +59,44 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		log.Printf("[INFO] HTTP server starting in %s mode on port %s", appCfg.Env, appCfg.Port)
+		slog.Info("HTTP server starting", "env", appCfg.Env, "port", appCfg.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[ERROR] HTTP server error: %v", err)
+			slog.Error("HTTP server error", "error", err)
 			cancel()
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.KafkaConsumer.Run(ctx)
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	select {
 	case <-quit:
-		log.Println("[INFO] Shutting down gracefully...")
+		slog.Info("Shutting down gracefully")
 	case <-ctx.Done():
-		log.Println("[INFO] Context cancelled, shutting down...")
+		slog.Info("Context cancelled, shutting down")
 	}
 
 	cancel()
 
-	log.Println("[INFO] Cleaning up resources...")
+	slog.Info("Cleaning up resources")
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("[ERROR] HTTP server forced to shutdown: %v", err)
+		slog.Error("HTTP server forced to shutdown", "error", err)
 	} else {
-		log.Println("[INFO] HTTP server stopped successfully")
-	}
-
-	if app.KafkaProducer != nil {
-		app.KafkaProducer.Close()
-	}
-
-	if app.KafkaConsumer != nil {
-		app.KafkaConsumer.Close()
+		slog.Info("HTTP server stopped successfully")
 	}
 
+	// Let the consume loop finish the records in flight and commit them
+	// before the clients are closed.
 	done := make(chan struct{})
 	go func() {
 		wg.Wait()
@@ -82,10 +105,38 @@ func main() {
 
 	select {
 	case <-done:
-		log.Println("[INFO] All services stopped successfully")
+		slog.Info("All services stopped successfully")
 	case <-time.After(5 * time.Second):
-		log.Println("[WARN] Timeout waiting for services to stop")
+		slog.Warn("Timeout waiting for services to stop")
 	}
 
-	log.Println("[INFO] Application shutdown complete")
+	if app.KafkaProducer != nil {
+		app.KafkaProducer.Close()
+	}
+
+	if app.KafkaConsumer != nil {
+		app.KafkaConsumer.Close()
+	}
+
+	if app.DLQManager != nil {
+		app.DLQManager.Close()
+	}
+
+	if app.Redis != nil {
+		if err := app.Redis.Close(); err != nil {
+			slog.Error("Failed to close Redis client", "error", err)
+		}
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+
+	slog.Info("Application shutdown complete")
+}
+
+// fatal logs err and exits. slog has no Fatal level.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
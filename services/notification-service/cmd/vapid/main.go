@@ -0,0 +1,23 @@
+// Command vapid generates a VAPID key pair for the Web Push channel, in the
+// form VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY expect.
+//
+//	go run ./cmd/vapid >> .env
+//
+// Browsers subscribe with the public key, so replacing the pair of a running
+// deployment invalidates every subscription.
+package main
+
+import (
+	"fmt"
+	"notification/internal/push"
+	"os"
+)
+
+func main() {
+	public, private, err := push.GenerateKeys()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "vapid:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\nVAPID_PRIVATE_KEY=%s\n", public, private)
+}
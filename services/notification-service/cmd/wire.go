@@ -5,41 +5,173 @@ package main
 
 import (
 	"notification/configs"
+	"time"
 
+	"music-player/api/health"
+	"music-player/api/logger"
+	"music-player/api/metrics"
+	"music-player/api/pgclient"
+	"music-player/api/redisclient"
+	"music-player/api/tracing"
+	"notification/internal/email"
+	"notification/internal/events"
+	"notification/internal/handlers"
+	"notification/internal/i18n"
+	"notification/internal/inbox"
 	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/dedupe"
+	"notification/internal/kafka/deferred"
+	"notification/internal/kafka/dlq"
 	"notification/internal/kafka/producer"
+	"notification/internal/preferences"
+	"notification/internal/push"
+	"notification/internal/realtime"
+	"notification/internal/routes"
+	"notification/internal/sms"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 type App struct {
 	Router        *gin.Engine
 	KafkaProducer *producer.Producer
 	KafkaConsumer *consumer.Consumer
+	DLQManager    *dlq.Manager
+	Redis         *goredis.Client
 }
 
-func InitializeApp(app *configs.AppConfig, kafkaCfg *configs.KafkaConfig) (*App, error) {
+func InitializeApp(app *configs.AppConfig, kafkaCfg *configs.KafkaConfig, redisCfg *redisclient.Config, dbCfg *pgclient.Config, emailCfg *configs.EmailConfig, prefsCfg *configs.PreferencesConfig, realtimeCfg *configs.RealtimeConfig, pushCfg *configs.PushConfig, smsCfg *configs.SMSConfig, logCfg *logger.Config) (*App, error) {
 	wire.Build(
 		provideRouter,
 		provideApp,
+		pgclient.New,
+		preferences.NewStore,
+		provideUnsubscriber,
 		producer.NewProducer,
 		consumer.NewConsumer,
+		redisclient.New,
+		provideDedupeStore,
+		provideDeferredStore,
+		events.NewHandlers,
+		email.NewSMTPTransport,
+		wire.Bind(new(email.Transport), new(*email.SMTPTransport)),
+		i18n.NewCatalog,
+		email.NewRenderer,
+		email.NewSender,
+		provideRegistry,
+		provideHealthRegistry,
+		handlers.NewHealthHandler,
+		provideLogLevelHandler,
+		dlq.NewManager,
+		provideDLQHandler,
+		provideTemplateHandler,
+		handlers.NewPreferencesHandler,
+		handlers.NewUnsubscribeHandler,
+		inbox.NewStore,
+		inbox.NewEvents,
+		inbox.NewNotifier,
+		realtime.NewPublisher,
+		handlers.NewInboxHandler,
+		push.NewStore,
+		provideVAPIDKeys,
+		push.NewSender,
+		handlers.NewPushHandler,
+		sms.NewProvider,
+		sms.NewLimiter,
+		sms.NewSender,
 	)
 
 	return nil, nil
 }
 
-func provideApp(router *gin.Engine, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer) *App {
+func provideApp(router *gin.Engine, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer, dlqManager *dlq.Manager, redisClient *goredis.Client) *App {
 	return &App{
 		Router:        router,
 		KafkaProducer: kafkaProducer,
 		KafkaConsumer: kafkaConsumer,
+		DLQManager:    dlqManager,
+		Redis:         redisClient,
 	}
 }
 
-func provideRouter() *gin.Engine {
-	r := gin.Default()
+func provideRouter(
+	app *configs.AppConfig,
+	healthHandler *handlers.HealthHandler,
+	logLevelHandler *logger.LevelHandler,
+	dlqHandler *handlers.DLQHandler,
+	templateHandler *handlers.TemplateHandler,
+	preferencesHandler *handlers.PreferencesHandler,
+	unsubscribeHandler *handlers.UnsubscribeHandler,
+	inboxHandler *handlers.InboxHandler,
+	pushHandler *handlers.PushHandler,
+) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(tracing.GinMiddleware("notification-service"))
+	r.Use(logger.GinMiddleware())
+	r.Use(metrics.HTTPMiddleware())
+	routes.RegisterHealthRoutes(r, healthHandler)
+	routes.RegisterMetricsRoutes(r)
+	routes.RegisterLogLevelRoutes(r, logLevelHandler)
+	routes.RegisterDLQRoutes(r, dlqHandler)
+	routes.RegisterTemplateRoutes(r, templateHandler)
+	routes.RegisterAPIRoutes(r, app.InternalToken, preferencesHandler, unsubscribeHandler, inboxHandler, pushHandler)
 
 	return r
 }
+
+func provideRegistry(eventHandlers *events.Handlers) *consumer.Registry {
+	registry := consumer.NewRegistry()
+	eventHandlers.Register(registry)
+	return registry
+}
+
+func provideLogLevelHandler(logCfg *logger.Config) *logger.LevelHandler {
+	return logger.NewLevelHandler(logCfg.AdminToken)
+}
+
+// provideDedupeStore returns nil, which the consumer treats as disabled, when
+// KAFKA_DEDUPE_TTL is 0.
+func provideDedupeStore(redisClient *goredis.Client, kafkaCfg *configs.KafkaConfig) *dedupe.Store {
+	if kafkaCfg.DedupeTTL <= 0 {
+		return nil
+	}
+	return dedupe.NewStore(redisClient, kafkaCfg.GroupID, kafkaCfg.DedupeTTL)
+}
+
+func provideDeferredStore(redisClient *goredis.Client, kafkaCfg *configs.KafkaConfig) *deferred.Store {
+	return deferred.NewStore(redisClient, kafkaCfg.GroupID)
+}
+
+func provideDLQHandler(manager *dlq.Manager, kafkaCfg *configs.KafkaConfig) *handlers.DLQHandler {
+	return handlers.NewDLQHandler(manager, kafkaCfg.DLQAdminToken)
+}
+
+func provideTemplateHandler(renderer *email.Renderer, unsubscriber *preferences.Unsubscriber, emailCfg *configs.EmailConfig) *handlers.TemplateHandler {
+	return handlers.NewTemplateHandler(renderer, unsubscriber, emailCfg.PreviewToken)
+}
+
+func provideUnsubscriber(prefsCfg *configs.PreferencesConfig) *preferences.Unsubscriber {
+	return preferences.NewUnsubscriber(prefsCfg.UnsubscribeSecret, prefsCfg.UnsubscribeBaseURL)
+}
+
+// provideVAPIDKeys returns nil, which disables push, when no key pair is
+// configured. A key pair that does not load stops the service.
+func provideVAPIDKeys(pushCfg *configs.PushConfig) (*push.Keys, error) {
+	if pushCfg.VAPIDPrivateKey == "" {
+		return nil, nil
+	}
+	return push.ParseKeys(pushCfg.VAPIDPublicKey, pushCfg.VAPIDPrivateKey, pushCfg.VAPIDSubject)
+}
+
+func provideHealthRegistry(gormDB *gorm.DB, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer, redisClient *goredis.Client) *health.Registry {
+	registry := health.NewRegistry(2 * time.Second)
+	registry.Register("postgres", health.GormCheck(gormDB))
+	registry.Register("redis", health.RedisCheck(redisClient))
+	registry.Register("kafka_producer", health.PingCheck(kafkaProducer))
+	registry.Register("kafka_consumer", health.PingCheck(kafkaConsumer))
+	return registry
+}
@@ -8,24 +8,106 @@ package main
 
 import (
 	"github.com/gin-gonic/gin"
+	redis2 "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"music-player/api/health"
+	"music-player/api/logger"
+	"music-player/api/metrics"
+	"music-player/api/pgclient"
+	"music-player/api/redisclient"
+	"music-player/api/tracing"
 	"notification/configs"
+	"notification/internal/email"
+	"notification/internal/events"
+	"notification/internal/handlers"
+	"notification/internal/i18n"
+	"notification/internal/inbox"
 	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/dedupe"
+	"notification/internal/kafka/deferred"
+	"notification/internal/kafka/dlq"
 	"notification/internal/kafka/producer"
+	"notification/internal/preferences"
+	"notification/internal/push"
+	"notification/internal/realtime"
+	"notification/internal/routes"
+	"notification/internal/sms"
+	"time"
+)
+
+import (
+	_ "time/tzdata"
 )
 
 // Injectors from wire.go:
 
-func InitializeApp(app *configs.AppConfig, kafkaCfg *configs.KafkaConfig) (*App, error) {
-	engine := provideRouter()
+func InitializeApp(app *configs.AppConfig, kafkaCfg *configs.KafkaConfig, redisCfg *redisclient.Config, dbCfg *pgclient.Config, emailCfg *configs.EmailConfig, prefsCfg *configs.PreferencesConfig, realtimeCfg *configs.RealtimeConfig, pushCfg *configs.PushConfig, smsCfg *configs.SMSConfig, logCfg *logger.Config) (*App, error) {
+	gormDB, err := pgclient.New(dbCfg)
+	if err != nil {
+		return nil, err
+	}
 	producerProducer, err := producer.NewProducer(kafkaCfg)
 	if err != nil {
 		return nil, err
 	}
-	consumerConsumer, err := consumer.NewConsumer(kafkaCfg)
+	smtpTransport := email.NewSMTPTransport(emailCfg)
+	catalog, err := i18n.NewCatalog()
+	if err != nil {
+		return nil, err
+	}
+	renderer, err := email.NewRenderer(catalog)
+	if err != nil {
+		return nil, err
+	}
+	store := preferences.NewStore(gormDB)
+	unsubscriber := provideUnsubscriber(prefsCfg)
+	sender := email.NewSender(smtpTransport, renderer, producerProducer, store, unsubscriber, emailCfg)
+	inboxStore := inbox.NewStore(gormDB)
+	client := redisclient.New(redisCfg)
+	publisher := realtime.NewPublisher(client, realtimeCfg)
+	inboxEvents := inbox.NewEvents(inboxStore, publisher)
+	notifier := inbox.NewNotifier(inboxStore, inboxEvents, store, catalog)
+	pushStore, err := push.NewStore(gormDB, pushCfg)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := provideVAPIDKeys(pushCfg)
+	if err != nil {
+		return nil, err
+	}
+	pushSender := push.NewSender(pushStore, keys, producerProducer, store, catalog, pushCfg)
+	provider, err := sms.NewProvider(smsCfg)
+	if err != nil {
+		return nil, err
+	}
+	limiter := sms.NewLimiter(client, smsCfg)
+	smsSender := sms.NewSender(provider, limiter, producerProducer, store, catalog)
+	eventsHandlers := events.NewHandlers(sender, notifier, pushSender, smsSender, emailCfg)
+	registry := provideRegistry(eventsHandlers)
+	dedupeStore := provideDedupeStore(client, kafkaCfg)
+	deferredStore := provideDeferredStore(client, kafkaCfg)
+	consumerConsumer, err := consumer.NewConsumer(kafkaCfg, registry, dedupeStore, deferredStore)
+	if err != nil {
+		return nil, err
+	}
+	healthRegistry := provideHealthRegistry(gormDB, producerProducer, consumerConsumer, client)
+	healthHandler := handlers.NewHealthHandler(healthRegistry)
+	logLevelHandler := provideLogLevelHandler(logCfg)
+	manager, err := dlq.NewManager(kafkaCfg)
 	if err != nil {
 		return nil, err
 	}
-	mainApp := provideApp(engine, producerProducer, consumerConsumer)
+	dlqHandler := provideDLQHandler(manager, kafkaCfg)
+	templateHandler := provideTemplateHandler(renderer, unsubscriber, emailCfg)
+	preferencesHandler := handlers.NewPreferencesHandler(store)
+	unsubscribeHandler, err := handlers.NewUnsubscribeHandler(store, unsubscriber, catalog)
+	if err != nil {
+		return nil, err
+	}
+	inboxHandler := handlers.NewInboxHandler(inboxStore, inboxEvents)
+	pushHandler := handlers.NewPushHandler(pushStore, pushSender)
+	engine := provideRouter(app, healthHandler, logLevelHandler, dlqHandler, templateHandler, preferencesHandler, unsubscribeHandler, inboxHandler, pushHandler)
+	mainApp := provideApp(engine, producerProducer, consumerConsumer, manager, client)
 	return mainApp, nil
 }
 
@@ -35,18 +117,95 @@ type App struct {
 	Router        *gin.Engine
 	KafkaProducer *producer.Producer
 	KafkaConsumer *consumer.Consumer
+	DLQManager    *dlq.Manager
+	Redis         *redis2.Client
 }
 
-func provideApp(router *gin.Engine, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer) *App {
+func provideApp(router *gin.Engine, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer, dlqManager *dlq.Manager, redisClient *redis2.Client) *App {
 	return &App{
 		Router:        router,
 		KafkaProducer: kafkaProducer,
 		KafkaConsumer: kafkaConsumer,
+		DLQManager:    dlqManager,
+		Redis:         redisClient,
 	}
 }
 
-func provideRouter() *gin.Engine {
-	r := gin.Default()
+func provideRouter(
+	app *configs.AppConfig,
+	healthHandler *handlers.HealthHandler,
+	logLevelHandler *logger.LevelHandler,
+	dlqHandler *handlers.DLQHandler,
+	templateHandler *handlers.TemplateHandler,
+	preferencesHandler *handlers.PreferencesHandler,
+	unsubscribeHandler *handlers.UnsubscribeHandler,
+	inboxHandler *handlers.InboxHandler,
+	pushHandler *handlers.PushHandler,
+) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(tracing.GinMiddleware("notification-service"))
+	r.Use(logger.GinMiddleware())
+	r.Use(metrics.HTTPMiddleware())
+	routes.RegisterHealthRoutes(r, healthHandler)
+	routes.RegisterMetricsRoutes(r)
+	routes.RegisterLogLevelRoutes(r, logLevelHandler)
+	routes.RegisterDLQRoutes(r, dlqHandler)
+	routes.RegisterTemplateRoutes(r, templateHandler)
+	routes.RegisterAPIRoutes(r, app.InternalToken, preferencesHandler, unsubscribeHandler, inboxHandler, pushHandler)
 
 	return r
 }
+
+func provideRegistry(eventHandlers *events.Handlers) *consumer.Registry {
+	registry := consumer.NewRegistry()
+	eventHandlers.Register(registry)
+	return registry
+}
+
+func provideLogLevelHandler(logCfg *logger.Config) *logger.LevelHandler {
+	return logger.NewLevelHandler(logCfg.AdminToken)
+}
+
+// provideDedupeStore returns nil, which the consumer treats as disabled, when
+// KAFKA_DEDUPE_TTL is 0.
+func provideDedupeStore(redisClient *redis2.Client, kafkaCfg *configs.KafkaConfig) *dedupe.Store {
+	if kafkaCfg.DedupeTTL <= 0 {
+		return nil
+	}
+	return dedupe.NewStore(redisClient, kafkaCfg.GroupID, kafkaCfg.DedupeTTL)
+}
+
+func provideDeferredStore(redisClient *redis2.Client, kafkaCfg *configs.KafkaConfig) *deferred.Store {
+	return deferred.NewStore(redisClient, kafkaCfg.GroupID)
+}
+
+func provideDLQHandler(manager *dlq.Manager, kafkaCfg *configs.KafkaConfig) *handlers.DLQHandler {
+	return handlers.NewDLQHandler(manager, kafkaCfg.DLQAdminToken)
+}
+
+func provideTemplateHandler(renderer *email.Renderer, unsubscriber *preferences.Unsubscriber, emailCfg *configs.EmailConfig) *handlers.TemplateHandler {
+	return handlers.NewTemplateHandler(renderer, unsubscriber, emailCfg.PreviewToken)
+}
+
+func provideUnsubscriber(prefsCfg *configs.PreferencesConfig) *preferences.Unsubscriber {
+	return preferences.NewUnsubscriber(prefsCfg.UnsubscribeSecret, prefsCfg.UnsubscribeBaseURL)
+}
+
+// provideVAPIDKeys returns nil, which disables push, when no key pair is
+// configured. A key pair that does not load stops the service.
+func provideVAPIDKeys(pushCfg *configs.PushConfig) (*push.Keys, error) {
+	if pushCfg.VAPIDPrivateKey == "" {
+		return nil, nil
+	}
+	return push.ParseKeys(pushCfg.VAPIDPublicKey, pushCfg.VAPIDPrivateKey, pushCfg.VAPIDSubject)
+}
+
+func provideHealthRegistry(gormDB *gorm.DB, kafkaProducer *producer.Producer, kafkaConsumer *consumer.Consumer, redisClient *redis2.Client) *health.Registry {
+	registry := health.NewRegistry(2 * time.Second)
+	registry.Register("postgres", health.GormCheck(gormDB))
+	registry.Register("redis", health.RedisCheck(redisClient))
+	registry.Register("kafka_producer", health.PingCheck(kafkaProducer))
+	registry.Register("kafka_consumer", health.PingCheck(kafkaConsumer))
+	return registry
+}
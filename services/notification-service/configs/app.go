@@ -1,7 +1,7 @@
 package configs
 
 import (
-	"log"
+	"log/slog"
 
 	"github.com/spf13/viper"
 )
@@ -9,6 +9,9 @@ import (
 type AppConfig struct {
 	Port string
 	Env  string
+	// InternalToken authenticates the gateway on the /api/v1 routes. They
+	// are not registered without it.
+	InternalToken string
 }
 
 func LoadAppConfig() *AppConfig {
@@ -17,12 +20,13 @@ func LoadAppConfig() *AppConfig {
 
 	err := viper.ReadInConfig()
 	if err != nil {
-		log.Printf("[INFO] No .env file found or error reading config: %v", err)
+		slog.Info("No .env file found or error reading config", "error", err)
 	}
 
 	cfg := &AppConfig{
-		Port: viper.GetString("APP_PORT"),
-		Env:  viper.GetString("APP_ENV"),
+		Port:          viper.GetString("APP_PORT"),
+		Env:           viper.GetString("APP_ENV"),
+		InternalToken: viper.GetString("INTERNAL_API_TOKEN"),
 	}
 
 	return cfg
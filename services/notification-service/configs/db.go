@@ -0,0 +1,23 @@
+package configs
+
+import (
+	"log/slog"
+	"music-player/api/pgclient"
+
+	"github.com/spf13/viper"
+)
+
+// LoadDBConfig reads the POSTGRES_* connection settings.
+func LoadDBConfig() *pgclient.Config {
+	cfg := &pgclient.Config{
+		Host:     viper.GetString("POSTGRES_HOST"),
+		Port:     viper.GetString("POSTGRES_PORT"),
+		User:     viper.GetString("POSTGRES_USER"),
+		Password: viper.GetString("POSTGRES_PASSWORD"),
+		DBName:   viper.GetString("POSTGRES_DB"),
+	}
+	if missing := cfg.Missing(); len(missing) > 0 {
+		slog.Warn("Database config is incomplete. Please check your environment variables or .env file", "missing", missing)
+	}
+	return cfg
+}
@@ -0,0 +1,80 @@
+package configs
+
+import (
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// SMTP connection security modes.
+const (
+	SMTPTLSNone     = "none"
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "tls"
+)
+
+// EmailConfig configures the SMTP transport and the sender identity. The
+// defaults target a local Mailpit on localhost:1025 without TLS or auth.
+type EmailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// TLS is SMTPTLSNone, SMTPTLSStartTLS or SMTPTLSImplicit.
+	TLS     string
+	Timeout time.Duration
+
+	From     string
+	FromName string
+	// LinkBaseURL is the web app origin that links in emails point to.
+	LinkBaseURL string
+
+	// PreviewToken enables the /admin/templates preview endpoints.
+	PreviewToken string
+}
+
+func LoadEmailConfig() *EmailConfig {
+	viper.SetDefault("SMTP_HOST", "localhost")
+	viper.SetDefault("SMTP_PORT", "1025")
+	viper.SetDefault("SMTP_TLS", SMTPTLSNone)
+	viper.SetDefault("SMTP_TIMEOUT", "10s")
+	viper.SetDefault("EMAIL_FROM", "no-reply@musicplayer.local")
+	viper.SetDefault("EMAIL_FROM_NAME", "Music Player")
+	viper.SetDefault("EMAIL_LINK_BASE_URL", "http://localhost:3000")
+
+	cfg := &EmailConfig{
+		Host:        viper.GetString("SMTP_HOST"),
+		Port:        viper.GetString("SMTP_PORT"),
+		Username:    viper.GetString("SMTP_USERNAME"),
+		Password:    viper.GetString("SMTP_PASSWORD"),
+		TLS:         strings.ToLower(viper.GetString("SMTP_TLS")),
+		Timeout:     viper.GetDuration("SMTP_TIMEOUT"),
+		From:        viper.GetString("EMAIL_FROM"),
+		FromName:    viper.GetString("EMAIL_FROM_NAME"),
+		LinkBaseURL: strings.TrimRight(viper.GetString("EMAIL_LINK_BASE_URL"), "/"),
+
+		PreviewToken: viper.GetString("EMAIL_PREVIEW_TOKEN"),
+	}
+
+	switch cfg.TLS {
+	case SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit:
+	default:
+		slog.Warn("Unknown SMTP_TLS, using starttls", "value", cfg.TLS)
+		cfg.TLS = SMTPTLSStartTLS
+	}
+	if cfg.TLS == SMTPTLSNone && !isLoopback(cfg.Host) {
+		slog.Warn("SMTP_TLS=none with a remote SMTP host sends mail in clear text", "host", cfg.Host)
+	}
+	return cfg
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -1,8 +1,10 @@
 package configs
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,6 +15,45 @@ type KafkaConfig struct {
 	ClientID string
 	Debug    bool
 	Topics   []string
+
+	// Workers bounds how many partitions are processed concurrently;
+	// records of one partition are always handled in order.
+	Workers        int
+	MaxPollRecords int
+	// HandlerTimeout bounds a single handler attempt; a failing record is
+	// tried HandlerAttempts times before its partition is rewound.
+	HandlerTimeout  time.Duration
+	HandlerAttempts int
+
+	// RetryDelays holds the delay of each retry tier. A record that still
+	// fails goes to the next tier's topic and, after the last one, to the
+	// dead-letter topic.
+	RetryDelays []time.Duration
+	// RetryTopicPrefix names the retry and dead-letter topics; it defaults to
+	// the group ID since retries belong to one consumer group.
+	RetryTopicPrefix string
+	// DLQAdminToken enables the /admin/dlq endpoints.
+	DLQAdminToken string
+
+	// DedupeTTL is how long a handled MessageID is remembered in Redis, so
+	// a redelivery within it is skipped. Zero disables deduplication.
+	DedupeTTL time.Duration
+}
+
+// RetryTopic returns the topic of retry tier n, counting from 1.
+func (c *KafkaConfig) RetryTopic(n int) string {
+	return fmt.Sprintf("%s.retry.%d", c.RetryTopicPrefix, n)
+}
+
+// DeferredTopic returns the topic deferred records are published to once
+// they are due.
+func (c *KafkaConfig) DeferredTopic() string {
+	return c.RetryTopicPrefix + ".deferred"
+}
+
+// DLQTopic returns the dead-letter topic.
+func (c *KafkaConfig) DLQTopic() string {
+	return c.RetryTopicPrefix + ".dlq"
 }
 
 func LoadKafkaConfig() *KafkaConfig {
@@ -23,16 +64,39 @@ func LoadKafkaConfig() *KafkaConfig {
 		return def
 	}
 
+	viper.SetDefault("KAFKA_CONSUMER_WORKERS", 8)
+	viper.SetDefault("KAFKA_MAX_POLL_RECORDS", 500)
+	viper.SetDefault("KAFKA_HANDLER_TIMEOUT", "30s")
+	viper.SetDefault("KAFKA_HANDLER_ATTEMPTS", 3)
+	viper.SetDefault("KAFKA_RETRY_DELAYS", "30s,5m,30m")
+	viper.SetDefault("KAFKA_DEDUPE_TTL", "24h")
+
 	cfg := &KafkaConfig{
 		Brokers:  splitTrim(get("KAFKA_BROKERS", "localhost:9092")),
 		GroupID:  get("KAFKA_GROUP_ID", "default-group"),
 		ClientID: get("KAFKA_CLIENT_ID", "default-client"),
 		Topics:   splitTrim(get("KAFKA_TOPICS", "")),
 		Debug:    get("APP_ENV", "") == "development",
+
+		Workers:         viper.GetInt("KAFKA_CONSUMER_WORKERS"),
+		MaxPollRecords:  viper.GetInt("KAFKA_MAX_POLL_RECORDS"),
+		HandlerTimeout:  viper.GetDuration("KAFKA_HANDLER_TIMEOUT"),
+		HandlerAttempts: max(viper.GetInt("KAFKA_HANDLER_ATTEMPTS"), 1),
+		DLQAdminToken:   viper.GetString("KAFKA_DLQ_ADMIN_TOKEN"),
+		DedupeTTL:       viper.GetDuration("KAFKA_DEDUPE_TTL"),
+	}
+	cfg.RetryTopicPrefix = get("KAFKA_RETRY_TOPIC_PREFIX", cfg.GroupID)
+	for _, d := range splitTrim(viper.GetString("KAFKA_RETRY_DELAYS")) {
+		delay, err := time.ParseDuration(d)
+		if err != nil || delay <= 0 {
+			slog.Warn("Ignoring invalid KAFKA_RETRY_DELAYS entry", "value", d)
+			continue
+		}
+		cfg.RetryDelays = append(cfg.RetryDelays, delay)
 	}
 
 	if len(cfg.Brokers) == 0 {
-		log.Println("[WARN] KAFKA_BROKERS not set, defaulting to localhost:9092")
+		slog.Warn("KAFKA_BROKERS not set, defaulting to localhost:9092")
 	}
 	return cfg
 }
@@ -0,0 +1,30 @@
+package configs
+
+import (
+	"log/slog"
+
+	"github.com/spf13/viper"
+)
+
+// PreferencesConfig configures the unsubscribe links added to optional
+// emails.
+type PreferencesConfig struct {
+	// UnsubscribeSecret signs the links; without it no links are sent.
+	UnsubscribeSecret string
+	// UnsubscribeBaseURL is the public URL of the unsubscribe endpoint,
+	// served through the gateway.
+	UnsubscribeBaseURL string
+}
+
+func LoadPreferencesConfig() *PreferencesConfig {
+	viper.SetDefault("UNSUBSCRIBE_BASE_URL", "http://localhost:3000/api/v1/notifications/unsubscribe")
+
+	cfg := &PreferencesConfig{
+		UnsubscribeSecret:  viper.GetString("UNSUBSCRIBE_SECRET"),
+		UnsubscribeBaseURL: viper.GetString("UNSUBSCRIBE_BASE_URL"),
+	}
+	if cfg.UnsubscribeSecret == "" {
+		slog.Warn("UNSUBSCRIBE_SECRET is not set: optional emails are sent without unsubscribe links")
+	}
+	return cfg
+}
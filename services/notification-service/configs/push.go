@@ -0,0 +1,57 @@
+package configs
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// PushConfig configures the Web Push channel. Without a VAPID key pair the
+// channel is disabled; `go run ./cmd/vapid` generates one.
+type PushConfig struct {
+	// VAPIDPublicKey is the uncompressed P-256 point and VAPIDPrivateKey
+	// the raw scalar, both base64url. Browsers subscribe with the public
+	// key, so changing the pair invalidates every subscription.
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	// VAPIDSubject is the mailto: or https: contact sent to push services.
+	VAPIDSubject string
+
+	Timeout time.Duration
+	// AllowedHosts are the push services subscriptions may point to. A
+	// host matches itself and its subdomains. Empty allows any HTTPS host,
+	// which only development accepts: the service POSTs to the endpoints.
+	AllowedHosts []string
+	// MaxSubscriptionsPerUser caps the browsers of a user; the oldest
+	// subscription is dropped beyond it.
+	MaxSubscriptionsPerUser int
+	// Development is set when APP_ENV is development.
+	Development bool
+}
+
+func LoadPushConfig() *PushConfig {
+	viper.SetDefault("VAPID_SUBJECT", "mailto:no-reply@musicplayer.local")
+	viper.SetDefault("PUSH_TIMEOUT", "10s")
+	viper.SetDefault("PUSH_ALLOWED_HOSTS", "fcm.googleapis.com,push.services.mozilla.com,notify.windows.com,push.apple.com")
+	viper.SetDefault("PUSH_MAX_SUBSCRIPTIONS_PER_USER", 10)
+
+	cfg := &PushConfig{
+		VAPIDPublicKey:          viper.GetString("VAPID_PUBLIC_KEY"),
+		VAPIDPrivateKey:         viper.GetString("VAPID_PRIVATE_KEY"),
+		VAPIDSubject:            viper.GetString("VAPID_SUBJECT"),
+		Timeout:                 viper.GetDuration("PUSH_TIMEOUT"),
+		MaxSubscriptionsPerUser: viper.GetInt("PUSH_MAX_SUBSCRIPTIONS_PER_USER"),
+		Development:             viper.GetString("APP_ENV") == "development",
+	}
+	for _, host := range strings.Split(viper.GetString("PUSH_ALLOWED_HOSTS"), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			cfg.AllowedHosts = append(cfg.AllowedHosts, host)
+		}
+	}
+	if cfg.VAPIDPrivateKey == "" {
+		slog.Warn("VAPID_PRIVATE_KEY is not set: push notifications are disabled")
+	}
+	return cfg
+}
@@ -0,0 +1,27 @@
+package configs
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// RealtimeConfig bounds the per-user Redis streams the gateway replays to
+// reconnecting clients.
+type RealtimeConfig struct {
+	// StreamMaxLen is roughly how many events a stream keeps.
+	StreamMaxLen int64
+	// StreamTTL drops the stream of a user who received nothing for that
+	// long.
+	StreamTTL time.Duration
+}
+
+func LoadRealtimeConfig() *RealtimeConfig {
+	viper.SetDefault("REALTIME_STREAM_MAXLEN", 200)
+	viper.SetDefault("REALTIME_STREAM_TTL", "24h")
+
+	return &RealtimeConfig{
+		StreamMaxLen: viper.GetInt64("REALTIME_STREAM_MAXLEN"),
+		StreamTTL:    viper.GetDuration("REALTIME_STREAM_TTL"),
+	}
+}
@@ -0,0 +1,22 @@
+package configs
+
+import (
+	"log/slog"
+	"music-player/api/redisclient"
+
+	"github.com/spf13/viper"
+)
+
+// LoadRedisConfig reads the REDIS_* connection settings.
+func LoadRedisConfig() *redisclient.Config {
+	cfg := &redisclient.Config{
+		Host:     viper.GetString("REDIS_HOST"),
+		Port:     viper.GetString("REDIS_PORT"),
+		Username: viper.GetString("REDIS_USERNAME"),
+		Password: viper.GetString("REDIS_PASSWORD"),
+	}
+	if cfg.Host == "" || cfg.Port == "" {
+		slog.Warn("Some Redis config fields are empty. Please check your environment variables or .env file")
+	}
+	return cfg
+}
@@ -0,0 +1,54 @@
+package configs
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// SMSConfig configures the SMS channel. SMS_PROVIDER must be set outside
+// development: production sets it to http and the endpoint of an SMS
+// gateway, and the fake provider only logs that a message was sent.
+type SMSConfig struct {
+	// Provider is "fake" or "http". Empty means fake in development and
+	// stops the service elsewhere.
+	Provider string
+	// HTTPURL receives a JSON POST of {"from", "to", "body"} per message,
+	// authorized with HTTPToken as a bearer token.
+	HTTPURL   string
+	HTTPToken string
+	// From is the sender ID or number messages come from.
+	From    string
+	Timeout time.Duration
+	// RateLimit is the most messages a number receives per RateWindow,
+	// whatever asked for them, so that a leaked endpoint or a stuck retry
+	// cannot flood a phone or run up the bill.
+	RateLimit  int
+	RateWindow time.Duration
+	// Development is set when APP_ENV is development.
+	Development bool
+}
+
+func LoadSMSConfig() *SMSConfig {
+	viper.SetDefault("SMS_FROM", "MusicPlayer")
+	viper.SetDefault("SMS_TIMEOUT", "10s")
+	viper.SetDefault("SMS_RATE_LIMIT", 5)
+	viper.SetDefault("SMS_RATE_WINDOW", "1h")
+
+	cfg := &SMSConfig{
+		Provider:   viper.GetString("SMS_PROVIDER"),
+		HTTPURL:    viper.GetString("SMS_HTTP_URL"),
+		HTTPToken:  viper.GetString("SMS_HTTP_TOKEN"),
+		From:       viper.GetString("SMS_FROM"),
+		Timeout:    viper.GetDuration("SMS_TIMEOUT"),
+		RateLimit:  viper.GetInt("SMS_RATE_LIMIT"),
+		RateWindow: viper.GetDuration("SMS_RATE_WINDOW"),
+
+		Development: viper.GetString("APP_ENV") == "development",
+	}
+	if cfg.Provider == "fake" || (cfg.Provider == "" && cfg.Development) {
+		slog.Warn("SMS_PROVIDER is fake: text messages are not sent")
+	}
+	return cfg
+}
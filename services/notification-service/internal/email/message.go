@@ -0,0 +1,135 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is an email with a plain-text and an HTML alternative.
+type Message struct {
+	From    mail.Address
+	To      mail.Address
+	Subject string
+	Text    string
+	HTML    string
+	// Language is the Content-Language of the bodies, if set.
+	Language string
+	// Headers are extra header fields, such as List-Unsubscribe.
+	Headers map[string]string
+}
+
+// NewMessageID returns a Message-ID in the domain of from.
+func NewMessageID(from string) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return messageID(b, from)
+}
+
+// MessageIDFor returns the Message-ID for key in the domain of from. The
+// same key always yields the same ID, so a message sent again after a
+// redelivery is recognised as a duplicate by mail clients.
+func MessageIDFor(key, from string) string {
+	sum := sha256.Sum256([]byte(key))
+	return messageID(sum[:16], from)
+}
+
+func messageID(b []byte, from string) string {
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok && d != "" {
+		domain = d
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// Encode renders m as a multipart/alternative MIME message. Both bodies are
+// quoted-printable so the message is 7-bit clean.
+func (m *Message) Encode(messageID string, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	header := map[string]string{
+		"From":         m.From.String(),
+		"To":           m.To.String(),
+		"Subject":      mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date":         date.Format(time.RFC1123Z),
+		"Message-ID":   messageID,
+		"MIME-Version": "1.0",
+		"Content-Type": `multipart/alternative; boundary="` + mw.Boundary() + `"`,
+	}
+	if m.Language != "" {
+		header["Content-Language"] = m.Language
+	}
+	for k, v := range m.Headers {
+		header[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var msg bytes.Buffer
+	for _, k := range keys {
+		v := header[k]
+		if strings.ContainsAny(k+v, "\r\n") {
+			return nil, permanent(fmt.Errorf("email: header %s contains a line break", k))
+		}
+		fmt.Fprintf(&msg, "%s: %s\r\n", k, v)
+	}
+	msg.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	msg.Write(buf.Bytes())
+	return msg.Bytes(), nil
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether sending failed in a way a retry cannot fix:
+// an invalid address or template, or a 5xx SMTP reply.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,22 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessageIDFor(t *testing.T) {
+	a := MessageIDFor("m1\nwelcome\na@example.com", "noreply@example.com")
+	if b := MessageIDFor("m1\nwelcome\na@example.com", "noreply@example.com"); a != b {
+		t.Errorf("MessageIDFor is not stable: %s != %s", a, b)
+	}
+	if b := MessageIDFor("m2\nwelcome\na@example.com", "noreply@example.com"); a == b {
+		t.Error("different keys share a Message-ID")
+	}
+	if !strings.HasPrefix(a, "<") || !strings.HasSuffix(a, "@example.com>") {
+		t.Errorf("MessageIDFor = %s; want <hex@example.com>", a)
+	}
+	if id := NewMessageID("noreply"); !strings.HasSuffix(id, "@localhost>") {
+		t.Errorf("NewMessageID without a domain = %s; want @localhost", id)
+	}
+}
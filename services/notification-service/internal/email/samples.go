@@ -0,0 +1,24 @@
+package email
+
+import "time"
+
+// samples is the data the template preview renders each template with. It
+// has the shape the event handlers pass.
+var samples = map[string]map[string]any{
+	TemplateWelcome: {
+		"Name":     "Nguyễn Văn An",
+		"Username": "vanan",
+		"LoginURL": "https://example.com/login",
+	},
+	TemplatePasswordReset: {
+		"Name":      "Nguyễn Văn An",
+		"ResetURL":  "https://example.com/reset-password?token=sample",
+		"ExpiresIn": time.Hour,
+	},
+}
+
+// Sample returns the preview data of template name.
+func Sample(name string) (map[string]any, bool) {
+	data, ok := samples[name]
+	return data, ok
+}
@@ -0,0 +1,230 @@
+// Package email is the email channel: it renders the embedded templates,
+// delivers messages over SMTP and reports the outcome on
+// notification.email.sent and notification.email.failed.
+package email
+
+import (
+	"context"
+	"fmt"
+	"music-player/api/logger"
+	"music-player/api/tracing"
+	"net/mail"
+	"notification/configs"
+	"notification/internal/kafka/envelope"
+	"notification/internal/kafka/producer"
+	"notification/internal/metrics"
+	"notification/internal/preferences"
+	"time"
+)
+
+var lg = logger.For("email")
+
+const source = "notification-service"
+
+// Request asks for template to be rendered with data and sent to To.
+type Request struct {
+	To       string
+	Name     string
+	Template string
+	// Locale is the recipient's locale, e.g. "vi"; see Renderer.Render.
+	Locale string
+	// Category defaults to the template's; see ResolveCategory.
+	Category preferences.Category
+	Data     any
+	UserID   string
+	// CausationID is the message_id of the event that asked for the email.
+	CausationID string
+	Headers     map[string]string
+}
+
+// Sent is the data of a notification.email.sent event.
+type Sent struct {
+	MessageID         string    `json:"message_id"`
+	ProviderMessageID string    `json:"provider_message_id,omitempty"`
+	CausationID       string    `json:"causation_id,omitempty"`
+	UserID            string    `json:"user_id,omitempty"`
+	Email             string    `json:"email"`
+	Template          string    `json:"template"`
+	Locale            string    `json:"locale"`
+	SentAt            time.Time `json:"sent_at"`
+}
+
+// Failed is the data of a notification.email.failed event.
+type Failed struct {
+	MessageID   string    `json:"message_id,omitempty"`
+	CausationID string    `json:"causation_id,omitempty"`
+	UserID      string    `json:"user_id,omitempty"`
+	Email       string    `json:"email"`
+	Template    string    `json:"template"`
+	Error       string    `json:"error"`
+	FailedAt    time.Time `json:"failed_at"`
+}
+
+type Sender struct {
+	transport    Transport
+	renderer     *Renderer
+	producer     *producer.Producer
+	preferences  *preferences.Store
+	unsubscriber *preferences.Unsubscriber
+	from         mail.Address
+}
+
+func NewSender(transport Transport, renderer *Renderer, producer *producer.Producer, prefs *preferences.Store, unsubscriber *preferences.Unsubscriber, cfg *configs.EmailConfig) *Sender {
+	return &Sender{
+		transport:    transport,
+		renderer:     renderer,
+		producer:     producer,
+		preferences:  prefs,
+		unsubscriber: unsubscriber,
+		from:         mail.Address{Name: cfg.FromName, Address: cfg.From},
+	}
+}
+
+// Send renders and delivers req, unless the recipient turned its category
+// off. Only mandatory emails are sent without a UserID, as there are no
+// preferences to check. Permanent failures (see IsPermanent) are reported on
+// notification.email.failed before being returned; other errors are left to
+// the consumer to retry, and a message that exhausts the retry tiers is
+// found in the dead-letter topic. An email held back by quiet hours returns
+// a *preferences.QuietHoursError.
+func (s *Sender) Send(ctx context.Context, req Request) error {
+	start := time.Now()
+	label := req.Template
+	if !s.renderer.Has(label) {
+		label = "unknown"
+	}
+
+	category, err := ResolveCategory(req.Template, req.Category)
+	if err == nil && req.UserID == "" && !preferences.Mandatory(preferences.ChannelEmail, category) {
+		err = permanent(fmt.Errorf("email: %s emails need a user_id to check preferences", category))
+	}
+	if err != nil {
+		s.fail(ctx, req, label, "", err, start)
+		return err
+	}
+	req.Category = category
+
+	decision, until, err := s.preferences.Check(ctx, req.UserID, preferences.ChannelEmail, req.Category)
+	if err != nil {
+		metrics.ObserveEmail(label, "error", time.Since(start))
+		return err
+	}
+	switch decision {
+	case preferences.OptedOut:
+		metrics.ObserveEmail(label, "suppressed", time.Since(start))
+		lg.InfoContext(ctx, "Email suppressed by preferences", "template", req.Template, "category", req.Category, "user_id", req.UserID)
+		return nil
+	case preferences.Quiet:
+		metrics.ObserveEmail(label, "deferred", time.Since(start))
+		lg.InfoContext(ctx, "Email held back by quiet hours", "template", req.Template, "category", req.Category, "user_id", req.UserID, "until", until)
+		return &preferences.QuietHoursError{Until: until}
+	}
+
+	messageID, providerID, locale, err := s.send(ctx, req)
+	if err != nil {
+		if IsPermanent(err) {
+			s.fail(ctx, req, label, messageID, err, start)
+		} else {
+			metrics.ObserveEmail(label, "error", time.Since(start))
+		}
+		return err
+	}
+
+	metrics.ObserveEmail(label, "sent", time.Since(start))
+	lg.InfoContext(ctx, "Email sent", "template", req.Template, "locale", locale, "email", req.To, "message_id", messageID, "provider_message_id", providerID)
+	// The email is out: failing to report it must not get it sent again.
+	s.publish(ctx, envelope.TopicEmailSent, req.UserID, Sent{
+		MessageID:         messageID,
+		ProviderMessageID: providerID,
+		CausationID:       req.CausationID,
+		UserID:            req.UserID,
+		Email:             req.To,
+		Template:          req.Template,
+		Locale:            locale,
+		SentAt:            time.Now().UTC(),
+	})
+	return nil
+}
+
+// fail reports a permanent failure on notification.email.failed.
+func (s *Sender) fail(ctx context.Context, req Request, label, messageID string, err error, start time.Time) {
+	metrics.ObserveEmail(label, "failed", time.Since(start))
+	lg.WarnContext(ctx, "Email failed permanently", "template", req.Template, "email", req.To, "error", err)
+	s.publish(ctx, envelope.TopicEmailFailed, req.UserID, Failed{
+		MessageID:   messageID,
+		CausationID: req.CausationID,
+		UserID:      req.UserID,
+		Email:       req.To,
+		Template:    req.Template,
+		Error:       err.Error(),
+		FailedAt:    time.Now().UTC(),
+	})
+}
+
+func (s *Sender) send(ctx context.Context, req Request) (messageID, providerID, locale string, err error) {
+	to, err := mail.ParseAddress(req.To)
+	if err != nil {
+		return "", "", "", permanent(err)
+	}
+	if req.Name != "" {
+		to.Name = req.Name
+	}
+
+	unsubscribeURL := s.unsubscriber.URL(req.UserID, preferences.ChannelEmail, req.Category)
+	rendered, err := s.renderer.Render(req.Template, req.Data, Options{Locale: req.Locale, UnsubscribeURL: unsubscribeURL})
+	if err != nil {
+		return "", "", "", err
+	}
+	headers := req.Headers
+	if unsubscribeURL != "" {
+		// RFC 8058 one-click unsubscribe: mail clients POST
+		// "List-Unsubscribe=One-Click" to the URL.
+		headers = make(map[string]string, len(req.Headers)+2)
+		for k, v := range req.Headers {
+			headers[k] = v
+		}
+		headers["List-Unsubscribe"] = "<" + unsubscribeURL + ">"
+		headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	}
+
+	msg := &Message{
+		From:     s.from,
+		To:       *to,
+		Subject:  rendered.Subject,
+		Text:     rendered.Text,
+		HTML:     rendered.HTML,
+		Language: rendered.Locale,
+		Headers:  headers,
+	}
+	messageID = NewMessageID(s.from.Address)
+	if req.CausationID != "" {
+		messageID = MessageIDFor(req.CausationID+"\n"+req.Template+"\n"+to.Address, s.from.Address)
+	}
+	raw, err := msg.Encode(messageID, time.Now())
+	if err != nil {
+		return "", "", "", err
+	}
+
+	providerID, err = s.transport.Send(ctx, s.from.Address, []string{to.Address}, raw)
+	if err != nil {
+		return messageID, "", rendered.Locale, classify(err)
+	}
+	return messageID, providerID, rendered.Locale, nil
+}
+
+func (s *Sender) publish(ctx context.Context, topic envelope.Topic, key string, data any) {
+	env, err := envelope.NewEnvelope(source, envelope.PriorityNormal, data)
+	if err == nil {
+		if key != "" {
+			env.Metadata = &envelope.Metadata{UserID: key}
+		}
+		tracing.StampEnvelope(ctx, env)
+		var b []byte
+		if b, err = env.Marshal(); err == nil {
+			err = s.producer.Publish(ctx, topic.String(), key, b)
+		}
+	}
+	if err != nil {
+		lg.ErrorContext(ctx, "Failed to publish email outcome", "topic", topic, "error", err)
+	}
+}
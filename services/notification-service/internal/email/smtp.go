@@ -0,0 +1,161 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"notification/configs"
+	"strings"
+	"time"
+)
+
+// Transport delivers an encoded message. It returns the identifier the
+// provider assigned to it, or "" if it gave none.
+type Transport interface {
+	Send(ctx context.Context, from string, to []string, msg []byte) (string, error)
+}
+
+// SMTPTransport opens one SMTP connection per message.
+type SMTPTransport struct {
+	cfg *configs.EmailConfig
+}
+
+func NewSMTPTransport(cfg *configs.EmailConfig) *SMTPTransport {
+	return &SMTPTransport{cfg: cfg}
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, from string, to []string, msg []byte) (string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > t.cfg.Timeout {
+		deadline = time.Now().Add(t.cfg.Timeout)
+	}
+	conn, err := t.dial(ctx, deadline)
+	if err != nil {
+		return "", err
+	}
+	// The deadline bounds the whole exchange: net/smtp has no context support.
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return "", err
+	}
+
+	c, err := smtp.NewClient(conn, t.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	defer c.Close()
+	return t.deliver(c, from, to, msg)
+}
+
+// deliver runs the SMTP exchange on a connected client.
+func (t *SMTPTransport) deliver(c *smtp.Client, from string, to []string, msg []byte) (string, error) {
+	if t.cfg.TLS == configs.SMTPTLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return "", permanent(errors.New("smtp: server does not support STARTTLS"))
+		}
+		if err := c.StartTLS(t.tlsConfig()); err != nil {
+			return "", err
+		}
+	}
+	if t.cfg.Username != "" {
+		// PlainAuth refuses to send credentials over a connection that is
+		// neither TLS nor to localhost. That is a configuration error, so
+		// it is checked here rather than retried.
+		if _, isTLS := c.TLSConnectionState(); !isTLS && !isLocalhost(t.cfg.Host) {
+			return "", permanent(fmt.Errorf("smtp: refusing to send credentials to %s without TLS", t.cfg.Host))
+		}
+		if err := c.Auth(smtp.PlainAuth("", t.cfg.Username, t.cfg.Password, t.cfg.Host)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return "", err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return "", err
+		}
+	}
+	reply, err := data(c, msg)
+	if err != nil {
+		return "", err
+	}
+	// The message is accepted; a failing QUIT does not change that.
+	_ = c.Quit()
+	return queueID(reply), nil
+}
+
+// isLocalhost matches the hosts net/smtp's PlainAuth sends credentials to
+// without TLS.
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func (t *SMTPTransport) dial(ctx context.Context, deadline time.Time) (net.Conn, error) {
+	addr := net.JoinHostPort(t.cfg.Host, t.cfg.Port)
+	d := &net.Dialer{Deadline: deadline}
+	if t.cfg.TLS == configs.SMTPTLSImplicit {
+		td := &tls.Dialer{NetDialer: d, Config: t.tlsConfig()}
+		return td.DialContext(ctx, "tcp", addr)
+	}
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+func (t *SMTPTransport) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: t.cfg.Host, MinVersion: tls.VersionTLS12}
+}
+
+// data sends DATA and the message and returns the server's final reply.
+// Client.Data discards that reply, which is where servers report the queue
+// ID of the message.
+func data(c *smtp.Client, msg []byte) (string, error) {
+	id, err := c.Text.Cmd("DATA")
+	if err != nil {
+		return "", err
+	}
+	c.Text.StartResponse(id)
+	_, _, err = c.Text.ReadResponse(354)
+	c.Text.EndResponse(id)
+	if err != nil {
+		return "", err
+	}
+
+	w := c.Text.DotWriter()
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	_, reply, err := c.Text.ReadResponse(250)
+	return reply, err
+}
+
+// queueID extracts the ID from replies such as "2.0.0 Ok: queued as
+// 4C3D2A1B", and falls back to the whole reply.
+func queueID(reply string) string {
+	if _, id, ok := strings.Cut(reply, "queued as "); ok {
+		if f := strings.Fields(id); len(f) > 0 {
+			return f[0]
+		}
+	}
+	return strings.TrimSpace(reply)
+}
+
+// classify marks SMTP 5xx replies as permanent: the server will refuse the
+// message again. Everything else, 4xx and connection errors included, is
+// worth retrying.
+func classify(err error) error {
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) && tpErr.Code >= 500 {
+		return permanent(fmt.Errorf("smtp: %w", err))
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"notification/configs"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mailpit is a stand-in for the Mailpit container: a plain SMTP server that
+// accepts every message, except for the recipients listed in reject, and
+// keeps what it received.
+type mailpit struct {
+	ln     net.Listener
+	reject map[string]string
+
+	mu       sync.Mutex
+	commands []string
+	messages []received
+}
+
+type received struct {
+	from string
+	to   []string
+	data string
+}
+
+func newMailpit(t *testing.T) *mailpit {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mailpit{ln: ln, reject: map[string]string{}}
+	t.Cleanup(func() { ln.Close() })
+	go m.serve()
+	return m
+}
+
+func (m *mailpit) port() string {
+	_, port, _ := net.SplitHostPort(m.ln.Addr().String())
+	return port
+}
+
+func (m *mailpit) serve() {
+	for {
+		conn, err := m.ln.Accept()
+		if err != nil {
+			return
+		}
+		go m.handle(conn)
+	}
+}
+
+func (m *mailpit) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+	reply("220 mailpit ESMTP")
+	var msg received
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		m.mu.Lock()
+		m.commands = append(m.commands, line)
+		m.mu.Unlock()
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-mailpit")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			msg = received{from: address(arg)}
+			reply("250 2.1.0 Ok")
+		case "RCPT":
+			rcpt := address(arg)
+			if rej, ok := m.reject[rcpt]; ok {
+				reply(rej)
+				continue
+			}
+			msg.to = append(msg.to, rcpt)
+			reply("250 2.1.5 Ok")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var body strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				body.WriteString(l)
+			}
+			msg.data = body.String()
+			m.mu.Lock()
+			m.messages = append(m.messages, msg)
+			m.mu.Unlock()
+			reply("250 2.0.0 Ok: queued as 4C3D2A1B")
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+func (m *mailpit) received() []received {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]received(nil), m.messages...)
+}
+
+func (m *mailpit) saw(verb string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.commands {
+		if strings.HasPrefix(strings.ToUpper(c), verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// address extracts the address from "FROM:<a@b>" or "TO:<a@b>".
+func address(arg string) string {
+	_, a, _ := strings.Cut(arg, "<")
+	a, _, _ = strings.Cut(a, ">")
+	return a
+}
+
+func testEmailConfig(port string) *configs.EmailConfig {
+	return &configs.EmailConfig{
+		Host:    "127.0.0.1",
+		Port:    port,
+		TLS:     configs.SMTPTLSNone,
+		Timeout: 5 * time.Second,
+	}
+}
+
+func TestSMTPTransportSend(t *testing.T) {
+	mp := newMailpit(t)
+	tr := NewSMTPTransport(testEmailConfig(mp.port()))
+
+	msg := &Message{
+		To:      mail.Address{Address: "a@example.com"},
+		From:    mail.Address{Address: "no-reply@example.com"},
+		Subject: "Welcome",
+		Text:    "Hi",
+		HTML:    "<p>Hi</p>",
+	}
+	raw, err := msg.Encode("<id@example.com>", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := tr.Send(context.Background(), "no-reply@example.com", []string{"a@example.com"}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "4C3D2A1B" {
+		t.Errorf("provider ID = %q; want the queue ID", id)
+	}
+
+	got := mp.received()
+	if len(got) != 1 {
+		t.Fatalf("%d messages received; want 1", len(got))
+	}
+	if got[0].from != "no-reply@example.com" || len(got[0].to) != 1 || got[0].to[0] != "a@example.com" {
+		t.Errorf("envelope = %s -> %v", got[0].from, got[0].to)
+	}
+	if !strings.Contains(got[0].data, "Message-ID: <id@example.com>") || !strings.Contains(got[0].data, "Subject: Welcome") {
+		t.Errorf("message is missing its headers:\n%s", got[0].data)
+	}
+	if mp.saw("AUTH") {
+		t.Error("AUTH sent without credentials configured")
+	}
+}
+
+func TestSMTPTransportClassify(t *testing.T) {
+	mp := newMailpit(t)
+	mp.reject["gone@example.com"] = "550 5.1.1 No such user"
+	mp.reject["busy@example.com"] = "451 4.3.0 Try again later"
+	tr := NewSMTPTransport(testEmailConfig(mp.port()))
+
+	tests := []struct {
+		to            string
+		wantPermanent bool
+	}{
+		{"gone@example.com", true},
+		{"busy@example.com", false},
+	}
+	for _, tt := range tests {
+		_, err := tr.Send(context.Background(), "no-reply@example.com", []string{tt.to}, []byte("Subject: x\r\n\r\nx\r\n"))
+		if err == nil {
+			t.Fatalf("Send(%s) succeeded; want an error", tt.to)
+		}
+		if got := IsPermanent(classify(err)); got != tt.wantPermanent {
+			t.Errorf("Send(%s) permanent = %v; want %v (%v)", tt.to, got, tt.wantPermanent, err)
+		}
+	}
+	if len(mp.received()) != 0 {
+		t.Error("a rejected message was delivered")
+	}
+
+	// Connection errors are worth retrying.
+	mp.ln.Close()
+	_, err := tr.Send(context.Background(), "no-reply@example.com", []string{"a@example.com"}, nil)
+	if err == nil || IsPermanent(classify(err)) {
+		t.Errorf("Send to a closed port = %v; want a transient error", err)
+	}
+}
+
+func TestSMTPTransportAuth(t *testing.T) {
+	mp := newMailpit(t)
+
+	// Credentials go to localhost without TLS, as with Mailpit.
+	cfg := testEmailConfig(mp.port())
+	cfg.Username, cfg.Password = "user", "secret"
+	if _, err := NewSMTPTransport(cfg).Send(context.Background(), "no-reply@example.com", []string{"a@example.com"}, []byte("x\r\n")); err != nil {
+		t.Fatalf("Send with credentials to localhost = %v", err)
+	}
+	if !mp.saw("AUTH PLAIN") {
+		t.Error("credentials were not sent to localhost")
+	}
+
+	// A remote host without TLS never gets them, and retrying cannot fix it.
+	mp = newMailpit(t)
+	conn, err := net.Dial("tcp", mp.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := smtp.NewClient(conn, "smtp.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	remote := testEmailConfig(mp.port())
+	remote.Host = "smtp.example.com"
+	remote.Username, remote.Password = "user", "secret"
+	_, err = NewSMTPTransport(remote).deliver(c, "no-reply@example.com", []string{"a@example.com"}, []byte("x\r\n"))
+	if !IsPermanent(classify(err)) {
+		t.Errorf("deliver to a remote host without TLS = %v; want a permanent error", err)
+	}
+	if mp.saw("AUTH") || mp.saw("MAIL") {
+		t.Error("the exchange went on after refusing to authenticate")
+	}
+}
+
+func TestQueueID(t *testing.T) {
+	tests := []struct{ reply, want string }{
+		{"2.0.0 Ok: queued as 4C3D2A1B", "4C3D2A1B"},
+		{"Ok: queued as 01HABC <x>", "01HABC"},
+		{" 2.0.0 accepted ", "2.0.0 accepted"},
+	}
+	for _, tt := range tests {
+		if got := queueID(tt.reply); got != tt.want {
+			t.Errorf("queueID(%q) = %q; want %q", tt.reply, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"notification/internal/i18n"
+	"notification/internal/preferences"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Template names. Each has a <name>.txt file defining "subject" and "text",
+// and a <name>.html file defining "content", rendered inside layout.html.
+// Wording comes from the i18n catalog through the t, plural, number, date,
+// datetime and duration template functions.
+const (
+	TemplateWelcome       = "welcome"
+	TemplatePasswordReset = "password_reset"
+)
+
+// templateCategories is the preference category each template belongs to.
+var templateCategories = map[string]preferences.Category{
+	TemplateWelcome:       preferences.CategoryAccount,
+	TemplatePasswordReset: preferences.CategorySecurity,
+}
+
+// TemplateCategory returns the preference category of template name, or ""
+// for unknown templates.
+func TemplateCategory(name string) preferences.Category {
+	return templateCategories[name]
+}
+
+// ResolveCategory returns the preference category of a request for
+// template. A requested category must be the template's own: a welcome email
+// sent as "security" would skip the recipient's opt-out. For a template
+// without a category, the requested one is used if it is known.
+func ResolveCategory(template string, requested preferences.Category) (preferences.Category, error) {
+	category := TemplateCategory(template)
+	switch {
+	case category == "":
+		category = requested
+	case requested != "" && requested != category:
+		return "", permanent(fmt.Errorf("email: template %q is in category %q, not %q", template, category, requested))
+	}
+	if _, ok := preferences.ParseCategory(string(category)); !ok {
+		return "", permanent(fmt.Errorf("email: template %q needs a known category, got %q", template, category))
+	}
+	return category, nil
+}
+
+// Options are the per-recipient parts of a rendering.
+type Options struct {
+	// Locale is the recipient's locale; see Render.
+	Locale string
+	// UnsubscribeURL, if set, is linked at the bottom of the email.
+	UnsubscribeURL string
+}
+
+// Rendered is the output of a template.
+type Rendered struct {
+	// Locale is the locale the template was rendered in.
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// emailTemplate is a template parsed once per locale, with the template
+// functions bound to that locale.
+type emailTemplate struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// Renderer renders the embedded templates. They are parsed once, so a broken
+// template stops the service at startup rather than at send time.
+type Renderer struct {
+	catalog   *i18n.Catalog
+	templates map[string]emailTemplate
+}
+
+func NewRenderer(catalog *i18n.Catalog) (*Renderer, error) {
+	funcs := catalog.Localizer(i18n.Fallback).FuncMap()
+	layout, err := htmltemplate.New("layout.html").Option("missingkey=error").Funcs(funcs).ParseFS(templateFS, "templates/layout.html")
+	if err != nil {
+		return nil, err
+	}
+	files, err := fs.Glob(templateFS, "templates/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{catalog: catalog, templates: make(map[string]emailTemplate, len(files))}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+		text, err := texttemplate.New(name).Option("missingkey=error").Funcs(funcs).ParseFS(templateFS, file)
+		if err != nil {
+			return nil, err
+		}
+		html, err := layout.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := html.ParseFS(templateFS, "templates/"+name+".html"); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+		for _, t := range []string{"subject", "text"} {
+			if text.Lookup(t) == nil {
+				return nil, fmt.Errorf("template %s: %s is not defined", name, t)
+			}
+		}
+		if r.templates[name], err = localize(catalog, text, html); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+	return r, nil
+}
+
+func localize(catalog *i18n.Catalog, text *texttemplate.Template, html *htmltemplate.Template) (emailTemplate, error) {
+	t := emailTemplate{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	for _, locale := range catalog.Locales() {
+		funcs := catalog.Localizer(locale).FuncMap()
+		tc, err := text.Clone()
+		if err != nil {
+			return t, err
+		}
+		hc, err := html.Clone()
+		if err != nil {
+			return t, err
+		}
+		t.text[locale] = tc.Funcs(funcs)
+		t.html[locale] = hc.Funcs(funcs)
+	}
+	return t, nil
+}
+
+// Names returns the available templates, sorted.
+func (r *Renderer) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether template name exists.
+func (r *Renderer) Has(name string) bool {
+	_, ok := r.templates[name]
+	return ok
+}
+
+// Locales returns the locales templates can be rendered in, sorted.
+func (r *Renderer) Locales() []string {
+	return r.catalog.Locales()
+}
+
+// Render executes template name with data in opts.Locale. Unsupported
+// locales fall back to English, as do messages a locale does not translate.
+// Unknown templates and data that does not fit the template are permanent
+// errors.
+func (r *Renderer) Render(name string, data any, opts Options) (*Rendered, error) {
+	t, ok := r.templates[name]
+	if !ok {
+		return nil, permanent(fmt.Errorf("email: unknown template %q", name))
+	}
+	locale := r.catalog.Match(opts.Locale)
+
+	var subject, text, html bytes.Buffer
+	if err := t.text[locale].ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, permanent(err)
+	}
+	if err := t.text[locale].ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, permanent(err)
+	}
+	out := &Rendered{
+		Locale:  locale,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}
+	if opts.UnsubscribeURL != "" {
+		footer, err := r.catalog.Localizer(locale).T("email.unsubscribe_text", "url", opts.UnsubscribeURL)
+		if err != nil {
+			return nil, permanent(err)
+		}
+		out.Text += "\n--\n" + footer + "\n"
+	}
+	view := struct {
+		Subject        string
+		UnsubscribeURL string
+		Data           any
+	}{out.Subject, opts.UnsubscribeURL, data}
+	if err := t.html[locale].ExecuteTemplate(&html, "layout", view); err != nil {
+		return nil, permanent(err)
+	}
+	out.HTML = html.String()
+	return out, nil
+}
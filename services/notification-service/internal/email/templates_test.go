@@ -0,0 +1,34 @@
+package email
+
+import (
+	"notification/internal/preferences"
+	"testing"
+)
+
+func TestResolveCategory(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		requested preferences.Category
+		want      preferences.Category
+		wantErr   bool
+	}{
+		{"template default", TemplateWelcome, "", preferences.CategoryAccount, false},
+		{"same as template", TemplatePasswordReset, preferences.CategorySecurity, preferences.CategorySecurity, false},
+		// Sending an optional email as security would skip the opt-out.
+		{"escalated", TemplateWelcome, preferences.CategorySecurity, "", true},
+		{"downgraded", TemplatePasswordReset, preferences.CategoryMarketing, "", true},
+		{"unknown template", "newsletter", preferences.CategoryMarketing, preferences.CategoryMarketing, false},
+		{"unknown template without category", "newsletter", "", "", true},
+		{"unknown category", "newsletter", "gossip", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveCategory(tt.template, tt.requested)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s: ResolveCategory = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !IsPermanent(err) {
+			t.Errorf("%s: %v is not permanent", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"notification/internal/email"
+	"notification/internal/inbox"
+	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/envelope"
+	"notification/internal/preferences"
+)
+
+// EmailSend is the data of a notification.email.send event, which lets other
+// services send one of the embedded templates.
+type EmailSend struct {
+	To       string         `json:"to"`
+	Name     string         `json:"name"`
+	Template string         `json:"template"`
+	Locale   string         `json:"locale"`
+	Data     map[string]any `json:"data"`
+	UserID   string         `json:"user_id"`
+	// Category is the preference category. It defaults to the template's
+	// and, when set, must match it.
+	Category string `json:"category"`
+}
+
+// EmailSend sends the email, and for a user whose template has inbox texts,
+// puts it in their inbox too. The texts' placeholders are filled from Data.
+func (h *Handlers) EmailSend(ctx context.Context, env *envelope.Envelope, data EmailSend) error {
+	if data.To == "" || data.Template == "" {
+		return consumer.Permanent(fmt.Errorf("notification.email.send %s: to and template are required", env.MessageID))
+	}
+	// A category that is unknown or not the template's is left to the email
+	// sender, which reports it on notification.email.failed.
+	category, err := email.ResolveCategory(data.Template, preferences.Category(data.Category))
+	if err == nil && data.UserID != "" && h.inbox.Has(data.Template) {
+		if err := h.notify(ctx, env, inbox.Request{
+			UserID:   data.UserID,
+			Kind:     data.Template,
+			Category: category,
+			Locale:   data.Locale,
+			Args:     data.Data,
+		}); err != nil {
+			return err
+		}
+	}
+	return h.sendEmail(ctx, env, email.Request{
+		To:       data.To,
+		Name:     data.Name,
+		Template: data.Template,
+		Locale:   data.Locale,
+		Category: preferences.Category(data.Category),
+		Data:     data.Data,
+		UserID:   data.UserID,
+	})
+}
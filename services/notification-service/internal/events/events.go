@@ -0,0 +1,73 @@
+// Package events holds the handlers for the domain events the notification
+// service consumes, and the payload types they decode.
+package events
+
+import (
+	"context"
+	"errors"
+	"music-player/api/logger"
+	"notification/configs"
+	"notification/internal/email"
+	"notification/internal/inbox"
+	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/envelope"
+	"notification/internal/preferences"
+	"notification/internal/push"
+	"notification/internal/sms"
+)
+
+var lg = logger.For("events")
+
+// Handlers processes domain events.
+type Handlers struct {
+	email *email.Sender
+	inbox *inbox.Notifier
+	push  *push.Sender
+	sms   *sms.Sender
+	// linkBaseURL is the web app origin links in notifications point to.
+	linkBaseURL string
+}
+
+func NewHandlers(sender *email.Sender, notifier *inbox.Notifier, pushSender *push.Sender, smsSender *sms.Sender, emailCfg *configs.EmailConfig) *Handlers {
+	return &Handlers{email: sender, inbox: notifier, push: pushSender, sms: smsSender, linkBaseURL: emailCfg.LinkBaseURL}
+}
+
+// Register adds a handler for every consumed topic to reg.
+func (h *Handlers) Register(reg *consumer.Registry) {
+	consumer.Register(reg, envelope.TopicUserRegistered, h.UserRegistered)
+	consumer.Register(reg, envelope.TopicUserPasswordReset, h.PasswordResetRequested)
+	consumer.Register(reg, envelope.TopicEmailSend, h.EmailSend)
+	consumer.Register(reg, envelope.TopicPushSend, h.PushSend)
+	consumer.Register(reg, envelope.TopicSMSSend, h.SMSSend)
+}
+
+// notify puts req in the user's inbox. It runs before the email of the same
+// event: a retry after a failed email finds the notification already there
+// rather than sending the email twice.
+func (h *Handlers) notify(ctx context.Context, env *envelope.Envelope, req inbox.Request) error {
+	req.SourceID = env.MessageID
+	return h.inbox.Notify(ctx, req)
+}
+
+// sendEmail sends req; see consumerError.
+func (h *Handlers) sendEmail(ctx context.Context, env *envelope.Envelope, req email.Request) error {
+	req.CausationID = env.MessageID
+	err := h.email.Send(ctx, req)
+	return consumerError(err, email.IsPermanent(err))
+}
+
+// consumerError marks the error of a sender for the consumer: permanent
+// failures are dead-lettered without retries, and notifications held back
+// by quiet hours are deferred until the quiet hours end.
+func consumerError(err error, permanent bool) error {
+	var quiet *preferences.QuietHoursError
+	switch {
+	case err == nil:
+		return nil
+	case permanent:
+		return consumer.Permanent(err)
+	case errors.As(err, &quiet):
+		return consumer.Defer(err, quiet.Until)
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"notification/internal/email"
+	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/envelope"
+	"notification/internal/preferences"
+	"notification/internal/push"
+)
+
+// PushSend is the data of a notification.push.send event, which sends a
+// push notification to every browser of a user. The envelope's priority
+// sets its TTL and urgency.
+type PushSend struct {
+	UserID   string         `json:"user_id"`
+	Template string         `json:"template"`
+	Locale   string         `json:"locale"`
+	Data     map[string]any `json:"data"`
+	// Category is the preference category. It defaults to the template's
+	// and, when set, must match it.
+	Category string `json:"category"`
+	URL      string `json:"url"`
+	Tag      string `json:"tag"`
+}
+
+// PushSend sends the push notification. Its texts are those of the in-app
+// notification of the same template, filled from Data.
+func (h *Handlers) PushSend(ctx context.Context, env *envelope.Envelope, data PushSend) error {
+	if data.UserID == "" || data.Template == "" {
+		return consumer.Permanent(fmt.Errorf("notification.push.send %s: user_id and template are required", env.MessageID))
+	}
+	category, err := email.ResolveCategory(data.Template, preferences.Category(data.Category))
+	if err != nil {
+		return consumer.Permanent(fmt.Errorf("notification.push.send %s: %w", env.MessageID, err))
+	}
+	err = h.push.Send(ctx, push.Request{
+		UserID:      data.UserID,
+		Template:    data.Template,
+		Category:    category,
+		Locale:      data.Locale,
+		Args:        data.Data,
+		URL:         data.URL,
+		Tag:         data.Tag,
+		Priority:    env.Priority,
+		CausationID: env.MessageID,
+	})
+	return consumerError(err, push.IsPermanent(err))
+}
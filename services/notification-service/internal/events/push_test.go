@@ -0,0 +1,29 @@
+package events
+
+import (
+	"context"
+	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/envelope"
+	"testing"
+)
+
+func TestPushSendRejectsCategory(t *testing.T) {
+	h := &Handlers{}
+	env := &envelope.Envelope{MessageID: "m1"}
+
+	tests := []struct {
+		name string
+		data PushSend
+	}{
+		{"missing user", PushSend{Template: "welcome"}},
+		// A welcome sent as security would skip the user's opt-out.
+		{"not the template's category", PushSend{UserID: "u1", Template: "welcome", Category: "security"}},
+		{"unknown category", PushSend{UserID: "u1", Template: "promo", Category: "nope"}},
+		{"template without a category", PushSend{UserID: "u1", Template: "promo"}},
+	}
+	for _, tt := range tests {
+		if err := h.PushSend(context.Background(), env, tt.data); !consumer.IsPermanent(err) {
+			t.Errorf("%s: PushSend = %v; want a permanent error", tt.name, err)
+		}
+	}
+}
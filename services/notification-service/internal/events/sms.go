@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/envelope"
+	"notification/internal/preferences"
+	"notification/internal/sms"
+)
+
+// SMSSend is the data of a notification.sms.send event, which texts one
+// number.
+type SMSSend struct {
+	// To is an E.164 number.
+	To string `json:"to"`
+	// UserID is the recipient whose preferences apply, if any.
+	UserID   string         `json:"user_id"`
+	Template string         `json:"template"`
+	Locale   string         `json:"locale"`
+	Data     map[string]any `json:"data"`
+	// Category is the preference category. It defaults to the template's
+	// and, when set, must match it.
+	Category string `json:"category"`
+}
+
+// SMSSend sends the text message.
+func (h *Handlers) SMSSend(ctx context.Context, env *envelope.Envelope, data SMSSend) error {
+	if data.To == "" || data.Template == "" {
+		return consumer.Permanent(fmt.Errorf("notification.sms.send %s: to and template are required", env.MessageID))
+	}
+	category, err := sms.ResolveCategory(data.Template, preferences.Category(data.Category))
+	if err != nil {
+		return consumer.Permanent(fmt.Errorf("notification.sms.send %s: %w", env.MessageID, err))
+	}
+	err = h.sms.Send(ctx, sms.Request{
+		To:          data.To,
+		UserID:      data.UserID,
+		Template:    data.Template,
+		Category:    category,
+		Locale:      data.Locale,
+		Args:        data.Data,
+		CausationID: env.MessageID,
+	})
+	return consumerError(err, sms.IsPermanent(err))
+}
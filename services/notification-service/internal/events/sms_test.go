@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/envelope"
+	"notification/internal/sms"
+	"testing"
+)
+
+func TestSMSSendRejectsCategory(t *testing.T) {
+	h := &Handlers{}
+	env := &envelope.Envelope{MessageID: "m1"}
+
+	tests := []struct {
+		name string
+		data SMSSend
+	}{
+		{"missing number", SMSSend{Template: sms.TemplateLoginCode}},
+		{"missing template", SMSSend{To: "+84912345678"}},
+		// A code sent as marketing would be held back by quiet hours.
+		{"not the template's category", SMSSend{To: "+84912345678", Template: sms.TemplateLoginCode, Category: "marketing"}},
+		{"unknown category", SMSSend{To: "+84912345678", Template: "promo", Category: "nope"}},
+		{"template without a category", SMSSend{To: "+84912345678", Template: "promo"}},
+	}
+	for _, tt := range tests {
+		if err := h.SMSSend(context.Background(), env, tt.data); !consumer.IsPermanent(err) {
+			t.Errorf("%s: SMSSend = %v; want a permanent error", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"notification/internal/email"
+	"notification/internal/inbox"
+	"notification/internal/kafka/consumer"
+	"notification/internal/kafka/envelope"
+	"time"
+)
+
+// UserRegistered is the data of a user.registered event published by
+// auth-service.
+type UserRegistered struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	FullName  string `json:"full_name"`
+	Locale    string `json:"locale"`
+	CreatedAt string `json:"created_at"`
+}
+
+// UserRegistered welcomes the user in the inbox and by email.
+func (h *Handlers) UserRegistered(ctx context.Context, env *envelope.Envelope, data UserRegistered) error {
+	if data.UserID == "" || data.Email == "" {
+		return consumer.Permanent(fmt.Errorf("user.registered %s: user_id and email are required", env.MessageID))
+	}
+	lg.InfoContext(ctx, "User registered", "user_id", data.UserID, "source", env.Source)
+
+	if err := h.notify(ctx, env, inbox.Request{
+		UserID:   data.UserID,
+		Kind:     email.TemplateWelcome,
+		Category: email.TemplateCategory(email.TemplateWelcome),
+		Locale:   data.Locale,
+		Args:     map[string]any{"username": data.Username},
+	}); err != nil {
+		return err
+	}
+	return h.sendEmail(ctx, env, email.Request{
+		To:       data.Email,
+		Name:     data.FullName,
+		Template: email.TemplateWelcome,
+		Locale:   data.Locale,
+		UserID:   data.UserID,
+		Data: map[string]any{
+			"Name":     displayName(data.FullName, data.Username),
+			"Username": data.Username,
+			"LoginURL": h.linkBaseURL + "/login",
+		},
+	})
+}
+
+// PasswordResetRequested is the data of a user.password_reset_requested
+// event. Token is the single-use reset token; the link to the web app is
+// built here.
+type PasswordResetRequested struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	Locale    string    `json:"locale"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PasswordResetRequested sends the password reset link, and tells the inbox
+// a reset was asked for without the link itself. A request that has already
+// expired, for instance one replayed from the dead-letter topic, is
+// dropped rather than sending a dead link.
+func (h *Handlers) PasswordResetRequested(ctx context.Context, env *envelope.Envelope, data PasswordResetRequested) error {
+	if data.UserID == "" || data.Email == "" || data.Token == "" {
+		return consumer.Permanent(fmt.Errorf("user.password_reset_requested %s: user_id, email and token are required", env.MessageID))
+	}
+	remaining := time.Until(data.ExpiresAt)
+	if remaining <= 0 {
+		return consumer.Permanent(fmt.Errorf("user.password_reset_requested %s: token expired at %s", env.MessageID, data.ExpiresAt))
+	}
+
+	if err := h.notify(ctx, env, inbox.Request{
+		UserID:   data.UserID,
+		Kind:     email.TemplatePasswordReset,
+		Category: email.TemplateCategory(email.TemplatePasswordReset),
+		Locale:   data.Locale,
+	}); err != nil {
+		return err
+	}
+
+	return h.sendEmail(ctx, env, email.Request{
+		To:       data.Email,
+		Name:     data.FullName,
+		Template: email.TemplatePasswordReset,
+		Locale:   data.Locale,
+		UserID:   data.UserID,
+		Data: map[string]any{
+			"Name":      displayName(data.FullName, data.Username),
+			"ResetURL":  h.linkBaseURL + "/reset-password?token=" + url.QueryEscape(data.Token),
+			"ExpiresIn": remaining,
+		},
+	})
+}
+
+func displayName(fullName, username string) string {
+	if fullName != "" {
+		return fullName
+	}
+	return username
+}
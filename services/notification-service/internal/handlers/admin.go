@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"notification/internal/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authorizeAdmin rejects requests without token as their bearer token.
+func authorizeAdmin(c *gin.Context, token string) {
+	got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		utils.Fail(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid admin token")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
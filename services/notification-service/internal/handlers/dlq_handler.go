@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"music-player/api/logger"
+	"net/http"
+	"notification/internal/kafka/dlq"
+	"notification/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var lg = logger.For("handlers")
+
+const (
+	dlqDefaultLimit   = 50
+	dlqMaxLimit       = 500
+	dlqRequestTimeout = 30 * time.Second
+)
+
+// DLQHandler lets operators inspect, replay and purge the dead-letter topic.
+// Every request must carry the DLQ admin token as a bearer token.
+type DLQHandler struct {
+	manager *dlq.Manager
+	token   string
+}
+
+func NewDLQHandler(manager *dlq.Manager, token string) *DLQHandler {
+	return &DLQHandler{manager: manager, token: token}
+}
+
+// Enabled reports whether an admin token is configured. Routes are not
+// registered otherwise.
+func (h *DLQHandler) Enabled() bool {
+	return h.token != ""
+}
+
+// Authorize rejects requests without the admin bearer token.
+func (h *DLQHandler) Authorize(c *gin.Context) {
+	authorizeAdmin(c, h.token)
+}
+
+// List returns the partition ranges and up to limit records, optionally of
+// one partition or one offset.
+func (h *DLQHandler) List(c *gin.Context) {
+	sel, ok := dlqSelection(c)
+	if !ok {
+		return
+	}
+	limit := dlqDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "limit must be a positive integer")
+			return
+		}
+		limit = min(n, dlqMaxLimit)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), dlqRequestTimeout)
+	defer cancel()
+	msgs, partitions, err := h.manager.List(ctx, sel, limit)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if msgs == nil {
+		msgs = []dlq.Message{}
+	}
+	utils.Success(c, http.StatusOK, msgs, gin.H{"topic": h.manager.Topic(), "partitions": partitions})
+}
+
+// Replay publishes the selected records back to their original topic.
+func (h *DLQHandler) Replay(c *gin.Context) {
+	sel, ok := dlqSelection(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), dlqRequestTimeout)
+	defer cancel()
+	n, err := h.manager.Replay(ctx, sel)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusOK, gin.H{"replayed": n})
+}
+
+// Purge deletes the selected partitions up to and including the selected
+// offset. Purging the whole topic requires all=true.
+func (h *DLQHandler) Purge(c *gin.Context) {
+	sel, ok := dlqSelection(c)
+	if !ok {
+		return
+	}
+	sel.All = c.Query("all") == "true"
+	ctx, cancel := context.WithTimeout(c.Request.Context(), dlqRequestTimeout)
+	defer cancel()
+	n, err := h.manager.Purge(ctx, sel)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusOK, gin.H{"purged": n})
+}
+
+func (h *DLQHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, dlq.ErrInvalidSelection):
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	case errors.Is(err, dlq.ErrPurgeUnconfirmed):
+		utils.Fail(c, http.StatusBadRequest, "CONFIRMATION_REQUIRED", "pass partition to purge one partition, or all=true to purge the whole topic")
+		return
+	}
+	lg.ErrorContext(c.Request.Context(), "DLQ admin request failed", "path", c.FullPath(), "error", err)
+	utils.Fail(c, http.StatusBadGateway, "KAFKA_ERROR", err.Error())
+}
+
+// dlqSelection reads the partition and offset query parameters.
+func dlqSelection(c *gin.Context) (dlq.Selection, bool) {
+	var sel dlq.Selection
+	if v := c.Query("partition"); v != "" {
+		p, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || p < 0 {
+			utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "partition must be a non-negative integer")
+			return sel, false
+		}
+		p32 := int32(p)
+		sel.Partition = &p32
+	}
+	if v := c.Query("offset"); v != "" {
+		o, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || o < 0 {
+			utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "offset must be a non-negative integer")
+			return sel, false
+		}
+		sel.Offset = &o
+	}
+	return sel, true
+}
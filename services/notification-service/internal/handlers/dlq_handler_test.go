@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"notification/internal/kafka/dlq"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDLQPurgeRequiresConfirmation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewDLQHandler(&dlq.Manager{}, "admin-token")
+	r := gin.New()
+	r.DELETE("/admin/dlq", h.Authorize, h.Purge)
+
+	tests := []struct {
+		name     string
+		query    string
+		token    string
+		wantCode int
+		wantBody string
+	}{
+		{"no token", "", "", http.StatusUnauthorized, ""},
+		{"whole topic without all", "", "admin-token", http.StatusBadRequest, "CONFIRMATION_REQUIRED"},
+		{"all=false", "?all=false", "admin-token", http.StatusBadRequest, "CONFIRMATION_REQUIRED"},
+		{"offset without partition", "?offset=3&all=true", "admin-token", http.StatusBadRequest, "INVALID_REQUEST"},
+		{"bad partition", "?partition=x", "admin-token", http.StatusBadRequest, "INVALID_REQUEST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/admin/dlq"+tt.query, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d; want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s; want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"notification/internal/inbox"
+	"notification/internal/middleware"
+	"notification/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InboxHandler serves the in-app notifications of the authenticated user.
+type InboxHandler struct {
+	store  *inbox.Store
+	events *inbox.Events
+}
+
+func NewInboxHandler(store *inbox.Store, events *inbox.Events) *InboxHandler {
+	return &InboxHandler{store: store, events: events}
+}
+
+// List returns a page of notifications, newest first. The filter query
+// parameter is all (default), unread or archived; cursor is the nextCursor
+// of the previous page. The unread count is returned in meta so the badge
+// can be refreshed with the list.
+func (h *InboxHandler) List(c *gin.Context) {
+	filter, ok := inbox.ParseFilter(c.DefaultQuery("filter", string(inbox.FilterAll)))
+	if !ok {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "filter must be all, unread or archived")
+		return
+	}
+	limit := inbox.DefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "limit must be a positive integer")
+			return
+		}
+		limit = min(n, inbox.MaxLimit)
+	}
+
+	ctx := c.Request.Context()
+	userID := middleware.UserID(c)
+	page, err := h.store.List(ctx, userID, inbox.Query{Filter: filter, Cursor: c.Query("cursor"), Limit: limit})
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	unread, err := h.store.UnreadCount(ctx, userID)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusOK, page.Items, gin.H{
+		"nextCursor":  page.NextCursor,
+		"unreadCount": unread,
+		"limit":       limit,
+	})
+}
+
+// UnreadCount returns the number for the bell badge.
+func (h *InboxHandler) UnreadCount(c *gin.Context) {
+	n, err := h.store.UnreadCount(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusOK, gin.H{"unreadCount": n})
+}
+
+// MarkRead marks notification :id as read and returns it.
+func (h *InboxHandler) MarkRead(c *gin.Context) {
+	h.change(c, h.store.MarkRead)
+}
+
+// Archive moves notification :id out of the inbox and returns it.
+func (h *InboxHandler) Archive(c *gin.Context) {
+	h.change(c, h.store.Archive)
+}
+
+// MarkAllRead marks every unread notification as read.
+func (h *InboxHandler) MarkAllRead(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := middleware.UserID(c)
+	n, err := h.store.MarkAllRead(ctx, userID)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if n > 0 {
+		h.events.ReadAll(ctx, userID)
+	}
+	utils.Success(c, http.StatusOK, gin.H{"updated": n})
+}
+
+func (h *InboxHandler) change(c *gin.Context, apply func(ctx context.Context, userID string, id int64) (*inbox.Notification, error)) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.Fail(c, http.StatusNotFound, "NOTIFICATION_NOT_FOUND", "Notification not found")
+		return
+	}
+	n, err := apply(c.Request.Context(), middleware.UserID(c), id)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.events.Updated(c.Request.Context(), n)
+	utils.Success(c, http.StatusOK, n)
+}
+
+func (h *InboxHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, inbox.ErrNotFound):
+		utils.Fail(c, http.StatusNotFound, "NOTIFICATION_NOT_FOUND", "Notification not found")
+	case errors.Is(err, inbox.ErrInvalidCursor):
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid cursor")
+	default:
+		lg.ErrorContext(c.Request.Context(), "Inbox request failed", "path", c.FullPath(), "error", err)
+		utils.Fail(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to access notifications")
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"notification/internal/middleware"
+	"notification/internal/preferences"
+	"notification/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreferencesHandler serves the notification preferences of the
+// authenticated user.
+type PreferencesHandler struct {
+	store *preferences.Store
+}
+
+func NewPreferencesHandler(store *preferences.Store) *PreferencesHandler {
+	return &PreferencesHandler{store: store}
+}
+
+// Get returns the user's preferences, defaults included.
+func (h *PreferencesHandler) Get(c *gin.Context) {
+	p, err := h.store.Get(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusOK, p)
+}
+
+// Update changes the fields present in the body and returns the result.
+func (h *PreferencesHandler) Update(c *gin.Context) {
+	var req preferences.Update
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	p, err := h.store.Update(c.Request.Context(), middleware.UserID(c), &req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusOK, p)
+}
+
+func (h *PreferencesHandler) fail(c *gin.Context, err error) {
+	if errors.Is(err, preferences.ErrInvalid) {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_PREFERENCES", err.Error())
+		return
+	}
+	lg.ErrorContext(c.Request.Context(), "Preferences request failed", "path", c.FullPath(), "error", err)
+	utils.Fail(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to access preferences")
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"notification/internal/middleware"
+	"notification/internal/push"
+	"notification/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PushHandler serves the Web Push subscriptions of the authenticated user.
+type PushHandler struct {
+	store  *push.Store
+	sender *push.Sender
+}
+
+func NewPushHandler(store *push.Store, sender *push.Sender) *PushHandler {
+	return &PushHandler{store: store, sender: sender}
+}
+
+// PublicKey returns the VAPID public key to pass as applicationServerKey to
+// pushManager.subscribe().
+func (h *PushHandler) PublicKey(c *gin.Context) {
+	if !h.requireEnabled(c) {
+		return
+	}
+	utils.Success(c, http.StatusOK, gin.H{"publicKey": h.sender.PublicKey()})
+}
+
+// List returns the user's subscribed browsers, most recent first.
+func (h *PushHandler) List(c *gin.Context) {
+	subs, err := h.store.List(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusOK, subs)
+}
+
+// Subscribe stores the PushSubscription in the body. Subscribing the same
+// endpoint again updates it.
+func (h *PushHandler) Subscribe(c *gin.Context) {
+	if !h.requireEnabled(c) {
+		return
+	}
+	var req push.Subscribe
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	req.UserAgent = c.Request.UserAgent()
+	sub, err := h.store.Save(c.Request.Context(), middleware.UserID(c), &req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusCreated, sub)
+}
+
+// Unsubscribe deletes the subscription whose endpoint is in the body, after
+// the browser called pushSubscription.unsubscribe().
+func (h *PushHandler) Unsubscribe(c *gin.Context) {
+	var req struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	if err := h.store.Delete(c.Request.Context(), middleware.UserID(c), req.Endpoint); err != nil {
+		h.fail(c, err)
+		return
+	}
+	utils.Success(c, http.StatusOK, gin.H{"endpoint": req.Endpoint})
+}
+
+func (h *PushHandler) requireEnabled(c *gin.Context) bool {
+	if h.sender.Enabled() {
+		return true
+	}
+	utils.Fail(c, http.StatusServiceUnavailable, "PUSH_DISABLED", "Push notifications are not configured")
+	return false
+}
+
+func (h *PushHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, push.ErrInvalid):
+		utils.Fail(c, http.StatusBadRequest, "INVALID_SUBSCRIPTION", err.Error())
+	case errors.Is(err, push.ErrNotFound):
+		utils.Fail(c, http.StatusNotFound, "SUBSCRIPTION_NOT_FOUND", "Push subscription not found")
+	default:
+		lg.ErrorContext(c.Request.Context(), "Push request failed", "path", c.FullPath(), "error", err)
+		utils.Fail(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to access push subscriptions")
+	}
+}
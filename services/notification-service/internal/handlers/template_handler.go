@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"notification/internal/email"
+	"notification/internal/preferences"
+	"notification/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// previewUserID is the recipient of previews, for their unsubscribe link.
+const previewUserID = "preview"
+
+// TemplateHandler previews the email templates with sample data. Every
+// request must carry the preview token as a bearer token.
+type TemplateHandler struct {
+	renderer     *email.Renderer
+	unsubscriber *preferences.Unsubscriber
+	token        string
+}
+
+func NewTemplateHandler(renderer *email.Renderer, unsubscriber *preferences.Unsubscriber, token string) *TemplateHandler {
+	return &TemplateHandler{renderer: renderer, unsubscriber: unsubscriber, token: token}
+}
+
+// Enabled reports whether a preview token is configured. Routes are not
+// registered otherwise.
+func (h *TemplateHandler) Enabled() bool {
+	return h.token != ""
+}
+
+// Authorize rejects requests without the preview bearer token.
+func (h *TemplateHandler) Authorize(c *gin.Context) {
+	authorizeAdmin(c, h.token)
+}
+
+// List returns the template names and the locales they can be rendered in.
+func (h *TemplateHandler) List(c *gin.Context) {
+	utils.Success(c, http.StatusOK, gin.H{
+		"templates": h.renderer.Names(),
+		"locales":   h.renderer.Locales(),
+	})
+}
+
+// Preview renders template :name in the locale query parameter, which falls
+// back like a real send does. format=html and format=text return the body
+// alone, ready to open in a browser; the default returns every part as JSON.
+func (h *TemplateHandler) Preview(c *gin.Context) {
+	name := c.Param("name")
+	if !h.renderer.Has(name) {
+		utils.Fail(c, http.StatusNotFound, "TEMPLATE_NOT_FOUND", "Unknown template "+name)
+		return
+	}
+	data, _ := email.Sample(name)
+
+	rendered, err := h.renderer.Render(name, data, email.Options{
+		Locale:         c.Query("locale"),
+		UnsubscribeURL: h.unsubscriber.URL(previewUserID, preferences.ChannelEmail, email.TemplateCategory(name)),
+	})
+	if err != nil {
+		lg.WarnContext(c.Request.Context(), "Template preview failed", "template", name, "error", err)
+		utils.Fail(c, http.StatusUnprocessableEntity, "RENDER_FAILED", err.Error())
+		return
+	}
+
+	c.Header("Content-Language", rendered.Locale)
+	switch c.Query("format") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTML))
+	case "text":
+		c.String(http.StatusOK, "Subject: %s\n\n%s", rendered.Subject, rendered.Text)
+	case "", "json":
+		utils.Success(c, http.StatusOK, rendered)
+	default:
+		utils.Fail(c, http.StatusBadRequest, "INVALID_REQUEST", "format must be html, text or json")
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	htmltemplate "html/template"
+	"net/http"
+	"notification/internal/i18n"
+	"notification/internal/metrics"
+	"notification/internal/preferences"
+	"notification/internal/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const unsubscribePage = `<!DOCTYPE html>
+<html lang="{{locale}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{t "unsubscribe.title"}}</title>
+</head>
+<body style="max-width:480px;margin:48px auto;padding:0 16px;font-family:Arial,Helvetica,sans-serif;color:#18181b;">
+<h1 style="font-size:20px;">Music Player</h1>
+{{- if not .Valid}}
+<p>{{t "unsubscribe.invalid"}}</p>
+{{- else if .Done}}
+<p>{{t "unsubscribe.done" "category" (t .CategoryKey) "channel" (t .ChannelKey)}}</p>
+{{- else}}
+<form method="post">
+<input type="hidden" name="token" value="{{.Token}}">
+<p>{{t "unsubscribe.confirm" "category" (t .CategoryKey) "channel" (t .ChannelKey)}}</p>
+<button type="submit" style="padding:12px 24px;background:#16a34a;color:#ffffff;border:0;border-radius:6px;">{{t "unsubscribe.button"}}</button>
+</form>
+{{- end}}
+</body>
+</html>
+`
+
+// UnsubscribeHandler serves the links of List-Unsubscribe headers and email
+// footers. The signed token is the only credential: the links are opened
+// from a mailbox, without a session.
+type UnsubscribeHandler struct {
+	store        *preferences.Store
+	unsubscriber *preferences.Unsubscriber
+	catalog      *i18n.Catalog
+	pages        map[string]*htmltemplate.Template
+}
+
+func NewUnsubscribeHandler(store *preferences.Store, unsubscriber *preferences.Unsubscriber, catalog *i18n.Catalog) (*UnsubscribeHandler, error) {
+	page, err := htmltemplate.New("unsubscribe").Funcs(catalog.Localizer(i18n.Fallback).FuncMap()).Parse(unsubscribePage)
+	if err != nil {
+		return nil, err
+	}
+	h := &UnsubscribeHandler{store: store, unsubscriber: unsubscriber, catalog: catalog, pages: make(map[string]*htmltemplate.Template)}
+	for _, locale := range catalog.Locales() {
+		clone, err := page.Clone()
+		if err != nil {
+			return nil, err
+		}
+		h.pages[locale] = clone.Funcs(catalog.Localizer(locale).FuncMap())
+	}
+	return h, nil
+}
+
+type unsubscribeView struct {
+	Valid       bool
+	Done        bool
+	Token       string
+	CategoryKey string
+	ChannelKey  string
+}
+
+// Confirm shows what the link turns off and a button to do it. Opening the
+// link changes nothing, because mail scanners follow links too.
+func (h *UnsubscribeHandler) Confirm(c *gin.Context) {
+	token := c.Query("token")
+	target, err := h.unsubscriber.Parse(token)
+	view := unsubscribeView{Valid: err == nil, Token: token}
+	if err == nil {
+		view.CategoryKey, view.ChannelKey = "category."+string(target.Category), "channel."+string(target.Channel)
+	}
+	h.render(c, view)
+}
+
+// Unsubscribe turns off the category the token names. It answers both the
+// confirmation form and RFC 8058 one-click requests, which mail clients send
+// as a POST with the body "List-Unsubscribe=One-Click".
+func (h *UnsubscribeHandler) Unsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	target, err := h.unsubscriber.Parse(token)
+	if err != nil {
+		metrics.ObserveUnsubscribe("", "", "invalid")
+		h.render(c, unsubscribeView{})
+		return
+	}
+
+	_, err = h.store.Update(c.Request.Context(), target.UserID, &preferences.Update{
+		Channels: map[preferences.Channel]map[preferences.Category]bool{target.Channel: {target.Category: false}},
+	})
+	if err != nil {
+		if errors.Is(err, preferences.ErrInvalid) {
+			metrics.ObserveUnsubscribe(string(target.Channel), string(target.Category), "invalid")
+			h.render(c, unsubscribeView{})
+			return
+		}
+		lg.ErrorContext(c.Request.Context(), "Unsubscribe failed", "user_id", target.UserID, "error", err)
+		utils.Fail(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to unsubscribe")
+		return
+	}
+	metrics.ObserveUnsubscribe(string(target.Channel), string(target.Category), "unsubscribed")
+	lg.InfoContext(c.Request.Context(), "User unsubscribed", "user_id", target.UserID, "channel", target.Channel, "category", target.Category)
+
+	h.render(c, unsubscribeView{
+		Valid:       true,
+		Done:        true,
+		CategoryKey: "category." + string(target.Category),
+		ChannelKey:  "channel." + string(target.Channel),
+	})
+}
+
+func (h *UnsubscribeHandler) render(c *gin.Context, view unsubscribeView) {
+	var b strings.Builder
+	if err := h.pages[h.catalog.Match(c.GetHeader("Accept-Language"))].Execute(&b, view); err != nil {
+		lg.ErrorContext(c.Request.Context(), "Failed to render unsubscribe page", "error", err)
+		utils.Fail(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to render page")
+		return
+	}
+	status := http.StatusOK
+	if !view.Valid {
+		status = http.StatusBadRequest
+	}
+	c.Data(status, "text/html; charset=utf-8", []byte(b.String()))
+}
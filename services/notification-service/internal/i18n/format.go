@@ -0,0 +1,41 @@
+package i18n
+
+import (
+	"fmt"
+	"time"
+)
+
+// format holds the formatting rules of a locale.
+type format struct {
+	group   string
+	decimal string
+	clock   string
+	date    func(time.Time) string
+	// plural returns the CLDR plural category of n.
+	plural func(n float64) string
+}
+
+var formats = map[string]format{
+	English: {
+		group:   ",",
+		decimal: ".",
+		clock:   "3:04 PM",
+		date:    func(t time.Time) string { return t.Format("January 2, 2006") },
+		plural: func(n float64) string {
+			if n == 1 {
+				return "one"
+			}
+			return "other"
+		},
+	},
+	Vietnamese: {
+		group:   ".",
+		decimal: ",",
+		clock:   "15:04",
+		date: func(t time.Time) string {
+			return fmt.Sprintf("ngày %d tháng %d năm %d", t.Day(), int(t.Month()), t.Year())
+		},
+		// Vietnamese nouns do not inflect for number.
+		plural: func(float64) string { return "other" },
+	},
+}
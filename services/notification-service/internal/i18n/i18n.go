@@ -0,0 +1,126 @@
+// Package i18n holds the message catalog used to localize notifications:
+// translations with per-key fallback to English, plural forms, and locale
+// aware number, date and duration formatting.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"music-player/api/locale"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// Supported locales. Every key must exist in English, which is the fallback
+// for keys missing from another locale and for unsupported locales.
+const (
+	English    = "en"
+	Vietnamese = "vi"
+	Fallback   = English
+)
+
+// message is a catalog entry: a plain string, or an object of plural forms
+// keyed by CLDR category ("one", "other").
+type message map[string]string
+
+func (m *message) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*m = message{"other": s}
+		return nil
+	}
+	var forms map[string]string
+	if err := json.Unmarshal(b, &forms); err != nil {
+		return err
+	}
+	if _, ok := forms["other"]; !ok {
+		return fmt.Errorf("plural forms without \"other\"")
+	}
+	*m = forms
+	return nil
+}
+
+// Catalog is the set of translations embedded in locales/<locale>.json.
+type Catalog struct {
+	messages map[string]map[string]message
+	locales  []string
+}
+
+// NewCatalog loads the embedded translations. It fails if a locale has keys
+// English does not, so a typo in a key is caught at startup.
+func NewCatalog() (*Catalog, error) {
+	files, err := fs.Glob(localeFS, "locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{messages: make(map[string]map[string]message, len(files))}
+	for _, file := range files {
+		b, err := localeFS.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var msgs map[string]message
+		if err := json.Unmarshal(b, &msgs); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", file, err)
+		}
+		locale := strings.TrimSuffix(path.Base(file), ".json")
+		if _, ok := formats[locale]; !ok {
+			return nil, fmt.Errorf("i18n: %s: no formatting rules for locale %q", file, locale)
+		}
+		c.messages[locale] = msgs
+	}
+
+	base, ok := c.messages[Fallback]
+	if !ok {
+		return nil, fmt.Errorf("i18n: fallback locale %q is missing", Fallback)
+	}
+	for locale, msgs := range c.messages {
+		for key := range msgs {
+			if _, ok := base[key]; !ok {
+				return nil, fmt.Errorf("i18n: %s: key %q is not in %s", locale, key, Fallback)
+			}
+		}
+	}
+	for l := range c.messages {
+		c.locales = append(c.locales, l)
+	}
+	sort.Strings(c.locales)
+	return c, nil
+}
+
+// Locales returns the supported locales, sorted.
+func (c *Catalog) Locales() []string {
+	return slices.Clone(c.locales)
+}
+
+// Match returns the supported locale for a language tag such as "vi-VN" or
+// an Accept-Language list, or Fallback; see locale.Match.
+func (c *Catalog) Match(tag string) string {
+	if l := locale.Match(tag, c.locales...); l != "" {
+		return l
+	}
+	return Fallback
+}
+
+// Has reports whether key is translated. Every key is in Fallback.
+func (c *Catalog) Has(key string) bool {
+	_, ok := c.messages[Fallback][key]
+	return ok
+}
+
+// Localizer returns the localizer of the locale Match picks for tag.
+func (c *Catalog) Localizer(tag string) *Localizer {
+	locale := c.Match(tag)
+	chain := []map[string]message{c.messages[locale]}
+	if locale != Fallback {
+		chain = append(chain, c.messages[Fallback])
+	}
+	return &Localizer{locale: locale, chain: chain, format: formats[locale]}
+}
@@ -0,0 +1,71 @@
+package i18n
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c, err := NewCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCatalogMatch(t *testing.T) {
+	c := newTestCatalog(t)
+	tests := []struct{ tag, want string }{
+		{"vi", Vietnamese},
+		{"vi-VN", Vietnamese},
+		{"en-GB", English},
+		{"fr-FR,vi;q=0.9,en;q=0.8", Vietnamese},
+		{"fr", Fallback},
+		{"", Fallback},
+	}
+	for _, tt := range tests {
+		if got := c.Match(tt.tag); got != tt.want {
+			t.Errorf("Match(%q) = %q; want %q", tt.tag, got, tt.want)
+		}
+		if got := c.Localizer(tt.tag).Locale(); got != tt.want {
+			t.Errorf("Localizer(%q).Locale() = %q; want %q", tt.tag, got, tt.want)
+		}
+	}
+	if got := c.Locales(); len(got) != 2 || got[0] != English || got[1] != Vietnamese {
+		t.Errorf("Locales = %v; want [en vi]", got)
+	}
+}
+
+func TestLocalizerFallback(t *testing.T) {
+	var en, vi map[string]message
+	if err := json.Unmarshal([]byte(`{"greeting": "Hello {name}", "only.en": "English only"}`), &en); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"greeting": "Xin chào {name}"}`), &vi); err != nil {
+		t.Fatal(err)
+	}
+	c := &Catalog{messages: map[string]map[string]message{English: en, Vietnamese: vi}, locales: []string{English, Vietnamese}}
+
+	l := c.Localizer("vi")
+	if got, _ := l.T("greeting", "name", "An"); got != "Xin chào An" {
+		t.Errorf("T(greeting) = %q; want the Vietnamese translation", got)
+	}
+	// A key missing from the locale falls back to English.
+	if got, _ := l.T("only.en"); got != "English only" {
+		t.Errorf("T(only.en) = %q; want the English fallback", got)
+	}
+	if _, err := l.T("missing"); err == nil {
+		t.Error("T(missing) succeeded; want an error")
+	}
+	if _, err := l.T("greeting", "name"); err == nil {
+		t.Error("T with an odd number of arguments succeeded; want an error")
+	}
+}
+
+func TestMessagePluralFormsNeedOther(t *testing.T) {
+	var m map[string]message
+	if err := json.Unmarshal([]byte(`{"k": {"one": "x"}}`), &m); err == nil {
+		t.Error("plural forms without other were accepted")
+	}
+}
@@ -0,0 +1,197 @@
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Localizer translates and formats for one locale.
+type Localizer struct {
+	locale string
+	// chain is the locale's messages followed by the fallback's.
+	chain  []map[string]message
+	format format
+}
+
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// T returns the translation of key with {name} placeholders replaced by
+// args, given as name, value pairs.
+func (l *Localizer) T(key string, args ...any) (string, error) {
+	return l.translate(key, "other", args)
+}
+
+// Plural returns the plural form of key for n, with {count} replaced by the
+// formatted n and other placeholders by args.
+func (l *Localizer) Plural(key string, n any, args ...any) (string, error) {
+	f, err := toFloat(n)
+	if err != nil {
+		return "", fmt.Errorf("i18n: plural %q: %w", key, err)
+	}
+	count, err := l.Number(n)
+	if err != nil {
+		return "", err
+	}
+	return l.translate(key, l.format.plural(f), append([]any{"count", count}, args...))
+}
+
+func (l *Localizer) translate(key, form string, args []any) (string, error) {
+	if len(args)%2 != 0 {
+		return "", fmt.Errorf("i18n: %q: arguments must be name, value pairs", key)
+	}
+	for _, msgs := range l.chain {
+		m, ok := msgs[key]
+		if !ok {
+			continue
+		}
+		s, ok := m[form]
+		if !ok {
+			s = m["other"]
+		}
+		for i := 0; i < len(args); i += 2 {
+			s = strings.ReplaceAll(s, "{"+fmt.Sprint(args[i])+"}", fmt.Sprint(args[i+1]))
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("i18n: unknown key %q", key)
+}
+
+// Number formats an integer or a float with up to two decimals, using the
+// locale's separators: 1,234.5 in English and 1.234,5 in Vietnamese.
+func (l *Localizer) Number(v any) (string, error) {
+	f, err := toFloat(v)
+	if err != nil {
+		return "", err
+	}
+	s := strconv.FormatFloat(math.Abs(f), 'f', 2, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	frac = strings.TrimRight(frac, "0")
+
+	var b strings.Builder
+	if f < 0 && s != "0.00" {
+		b.WriteByte('-')
+	}
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.format.group)
+		}
+		b.WriteRune(d)
+	}
+	if frac != "" {
+		b.WriteString(l.format.decimal)
+		b.WriteString(frac)
+	}
+	return b.String(), nil
+}
+
+// Date formats a time.Time or an RFC 3339 string as a date.
+func (l *Localizer) Date(v any) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return l.format.date(t), nil
+}
+
+// DateTime formats a time.Time or an RFC 3339 string as a date and a time
+// of day.
+func (l *Localizer) DateTime(v any) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return l.format.date(t) + " " + t.Format(l.format.clock), nil
+}
+
+// Duration renders a time.Duration, or a string such as "90m", rounded to
+// the largest whole unit: "2 hours", "45 minutes", "1 day".
+func (l *Localizer) Duration(v any) (string, error) {
+	var d time.Duration
+	switch x := v.(type) {
+	case time.Duration:
+		d = x
+	case string:
+		var err error
+		if d, err = time.ParseDuration(x); err != nil {
+			return "", fmt.Errorf("i18n: duration: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("i18n: duration: unsupported type %T", v)
+	}
+
+	minutes := max(int(d.Round(time.Minute).Minutes()), 1)
+	switch {
+	case minutes%(24*60) == 0:
+		return l.Plural("duration.days", minutes/(24*60))
+	case minutes%60 == 0:
+		return l.Plural("duration.hours", minutes/60)
+	default:
+		return l.Plural("duration.minutes", minutes)
+	}
+}
+
+// FuncMap returns the template functions bound to the locale: t, plural,
+// number, date, datetime, duration and locale.
+func (l *Localizer) FuncMap() map[string]any {
+	return map[string]any{
+		"t":        l.T,
+		"plural":   l.Plural,
+		"number":   l.Number,
+		"date":     l.Date,
+		"datetime": l.DateTime,
+		"duration": l.Duration,
+		"locale":   l.Locale,
+	}
+}
+
+func toFloat(v any) (float64, error) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint:
+		return float64(x), nil
+	case uint32:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case float32:
+		return float64(x), nil
+	case float64:
+		// Numbers decoded from JSON template data are float64.
+		return x, nil
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		if err != nil {
+			return 0, fmt.Errorf("i18n: %q is not a number", x)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("i18n: unsupported number type %T", v)
+}
+
+func toTime(v any) (time.Time, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case *time.Time:
+		if x != nil {
+			return *x, nil
+		}
+	case string:
+		t, err := time.Parse(time.RFC3339, x)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("i18n: date: %w", err)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("i18n: date: unsupported type %T", v)
+}
@@ -0,0 +1,124 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlural(t *testing.T) {
+	c := newTestCatalog(t)
+	tests := []struct {
+		locale string
+		n      any
+		want   string
+	}{
+		{English, 1, "1 day"},
+		{English, 2, "2 days"},
+		{English, 0, "0 days"},
+		{English, 1.5, "1.5 days"},
+		{English, "1", "1 day"},
+		{English, int64(1000), "1,000 days"},
+		{Vietnamese, 1, "1 ngày"},
+		{Vietnamese, 1000, "1.000 ngày"},
+	}
+	for _, tt := range tests {
+		got, err := c.Localizer(tt.locale).Plural("duration.days", tt.n)
+		if err != nil || got != tt.want {
+			t.Errorf("%s Plural(%v) = %q, %v; want %q", tt.locale, tt.n, got, err, tt.want)
+		}
+	}
+	if _, err := c.Localizer(English).Plural("duration.days", "many"); err == nil {
+		t.Error("Plural with a non-number succeeded; want an error")
+	}
+}
+
+func TestNumber(t *testing.T) {
+	c := newTestCatalog(t)
+	tests := []struct {
+		v      any
+		en, vi string
+	}{
+		{0, "0", "0"},
+		{999, "999", "999"},
+		{1234, "1,234", "1.234"},
+		{1234567, "1,234,567", "1.234.567"},
+		{1234.5, "1,234.5", "1.234,5"},
+		{0.126, "0.13", "0,13"},
+		{2.0, "2", "2"},
+		{-1234.25, "-1,234.25", "-1.234,25"},
+		{-0.001, "0", "0"},
+		{float32(1.5), "1.5", "1,5"},
+		{"42", "42", "42"},
+	}
+	for _, tt := range tests {
+		for locale, want := range map[string]string{English: tt.en, Vietnamese: tt.vi} {
+			got, err := c.Localizer(locale).Number(tt.v)
+			if err != nil || got != want {
+				t.Errorf("%s Number(%v) = %q, %v; want %q", locale, tt.v, got, err, want)
+			}
+		}
+	}
+	if _, err := c.Localizer(English).Number(struct{}{}); err == nil {
+		t.Error("Number(struct{}) succeeded; want an error")
+	}
+}
+
+func TestDate(t *testing.T) {
+	c := newTestCatalog(t)
+	at := time.Date(2025, 3, 7, 14, 5, 0, 0, time.UTC)
+	tests := []struct {
+		locale         string
+		date, datetime string
+	}{
+		{English, "March 7, 2025", "March 7, 2025 2:05 PM"},
+		{Vietnamese, "ngày 7 tháng 3 năm 2025", "ngày 7 tháng 3 năm 2025 14:05"},
+	}
+	for _, tt := range tests {
+		l := c.Localizer(tt.locale)
+		for _, v := range []any{at, &at, "2025-03-07T14:05:00Z"} {
+			if got, err := l.Date(v); err != nil || got != tt.date {
+				t.Errorf("%s Date(%v) = %q, %v; want %q", tt.locale, v, got, err, tt.date)
+			}
+			if got, err := l.DateTime(v); err != nil || got != tt.datetime {
+				t.Errorf("%s DateTime(%v) = %q, %v; want %q", tt.locale, v, got, err, tt.datetime)
+			}
+		}
+	}
+	if _, err := c.Localizer(English).Date("7 March"); err == nil {
+		t.Error("Date of a non-RFC 3339 string succeeded; want an error")
+	}
+}
+
+func TestDuration(t *testing.T) {
+	c := newTestCatalog(t)
+	tests := []struct {
+		locale string
+		v      any
+		want   string
+	}{
+		{English, time.Minute, "1 minute"},
+		{English, 45 * time.Minute, "45 minutes"},
+		{English, "90m", "90 minutes"},
+		{English, 2 * time.Hour, "2 hours"},
+		{English, "1h", "1 hour"},
+		{English, 24 * time.Hour, "1 day"},
+		{English, 72 * time.Hour, "3 days"},
+		// Rounded to the minute, and never below one.
+		{English, 10 * time.Second, "1 minute"},
+		{English, 59*time.Minute + 50*time.Second, "1 hour"},
+		{Vietnamese, 15 * time.Minute, "15 phút"},
+		{Vietnamese, time.Hour, "1 giờ"},
+		{Vietnamese, 48 * time.Hour, "2 ngày"},
+	}
+	for _, tt := range tests {
+		got, err := c.Localizer(tt.locale).Duration(tt.v)
+		if err != nil || got != tt.want {
+			t.Errorf("%s Duration(%v) = %q, %v; want %q", tt.locale, tt.v, got, err, tt.want)
+		}
+	}
+	for _, v := range []any{"soon", 5} {
+		if _, err := c.Localizer(English).Duration(v); err == nil {
+			t.Errorf("Duration(%v) succeeded; want an error", v)
+		}
+	}
+}
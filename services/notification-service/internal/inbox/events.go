@@ -0,0 +1,49 @@
+package inbox
+
+import (
+	"context"
+	"notification/internal/realtime"
+
+	apirealtime "music-player/api/realtime"
+)
+
+// Change is the data of the realtime events about an inbox. Notification
+// is omitted when every notification changed.
+type Change struct {
+	Notification *Notification `json:"notification,omitempty"`
+	UnreadCount  int64         `json:"unreadCount"`
+}
+
+// Events pushes inbox changes to the user's open connections, so the bell
+// badge of every device follows without polling.
+type Events struct {
+	store     *Store
+	publisher *realtime.Publisher
+}
+
+func NewEvents(store *Store, publisher *realtime.Publisher) *Events {
+	return &Events{store: store, publisher: publisher}
+}
+
+func (e *Events) Created(ctx context.Context, n *Notification) {
+	e.publish(ctx, n.UserID, apirealtime.EventNotificationCreated, n)
+}
+
+func (e *Events) Updated(ctx context.Context, n *Notification) {
+	e.publish(ctx, n.UserID, apirealtime.EventNotificationUpdated, n)
+}
+
+func (e *Events) ReadAll(ctx context.Context, userID string) {
+	e.publish(ctx, userID, apirealtime.EventNotificationsReadAll, nil)
+}
+
+// publish sends the change with the current unread count. Events are best
+// effort, so a failure to count is only logged.
+func (e *Events) publish(ctx context.Context, userID, eventType string, n *Notification) {
+	unread, err := e.store.UnreadCount(ctx, userID)
+	if err != nil {
+		lg.WarnContext(ctx, "Failed to count unread notifications for realtime event", "type", eventType, "user_id", userID, "error", err)
+		return
+	}
+	e.publisher.Publish(ctx, userID, eventType, Change{Notification: n, UnreadCount: unread})
+}
@@ -0,0 +1,121 @@
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"music-player/api/logger"
+	"notification/internal/i18n"
+	"notification/internal/metrics"
+	"notification/internal/preferences"
+	"unicode/utf8"
+)
+
+var lg = logger.For("inbox")
+
+// Request asks for a notification of Kind in UserID's inbox. Its title and
+// body are the translations of inbox.<kind>.title and inbox.<kind>.body.
+type Request struct {
+	UserID   string
+	Kind     string
+	Category preferences.Category
+	// Locale is the recipient's locale, e.g. "vi"; unsupported ones fall back
+	// to English like emails do.
+	Locale string
+	// Args fill the {name} placeholders of the texts.
+	Args map[string]any
+	// SourceID is the message_id of the event asking for the notification.
+	SourceID string
+}
+
+// Notifier puts notifications in inboxes, unless the user turned the
+// category off for the in-app channel.
+type Notifier struct {
+	store       *Store
+	events      *Events
+	preferences *preferences.Store
+	catalog     *i18n.Catalog
+}
+
+func NewNotifier(store *Store, events *Events, prefs *preferences.Store, catalog *i18n.Catalog) *Notifier {
+	return &Notifier{store: store, events: events, preferences: prefs, catalog: catalog}
+}
+
+// Has reports whether notifications of kind have texts.
+func (n *Notifier) Has(kind string) bool {
+	return n.catalog.Has("inbox."+kind+".title") && n.catalog.Has("inbox."+kind+".body")
+}
+
+// Notify creates the notification req asks for and pushes it to the user's
+// open connections. Creating it again for the same SourceID does nothing.
+func (n *Notifier) Notify(ctx context.Context, req Request) error {
+	if !n.Has(req.Kind) {
+		return fmt.Errorf("inbox: no texts for kind %q", req.Kind)
+	}
+	if _, ok := preferences.ParseCategory(string(req.Category)); !ok {
+		return fmt.Errorf("inbox: kind %q needs a known category, got %q", req.Kind, req.Category)
+	}
+
+	decision, _, err := n.preferences.Check(ctx, req.UserID, preferences.ChannelInApp, req.Category)
+	if err != nil {
+		metrics.ObserveInbox(req.Kind, "error")
+		return err
+	}
+	if decision != preferences.Allow {
+		metrics.ObserveInbox(req.Kind, "suppressed")
+		lg.InfoContext(ctx, "In-app notification suppressed by preferences", "kind", req.Kind, "category", req.Category, "user_id", req.UserID, "decision", decision)
+		return nil
+	}
+
+	l := n.catalog.Localizer(req.Locale)
+	args := make([]any, 0, 2*len(req.Args))
+	for k, v := range req.Args {
+		args = append(args, k, v)
+	}
+	title, err := l.T("inbox."+req.Kind+".title", args...)
+	if err != nil {
+		return err
+	}
+	body, err := l.T("inbox."+req.Kind+".body", args...)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		UserID:   req.UserID,
+		Kind:     req.Kind,
+		Category: string(req.Category),
+		Locale:   l.Locale(),
+		Title:    truncate(title, maxTitleLength),
+		Body:     body,
+		SourceID: req.SourceID,
+	}
+	created, err := n.store.Create(ctx, notification)
+	if err != nil {
+		metrics.ObserveInbox(req.Kind, "error")
+		return err
+	}
+	if !created {
+		metrics.ObserveInbox(req.Kind, "duplicate")
+		lg.DebugContext(ctx, "In-app notification already created", "kind", req.Kind, "user_id", req.UserID, "source_id", req.SourceID)
+		return nil
+	}
+	metrics.ObserveInbox(req.Kind, "created")
+	lg.InfoContext(ctx, "In-app notification created", "kind", req.Kind, "user_id", req.UserID, "notification_id", notification.ID)
+	n.events.Created(ctx, notification)
+	return nil
+}
+
+// maxTitleLength is the length of notifications.title. Titles fill in event
+// args, so a long one is cut rather than failing the insert, which retrying
+// cannot fix.
+const maxTitleLength = 255
+
+// truncate cuts s to n characters, ending it with an ellipsis when it was
+// longer.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,29 @@
+package inbox
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"too long", 5, "too …"},
+		{"Xin chào bạn", 6, "Xin c…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q; want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+
+	long := truncate(strings.Repeat("ạ", 300), maxTitleLength)
+	if n := len([]rune(long)); n != maxTitleLength {
+		t.Errorf("truncated title has %d characters; want %d", n, maxTitleLength)
+	}
+}
@@ -0,0 +1,191 @@
+// Package inbox is the in-app channel: notifications kept in Postgres for
+// the player's bell icon, with their read and archived state.
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notification is an entry of a user's inbox. Title and Body are rendered in
+// the user's locale when it is created.
+type Notification struct {
+	ID       int64  `json:"id" gorm:"primaryKey"`
+	UserID   string `json:"-"`
+	Kind     string `json:"kind"`
+	Category string `json:"category"`
+	Locale   string `json:"locale"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	// SourceID is the message_id of the event the notification came from.
+	// An event handled twice creates a single notification.
+	SourceID   string     `json:"-"`
+	ReadAt     *time.Time `json:"readAt"`
+	ArchivedAt *time.Time `json:"archivedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (Notification) TableName() string { return "notifications" }
+
+// Filter selects the notifications List returns.
+type Filter string
+
+const (
+	// FilterAll is every notification that is not archived.
+	FilterAll      Filter = "all"
+	FilterUnread   Filter = "unread"
+	FilterArchived Filter = "archived"
+)
+
+func ParseFilter(s string) (Filter, bool) {
+	switch f := Filter(s); f {
+	case FilterAll, FilterUnread, FilterArchived:
+		return f, true
+	}
+	return "", false
+}
+
+// Page sizes of List.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrNotFound      = errors.New("notification not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Query is a page request. Cursor is the NextCursor of the previous page,
+// empty for the first one.
+type Query struct {
+	Filter Filter
+	Cursor string
+	Limit  int
+}
+
+// Page is a slice of an inbox, newest first. NextCursor is empty on the last
+// page.
+type Page struct {
+	Items      []Notification `json:"items"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// Store keeps the inboxes. Every method is scoped to one user, so a user
+// cannot read or change another's notifications by ID.
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create inserts n and reports whether it was new. A notification with the
+// same user and SourceID already in the store is left as it is.
+func (s *Store) Create(ctx context.Context, n *Notification) (bool, error) {
+	res := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "source_id"}},
+		DoNothing: true,
+	}).Create(n)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// List returns a page of userID's inbox, newest first. IDs grow with
+// insertion, so the cursor is the last ID returned and pages stay stable
+// while new notifications arrive.
+func (s *Store) List(ctx context.Context, userID string, q Query) (*Page, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	tx := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	switch q.Filter {
+	case FilterUnread:
+		tx = tx.Where("read_at IS NULL AND archived_at IS NULL")
+	case FilterArchived:
+		tx = tx.Where("archived_at IS NOT NULL")
+	default:
+		tx = tx.Where("archived_at IS NULL")
+	}
+	if q.Cursor != "" {
+		var before int64
+		if _, err := fmt.Sscan(q.Cursor, &before); err != nil || before <= 0 {
+			return nil, ErrInvalidCursor
+		}
+		tx = tx.Where("id < ?", before)
+	}
+
+	// One extra row tells whether there is a next page.
+	items := make([]Notification, 0, limit+1)
+	if err := tx.Order("id DESC").Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	page := &Page{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = fmt.Sprint(page.Items[limit-1].ID)
+	}
+	return page, nil
+}
+
+// UnreadCount returns how many of userID's notifications are neither read
+// nor archived.
+func (s *Store) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	var n int64
+	err := s.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL AND archived_at IS NULL", userID).
+		Count(&n).Error
+	return n, err
+}
+
+// MarkRead marks notification id of userID as read, keeping the time of the
+// first read, and returns it.
+func (s *Store) MarkRead(ctx context.Context, userID string, id int64) (*Notification, error) {
+	return s.update(ctx, userID, id, map[string]any{
+		"read_at": gorm.Expr("COALESCE(read_at, ?)", time.Now()),
+	})
+}
+
+// Archive moves notification id of userID out of the inbox and returns it.
+// An archived notification counts as read.
+func (s *Store) Archive(ctx context.Context, userID string, id int64) (*Notification, error) {
+	now := time.Now()
+	return s.update(ctx, userID, id, map[string]any{
+		"read_at":     gorm.Expr("COALESCE(read_at, ?)", now),
+		"archived_at": gorm.Expr("COALESCE(archived_at, ?)", now),
+	})
+}
+
+// MarkAllRead marks every unread notification of userID as read and returns
+// how many there were.
+func (s *Store) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	res := s.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL AND archived_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return res.RowsAffected, res.Error
+}
+
+func (s *Store) update(ctx context.Context, userID string, id int64, values map[string]any) (*Notification, error) {
+	var n Notification
+	res := s.db.WithContext(ctx).Model(&n).Clauses(clause.Returning{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(values)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &n, nil
+}
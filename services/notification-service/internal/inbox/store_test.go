@@ -0,0 +1,247 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestStore returns a store over an in-memory SQLite database with the
+// notifications table of migrations/02_create_notifications.sql.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a database of its own.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	err = db.Exec(`CREATE TABLE notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		category TEXT NOT NULL,
+		locale TEXT NOT NULL,
+		title TEXT NOT NULL,
+		body TEXT NOT NULL,
+		source_id TEXT NOT NULL,
+		read_at DATETIME,
+		archived_at DATETIME,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, source_id)
+	)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewStore(db)
+}
+
+// seed creates n notifications for userID and returns their IDs, oldest
+// first.
+func seed(t *testing.T, s *Store, userID string, n int) []int64 {
+	t.Helper()
+	ids := make([]int64, n)
+	for i := range n {
+		notification := &Notification{
+			UserID:   userID,
+			Kind:     "welcome",
+			Category: "account",
+			Locale:   "en",
+			Title:    fmt.Sprintf("Title %d", i),
+			Body:     "Body",
+			SourceID: fmt.Sprintf("%s-%d", userID, i),
+		}
+		if _, err := s.Create(context.Background(), notification); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = notification.ID
+	}
+	return ids
+}
+
+func itemIDs(items []Notification) []int64 {
+	ids := make([]int64, len(items))
+	for i, n := range items {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+func TestStoreCreateIsIdempotent(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	n := Notification{UserID: "u1", Kind: "welcome", Category: "account", Locale: "en", Title: "Hi", Body: "Hi", SourceID: "m1"}
+
+	first := n
+	if created, err := s.Create(ctx, &first); err != nil || !created {
+		t.Fatalf("Create = %v, %v; want created", created, err)
+	}
+	again := n
+	if created, err := s.Create(ctx, &again); err != nil || created {
+		t.Fatalf("Create again = %v, %v; want not created", created, err)
+	}
+	// The same event for another user is another notification.
+	other := n
+	other.UserID = "u2"
+	if created, err := s.Create(ctx, &other); err != nil || !created {
+		t.Fatalf("Create for another user = %v, %v; want created", created, err)
+	}
+}
+
+func TestStoreListPages(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	ids := seed(t, s, "u1", 5)
+
+	var got []int64
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("too many pages")
+		}
+		page, err := s.List(ctx, "u1", Query{Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) > 2 {
+			t.Fatalf("page of %d items; want at most 2", len(page.Items))
+		}
+		got = append(got, itemIDs(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+
+		// A notification arriving between pages shows on the first page
+		// only, and does not shift the next ones.
+		if pages == 0 {
+			late := &Notification{UserID: "u1", Kind: "welcome", Category: "account", Locale: "en", Title: "Late", Body: "Late", SourceID: "late"}
+			if _, err := s.Create(ctx, late); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	want := []int64{ids[4], ids[3], ids[2], ids[1], ids[0]}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("pages = %v; want %v", got, want)
+	}
+}
+
+func TestStoreListLastPageHasNoCursor(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s, "u1", 2)
+
+	page, err := s.List(context.Background(), "u1", Query{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.NextCursor != "" {
+		t.Errorf("List = %d items, cursor %q; want 2 items and no cursor", len(page.Items), page.NextCursor)
+	}
+}
+
+func TestStoreListInvalidCursor(t *testing.T) {
+	s := newTestStore(t)
+	for _, cursor := range []string{"x", "0", "-3"} {
+		if _, err := s.List(context.Background(), "u1", Query{Cursor: cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("List(cursor %q) = %v; want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
+
+func TestStoreListFilters(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	ids := seed(t, s, "u1", 3)
+	if _, err := s.MarkRead(ctx, "u1", ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Archive(ctx, "u1", ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter Filter
+		want   []int64
+	}{
+		{FilterAll, []int64{ids[2], ids[0]}},
+		{FilterUnread, []int64{ids[2]}},
+		{FilterArchived, []int64{ids[1]}},
+	}
+	for _, tt := range tests {
+		page, err := s.List(ctx, "u1", Query{Filter: tt.filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := itemIDs(page.Items); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("List(%s) = %v; want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	count, err := s.UnreadCount(ctx, "u1")
+	if err != nil || count != 1 {
+		t.Errorf("UnreadCount = %d, %v; want 1", count, err)
+	}
+}
+
+func TestStoreScopedToUser(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	mine := seed(t, s, "u1", 2)
+	theirs := seed(t, s, "u2", 1)
+
+	page, err := s.List(ctx, "u1", Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := itemIDs(page.Items); fmt.Sprint(got) != fmt.Sprint([]int64{mine[1], mine[0]}) {
+		t.Errorf("List(u1) = %v; want only u1's", got)
+	}
+
+	// Another user's notification cannot be changed by ID.
+	if _, err := s.MarkRead(ctx, "u1", theirs[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MarkRead of another user's = %v; want ErrNotFound", err)
+	}
+	if _, err := s.Archive(ctx, "u1", theirs[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Archive of another user's = %v; want ErrNotFound", err)
+	}
+
+	if n, err := s.MarkAllRead(ctx, "u1"); err != nil || n != 2 {
+		t.Errorf("MarkAllRead(u1) = %d, %v; want 2", n, err)
+	}
+	if n, err := s.UnreadCount(ctx, "u2"); err != nil || n != 1 {
+		t.Errorf("UnreadCount(u2) = %d, %v; want u2's untouched", n, err)
+	}
+}
+
+func TestStoreMarkReadKeepsFirstRead(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	ids := seed(t, s, "u1", 1)
+
+	first, err := s.MarkRead(ctx, "u1", ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ReadAt == nil || first.ID != ids[0] {
+		t.Fatalf("MarkRead = %+v; want it read", first)
+	}
+	again, err := s.MarkRead(ctx, "u1", ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ReadAt == nil || !again.ReadAt.Equal(*first.ReadAt) {
+		t.Errorf("ReadAt after a second read = %v; want %v", again.ReadAt, first.ReadAt)
+	}
+}
@@ -3,31 +3,85 @@ package consumer
 import (
 	"context"
 	"fmt"
-	"log"
+	"music-player/api/logger"
+	"music-player/api/metrics"
 	"notification/configs"
-	"os"
+	"notification/internal/kafka/dedupe"
+	"notification/internal/kafka/deferred"
+	"slices"
 	"time"
 
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
+var lg = logger.For("kafka.consumer")
+
+// client is the part of *kgo.Client the consume loop uses, so tests can
+// run it against a fake.
+type client interface {
+	PollRecords(ctx context.Context, maxPollRecords int) kgo.Fetches
+	AllowRebalance()
+	SetOffsets(offsets map[string]map[int32]kgo.EpochOffset)
+	PauseFetchPartitions(topicPartitions map[string][]int32) map[string][]int32
+	ResumeFetchPartitions(topicPartitions map[string][]int32)
+	CommitRecords(ctx context.Context, rs ...*kgo.Record) error
+	ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults
+	Ping(ctx context.Context) error
+	Close()
+}
+
 type Consumer struct {
-	cl *kgo.Client
+	cl       client
+	cfg      *configs.KafkaConfig
+	registry *Registry
+	// dedupe is nil when deduplication is disabled.
+	dedupe   *dedupe.Store
+	deferred *deferred.Store
+	topics   []string
+	// workers bounds the partitions processed at once.
+	workers chan struct{}
 }
 
-func NewConsumer(cfg *configs.KafkaConfig) (*Consumer, error) {
+// NewConsumer joins the consumer group for KAFKA_TOPICS or, when that is
+// empty, for every topic in registry, plus the retry tier and deferred
+// topics. Offsets are committed by Run. store may be nil to handle
+// redeliveries again; deferrals holds the records handlers Defer.
+func NewConsumer(cfg *configs.KafkaConfig, registry *Registry, store *dedupe.Store, deferrals *deferred.Store) (*Consumer, error) {
+	topics := cfg.Topics
+	if len(topics) == 0 {
+		topics = registry.Topics()
+	}
+	for _, t := range topics {
+		if _, ok := registry.Handler(t); !ok {
+			lg.Warn("Consuming topic without a handler, its records will be skipped", "topic", t)
+		}
+	}
+
+	c := &Consumer{
+		cfg:      cfg,
+		registry: registry,
+		dedupe:   store,
+		deferred: deferrals,
+		workers:  make(chan struct{}, max(cfg.Workers, 1)),
+	}
+	c.topics = slices.Concat(topics, c.retryTopics(), []string{cfg.DeferredTopic()})
+
 	opts := []kgo.Opt{
 		kgo.SeedBrokers(cfg.Brokers...),
 		kgo.ClientID(cfg.ClientID),
 		kgo.ConsumerGroup(cfg.GroupID),
-		kgo.ConsumeTopics(cfg.Topics...),
+		kgo.ConsumeTopics(c.topics...),
+		// Retry and dead-letter topics are created on first use.
+		kgo.AllowAutoTopicCreation(),
+		kgo.DisableAutoCommit(),
 		kgo.BlockRebalanceOnPoll(),
 		kgo.SessionTimeout(45 * time.Second),
 		kgo.HeartbeatInterval(3 * time.Second),
+		kgo.WithHooks(metrics.KafkaHooks{}),
 	}
 
 	if cfg.Debug {
-		opts = append(opts, kgo.WithLogger(kgo.BasicLogger(os.Stderr, kgo.LogLevelInfo, nil)))
+		opts = append(opts, kgo.WithLogger(logger.Kgo("kafka.client")))
 	}
 
 	client, err := kgo.NewClient(opts...)
@@ -43,11 +97,17 @@ func NewConsumer(cfg *configs.KafkaConfig) (*Consumer, error) {
 		return nil, fmt.Errorf("[ERROR] Failed to connect to Kafka brokers: %w", err)
 	}
 
-	log.Printf("[INFO] Kafka consumer connected to %v (group: %s)", cfg.Brokers, cfg.GroupID)
-	return &Consumer{cl: client}, nil
+	lg.Info("Kafka consumer connected", "brokers", cfg.Brokers, "group", cfg.GroupID, "topics", c.topics, "dlq", cfg.DLQTopic())
+	c.cl = client
+	return c, nil
+}
+
+// Ping checks connectivity to the Kafka brokers
+func (c *Consumer) Ping(ctx context.Context) error {
+	return c.cl.Ping(ctx)
 }
 
 func (c *Consumer) Close() {
 	c.cl.Close()
-	log.Println("[INFO] Kafka consumer connection closed")
+	lg.Info("Kafka consumer connection closed")
 }
@@ -0,0 +1,73 @@
+package consumer
+
+import (
+	"context"
+	"notification/internal/kafka/dedupe"
+	"notification/internal/kafka/envelope"
+	"notification/internal/metrics"
+	"time"
+)
+
+const settleTimeout = 5 * time.Second
+
+// inProgressRecheck is how long a partition waits before looking again at a
+// message claimed by another consumer, typically the previous owner of the
+// partition still running its handler. The record is not handled, forwarded
+// or counted as a retry meanwhile; the claim is done or expired eventually.
+const inProgressRecheck = time.Second
+
+// claim takes the dedupe claim of env. Without a store every message is
+// Claimed with a nil claim. If Redis fails the message is handled anyway: a
+// possible duplicate is better than a consumer stalled on Redis.
+func (c *Consumer) claim(ctx context.Context, topic string, env *envelope.Envelope) (*dedupe.Claim, dedupe.State) {
+	if c.dedupe == nil {
+		return nil, dedupe.Claimed
+	}
+	claim, state, err := c.dedupe.Claim(ctx, env.MessageID, c.claimLease())
+	if err != nil {
+		metrics.ObserveDedupe(topic, "error")
+		lg.WarnContext(ctx, "Dedupe check failed, handling event without it", "topic", topic, "message_id", env.MessageID, "error", err)
+		return nil, dedupe.Claimed
+	}
+	switch state {
+	case dedupe.Done:
+		metrics.ObserveDedupe(topic, "hit")
+	case dedupe.InProgress:
+		metrics.ObserveDedupe(topic, "in_progress")
+	default:
+		metrics.ObserveDedupe(topic, "miss")
+	}
+	return claim, state
+}
+
+// settle marks the claim done when the handler succeeded and releases it
+// otherwise. It runs before the offset is committed, so a crash in between
+// redelivers a record that is already marked done and it is skipped. The mark
+// cannot be atomic with the handler's side effects: a crash between the two
+// runs the handler again, which is why handlers pass the envelope's
+// MessageID on as an idempotency key (inbox source_id, email Message-ID,
+// push Topic, SMS Idempotency-Key).
+func (c *Consumer) settle(ctx context.Context, claim *dedupe.Claim, err error, attrs []any) {
+	if claim == nil {
+		return
+	}
+	sctx, cancel := context.WithTimeout(ctx, settleTimeout)
+	defer cancel()
+	if err == nil {
+		if cerr := claim.Complete(sctx); cerr != nil {
+			lg.ErrorContext(ctx, "Failed to mark event handled, a redelivery would run it again", append(attrs, "error", cerr)...)
+		}
+		return
+	}
+	if rerr := claim.Release(sctx); rerr != nil {
+		// The lease expires on its own; until then retries see InProgress.
+		lg.WarnContext(ctx, "Failed to release dedupe claim", append(attrs, "error", rerr)...)
+	}
+}
+
+// claimLease covers every handler attempt with the backoff between them, so
+// a claim outlives the consumer holding it only if that consumer died.
+func (c *Consumer) claimLease() time.Duration {
+	attempts := time.Duration(c.cfg.HandlerAttempts)
+	return attempts*c.cfg.HandlerTimeout + (attempts-1)*retryBackoffMax + settleTimeout
+}
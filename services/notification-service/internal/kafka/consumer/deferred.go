@@ -0,0 +1,95 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"notification/internal/kafka/deferred"
+	"notification/internal/kafka/dlq"
+	"strconv"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	// deferredPoll is how often due records are looked for.
+	deferredPoll = 5 * time.Second
+	// deferredLease is how long a record taken from the store is hidden
+	// from other consumers while it is produced.
+	deferredLease = time.Minute
+	deferredBatch = 100
+)
+
+// deferRecord stores r until it is due. It goes to the deferred topic with
+// its origin and retry count, so a deferral neither uses up a retry tier nor
+// resets the count.
+func (c *Consumer) deferRecord(ctx context.Context, r *kgo.Record, until time.Time) error {
+	if c.deferred == nil {
+		return errors.New("consumer: no deferred store")
+	}
+	set := map[string]string{}
+	if n := dlq.RetryCount(r); n > 0 {
+		set[dlq.HeaderRetryCount] = strconv.Itoa(n)
+	}
+	out := dlq.Forward(r, c.cfg.DeferredTopic(), r.Value, set)
+	sctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forwardTimeout)
+	defer cancel()
+	return c.deferred.Add(sctx, out, until)
+}
+
+// releaseDeferred publishes deferred records as they become due, until ctx
+// is cancelled. Every consumer of the group runs it; the store hands each
+// record to one of them.
+func (c *Consumer) releaseDeferred(ctx context.Context) {
+	ticker := time.NewTicker(deferredPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// A full batch means more may be due.
+			for n := deferredBatch; n == deferredBatch && ctx.Err() == nil; {
+				n = c.releaseDue(ctx)
+			}
+		}
+	}
+}
+
+// releaseDue publishes the records due now and returns how many it took. A
+// record that fails to publish is taken again after deferredLease.
+func (c *Consumer) releaseDue(ctx context.Context) int {
+	entries, err := c.deferred.Take(ctx, time.Now(), deferredLease, deferredBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			lg.WarnContext(ctx, "Failed to read deferred records", "error", err)
+		}
+		return 0
+	}
+	if len(entries) == 0 {
+		return 0
+	}
+
+	byRecord := make(map[*kgo.Record]*deferred.Entry, len(entries))
+	records := make([]*kgo.Record, len(entries))
+	for i, e := range entries {
+		byRecord[e.Record] = e
+		records[i] = e.Record
+	}
+	pctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forwardTimeout)
+	defer cancel()
+	var produced []*deferred.Entry
+	for _, res := range c.cl.ProduceSync(pctx, records...) {
+		if res.Err != nil {
+			lg.WarnContext(ctx, "Failed to publish deferred record, will try again", "topic", dlq.OriginalTopic(res.Record), "error", res.Err)
+			continue
+		}
+		produced = append(produced, byRecord[res.Record])
+	}
+	if err := c.deferred.Remove(pctx, produced...); err != nil {
+		// They are taken and published again after the lease; dedupe skips
+		// the second copy.
+		lg.WarnContext(ctx, "Failed to remove published deferred records", "error", err)
+	}
+	return len(entries)
+}
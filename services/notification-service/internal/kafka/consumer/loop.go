@@ -0,0 +1,288 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"music-player/api/logger"
+	"music-player/api/tracing"
+	"notification/internal/kafka/dedupe"
+	"notification/internal/kafka/dlq"
+	"notification/internal/kafka/envelope"
+	"notification/internal/metrics"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	retryBackoffMin = 200 * time.Millisecond
+	retryBackoffMax = 5 * time.Second
+	commitTimeout   = 10 * time.Second
+)
+
+// Outcome labels for metrics.ObserveProcessed.
+const (
+	resultOK           = "ok"
+	resultUnhandled    = "unhandled"
+	resultDuplicate    = "duplicate"
+	resultInProgress   = "in_progress"
+	resultRetried      = "retried"
+	resultDeferred     = "deferred"
+	resultDeadLettered = "dead_lettered"
+	resultFailed       = "failed"
+)
+
+// Run polls records and dispatches them to the registered handlers until ctx
+// is cancelled.
+//
+// Partitions of a poll are processed concurrently by at most Workers
+// goroutines, records within a partition strictly in order. Rebalances are
+// held off (BlockRebalanceOnPoll) until every partition of the poll is done
+// and the offsets of the handled records are committed, so a partition never
+// changes owner with work in flight.
+//
+// A record whose handler still fails after HandlerAttempts is published to
+// the next retry tier (RetryTopic), which this consumer also reads, and its
+// offset is committed. A retry tier record read before its delay has passed
+// rewinds and pauses its partition until it is due, and so does a record
+// whose MessageID another consumer is still handling. Once the tiers are used
+// up, and straight away for permanent errors and invalid envelopes, the
+// record goes to the dead-letter topic. Only if that publish fails is the
+// partition rewound to the record so it is delivered again. A record whose
+// handler returns a Defer error is stored until it is due, then published to
+// the deferred topic, which this consumer also reads.
+//
+// When ctx is cancelled, handlers that are running finish (bounded by
+// HandlerTimeout), their offsets are committed and Run returns.
+func (c *Consumer) Run(ctx context.Context) {
+	lg.InfoContext(ctx, "Kafka consume loop started", "topics", c.topics, "workers", c.cfg.Workers)
+	defer lg.InfoContext(ctx, "Kafka consume loop stopped")
+	if c.deferred != nil {
+		go c.releaseDeferred(ctx)
+	}
+
+	for {
+		fetches := c.cl.PollRecords(ctx, c.cfg.MaxPollRecords)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			// Anything polled is uncommitted and will be delivered again.
+			c.cl.AllowRebalance()
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			lg.WarnContext(ctx, "Kafka fetch error", "topic", topic, "partition", partition, "error", err)
+		})
+		metrics.ObserveConsumerLag(fetches)
+
+		c.processFetches(ctx, fetches)
+		c.cl.AllowRebalance()
+	}
+}
+
+func (c *Consumer) processFetches(ctx context.Context, fetches kgo.Fetches) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		done    []*kgo.Record
+		rewind  = make(map[string]map[int32]kgo.EpochOffset)
+		delayed = make(map[string]map[int32]time.Time)
+	)
+
+	fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+		if len(p.Records) == 0 {
+			return
+		}
+		c.workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-c.workers
+				wg.Done()
+			}()
+
+			last, failed, due := c.processPartition(ctx, p.Records)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if last != nil {
+				done = append(done, last)
+			}
+			if failed != nil {
+				if rewind[failed.Topic] == nil {
+					rewind[failed.Topic] = make(map[int32]kgo.EpochOffset)
+				}
+				rewind[failed.Topic][failed.Partition] = kgo.EpochOffset{Epoch: failed.LeaderEpoch, Offset: failed.Offset}
+			}
+			if !due.IsZero() {
+				if delayed[failed.Topic] == nil {
+					delayed[failed.Topic] = make(map[int32]time.Time)
+				}
+				delayed[failed.Topic][failed.Partition] = due
+			}
+		}()
+	})
+	wg.Wait()
+
+	if len(rewind) > 0 {
+		c.cl.SetOffsets(rewind)
+	}
+	if len(delayed) > 0 {
+		c.pauseUntil(delayed)
+	}
+	if len(done) == 0 {
+		return
+	}
+	// Commit even when shutting down: the work is done.
+	commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commitTimeout)
+	defer cancel()
+	if err := c.cl.CommitRecords(commitCtx, done...); err != nil {
+		lg.ErrorContext(ctx, "Failed to commit offsets", "error", err)
+	}
+}
+
+// processPartition handles records of one partition in order. It returns the
+// last record that needs no further processing and the record to rewind to,
+// if any, with the time to pause its partition until when it is a retry
+// that is not yet due or is claimed by another consumer; records after it
+// are left for a later poll.
+func (c *Consumer) processPartition(ctx context.Context, records []*kgo.Record) (last, failed *kgo.Record, due time.Time) {
+	for _, r := range records {
+		if ctx.Err() != nil {
+			return last, nil, time.Time{}
+		}
+		if due, ok := notDue(r, time.Now()); ok {
+			return last, r, due
+		}
+		if ok, due := c.process(ctx, r); !ok {
+			return last, r, due
+		}
+		last = r
+	}
+	return last, nil, time.Time{}
+}
+
+// process runs the handler of one record and reports whether the record is
+// done with: handled, skipped, or handed to a retry tier or the dead-letter
+// topic. Otherwise the partition is rewound to the record, and paused until
+// due if that is set. Records of a retry tier are dispatched by their
+// original topic.
+func (c *Consumer) process(ctx context.Context, r *kgo.Record) (bool, time.Time) {
+	start := time.Now()
+	topic := dlq.OriginalTopic(r)
+
+	env, err := envelope.Unmarshal(r.Value)
+	if err == nil {
+		err = env.Validate()
+	}
+	if err != nil {
+		attrs := []any{"topic", topic, "partition", r.Partition, "offset", r.Offset, "error", err}
+		result, _, ferr := c.forward(ctx, r, nil, err, true)
+		if ferr != nil {
+			metrics.ObserveProcessed(topic, resultFailed, time.Since(start))
+			lg.ErrorContext(ctx, "Failed to dead-letter invalid envelope, will be redelivered", append(attrs, "forward_error", ferr)...)
+			return false, time.Time{}
+		}
+		metrics.ObserveProcessed(topic, result, time.Since(start))
+		lg.WarnContext(ctx, "Dead-lettered invalid envelope", attrs...)
+		return true, time.Time{}
+	}
+
+	handler, ok := c.registry.Handler(topic)
+	if !ok {
+		lg.DebugContext(ctx, "No handler for topic", "topic", topic, "message_id", env.MessageID)
+		metrics.ObserveProcessed(topic, resultUnhandled, time.Since(start))
+		return true, time.Time{}
+	}
+
+	// A handler that has started is allowed to finish during shutdown.
+	hctx, span := tracing.StartConsumerSpan(context.WithoutCancel(ctx), r, env)
+	defer span.End()
+	if env.Metadata != nil && env.Metadata.UserID != "" {
+		hctx = logger.WithUserID(hctx, env.Metadata.UserID)
+	}
+
+	attrs := []any{"topic", topic, "partition", r.Partition, "offset", r.Offset, "message_id", env.MessageID}
+	if r.Topic != topic {
+		attrs = append(attrs, "retry_topic", r.Topic)
+	}
+
+	claim, state := c.claim(hctx, topic, env)
+	switch state {
+	case dedupe.Done:
+		metrics.ObserveProcessed(topic, resultDuplicate, time.Since(start))
+		lg.InfoContext(hctx, "Skipping already handled event", attrs...)
+		return true, time.Time{}
+	case dedupe.InProgress:
+		metrics.ObserveProcessed(topic, resultInProgress, time.Since(start))
+		lg.InfoContext(hctx, "Event is being handled by another consumer, checking again later", attrs...)
+		return false, time.Now().Add(inProgressRecheck)
+	default:
+		err = c.runHandler(ctx, hctx, handler, env, r)
+		c.settle(hctx, claim, err, attrs)
+	}
+	if err == nil {
+		metrics.ObserveProcessed(topic, resultOK, time.Since(start))
+		lg.DebugContext(hctx, "Event handled", attrs...)
+		return true, time.Time{}
+	}
+
+	if until, ok := deferredUntil(err); ok {
+		attrs = append(attrs, "until", until, "reason", err)
+		if derr := c.deferRecord(hctx, r, until); derr != nil {
+			metrics.ObserveProcessed(topic, resultFailed, time.Since(start))
+			lg.ErrorContext(hctx, "Event could not be deferred, will be redelivered", append(attrs, "error", derr)...)
+			return false, time.Time{}
+		}
+		metrics.ObserveProcessed(topic, resultDeferred, time.Since(start))
+		lg.InfoContext(hctx, "Event deferred", attrs...)
+		return true, time.Time{}
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	attrs = append(attrs, "error", err)
+	if isShutdown(ctx, err) {
+		metrics.ObserveProcessed(topic, resultFailed, time.Since(start))
+		lg.WarnContext(hctx, "Event handler interrupted by shutdown, will be redelivered", attrs...)
+		return false, time.Time{}
+	}
+
+	result, count, ferr := c.forward(hctx, r, env, err, IsPermanent(err))
+	switch {
+	case ferr != nil:
+		metrics.ObserveProcessed(topic, resultFailed, time.Since(start))
+		lg.ErrorContext(hctx, "Event handler failed and the event could not be forwarded, will be redelivered", append(attrs, "forward_error", ferr)...)
+		return false, time.Time{}
+	case result == resultRetried:
+		metrics.ObserveProcessed(topic, result, time.Since(start))
+		lg.WarnContext(hctx, "Event handler failed, scheduled for retry", append(attrs, "retry_count", count)...)
+	default:
+		metrics.ObserveProcessed(topic, result, time.Since(start))
+		lg.ErrorContext(hctx, "Event handler failed, dead-lettered", append(attrs, "retry_count", count)...)
+	}
+	return true, time.Time{}
+}
+
+// runHandler calls handler up to HandlerAttempts times with exponential
+// backoff. ctx only stops the waits between attempts; each attempt runs on
+// hctx with HandlerTimeout.
+func (c *Consumer) runHandler(ctx, hctx context.Context, handler Handler, env *envelope.Envelope, r *kgo.Record) error {
+	backoff := retryBackoffMin
+	for attempt := 1; ; attempt++ {
+		actx, cancel := context.WithTimeout(hctx, c.cfg.HandlerTimeout)
+		err := handler(actx, env, r)
+		cancel()
+		if _, deferred := deferredUntil(err); err == nil || deferred || IsPermanent(err) || attempt >= c.cfg.HandlerAttempts {
+			return err
+		}
+
+		lg.DebugContext(hctx, "Retrying event handler", "topic", r.Topic, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, retryBackoffMax)
+	}
+}
@@ -0,0 +1,656 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"notification/configs"
+	"notification/internal/kafka/dedupe"
+	"notification/internal/kafka/deferred"
+	"notification/internal/kafka/dlq"
+	"notification/internal/kafka/envelope"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const testTopic = envelope.TopicEmailSend
+
+// fakeClient records what the consume loop commits, produces, rewinds and
+// pauses.
+type fakeClient struct {
+	mu         sync.Mutex
+	produceErr error
+	produced   []*kgo.Record
+	committed  []*kgo.Record
+	rewound    map[string]map[int32]kgo.EpochOffset
+	paused     map[string][]int32
+}
+
+func (f *fakeClient) PollRecords(ctx context.Context, maxPollRecords int) kgo.Fetches { return nil }
+func (f *fakeClient) AllowRebalance()                                                 {}
+func (f *fakeClient) ResumeFetchPartitions(topicPartitions map[string][]int32)        {}
+func (f *fakeClient) Ping(ctx context.Context) error                                  { return nil }
+func (f *fakeClient) Close()                                                          {}
+
+func (f *fakeClient) SetOffsets(offsets map[string]map[int32]kgo.EpochOffset) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rewound = offsets
+}
+
+func (f *fakeClient) PauseFetchPartitions(topicPartitions map[string][]int32) map[string][]int32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = topicPartitions
+	return topicPartitions
+}
+
+func (f *fakeClient) CommitRecords(ctx context.Context, rs ...*kgo.Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, rs...)
+	return nil
+}
+
+func (f *fakeClient) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var results kgo.ProduceResults
+	for _, r := range rs {
+		if f.produceErr == nil {
+			f.produced = append(f.produced, r)
+		}
+		results = append(results, kgo.ProduceResult{Record: r, Err: f.produceErr})
+	}
+	return results
+}
+
+func newTestConsumer(attempts int, delays ...time.Duration) (*Consumer, *Registry, *fakeClient) {
+	fc := &fakeClient{}
+	registry := NewRegistry()
+	return &Consumer{
+		cl: fc,
+		cfg: &configs.KafkaConfig{
+			HandlerTimeout:   time.Second,
+			HandlerAttempts:  attempts,
+			RetryDelays:      delays,
+			RetryTopicPrefix: "test",
+		},
+		registry: registry,
+		workers:  make(chan struct{}, 2),
+	}, registry, fc
+}
+
+func testRecord(t *testing.T, topic string, offset int64, headers ...kgo.RecordHeader) *kgo.Record {
+	t.Helper()
+	env, err := envelope.NewEnvelope("test", envelope.PriorityNormal, map[string]string{"to": "a@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kgo.Record{Topic: topic, Partition: 0, Offset: offset, Value: value, Headers: headers}
+}
+
+func fetchesOf(records ...*kgo.Record) kgo.Fetches {
+	byTopic := make(map[string]map[int32][]*kgo.Record)
+	for _, r := range records {
+		if byTopic[r.Topic] == nil {
+			byTopic[r.Topic] = make(map[int32][]*kgo.Record)
+		}
+		byTopic[r.Topic][r.Partition] = append(byTopic[r.Topic][r.Partition], r)
+	}
+	var fetch kgo.Fetch
+	for topic, partitions := range byTopic {
+		ft := kgo.FetchTopic{Topic: topic}
+		for p, rs := range partitions {
+			ft.Partitions = append(ft.Partitions, kgo.FetchPartition{Partition: p, Records: rs})
+		}
+		fetch.Topics = append(fetch.Topics, ft)
+	}
+	return kgo.Fetches{fetch}
+}
+
+func committedOffsets(fc *fakeClient) map[string]int64 {
+	out := make(map[string]int64)
+	for _, r := range fc.committed {
+		out[r.Topic+"/"+strconv.Itoa(int(r.Partition))] = r.Offset
+	}
+	return out
+}
+
+func TestProcessSuccessCommits(t *testing.T) {
+	c, registry, fc := newTestConsumer(1, time.Minute)
+	var handled []int64
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		handled = append(handled, r.Offset)
+		return nil
+	})
+
+	c.processFetches(context.Background(), fetchesOf(
+		testRecord(t, testTopic.String(), 5),
+		testRecord(t, testTopic.String(), 6),
+	))
+
+	if len(handled) != 2 {
+		t.Errorf("handled = %v; want both records", handled)
+	}
+	if got := committedOffsets(fc); got[testTopic.String()+"/0"] != 6 {
+		t.Errorf("committed = %v; want offset 6", got)
+	}
+	if len(fc.produced) != 0 || fc.rewound != nil {
+		t.Errorf("produced %d records, rewound %v; want neither", len(fc.produced), fc.rewound)
+	}
+}
+
+func TestProcessRoutesFailures(t *testing.T) {
+	transient := errors.New("smtp: connection reset")
+	tests := []struct {
+		name       string
+		delays     []time.Duration
+		err        error
+		headers    []kgo.RecordHeader
+		wantTopic  string
+		wantRetry  int
+		wantDelay  bool
+		recordFrom string
+	}{
+		{
+			name:      "transient goes to the first tier",
+			delays:    []time.Duration{time.Minute, time.Hour},
+			err:       transient,
+			wantTopic: "test.retry.1",
+			wantRetry: 1,
+			wantDelay: true,
+		},
+		{
+			name:   "transient from a tier goes to the next",
+			delays: []time.Duration{time.Minute, time.Hour},
+			err:    transient,
+			headers: []kgo.RecordHeader{
+				{Key: dlq.HeaderOriginalTopic, Value: []byte(testTopic)},
+				{Key: dlq.HeaderRetryCount, Value: []byte("1")},
+			},
+			recordFrom: "test.retry.1",
+			wantTopic:  "test.retry.2",
+			wantRetry:  2,
+			wantDelay:  true,
+		},
+		{
+			name:   "transient after the last tier is dead-lettered",
+			delays: []time.Duration{time.Minute, time.Hour},
+			err:    transient,
+			headers: []kgo.RecordHeader{
+				{Key: dlq.HeaderOriginalTopic, Value: []byte(testTopic)},
+				{Key: dlq.HeaderRetryCount, Value: []byte("2")},
+			},
+			recordFrom: "test.retry.2",
+			wantTopic:  "test.dlq",
+			wantRetry:  2,
+		},
+		{
+			name:      "transient without tiers is dead-lettered",
+			err:       transient,
+			wantTopic: "test.dlq",
+		},
+		{
+			name:      "permanent skips the tiers",
+			delays:    []time.Duration{time.Minute, time.Hour},
+			err:       Permanent(errors.New("invalid address")),
+			wantTopic: "test.dlq",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, registry, fc := newTestConsumer(1, tt.delays...)
+			registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+				return tt.err
+			})
+
+			from := testTopic.String()
+			if tt.recordFrom != "" {
+				from = tt.recordFrom
+			}
+			r := testRecord(t, from, 3, tt.headers...)
+			c.processFetches(context.Background(), fetchesOf(r))
+
+			if len(fc.produced) != 1 {
+				t.Fatalf("produced %d records; want 1", len(fc.produced))
+			}
+			out := fc.produced[0]
+			if out.Topic != tt.wantTopic {
+				t.Errorf("forwarded to %s; want %s", out.Topic, tt.wantTopic)
+			}
+			if got := dlq.RetryCount(out); got != tt.wantRetry {
+				t.Errorf("retry count = %d; want %d", got, tt.wantRetry)
+			}
+			if got := dlq.OriginalTopic(out); got != testTopic.String() {
+				t.Errorf("original topic = %s; want %s", got, testTopic)
+			}
+			if dlq.Header(out, dlq.HeaderError) != tt.err.Error() {
+				t.Errorf("error header = %q; want %q", dlq.Header(out, dlq.HeaderError), tt.err)
+			}
+			if hasDelay := !dlq.NotBefore(out).IsZero(); hasDelay != tt.wantDelay {
+				t.Errorf("not-before set = %v; want %v", hasDelay, tt.wantDelay)
+			}
+			if !tt.wantDelay && dlq.Header(out, dlq.HeaderFailedAt) == "" {
+				t.Error("dead-lettered record has no failed-at header")
+			}
+			if got := committedOffsets(fc); got[from+"/0"] != 3 {
+				t.Errorf("committed = %v; want the forwarded record", got)
+			}
+		})
+	}
+}
+
+func TestProcessInvalidEnvelope(t *testing.T) {
+	c, registry, fc := newTestConsumer(1, time.Minute)
+	called := false
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		called = true
+		return nil
+	})
+
+	value := []byte(`{"message_id":"1"}`)
+	c.processFetches(context.Background(), fetchesOf(&kgo.Record{Topic: testTopic.String(), Offset: 1, Value: value}))
+
+	if called {
+		t.Error("handler called for an invalid envelope")
+	}
+	if len(fc.produced) != 1 || fc.produced[0].Topic != "test.dlq" {
+		t.Fatalf("produced = %v; want one dead-lettered record", fc.produced)
+	}
+	if string(fc.produced[0].Value) != string(value) {
+		t.Errorf("dead-lettered value = %s; want the original %s", fc.produced[0].Value, value)
+	}
+	if len(fc.committed) != 1 {
+		t.Errorf("committed %d records; want 1", len(fc.committed))
+	}
+}
+
+func TestProcessDecodeErrorIsPermanent(t *testing.T) {
+	c, registry, fc := newTestConsumer(3, time.Minute)
+	calls := 0
+	Register(registry, testTopic, func(ctx context.Context, env *envelope.Envelope, data []int) error {
+		calls++
+		return nil
+	})
+
+	c.processFetches(context.Background(), fetchesOf(testRecord(t, testTopic.String(), 1)))
+
+	if calls != 0 {
+		t.Errorf("typed handler called %d times with undecodable data", calls)
+	}
+	if len(fc.produced) != 1 || fc.produced[0].Topic != "test.dlq" {
+		t.Fatalf("produced = %v; want one dead-lettered record", fc.produced)
+	}
+}
+
+func TestProcessWithoutHandlerCommits(t *testing.T) {
+	c, _, fc := newTestConsumer(1, time.Minute)
+
+	c.processFetches(context.Background(), fetchesOf(testRecord(t, "other.topic", 9)))
+
+	if got := committedOffsets(fc); got["other.topic/0"] != 9 {
+		t.Errorf("committed = %v; want offset 9", got)
+	}
+	if len(fc.produced) != 0 {
+		t.Errorf("produced %d records for an unhandled topic", len(fc.produced))
+	}
+}
+
+func TestProcessRetriesInProcess(t *testing.T) {
+	c, registry, fc := newTestConsumer(2, time.Minute)
+	calls := 0
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		calls++
+		if calls == 1 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+
+	c.processFetches(context.Background(), fetchesOf(testRecord(t, testTopic.String(), 1)))
+
+	if calls != 2 {
+		t.Errorf("handler called %d times; want 2", calls)
+	}
+	if len(fc.produced) != 0 || len(fc.committed) != 1 {
+		t.Errorf("produced %d, committed %d; want 0 and 1", len(fc.produced), len(fc.committed))
+	}
+}
+
+func TestProcessForwardFailureRewinds(t *testing.T) {
+	c, registry, fc := newTestConsumer(1, time.Minute)
+	fc.produceErr = errors.New("broker down")
+	var handled []int64
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		handled = append(handled, r.Offset)
+		if r.Offset == 2 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+
+	c.processFetches(context.Background(), fetchesOf(
+		testRecord(t, testTopic.String(), 1),
+		testRecord(t, testTopic.String(), 2),
+		testRecord(t, testTopic.String(), 3),
+	))
+
+	if len(handled) != 2 {
+		t.Errorf("handled = %v; want processing to stop at the failed record", handled)
+	}
+	if got := committedOffsets(fc); got[testTopic.String()+"/0"] != 1 {
+		t.Errorf("committed = %v; want offset 1 only", got)
+	}
+	if got := fc.rewound[testTopic.String()][0].Offset; got != 2 {
+		t.Errorf("rewound to %d; want 2", got)
+	}
+}
+
+func TestProcessRetryNotDue(t *testing.T) {
+	c, registry, fc := newTestConsumer(1, time.Minute)
+	called := false
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		called = true
+		return nil
+	})
+
+	due := time.Now().Add(time.Hour)
+	r := testRecord(t, "test.retry.1", 4,
+		kgo.RecordHeader{Key: dlq.HeaderOriginalTopic, Value: []byte(testTopic)},
+		kgo.RecordHeader{Key: dlq.HeaderNotBefore, Value: []byte(strconv.FormatInt(due.UnixMilli(), 10))},
+	)
+	c.processFetches(context.Background(), fetchesOf(r))
+
+	if called {
+		t.Error("handler called before the retry was due")
+	}
+	if got := fc.rewound["test.retry.1"][0].Offset; got != 4 {
+		t.Errorf("rewound to %d; want 4", got)
+	}
+	if len(fc.paused["test.retry.1"]) != 1 {
+		t.Errorf("paused = %v; want the retry partition", fc.paused)
+	}
+	if len(fc.committed) != 0 {
+		t.Errorf("committed %d records; want none", len(fc.committed))
+	}
+}
+
+func TestProcessShutdownRedelivers(t *testing.T) {
+	c, registry, fc := newTestConsumer(2, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	registry.Handle(testTopic, func(hctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		cancel()
+		return errors.New("temporary")
+	})
+
+	c.processFetches(ctx, fetchesOf(testRecord(t, testTopic.String(), 7)))
+
+	if len(fc.produced) != 0 {
+		t.Errorf("produced %d records; an interrupted record must not use a retry tier", len(fc.produced))
+	}
+	if got := fc.rewound[testTopic.String()][0].Offset; got != 7 {
+		t.Errorf("rewound to %d; want 7", got)
+	}
+}
+
+func TestForwardKeepsRecordVerbatim(t *testing.T) {
+	c, _, fc := newTestConsumer(1, time.Minute, time.Hour)
+	r := testRecord(t, testTopic.String(), 1, kgo.RecordHeader{Key: "traceparent", Value: []byte("00-abc-def-01")})
+	r.Key = []byte("user-1")
+	env, err := envelope.Unmarshal(r.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, count, err := c.forward(context.Background(), r, env, errors.New("temporary"), false)
+	if err != nil || result != resultRetried || count != 1 {
+		t.Fatalf("forward = %q, %d, %v; want %q, 1", result, count, err, resultRetried)
+	}
+	out := fc.produced[0]
+	if !bytes.Equal(out.Value, r.Value) || !bytes.Equal(out.Key, r.Key) {
+		t.Errorf("forwarded key/value = %s/%s; want %s/%s", out.Key, out.Value, r.Key, r.Value)
+	}
+	if dlq.Header(out, "traceparent") != "00-abc-def-01" {
+		t.Errorf("headers = %v; want the trace context kept", out.Headers)
+	}
+	if dlq.RetryCount(out) != 1 {
+		t.Errorf("retry count header = %d; want 1", dlq.RetryCount(out))
+	}
+}
+
+func TestForwardHonoursLegacyEnvelopeCount(t *testing.T) {
+	c, _, fc := newTestConsumer(1, time.Minute, time.Hour)
+	r := testRecord(t, "test.retry.1", 1, kgo.RecordHeader{Key: dlq.HeaderOriginalTopic, Value: []byte(testTopic)})
+	env, err := envelope.Unmarshal(r.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Metadata = &envelope.Metadata{RetryCount: 2}
+
+	result, _, err := c.forward(context.Background(), r, env, errors.New("temporary"), false)
+	if err != nil || result != resultDeadLettered {
+		t.Fatalf("forward = %q, %v; want %q", result, err, resultDeadLettered)
+	}
+	if fc.produced[0].Topic != "test.dlq" {
+		t.Errorf("forwarded to %s; want test.dlq", fc.produced[0].Topic)
+	}
+}
+
+func withTestDedupe(t *testing.T, c *Consumer) *dedupe.Store {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	c.dedupe = dedupe.NewStore(rdb, "test", time.Hour)
+	return c.dedupe
+}
+
+func TestProcessSkipsDuplicates(t *testing.T) {
+	c, registry, fc := newTestConsumer(1)
+	withTestDedupe(t, c)
+	calls := 0
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		calls++
+		return nil
+	})
+
+	r := testRecord(t, testTopic.String(), 1)
+	redelivered := *r
+	redelivered.Offset = 2
+	c.processFetches(context.Background(), fetchesOf(r, &redelivered))
+
+	if calls != 1 {
+		t.Errorf("handler called %d times; want the redelivery skipped", calls)
+	}
+	if got := committedOffsets(fc); got[testTopic.String()+"/0"] != 2 {
+		t.Errorf("committed = %v; want offset 2", got)
+	}
+}
+
+func TestProcessFailureReleasesClaim(t *testing.T) {
+	c, registry, fc := newTestConsumer(1, time.Minute)
+	withTestDedupe(t, c)
+	fail := true
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		if fail {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+
+	r := testRecord(t, testTopic.String(), 1)
+	c.processFetches(context.Background(), fetchesOf(r))
+	if len(fc.produced) != 1 {
+		t.Fatalf("produced %d records; want the failure retried", len(fc.produced))
+	}
+
+	// The retried copy is handled, not seen as in progress or done.
+	fail = false
+	retried := fc.produced[0]
+	retried.Offset = 0
+	retried.Headers = slices.DeleteFunc(retried.Headers, func(h kgo.RecordHeader) bool { return h.Key == dlq.HeaderNotBefore })
+	c.processFetches(context.Background(), fetchesOf(retried))
+	if len(fc.produced) != 1 {
+		t.Errorf("produced %d records; want the retry handled", len(fc.produced))
+	}
+}
+
+func TestProcessInProgressWaitsWithoutRetry(t *testing.T) {
+	// No retry tiers: an in-flight message must still not be dead-lettered.
+	c, registry, fc := newTestConsumer(1)
+	store := withTestDedupe(t, c)
+	called := false
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		called = true
+		return nil
+	})
+
+	r := testRecord(t, testTopic.String(), 5)
+	env, err := envelope.Unmarshal(r.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another consumer holds the claim.
+	if _, _, err := store.Claim(context.Background(), env.MessageID, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	c.processFetches(context.Background(), fetchesOf(r))
+
+	if called {
+		t.Error("handler called while another consumer holds the claim")
+	}
+	if len(fc.produced) != 0 {
+		t.Errorf("produced %v; want nothing forwarded", fc.produced)
+	}
+	if len(fc.committed) != 0 {
+		t.Errorf("committed %d records; want none", len(fc.committed))
+	}
+	if got := fc.rewound[testTopic.String()][0].Offset; got != 5 {
+		t.Errorf("rewound to %d; want 5", got)
+	}
+	if len(fc.paused[testTopic.String()]) != 1 {
+		t.Errorf("paused = %v; want the partition paused", fc.paused)
+	}
+}
+
+func withTestDeferred(t *testing.T, c *Consumer) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	c.deferred = deferred.NewStore(rdb, "test")
+}
+
+func TestProcessDeferHoldsRecordUntilDue(t *testing.T) {
+	c, registry, fc := newTestConsumer(3, time.Minute)
+	withTestDedupe(t, c)
+	withTestDeferred(t, c)
+	calls := 0
+	until := time.Now().Add(time.Hour)
+	registry.Handle(testTopic, func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error {
+		calls++
+		if calls == 1 {
+			return Defer(errors.New("quiet hours"), until)
+		}
+		return nil
+	})
+
+	r := testRecord(t, testTopic.String(), 4, kgo.RecordHeader{Key: "traceparent", Value: []byte("tp")})
+	c.processFetches(context.Background(), fetchesOf(r))
+
+	if calls != 1 {
+		t.Errorf("handler called %d times; want a deferral not retried in process", calls)
+	}
+	if len(fc.produced) != 0 {
+		t.Errorf("produced %v; want nothing until due", fc.produced)
+	}
+	if got := committedOffsets(fc); got[testTopic.String()+"/0"] != 4 {
+		t.Errorf("committed = %v; want offset 4", got)
+	}
+	if n := c.releaseDue(context.Background()); n != 0 {
+		t.Fatalf("released %d records before they were due", n)
+	}
+
+	taken, err := c.deferred.Take(context.Background(), until, time.Minute, 10)
+	if err != nil || len(taken) != 1 {
+		t.Fatalf("Take at until = %d, %v; want the record", len(taken), err)
+	}
+	if err := c.deferred.Remove(context.Background(), taken...); err != nil {
+		t.Fatal(err)
+	}
+	out := taken[0].Record
+	if out.Topic != "test.deferred" || dlq.OriginalTopic(out) != testTopic.String() || dlq.Header(out, "traceparent") != "tp" {
+		t.Errorf("deferred record = %s from %s; want test.deferred keeping origin and trace", out.Topic, dlq.OriginalTopic(out))
+	}
+	if dlq.RetryCount(out) != 0 || !dlq.NotBefore(out).IsZero() {
+		t.Errorf("deferred record carries retry state: %v", out.Headers)
+	}
+
+	// Delivered from the deferred topic, it runs the original handler, and
+	// the claim released by the deferral lets it through.
+	out.Offset = 0
+	c.processFetches(context.Background(), fetchesOf(out))
+	if calls != 2 {
+		t.Errorf("handler called %d times; want the deferred record handled", calls)
+	}
+}
+
+func TestReleaseDuePublishes(t *testing.T) {
+	c, _, fc := newTestConsumer(1)
+	withTestDeferred(t, c)
+	ctx := context.Background()
+	r := testRecord(t, testTopic.String(), 1)
+	if err := c.deferRecord(ctx, r, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	fc.produceErr = errors.New("broker down")
+	if n := c.releaseDue(ctx); n != 1 {
+		t.Fatalf("released %d; want 1 taken", n)
+	}
+	// A failed publish stays in the store, hidden for the lease.
+	if n := c.releaseDue(ctx); n != 0 {
+		t.Errorf("released %d during the lease; want 0", n)
+	}
+
+	fc.produceErr = nil
+	entries, err := c.deferred.Take(ctx, time.Now().Add(deferredLease), deferredLease, 10)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Take after the lease = %d, %v; want the record back", len(entries), err)
+	}
+}
+
+func TestProcessDeferKeepsRetryCount(t *testing.T) {
+	c, _, _ := newTestConsumer(1, time.Minute, time.Hour)
+	withTestDeferred(t, c)
+	ctx := context.Background()
+	r := testRecord(t, "test.retry.2", 1,
+		kgo.RecordHeader{Key: dlq.HeaderOriginalTopic, Value: []byte(testTopic.String())},
+		kgo.RecordHeader{Key: dlq.HeaderRetryCount, Value: []byte("2")},
+		kgo.RecordHeader{Key: dlq.HeaderNotBefore, Value: []byte("1")},
+	)
+	if err := c.deferRecord(ctx, r, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := c.deferred.Take(ctx, time.Now(), time.Minute, 10)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Take = %d, %v", len(entries), err)
+	}
+	out := entries[0].Record
+	if dlq.RetryCount(out) != 2 || !dlq.NotBefore(out).IsZero() || dlq.OriginalTopic(out) != testTopic.String() {
+		t.Errorf("deferred record headers = %v; want the count kept and the delay dropped", out.Headers)
+	}
+}
@@ -0,0 +1,111 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notification/internal/kafka/envelope"
+	"sort"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Handler processes one decoded and validated envelope. A returned error is
+// retried unless it is wrapped with Permanent or Defer.
+type Handler func(ctx context.Context, env *envelope.Envelope, r *kgo.Record) error
+
+// TypedHandler receives the envelope data decoded into T.
+type TypedHandler[T any] func(ctx context.Context, env *envelope.Envelope, data T) error
+
+// Registry maps topics to their handler.
+type Registry struct {
+	handlers map[string]Handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]Handler)}
+}
+
+// Handle registers h for topic. Registering a topic twice panics, as it is a
+// wiring mistake.
+func (r *Registry) Handle(topic envelope.Topic, h Handler) {
+	if _, ok := r.handlers[topic.String()]; ok {
+		panic(fmt.Sprintf("consumer: handler for %s registered twice", topic))
+	}
+	r.handlers[topic.String()] = h
+}
+
+// Register registers fn for topic, decoding the envelope data into T first.
+// Data that does not decode is a permanent failure.
+func Register[T any](r *Registry, topic envelope.Topic, fn TypedHandler[T]) {
+	r.Handle(topic, func(ctx context.Context, env *envelope.Envelope, _ *kgo.Record) error {
+		var data T
+		if err := env.GetData(&data); err != nil {
+			return Permanent(err)
+		}
+		return fn(ctx, env, data)
+	})
+}
+
+// Handler returns the handler registered for topic.
+func (r *Registry) Handler(topic string) (Handler, bool) {
+	h, ok := r.handlers[topic]
+	return h, ok
+}
+
+// Topics returns the registered topics, sorted.
+func (r *Registry) Topics() []string {
+	topics := make([]string, 0, len(r.handlers))
+	for t := range r.handlers {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the record goes straight to the
+// dead-letter topic.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+type deferredError struct {
+	err   error
+	until time.Time
+}
+
+func (e *deferredError) Error() string { return e.err.Error() }
+func (e *deferredError) Unwrap() error { return e.err }
+
+// Defer asks for the record to be handled again at until, without counting a
+// retry: it is held in Redis and published to the deferred topic once due.
+func Defer(err error, until time.Time) error {
+	return &deferredError{err: err, until: until}
+}
+
+// deferredUntil returns when a record whose handler returned err is due
+// again, if err was made with Defer.
+func deferredUntil(err error) (time.Time, bool) {
+	var d *deferredError
+	if errors.As(err, &d) {
+		return d.until, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,102 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"music-player/api/tracing"
+	"notification/internal/kafka/dlq"
+	"notification/internal/kafka/envelope"
+	"strconv"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const forwardTimeout = 10 * time.Second
+
+// retryTopics returns the topic of every retry tier.
+func (c *Consumer) retryTopics() []string {
+	topics := make([]string, 0, len(c.cfg.RetryDelays))
+	for n := 1; n <= len(c.cfg.RetryDelays); n++ {
+		topics = append(topics, c.cfg.RetryTopic(n))
+	}
+	return topics
+}
+
+// forward moves r out of the way after it failed: to the next retry tier
+// with that tier's delay, or to the dead-letter topic once the tiers are
+// used up or when the failure is permanent. The key, value and headers are
+// kept as they were consumed; the retry state lives in the pipeline headers.
+// env is nil when the value is not a valid envelope.
+//
+// It returns the metrics result and the retry count carried by the forwarded
+// record, or an error if the record could not be produced, in which case it
+// has to be redelivered.
+func (c *Consumer) forward(ctx context.Context, r *kgo.Record, env *envelope.Envelope, cause error, permanent bool) (string, int, error) {
+	count := dlq.RetryCount(r)
+	if env != nil && env.Metadata != nil {
+		// Records retried by earlier versions carry the count in the
+		// envelope.
+		count = max(count, env.Metadata.RetryCount)
+	}
+
+	now := time.Now()
+	topic, result := c.cfg.DLQTopic(), resultDeadLettered
+	set := map[string]string{
+		dlq.HeaderError: cause.Error(),
+	}
+	if next := count + 1; !permanent && next <= len(c.cfg.RetryDelays) {
+		topic, result, count = c.cfg.RetryTopic(next), resultRetried, next
+		set[dlq.HeaderNotBefore] = strconv.FormatInt(now.Add(c.cfg.RetryDelays[next-1]).UnixMilli(), 10)
+	} else {
+		set[dlq.HeaderFailedAt] = now.UTC().Format(time.RFC3339)
+	}
+	set[dlq.HeaderRetryCount] = strconv.Itoa(count)
+
+	out := dlq.Forward(r, topic, r.Value, set)
+	pctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forwardTimeout)
+	defer cancel()
+	pctx, span := tracing.StartProducerSpan(pctx, out)
+	res := c.cl.ProduceSync(pctx, out)
+	out, err := res.First()
+	tracing.EndProducerSpan(span, out, err)
+	if err != nil {
+		return "", 0, err
+	}
+	return result, count, nil
+}
+
+// notDue reports whether r belongs to a retry tier and was read before its
+// delay passed, and when it is due.
+func notDue(r *kgo.Record, now time.Time) (time.Time, bool) {
+	due := dlq.NotBefore(r)
+	return due, !due.IsZero() && now.Before(due)
+}
+
+// pauseUntil stops fetching the delayed partitions and resumes each once its
+// record is due. The pause outlives rebalances, so a partition moved to
+// another member and back still resumes on time.
+func (c *Consumer) pauseUntil(delayed map[string]map[int32]time.Time) {
+	pause := make(map[string][]int32)
+	for topic, partitions := range delayed {
+		for p := range partitions {
+			pause[topic] = append(pause[topic], p)
+		}
+	}
+	c.cl.PauseFetchPartitions(pause)
+
+	for topic, partitions := range delayed {
+		for p, due := range partitions {
+			resume := map[string][]int32{topic: {p}}
+			time.AfterFunc(time.Until(due), func() {
+				c.cl.ResumeFetchPartitions(resume)
+			})
+		}
+	}
+}
+
+// isShutdown reports whether err is only the consume loop stopping, in which
+// case the record is redelivered rather than moved to a retry tier.
+func isShutdown(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && errors.Is(err, ctx.Err())
+}
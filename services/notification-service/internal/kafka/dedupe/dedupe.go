@@ -0,0 +1,124 @@
+// Package dedupe remembers, per consumer group, which envelopes were handled
+// so that Kafka redeliveries of the same MessageID are skipped.
+package dedupe
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// State is the outcome of Claim.
+type State int
+
+const (
+	// Claimed means the caller owns the message and must Complete or Release
+	// the claim.
+	Claimed State = iota
+	// Done means the message was already handled within the TTL.
+	Done
+	// InProgress means another consumer holds an unexpired claim.
+	InProgress
+)
+
+func (s State) String() string {
+	switch s {
+	case Claimed:
+		return "claimed"
+	case Done:
+		return "done"
+	default:
+		return "in_progress"
+	}
+}
+
+const doneValue = "done"
+
+// claimScript sets the key to the claim token unless it exists, and reports
+// 0 when claimed, 1 when done and 2 when claimed by someone else.
+var claimScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if not v then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 0
+end
+if v == ARGV[3] then
+	return 1
+end
+return 2
+`)
+
+// releaseScript deletes the key only while it still holds the caller's claim.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Store keeps one key per MessageID in Redis. A key holds a claim token while
+// a consumer runs the handler, bounded by a lease so a crashed consumer does
+// not block the message for good, and "done" for TTL once the handler
+// succeeded.
+type Store struct {
+	rdb    *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewStore scopes keys to group: another consumer group handles the same
+// messages independently.
+func NewStore(rdb *redis.Client, group string, ttl time.Duration) *Store {
+	return &Store{rdb: rdb, prefix: "notification:dedupe:" + group + ":", ttl: ttl}
+}
+
+// Claim is held by the consumer running the handler of a message.
+type Claim struct {
+	store *Store
+	key   string
+	token string
+}
+
+// Claim marks id as being handled for lease, unless it is done or already
+// claimed. The returned claim is nil unless the state is Claimed.
+func (s *Store) Claim(ctx context.Context, id string, lease time.Duration) (*Claim, State, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, 0, err
+	}
+	key := s.prefix + id
+	n, err := claimScript.Run(ctx, s.rdb, []string{key}, token, lease.Milliseconds(), doneValue).Int()
+	if err != nil {
+		return nil, 0, err
+	}
+	switch n {
+	case 0:
+		return &Claim{store: s, key: key, token: token}, Claimed, nil
+	case 1:
+		return nil, Done, nil
+	default:
+		return nil, InProgress, nil
+	}
+}
+
+// Complete records the message as handled for the store's TTL.
+func (c *Claim) Complete(ctx context.Context) error {
+	return c.store.rdb.Set(ctx, c.key, doneValue, c.store.ttl).Err()
+}
+
+// Release drops the claim after the handler failed, so a redelivery can run
+// it again. A claim that expired and was taken over is left alone.
+func (c *Claim) Release(ctx context.Context) error {
+	return releaseScript.Run(ctx, c.store.rdb, []string{c.key}, c.token).Err()
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "claim:" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,108 @@
+package dedupe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T, group string) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewStore(rdb, group, time.Hour), mr
+}
+
+func TestClaimComplete(t *testing.T) {
+	s, mr := newTestStore(t, "g")
+	ctx := context.Background()
+
+	claim, state, err := s.Claim(ctx, "m1", time.Minute)
+	if err != nil || state != Claimed || claim == nil {
+		t.Fatalf("Claim = %v, %v, %v; want Claimed", claim, state, err)
+	}
+	if _, state, _ := s.Claim(ctx, "m1", time.Minute); state != InProgress {
+		t.Fatalf("second Claim = %v; want InProgress", state)
+	}
+
+	if err := claim.Complete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, state, _ := s.Claim(ctx, "m1", time.Minute); state != Done {
+		t.Fatalf("Claim after Complete = %v; want Done", state)
+	}
+	if ttl := mr.TTL("notification:dedupe:g:m1"); ttl != time.Hour {
+		t.Errorf("done TTL = %v; want the store TTL", ttl)
+	}
+
+	// The done mark expires with the TTL.
+	mr.FastForward(time.Hour)
+	if _, state, _ := s.Claim(ctx, "m1", time.Minute); state != Claimed {
+		t.Errorf("Claim after TTL = %v; want Claimed", state)
+	}
+}
+
+func TestClaimRelease(t *testing.T) {
+	s, _ := newTestStore(t, "g")
+	ctx := context.Background()
+
+	claim, _, err := s.Claim(ctx, "m1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := claim.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, state, _ := s.Claim(ctx, "m1", time.Minute); state != Claimed {
+		t.Errorf("Claim after Release = %v; want Claimed", state)
+	}
+}
+
+func TestClaimLeaseExpires(t *testing.T) {
+	s, mr := newTestStore(t, "g")
+	ctx := context.Background()
+
+	stale, _, err := s.Claim(ctx, "m1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(time.Minute)
+
+	// The holder died: after the lease another consumer takes over.
+	fresh, state, err := s.Claim(ctx, "m1", time.Minute)
+	if err != nil || state != Claimed {
+		t.Fatalf("Claim after lease = %v, %v; want Claimed", state, err)
+	}
+	// The late release of the stale claim leaves the new one alone.
+	if err := stale.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, state, _ := s.Claim(ctx, "m1", time.Minute); state != InProgress {
+		t.Errorf("Claim after stale release = %v; want InProgress", state)
+	}
+	if err := fresh.Complete(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreScopedToGroup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx := context.Background()
+
+	claim, _, err := NewStore(rdb, "a", time.Hour).Claim(ctx, "m1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := claim.Complete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, state, _ := NewStore(rdb, "b", time.Hour).Claim(ctx, "m1", time.Minute); state != Claimed {
+		t.Errorf("other group sees %v; want Claimed", state)
+	}
+}
@@ -0,0 +1,103 @@
+// Package deferred holds, per consumer group, the records whose handler
+// asked to run again later, such as notifications held back by quiet hours.
+// They wait in a Redis sorted set scored by when they are due.
+package deferred
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// takeScript returns up to ARGV[3] members due by ARGV[1] and moves them to
+// ARGV[2], so other consumers do not take them too. Members not removed by
+// then, because their taker died, become due again.
+var takeScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, m in ipairs(due) do
+	redis.call('ZADD', KEYS[1], ARGV[2], m)
+end
+return due
+`)
+
+// Store keeps the records of one consumer group.
+type Store struct {
+	rdb *redis.Client
+	key string
+}
+
+// NewStore scopes the records to group.
+func NewStore(rdb *redis.Client, group string) *Store {
+	return &Store{rdb: rdb, key: "notification:deferred:" + group}
+}
+
+// Entry is a record taken from the store.
+type Entry struct {
+	Record *kgo.Record
+	member string
+}
+
+// record is the stored form of a *kgo.Record.
+type record struct {
+	Topic   string   `json:"topic"`
+	Key     []byte   `json:"key,omitempty"`
+	Value   []byte   `json:"value"`
+	Headers []header `json:"headers,omitempty"`
+}
+
+type header struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Add stores r, which is produced as is once until has passed.
+func (s *Store) Add(ctx context.Context, r *kgo.Record, until time.Time) error {
+	rec := record{Topic: r.Topic, Key: r.Key, Value: r.Value}
+	for _, h := range r.Headers {
+		rec.Headers = append(rec.Headers, header{Key: h.Key, Value: h.Value})
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.rdb.ZAdd(ctx, s.key, redis.Z{Score: float64(until.UnixMilli()), Member: string(b)}).Err()
+}
+
+// Take returns up to limit records due at now. They must be removed once
+// produced; those that are not are returned again after lease.
+func (s *Store) Take(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Entry, error) {
+	members, err := takeScript.Run(ctx, s.rdb, []string{s.key}, now.UnixMilli(), now.Add(lease).UnixMilli(), limit).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, 0, len(members))
+	for _, m := range members {
+		var rec record
+		if err := json.Unmarshal([]byte(m), &rec); err != nil {
+			// Not written by Add: drop it rather than fail every poll.
+			s.rdb.ZRem(ctx, s.key, m)
+			continue
+		}
+		r := &kgo.Record{Topic: rec.Topic, Key: rec.Key, Value: rec.Value}
+		for _, h := range rec.Headers {
+			r.Headers = append(r.Headers, kgo.RecordHeader{Key: h.Key, Value: h.Value})
+		}
+		entries = append(entries, &Entry{Record: r, member: m})
+	}
+	return entries, nil
+}
+
+// Remove drops entries that were produced.
+func (s *Store) Remove(ctx context.Context, entries ...*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	members := make([]any, len(entries))
+	for i, e := range entries {
+		members[i] = e.member
+	}
+	return s.rdb.ZRem(ctx, s.key, members...).Err()
+}
@@ -0,0 +1,103 @@
+package deferred
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func newTestStore(t *testing.T) (*Store, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewStore(rdb, "g"), rdb
+}
+
+func TestTakeDue(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	now := time.Date(2025, 11, 1, 22, 30, 0, 0, time.UTC)
+
+	r := &kgo.Record{
+		Topic:   "g.deferred",
+		Key:     []byte("user-1"),
+		Value:   []byte(`{"message_id":"m1"}`),
+		Headers: []kgo.RecordHeader{{Key: "traceparent", Value: []byte("tp")}},
+	}
+	if err := s.Add(ctx, r, now.Add(8*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(ctx, &kgo.Record{Topic: "g.deferred", Value: []byte("m2")}, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := s.Take(ctx, now, time.Minute, 10); err != nil || len(got) != 0 {
+		t.Fatalf("Take before due = %d, %v; want none", len(got), err)
+	}
+	got, err := s.Take(ctx, now.Add(8*time.Hour), time.Minute, 10)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("Take when due = %d, %v; want both", len(got), err)
+	}
+	// The earliest comes first.
+	if string(got[0].Record.Value) != "m2" {
+		t.Errorf("first taken = %s; want m2", got[0].Record.Value)
+	}
+	out := got[1].Record
+	if out.Topic != r.Topic || string(out.Key) != "user-1" || string(out.Value) != string(r.Value) ||
+		len(out.Headers) != 1 || string(out.Headers[0].Value) != "tp" {
+		t.Errorf("taken record = %+v; want the stored one", out)
+	}
+}
+
+func TestTakeLease(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	now := time.Date(2025, 11, 1, 7, 0, 0, 0, time.UTC)
+	if err := s.Add(ctx, &kgo.Record{Topic: "t", Value: []byte("v")}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Take(ctx, now, time.Minute, 10)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("Take = %d, %v", len(got), err)
+	}
+	// Another consumer does not get it while the lease runs.
+	if again, _ := s.Take(ctx, now.Add(30*time.Second), time.Minute, 10); len(again) != 0 {
+		t.Errorf("taken twice within the lease")
+	}
+	// A taker that died without removing it hands it over after the lease.
+	if again, _ := s.Take(ctx, now.Add(time.Minute), time.Minute, 10); len(again) != 1 {
+		t.Fatalf("not taken again after the lease")
+	}
+
+	if err := s.Remove(ctx, got...); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := s.Take(ctx, now.Add(time.Hour), time.Minute, 10); len(again) != 0 {
+		t.Errorf("removed record taken again")
+	}
+}
+
+func TestTakeLimitAndGarbage(t *testing.T) {
+	s, rdb := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	for i := range 3 {
+		if err := s.Add(ctx, &kgo.Record{Topic: "t", Value: []byte{byte('a' + i)}}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rdb.ZAdd(ctx, s.key, redis.Z{Score: 0, Member: "not json"})
+
+	if got, _ := s.Take(ctx, now, time.Minute, 2); len(got) != 1 {
+		t.Errorf("Take(limit 2) = %d; want 1, the garbage member dropped", len(got))
+	}
+	if n := rdb.ZCard(ctx, s.key).Val(); n != 3 {
+		t.Errorf("%d members left; want the 3 records", n)
+	}
+}
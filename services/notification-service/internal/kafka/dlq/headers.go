@@ -0,0 +1,113 @@
+// Package dlq defines the headers of records moving through the retry tiers
+// and the dead-letter topic, and the tools to inspect, replay and purge the
+// dead-letter topic.
+package dlq
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Headers set on retried and dead-lettered records. The original-* headers
+// are set once, when a record first leaves its topic, and kept across tiers.
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderRetryCount        = "x-retry-count"
+	HeaderNotBefore         = "x-retry-not-before"
+	HeaderError             = "x-error"
+	HeaderFailedAt          = "x-failed-at"
+)
+
+// maxErrorLen bounds the error cause kept in HeaderError.
+const maxErrorLen = 1024
+
+// Header returns the value of the last header named key, or "".
+func Header(r *kgo.Record, key string) string {
+	for i := len(r.Headers) - 1; i >= 0; i-- {
+		if r.Headers[i].Key == key {
+			return string(r.Headers[i].Value)
+		}
+	}
+	return ""
+}
+
+// OriginalTopic returns the topic r was first published to.
+func OriginalTopic(r *kgo.Record) string {
+	if t := Header(r, HeaderOriginalTopic); t != "" {
+		return t
+	}
+	return r.Topic
+}
+
+// RetryCount returns the number of retries recorded on r.
+func RetryCount(r *kgo.Record) int {
+	n, _ := strconv.Atoi(Header(r, HeaderRetryCount))
+	return n
+}
+
+// NotBefore returns when a retried record becomes due, or the zero time.
+func NotBefore(r *kgo.Record) time.Time {
+	ms, err := strconv.ParseInt(Header(r, HeaderNotBefore), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// Forward returns a copy of r for topic carrying value. The original-*
+// headers are added if r has none yet, and the pipeline headers are replaced
+// by set. Other headers, such as the trace context, are kept.
+func Forward(r *kgo.Record, topic string, value []byte, set map[string]string) *kgo.Record {
+	out := &kgo.Record{Topic: topic, Key: r.Key, Value: value}
+	for _, h := range r.Headers {
+		if isPipelineHeader(h.Key) {
+			continue
+		}
+		out.Headers = append(out.Headers, h)
+	}
+
+	origin := map[string]string{
+		HeaderOriginalTopic:     r.Topic,
+		HeaderOriginalPartition: strconv.Itoa(int(r.Partition)),
+		HeaderOriginalOffset:    strconv.FormatInt(r.Offset, 10),
+	}
+	for _, key := range []string{HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset} {
+		v := Header(r, key)
+		if v == "" {
+			v = origin[key]
+		}
+		out.Headers = append(out.Headers, kgo.RecordHeader{Key: key, Value: []byte(v)})
+	}
+	for key, v := range set {
+		if key == HeaderError && len(v) > maxErrorLen {
+			v = v[:maxErrorLen]
+		}
+		out.Headers = append(out.Headers, kgo.RecordHeader{Key: key, Value: []byte(v)})
+	}
+	return out
+}
+
+// Strip returns a copy of r for topic carrying value, without any pipeline
+// header, as if it was published there for the first time.
+func Strip(r *kgo.Record, topic string, value []byte) *kgo.Record {
+	out := &kgo.Record{Topic: topic, Key: r.Key, Value: value}
+	for _, h := range r.Headers {
+		if !isPipelineHeader(h.Key) {
+			out.Headers = append(out.Headers, h)
+		}
+	}
+	return out
+}
+
+func isPipelineHeader(key string) bool {
+	switch key {
+	case HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset,
+		HeaderRetryCount, HeaderNotBefore, HeaderError, HeaderFailedAt:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package dlq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestForwardSetsOriginOnce(t *testing.T) {
+	r := &kgo.Record{
+		Topic:     "user.registered",
+		Partition: 3,
+		Offset:    42,
+		Key:       []byte("k"),
+		Value:     []byte(`{"a":1}`),
+		Headers:   []kgo.RecordHeader{{Key: "traceparent", Value: []byte("tp")}},
+	}
+
+	tier1 := Forward(r, "group.retry.1", r.Value, map[string]string{HeaderRetryCount: "1", HeaderError: "boom"})
+	tier1.Partition, tier1.Offset = 0, 7
+	tier2 := Forward(tier1, "group.retry.2", tier1.Value, map[string]string{HeaderRetryCount: "2"})
+
+	for _, out := range []*kgo.Record{tier1, tier2} {
+		if OriginalTopic(out) != "user.registered" || Header(out, HeaderOriginalPartition) != "3" || Header(out, HeaderOriginalOffset) != "42" {
+			t.Errorf("%s origin = %s/%s/%s; want user.registered/3/42", out.Topic,
+				OriginalTopic(out), Header(out, HeaderOriginalPartition), Header(out, HeaderOriginalOffset))
+		}
+		if Header(out, "traceparent") != "tp" {
+			t.Errorf("%s lost the trace context", out.Topic)
+		}
+		if string(out.Key) != "k" || string(out.Value) != `{"a":1}` {
+			t.Errorf("%s key/value = %s/%s; want them unchanged", out.Topic, out.Key, out.Value)
+		}
+	}
+	if RetryCount(tier2) != 2 {
+		t.Errorf("RetryCount = %d; want 2", RetryCount(tier2))
+	}
+	// Pipeline headers are replaced, not accumulated.
+	if Header(tier2, HeaderError) != "" {
+		t.Errorf("error header carried over: %q", Header(tier2, HeaderError))
+	}
+	n := 0
+	for _, h := range tier2.Headers {
+		if h.Key == HeaderOriginalTopic {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("%d original-topic headers; want 1", n)
+	}
+}
+
+func TestForwardTruncatesError(t *testing.T) {
+	long := make([]byte, 2*maxErrorLen)
+	for i := range long {
+		long[i] = 'x'
+	}
+	out := Forward(&kgo.Record{Topic: "t"}, "dlq", nil, map[string]string{HeaderError: string(long)})
+	if got := len(Header(out, HeaderError)); got != maxErrorLen {
+		t.Errorf("error header length = %d; want %d", got, maxErrorLen)
+	}
+}
+
+func TestStrip(t *testing.T) {
+	r := &kgo.Record{
+		Topic: "group.dlq",
+		Headers: []kgo.RecordHeader{
+			{Key: "traceparent", Value: []byte("tp")},
+			{Key: HeaderOriginalTopic, Value: []byte("user.registered")},
+			{Key: HeaderRetryCount, Value: []byte("3")},
+		},
+	}
+	out := Strip(r, "user.registered", []byte("v"))
+	if out.Topic != "user.registered" || len(out.Headers) != 1 || out.Headers[0].Key != "traceparent" {
+		t.Errorf("Strip = %s %v; want user.registered with only traceparent", out.Topic, out.Headers)
+	}
+}
+
+func TestNotBefore(t *testing.T) {
+	due := time.UnixMilli(1761998400123)
+	r := &kgo.Record{Headers: []kgo.RecordHeader{{Key: HeaderNotBefore, Value: []byte("1761998400123")}}}
+	if got := NotBefore(r); !got.Equal(due) {
+		t.Errorf("NotBefore = %v; want %v", got, due)
+	}
+	if got := NotBefore(&kgo.Record{}); !got.IsZero() {
+		t.Errorf("NotBefore without header = %v; want zero", got)
+	}
+}
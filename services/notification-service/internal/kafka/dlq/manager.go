@@ -0,0 +1,400 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"music-player/api/logger"
+	"notification/configs"
+	"notification/internal/kafka/envelope"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+var lg = logger.For("kafka.dlq")
+
+var (
+	ErrInvalidSelection = errors.New("dlq: offset requires a partition")
+	// ErrPurgeUnconfirmed is returned by Purge for a selection covering the
+	// whole topic without All set.
+	ErrPurgeUnconfirmed = errors.New("dlq: purging every partition requires confirmation")
+)
+
+// PartitionRange is the span of offsets currently in a partition of the
+// dead-letter topic: Start is the first retained offset, End the next one to
+// be written.
+type PartitionRange struct {
+	Partition int32 `json:"partition"`
+	Start     int64 `json:"start"`
+	End       int64 `json:"end"`
+}
+
+// Message is a dead-lettered record as shown to operators.
+type Message struct {
+	Partition         int32             `json:"partition"`
+	Offset            int64             `json:"offset"`
+	Timestamp         time.Time         `json:"timestamp"`
+	Key               string            `json:"key,omitempty"`
+	OriginalTopic     string            `json:"original_topic"`
+	OriginalPartition int32             `json:"original_partition"`
+	OriginalOffset    int64             `json:"original_offset"`
+	RetryCount        int               `json:"retry_count"`
+	Error             string            `json:"error"`
+	FailedAt          string            `json:"failed_at,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	Value             json.RawMessage   `json:"value"`
+}
+
+// Selection picks dead-lettered records: everything currently in the topic,
+// one partition, or a single record. All confirms that a selection without a
+// partition is meant to cover the whole topic; Purge refuses it otherwise.
+type Selection struct {
+	Partition *int32
+	Offset    *int64
+	All       bool
+}
+
+func (s Selection) validate() error {
+	if s.Offset != nil && s.Partition == nil {
+		return ErrInvalidSelection
+	}
+	return nil
+}
+
+// Manager inspects, replays and purges the dead-letter topic. It is used by
+// the admin HTTP endpoints and the dlq command.
+type Manager struct {
+	cfg   *configs.KafkaConfig
+	topic string
+	cl    *kgo.Client
+}
+
+func NewManager(cfg *configs.KafkaConfig) (*Manager, error) {
+	cl, err := kgo.NewClient(
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ClientID(cfg.ClientID+"-dlq"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	return &Manager{cfg: cfg, topic: cfg.DLQTopic(), cl: cl}, nil
+}
+
+// Topic returns the dead-letter topic.
+func (m *Manager) Topic() string {
+	return m.topic
+}
+
+func (m *Manager) Close() {
+	m.cl.Close()
+}
+
+// Partitions returns the offset range of every partition.
+func (m *Manager) Partitions(ctx context.Context) ([]PartitionRange, error) {
+	meta := kmsg.NewPtrMetadataRequest()
+	t := kmsg.NewMetadataRequestTopic()
+	t.Topic = kmsg.StringPtr(m.topic)
+	meta.Topics = append(meta.Topics, t)
+	resp, err := meta.RequestWith(ctx, m.cl)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Topics) != 1 {
+		return nil, fmt.Errorf("metadata for %s: unexpected response", m.topic)
+	}
+	if err := kerr.ErrorForCode(resp.Topics[0].ErrorCode); err != nil {
+		if errors.Is(err, kerr.UnknownTopicOrPartition) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("metadata for %s: %w", m.topic, err)
+	}
+
+	var partitions []int32
+	for _, p := range resp.Topics[0].Partitions {
+		partitions = append(partitions, p.Partition)
+	}
+	starts, err := m.listOffsets(ctx, partitions, -2)
+	if err != nil {
+		return nil, err
+	}
+	ends, err := m.listOffsets(ctx, partitions, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]PartitionRange, 0, len(partitions))
+	for _, p := range partitions {
+		ranges = append(ranges, PartitionRange{Partition: p, Start: starts[p], End: ends[p]})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Partition < ranges[j].Partition })
+	return ranges, nil
+}
+
+// listOffsets returns the earliest (timestamp -2) or next (-1) offset of
+// each partition.
+func (m *Manager) listOffsets(ctx context.Context, partitions []int32, timestamp int64) (map[int32]int64, error) {
+	req := kmsg.NewPtrListOffsetsRequest()
+	t := kmsg.NewListOffsetsRequestTopic()
+	t.Topic = m.topic
+	for _, p := range partitions {
+		rp := kmsg.NewListOffsetsRequestTopicPartition()
+		rp.Partition = p
+		rp.Timestamp = timestamp
+		t.Partitions = append(t.Partitions, rp)
+	}
+	req.Topics = append(req.Topics, t)
+
+	resp, err := req.RequestWith(ctx, m.cl)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make(map[int32]int64, len(partitions))
+	for _, rt := range resp.Topics {
+		for _, rp := range rt.Partitions {
+			if err := kerr.ErrorForCode(rp.ErrorCode); err != nil {
+				return nil, fmt.Errorf("list offsets of %s/%d: %w", m.topic, rp.Partition, err)
+			}
+			offsets[rp.Partition] = rp.Offset
+		}
+	}
+	return offsets, nil
+}
+
+// List returns up to limit selected records, oldest first in each partition,
+// along with the partition ranges.
+func (m *Manager) List(ctx context.Context, sel Selection, limit int) ([]Message, []PartitionRange, error) {
+	ranges, err := m.selected(ctx, sel)
+	if err != nil {
+		return nil, nil, err
+	}
+	var msgs []Message
+	err = m.scan(ctx, ranges, func(r *kgo.Record) (bool, error) {
+		msgs = append(msgs, toMessage(r))
+		return len(msgs) < limit, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := m.Partitions(ctx)
+	return msgs, all, err
+}
+
+// Replay publishes the selected records back to their original topic with
+// the retry count reset, so they go through the handler and the retry tiers
+// again. Replayed records stay in the dead-letter topic until purged.
+func (m *Manager) Replay(ctx context.Context, sel Selection) (int, error) {
+	ranges, err := m.selected(ctx, sel)
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	err = m.scan(ctx, ranges, func(r *kgo.Record) (bool, error) {
+		topic := OriginalTopic(r)
+		if topic == m.topic {
+			lg.WarnContext(ctx, "Skipping dead-lettered record without original topic", "partition", r.Partition, "offset", r.Offset)
+			return true, nil
+		}
+		out := Strip(r, topic, resetRetryCount(r.Value))
+		if err := m.cl.ProduceSync(ctx, out).FirstErr(); err != nil {
+			return false, fmt.Errorf("replay %d/%d to %s: %w", r.Partition, r.Offset, topic, err)
+		}
+		replayed++
+		lg.InfoContext(ctx, "Replayed dead-lettered record", "partition", r.Partition, "offset", r.Offset, "topic", topic)
+		return true, nil
+	})
+	return replayed, err
+}
+
+// Purge deletes the records of the selected partitions up to and including
+// the selected offset, or all of them when sel.All is set. Kafka can only
+// truncate a partition from its start, so a single record cannot be removed
+// on its own.
+func (m *Manager) Purge(ctx context.Context, sel Selection) (int64, error) {
+	if sel.Partition == nil && !sel.All {
+		return 0, ErrPurgeUnconfirmed
+	}
+	ranges, err := m.selected(ctx, sel)
+	if err != nil {
+		return 0, err
+	}
+
+	req := kmsg.NewPtrDeleteRecordsRequest()
+	req.TimeoutMillis = 15000
+	t := kmsg.NewDeleteRecordsRequestTopic()
+	t.Topic = m.topic
+	var purged int64
+	for _, pr := range ranges {
+		if pr.End <= pr.Start {
+			continue
+		}
+		// With a single-record selection, selected returns [offset, offset+1)
+		// but everything before the offset goes too.
+		start, err := m.startOf(ctx, pr.Partition)
+		if err != nil {
+			return 0, err
+		}
+		p := kmsg.NewDeleteRecordsRequestTopicPartition()
+		p.Partition = pr.Partition
+		p.Offset = pr.End
+		t.Partitions = append(t.Partitions, p)
+		purged += pr.End - start
+	}
+	if len(t.Partitions) == 0 {
+		return 0, nil
+	}
+	req.Topics = append(req.Topics, t)
+
+	resp, err := req.RequestWith(ctx, m.cl)
+	if err != nil {
+		return 0, err
+	}
+	for _, rt := range resp.Topics {
+		for _, rp := range rt.Partitions {
+			if err := kerr.ErrorForCode(rp.ErrorCode); err != nil {
+				return 0, fmt.Errorf("delete records of %s/%d: %w", m.topic, rp.Partition, err)
+			}
+		}
+	}
+	lg.InfoContext(ctx, "Purged dead-letter topic", "topic", m.topic, "records", purged)
+	return purged, nil
+}
+
+func (m *Manager) startOf(ctx context.Context, partition int32) (int64, error) {
+	starts, err := m.listOffsets(ctx, []int32{partition}, -2)
+	if err != nil {
+		return 0, err
+	}
+	return starts[partition], nil
+}
+
+// selected narrows the current partition ranges to sel.
+func (m *Manager) selected(ctx context.Context, sel Selection) ([]PartitionRange, error) {
+	if err := sel.validate(); err != nil {
+		return nil, err
+	}
+	ranges, err := m.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sel.Partition == nil {
+		return ranges, nil
+	}
+	for _, pr := range ranges {
+		if pr.Partition != *sel.Partition {
+			continue
+		}
+		if sel.Offset != nil {
+			if *sel.Offset < pr.Start || *sel.Offset >= pr.End {
+				return nil, nil
+			}
+			pr.Start, pr.End = *sel.Offset, *sel.Offset+1
+		}
+		return []PartitionRange{pr}, nil
+	}
+	return nil, nil
+}
+
+// scan reads the records in ranges and calls fn for each until it returns
+// false or every range is exhausted.
+func (m *Manager) scan(ctx context.Context, ranges []PartitionRange, fn func(*kgo.Record) (bool, error)) error {
+	offsets := make(map[int32]kgo.Offset)
+	ends := make(map[int32]int64)
+	for _, pr := range ranges {
+		if pr.Start < pr.End {
+			offsets[pr.Partition] = kgo.NewOffset().At(pr.Start)
+			ends[pr.Partition] = pr.End
+		}
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	reader, err := kgo.NewClient(
+		kgo.SeedBrokers(m.cfg.Brokers...),
+		kgo.ClientID(m.cfg.ClientID+"-dlq-reader"),
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{m.topic: offsets}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka reader: %w", err)
+	}
+	defer reader.Close()
+
+	for len(ends) > 0 {
+		fetches := reader.PollFetches(ctx)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if errs := fetches.Errors(); len(errs) > 0 {
+			return fmt.Errorf("read %s/%d: %w", errs[0].Topic, errs[0].Partition, errs[0].Err)
+		}
+		for iter := fetches.RecordIter(); !iter.Done(); {
+			r := iter.Next()
+			end, ok := ends[r.Partition]
+			if !ok || r.Offset >= end {
+				continue
+			}
+			more, err := fn(r)
+			if err != nil || !more {
+				return err
+			}
+			if r.Offset+1 >= end {
+				delete(ends, r.Partition)
+			}
+		}
+	}
+	return nil
+}
+
+func toMessage(r *kgo.Record) Message {
+	msg := Message{
+		Partition:     r.Partition,
+		Offset:        r.Offset,
+		Timestamp:     r.Timestamp,
+		Key:           string(r.Key),
+		OriginalTopic: OriginalTopic(r),
+		RetryCount:    RetryCount(r),
+		Error:         Header(r, HeaderError),
+		FailedAt:      Header(r, HeaderFailedAt),
+	}
+	if p, err := strconv.ParseInt(Header(r, HeaderOriginalPartition), 10, 32); err == nil {
+		msg.OriginalPartition = int32(p)
+	}
+	msg.OriginalOffset, _ = strconv.ParseInt(Header(r, HeaderOriginalOffset), 10, 64)
+	for _, h := range r.Headers {
+		if !isPipelineHeader(h.Key) {
+			if msg.Headers == nil {
+				msg.Headers = make(map[string]string)
+			}
+			msg.Headers[h.Key] = string(h.Value)
+		}
+	}
+	if json.Valid(r.Value) {
+		msg.Value = r.Value
+	} else {
+		msg.Value, _ = json.Marshal(string(r.Value))
+	}
+	return msg
+}
+
+// resetRetryCount clears Metadata.RetryCount of an envelope. The consumer
+// keeps values verbatim and counts retries in HeaderRetryCount, but records
+// dead-lettered by earlier versions carry the count in the envelope. Other
+// values are replayed unchanged.
+func resetRetryCount(value []byte) []byte {
+	env, err := envelope.Unmarshal(value)
+	if err != nil || env.Metadata == nil || env.Metadata.RetryCount == 0 {
+		return value
+	}
+	env.Metadata.RetryCount = 0
+	out, err := env.Marshal()
+	if err != nil {
+		return value
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"notification/internal/kafka/envelope"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestPurgeRequiresConfirmation(t *testing.T) {
+	// No client: the selection is rejected before Kafka is contacted.
+	m := &Manager{topic: "group.dlq"}
+	if _, err := m.Purge(context.Background(), Selection{}); !errors.Is(err, ErrPurgeUnconfirmed) {
+		t.Errorf("Purge(everything) = %v; want ErrPurgeUnconfirmed", err)
+	}
+	offset := int64(3)
+	if _, err := m.Purge(context.Background(), Selection{Offset: &offset, All: true}); !errors.Is(err, ErrInvalidSelection) {
+		t.Errorf("Purge(offset without partition) = %v; want ErrInvalidSelection", err)
+	}
+}
+
+func TestToMessage(t *testing.T) {
+	r := &kgo.Record{
+		Partition: 1,
+		Offset:    9,
+		Timestamp: time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC),
+		Key:       []byte("user-1"),
+		Value:     []byte(`{"message_id":"m1"}`),
+		Headers: []kgo.RecordHeader{
+			{Key: "traceparent", Value: []byte("tp")},
+			{Key: HeaderOriginalTopic, Value: []byte("user.registered")},
+			{Key: HeaderOriginalPartition, Value: []byte("4")},
+			{Key: HeaderOriginalOffset, Value: []byte("120")},
+			{Key: HeaderRetryCount, Value: []byte("3")},
+			{Key: HeaderError, Value: []byte("boom")},
+			{Key: HeaderFailedAt, Value: []byte("2025-11-01T12:00:00Z")},
+		},
+	}
+	msg := toMessage(r)
+	if msg.OriginalTopic != "user.registered" || msg.OriginalPartition != 4 || msg.OriginalOffset != 120 {
+		t.Errorf("origin = %s/%d/%d; want user.registered/4/120", msg.OriginalTopic, msg.OriginalPartition, msg.OriginalOffset)
+	}
+	if msg.RetryCount != 3 || msg.Error != "boom" || msg.FailedAt != "2025-11-01T12:00:00Z" {
+		t.Errorf("message = %+v", msg)
+	}
+	if len(msg.Headers) != 1 || msg.Headers["traceparent"] != "tp" {
+		t.Errorf("headers = %v; want only traceparent", msg.Headers)
+	}
+	if string(msg.Value) != `{"message_id":"m1"}` {
+		t.Errorf("value = %s; want the JSON as is", msg.Value)
+	}
+
+	// A value that is not JSON is shown as a string.
+	msg = toMessage(&kgo.Record{Value: []byte("not json")})
+	if string(msg.Value) != `"not json"` {
+		t.Errorf("value = %s; want a JSON string", msg.Value)
+	}
+}
+
+func TestResetRetryCount(t *testing.T) {
+	env := &envelope.Envelope{MessageID: "m1", Metadata: &envelope.Metadata{UserID: "u1", RetryCount: 3}}
+	legacy, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out envelope.Envelope
+	if err := json.Unmarshal(resetRetryCount(legacy), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Metadata.RetryCount != 0 || out.Metadata.UserID != "u1" {
+		t.Errorf("metadata = %+v; want retry count 0 and the rest kept", out.Metadata)
+	}
+
+	// Values without a count are replayed byte for byte.
+	for _, v := range []string{`{"message_id":"m1","metadata":{"user_id":"u1"}}`, "not json"} {
+		if got := string(resetRetryCount([]byte(v))); got != v {
+			t.Errorf("resetRetryCount(%s) = %s; want it unchanged", v, got)
+		}
+	}
+}
@@ -1,8 +1,9 @@
 package producer
 
 import (
+	"music-player/api/logger"
+	"music-player/api/metrics"
 	"notification/configs"
-	"os"
 	"time"
 
 	"github.com/twmb/franz-go/pkg/kgo"
@@ -31,10 +32,11 @@ func GetProducerOpts(cfg *configs.KafkaConfig, profile ProducerProfile) []kgo.Op
 		kgo.ConnIdleTimeout(90 * time.Second),
 		kgo.MetadataMaxAge(5 * time.Minute),
 		kgo.MetadataMinAge(10 * time.Second),
+		kgo.WithHooks(metrics.KafkaHooks{}),
 	}
 
 	if cfg.Debug {
-		baseOpts = append(baseOpts, kgo.WithLogger(kgo.BasicLogger(os.Stderr, kgo.LogLevelInfo, nil)))
+		baseOpts = append(baseOpts, kgo.WithLogger(logger.Kgo("kafka.client")))
 	}
 
 	switch profile {
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	emailsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_emails_total",
+		Help: "Email send attempts by template and outcome (sent, suppressed, deferred, failed, error).",
+	}, []string{"template", "result"})
+
+	emailSendSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "notification_email_send_seconds",
+		Help:    "Time to render and deliver an email by template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"template"})
+)
+
+// ObserveEmail records one send attempt. suppressed is an email the
+// recipient's preferences held back, failed a permanent failure and error
+// one that is retried.
+func ObserveEmail(template, result string, d time.Duration) {
+	emailsTotal.WithLabelValues(template, result).Inc()
+	emailSendSeconds.WithLabelValues(template).Observe(d.Seconds())
+}
@@ -0,0 +1,17 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var inboxNotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_inbox_total",
+	Help: "In-app notifications by kind and outcome (created, duplicate, suppressed, error).",
+}, []string{"kind", "result"})
+
+// ObserveInbox records one in-app notification request. duplicate is an
+// event handled again whose notification already exists.
+func ObserveInbox(kind, result string) {
+	inboxNotificationsTotal.WithLabelValues(kind, result).Inc()
+}
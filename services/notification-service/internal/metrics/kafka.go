@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+var (
+	kafkaProcessedRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_processed_total",
+		Help: "Consumed records by topic and outcome (ok, duplicate, in_progress, unhandled, retried, deferred, dead_lettered, failed).",
+	}, []string{"topic", "result"})
+
+	kafkaDedupeChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_dedupe_total",
+		Help: "MessageID deduplication checks by topic and outcome (miss, hit, in_progress, error).",
+	}, []string{"topic", "result"})
+
+	kafkaProcessingSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_processing_seconds",
+		Help:    "Time to process a consumed record, including handler retries, by topic.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
+
+	kafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Records between the last polled offset and the high watermark by topic and partition.",
+	}, []string{"topic", "partition"})
+)
+
+// ObserveConsumerLag updates the lag gauge from a poll result. Consume loops
+// call it after each PollFetches.
+func ObserveConsumerLag(fetches kgo.Fetches) {
+	fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+		if len(p.Records) == 0 {
+			return
+		}
+		last := p.Records[len(p.Records)-1].Offset
+		lag := p.HighWatermark - last - 1
+		if lag < 0 {
+			lag = 0
+		}
+		kafkaConsumerLag.WithLabelValues(p.Topic, strconv.Itoa(int(p.Partition))).Set(float64(lag))
+	})
+}
+
+// ObserveDedupe counts a MessageID deduplication check by outcome: miss,
+// hit, in_progress or error.
+func ObserveDedupe(topic, result string) {
+	kafkaDedupeChecks.WithLabelValues(topic, result).Inc()
+}
+
+// ObserveProcessed records the outcome of processing one consumed record.
+func ObserveProcessed(topic, result string, d time.Duration) {
+	kafkaProcessedRecords.WithLabelValues(topic, result).Inc()
+	kafkaProcessingSeconds.WithLabelValues(topic).Observe(d.Seconds())
+}
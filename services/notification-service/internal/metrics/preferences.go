@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	preferenceChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_preference_checks_total",
+		Help: "Notifications checked against user preferences by channel, category and decision (allow, opted_out, quiet_hours, error).",
+	}, []string{"channel", "category", "decision"})
+
+	unsubscribesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_unsubscribes_total",
+		Help: "Unsubscribe link uses by channel, category and result (unsubscribed, invalid).",
+	}, []string{"channel", "category", "result"})
+)
+
+func ObservePreferenceCheck(channel, category, decision string) {
+	preferenceChecksTotal.WithLabelValues(channel, category, decision).Inc()
+}
+
+func ObserveUnsubscribe(channel, category, result string) {
+	unsubscribesTotal.WithLabelValues(channel, category, result).Inc()
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	pushTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_push_total",
+		Help: "Push notification send attempts by template and outcome (sent, suppressed, deferred, no_subscriptions, disabled, failed, error).",
+	}, []string{"template", "result"})
+
+	pushSendSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "notification_push_send_seconds",
+		Help:    "Time to render and deliver a push notification to every browser of the user, by template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"template"})
+
+	pushDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_push_deliveries_total",
+		Help: "Push messages posted to push services by outcome (delivered, pruned, rejected, error).",
+	}, []string{"result"})
+)
+
+// ObservePush records one send attempt, which may reach several browsers.
+func ObservePush(template, result string, d time.Duration) {
+	pushTotal.WithLabelValues(template, result).Inc()
+	pushSendSeconds.WithLabelValues(template).Observe(d.Seconds())
+}
+
+// ObservePushDelivery records the reply of a push service for one browser:
+// pruned is a subscription it reported gone, rejected a refused message and
+// error one that is retried.
+func ObservePushDelivery(result string) {
+	pushDeliveriesTotal.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,15 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var realtimeEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_realtime_events_total",
+	Help: "Events published for the gateway to push to clients, by type and result (published, error).",
+}, []string{"type", "result"})
+
+func ObserveRealtimeEvent(eventType, result string) {
+	realtimeEventsTotal.WithLabelValues(eventType, result).Inc()
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	smsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_sms_total",
+		Help: "SMS send attempts by template and outcome (sent, suppressed, deferred, rate_limited, failed, error).",
+	}, []string{"template", "result"})
+
+	smsSendSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "notification_sms_send_seconds",
+		Help:    "Time for the SMS provider to accept a message, by provider.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider"})
+)
+
+// ObserveSMS records one send attempt. rate_limited is a message the
+// recipient's number had no allowance left for.
+func ObserveSMS(template, result string) {
+	smsTotal.WithLabelValues(template, result).Inc()
+}
+
+// ObserveSMSProvider records how long provider took to accept or refuse a
+// message.
+func ObserveSMSProvider(provider string, d time.Duration) {
+	smsSendSeconds.WithLabelValues(provider).Observe(d.Seconds())
+}
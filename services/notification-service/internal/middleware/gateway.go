@@ -0,0 +1,53 @@
+// Package middleware holds the Gin middleware of the /api/v1 routes.
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"notification/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers the gateway sets on the requests it forwards. It drops any the
+// client sent.
+const (
+	HeaderInternalToken = "X-Internal-Token"
+	HeaderUserID        = "X-User-ID"
+)
+
+const contextKeyUserID = "user_id"
+
+// RequireGateway admits requests that carry the internal token shared with
+// the gateway, and stores the user the gateway authenticated, if any.
+func RequireGateway(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader(HeaderInternalToken)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			utils.Fail(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid internal token")
+			c.Abort()
+			return
+		}
+		if userID := c.GetHeader(HeaderUserID); userID != "" {
+			c.Set(contextKeyUserID, userID)
+		}
+		c.Next()
+	}
+}
+
+// RequireUser rejects requests the gateway forwarded without a user.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(contextKeyUserID) == "" {
+			utils.Fail(c, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// UserID returns the user stored by RequireGateway.
+func UserID(c *gin.Context) string {
+	return c.GetString(contextKeyUserID)
+}
@@ -0,0 +1,259 @@
+// Package preferences stores which notifications each user wants, on which
+// channel, and when, and decides whether a notification may be sent.
+package preferences
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Channel is a way of reaching a user.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelInApp Channel = "in_app"
+	ChannelPush  Channel = "push"
+	ChannelSMS   Channel = "sms"
+)
+
+// Category groups notifications a user can opt in or out of as a whole.
+type Category string
+
+const (
+	// CategorySecurity covers password resets, sign-in alerts and second
+	// factor codes. Its emails are mandatory.
+	CategorySecurity    Category = "security"
+	CategoryAccount     Category = "account"
+	CategoryNewReleases Category = "new_releases"
+	CategorySocial      Category = "social"
+	CategoryMarketing   Category = "marketing"
+)
+
+var (
+	Channels   = []Channel{ChannelEmail, ChannelInApp, ChannelPush, ChannelSMS}
+	Categories = []Category{CategorySecurity, CategoryAccount, CategoryNewReleases, CategorySocial, CategoryMarketing}
+)
+
+// DefaultTimezone is the timezone of users who have not picked one.
+const DefaultTimezone = "Asia/Ho_Chi_Minh"
+
+// ErrInvalid is wrapped by the errors of updates that cannot be applied.
+var ErrInvalid = errors.New("invalid preferences")
+
+func ParseChannel(s string) (Channel, bool) {
+	c := Channel(s)
+	return c, slices.Contains(Channels, c)
+}
+
+func ParseCategory(s string) (Category, bool) {
+	c := Category(s)
+	return c, slices.Contains(Categories, c)
+}
+
+// Mandatory reports whether notifications of cat on ch are always sent,
+// whatever the user's preferences and quiet hours. Security texts are the
+// sign-in codes users ask for: turning them off would lock users out.
+func Mandatory(ch Channel, cat Category) bool {
+	return (ch == ChannelEmail || ch == ChannelSMS) && cat == CategorySecurity
+}
+
+// defaultEnabled is the matrix of users who changed nothing: marketing is
+// opt-in everywhere, and text messages cost money, so only security ones
+// are sent.
+func defaultEnabled(ch Channel, cat Category) bool {
+	switch {
+	case cat == CategoryMarketing:
+		return ch == ChannelInApp
+	case ch == ChannelSMS:
+		return cat == CategorySecurity
+	}
+	return true
+}
+
+// interrupts reports whether a channel alerts the user at once, which quiet
+// hours hold back. Emails and the in-app inbox wait to be read.
+func interrupts(ch Channel) bool {
+	return ch == ChannelPush || ch == ChannelSMS
+}
+
+// QuietHours is a daily window, in the user's timezone, during which
+// optional notifications do not interrupt: they are held back until it ends.
+// Start and End are "15:04"; a window with End before Start spans midnight.
+type QuietHours struct {
+	Enabled bool   `json:"enabled"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// validate accepts a disabled window without times, which keeps the stored
+// ones.
+func (q QuietHours) validate() error {
+	if !q.Enabled && q.Start == "" && q.End == "" {
+		return nil
+	}
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("%w: quiet hours start and end must differ", ErrInvalid)
+	}
+	return nil
+}
+
+// contains reports whether t, already in the user's timezone, falls inside
+// the window.
+func (q QuietHours) contains(t time.Time) bool {
+	if !q.Enabled {
+		return false
+	}
+	start, err1 := parseClock(q.Start)
+	end, err2 := parseClock(q.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// until returns when the window containing t, already in the user's
+// timezone, ends.
+func (q QuietHours) until(t time.Time) time.Time {
+	end, _ := parseClock(q.End)
+	e := time.Date(t.Year(), t.Month(), t.Day(), end/60, end%60, 0, 0, t.Location())
+	if !e.After(t) {
+		e = e.AddDate(0, 0, 1)
+	}
+	return e
+}
+
+// parseClock returns the minutes since midnight of "15:04".
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a time of day (HH:MM)", ErrInvalid, s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Preferences are the settings of a user, with defaults filled in.
+type Preferences struct {
+	UserID     string                        `json:"userId"`
+	Timezone   string                        `json:"timezone"`
+	QuietHours QuietHours                    `json:"quietHours"`
+	Channels   map[Channel]map[Category]bool `json:"channels"`
+	// Mandatory lists the categories of each channel that cannot be turned
+	// off.
+	Mandatory map[Channel][]Category `json:"mandatory"`
+	UpdatedAt *time.Time             `json:"updatedAt,omitempty"`
+}
+
+// Default returns the preferences of a user who has not changed any.
+func Default(userID string) *Preferences {
+	p := &Preferences{
+		UserID:     userID,
+		Timezone:   DefaultTimezone,
+		QuietHours: QuietHours{Start: "22:00", End: "07:00"},
+		Channels:   make(map[Channel]map[Category]bool, len(Channels)),
+		Mandatory:  make(map[Channel][]Category),
+	}
+	for _, ch := range Channels {
+		p.Channels[ch] = make(map[Category]bool, len(Categories))
+		for _, cat := range Categories {
+			p.Channels[ch][cat] = defaultEnabled(ch, cat)
+			if Mandatory(ch, cat) {
+				p.Channels[ch][cat] = true
+				p.Mandatory[ch] = append(p.Mandatory[ch], cat)
+			}
+		}
+	}
+	return p
+}
+
+// Decision is the outcome of checking a notification against preferences.
+type Decision string
+
+const (
+	Allow Decision = "allow"
+	// OptedOut means the user turned the category off on the channel.
+	OptedOut Decision = "opted_out"
+	// Quiet means the user is inside their quiet hours: the notification
+	// waits until they end.
+	Quiet Decision = "quiet_hours"
+)
+
+// Decide tells whether a notification of cat may be sent on ch at now and,
+// for Quiet, when the quiet hours end.
+func (p *Preferences) Decide(ch Channel, cat Category, now time.Time) (Decision, time.Time) {
+	if Mandatory(ch, cat) {
+		return Allow, time.Time{}
+	}
+	if !p.Channels[ch][cat] {
+		return OptedOut, time.Time{}
+	}
+	if interrupts(ch) && cat != CategorySecurity {
+		loc, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		if local := now.In(loc); p.QuietHours.contains(local) {
+			return Quiet, p.QuietHours.until(local)
+		}
+	}
+	return Allow, time.Time{}
+}
+
+// QuietHoursError is returned by senders for a notification held back by
+// quiet hours. It is to be sent again at Until.
+type QuietHoursError struct {
+	Until time.Time
+}
+
+func (e *QuietHoursError) Error() string {
+	return "held back by quiet hours until " + e.Until.UTC().Format(time.RFC3339)
+}
+
+// Update changes the fields that are set. Channels only needs the entries
+// that change.
+type Update struct {
+	Timezone   *string                       `json:"timezone"`
+	QuietHours *QuietHours                   `json:"quietHours"`
+	Channels   map[Channel]map[Category]bool `json:"channels"`
+}
+
+func (u *Update) validate() error {
+	if u.Timezone != nil {
+		if _, err := time.LoadLocation(*u.Timezone); err != nil || *u.Timezone == "" || *u.Timezone == "Local" {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalid, *u.Timezone)
+		}
+	}
+	if u.QuietHours != nil {
+		if err := u.QuietHours.validate(); err != nil {
+			return err
+		}
+	}
+	for ch, cats := range u.Channels {
+		if !slices.Contains(Channels, ch) {
+			return fmt.Errorf("%w: unknown channel %q", ErrInvalid, ch)
+		}
+		for cat, enabled := range cats {
+			if !slices.Contains(Categories, cat) {
+				return fmt.Errorf("%w: unknown category %q", ErrInvalid, cat)
+			}
+			if !enabled && Mandatory(ch, cat) {
+				return fmt.Errorf("%w: %s %s notifications cannot be turned off", ErrInvalid, cat, ch)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package preferences
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuietHoursAcrossMidnight(t *testing.T) {
+	q := QuietHours{Enabled: true, Start: "22:00", End: "07:00"}
+	day := func(h, m int) time.Time { return time.Date(2025, 11, 1, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		at        time.Time
+		quiet     bool
+		wantUntil time.Time
+	}{
+		{day(21, 59), false, time.Time{}},
+		{day(22, 0), true, day(31, 0)},
+		{day(23, 30), true, day(31, 0)},
+		{day(0, 0), true, day(7, 0)},
+		{day(6, 59), true, day(7, 0)},
+		{day(7, 0), false, time.Time{}},
+		{day(12, 0), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := q.contains(tt.at); got != tt.quiet {
+			t.Errorf("contains(%s) = %v; want %v", tt.at.Format("15:04"), got, tt.quiet)
+		}
+		if tt.quiet {
+			if got := q.until(tt.at); !got.Equal(tt.wantUntil) {
+				t.Errorf("until(%s) = %s; want %s", tt.at.Format("15:04"), got, tt.wantUntil)
+			}
+		}
+	}
+
+	// A window within a day.
+	q = QuietHours{Enabled: true, Start: "13:00", End: "14:30"}
+	if !q.contains(day(13, 0)) || q.contains(day(14, 30)) || q.contains(day(2, 0)) {
+		t.Error("same-day window matched the wrong times")
+	}
+	if got := q.until(day(13, 15)); !got.Equal(day(14, 30)) {
+		t.Errorf("until(13:15) = %s; want 14:30 the same day", got)
+	}
+
+	// Disabled windows never match.
+	q.Enabled = false
+	if q.contains(day(13, 15)) {
+		t.Error("disabled quiet hours matched")
+	}
+}
+
+func TestQuietHoursValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		q    QuietHours
+		ok   bool
+	}{
+		{"enabled", QuietHours{Enabled: true, Start: "22:00", End: "07:00"}, true},
+		{"disabled without times", QuietHours{}, true},
+		{"disabled with times", QuietHours{Start: "22:00", End: "07:00"}, true},
+		{"enabled without times", QuietHours{Enabled: true}, false},
+		{"disabled with a bad time", QuietHours{Start: "25:00", End: "07:00"}, false},
+		{"no length", QuietHours{Enabled: true, Start: "07:00", End: "07:00"}, false},
+		{"seconds", QuietHours{Enabled: true, Start: "22:00:00", End: "07:00"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.q.validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: validate = %v; want ok %v", tt.name, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: %v does not wrap ErrInvalid", tt.name, err)
+		}
+	}
+}
+
+func TestDecide(t *testing.T) {
+	// 23:30 in Ho Chi Minh City (UTC+7), inside the default quiet hours.
+	night := time.Date(2025, 11, 1, 16, 30, 0, 0, time.UTC)
+	morning := time.Date(2025, 11, 2, 0, 0, 0, 0, time.UTC) // 07:00 local
+	noon := time.Date(2025, 11, 1, 5, 0, 0, 0, time.UTC)
+
+	p := Default("u1")
+	p.QuietHours.Enabled = true
+	p.Channels[ChannelPush][CategorySocial] = false
+
+	tests := []struct {
+		name      string
+		ch        Channel
+		cat       Category
+		now       time.Time
+		want      Decision
+		wantUntil time.Time
+	}{
+		{"allowed", ChannelPush, CategoryNewReleases, noon, Allow, time.Time{}},
+		{"opted out", ChannelPush, CategorySocial, noon, OptedOut, time.Time{}},
+		{"opted out wins over quiet", ChannelPush, CategorySocial, night, OptedOut, time.Time{}},
+		{"marketing off by default", ChannelEmail, CategoryMarketing, noon, OptedOut, time.Time{}},
+		{"sms off by default", ChannelSMS, CategoryAccount, noon, OptedOut, time.Time{}},
+		{"quiet push", ChannelPush, CategoryNewReleases, night, Quiet, morning},
+		{"security push ignores quiet hours", ChannelPush, CategorySecurity, night, Allow, time.Time{}},
+		{"mandatory sms", ChannelSMS, CategorySecurity, night, Allow, time.Time{}},
+		{"email does not interrupt", ChannelEmail, CategoryAccount, night, Allow, time.Time{}},
+		{"inbox does not interrupt", ChannelInApp, CategorySocial, night, Allow, time.Time{}},
+	}
+	for _, tt := range tests {
+		got, until := p.Decide(tt.ch, tt.cat, tt.now)
+		if got != tt.want || !until.Equal(tt.wantUntil) {
+			t.Errorf("%s: Decide = %s, %s; want %s, %s", tt.name, got, until, tt.want, tt.wantUntil)
+		}
+	}
+
+	// Mandatory notifications are sent even if a stale row turned them off.
+	p.Channels[ChannelEmail][CategorySecurity] = false
+	if got, _ := p.Decide(ChannelEmail, CategorySecurity, noon); got != Allow {
+		t.Errorf("mandatory email = %s; want allow", got)
+	}
+
+	// Quiet hours follow the user's timezone.
+	p.Timezone = "Europe/Paris"
+	if got, _ := p.Decide(ChannelPush, CategoryNewReleases, night); got != Allow {
+		t.Errorf("17:30 in Paris = %s; want allow", got)
+	}
+}
+
+func TestUpdateValidate(t *testing.T) {
+	tz := func(s string) *string { return &s }
+	tests := []struct {
+		name string
+		u    Update
+		ok   bool
+	}{
+		{"timezone", Update{Timezone: tz("Europe/Paris")}, true},
+		{"unknown timezone", Update{Timezone: tz("Mars/Olympus")}, false},
+		{"local timezone", Update{Timezone: tz("Local")}, false},
+		{"quiet hours off", Update{QuietHours: &QuietHours{}}, true},
+		{"opt out", Update{Channels: map[Channel]map[Category]bool{ChannelPush: {CategorySocial: false}}}, true},
+		{"mandatory off", Update{Channels: map[Channel]map[Category]bool{ChannelEmail: {CategorySecurity: false}}}, false},
+		{"unknown channel", Update{Channels: map[Channel]map[Category]bool{"fax": {CategorySocial: true}}}, false},
+		{"unknown category", Update{Channels: map[Channel]map[Category]bool{ChannelPush: {"gossip": true}}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.u.validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: validate = %v; want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
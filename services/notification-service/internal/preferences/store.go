@@ -0,0 +1,166 @@
+package preferences
+
+import (
+	"context"
+	"errors"
+	"notification/internal/metrics"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// settingsRow holds the per-user settings of migrations/01.
+type settingsRow struct {
+	UserID            string `gorm:"primaryKey"`
+	Timezone          string
+	QuietHoursEnabled bool
+	QuietHoursStart   string
+	QuietHoursEnd     string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (settingsRow) TableName() string { return "notification_settings" }
+
+// subscriptionRow is a cell of the channel × category matrix the user set.
+// Cells without a row use the default.
+type subscriptionRow struct {
+	UserID    string `gorm:"primaryKey"`
+	Channel   string `gorm:"primaryKey"`
+	Category  string `gorm:"primaryKey"`
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+func (subscriptionRow) TableName() string { return "notification_subscriptions" }
+
+// Store keeps preferences in Postgres. Users without rows get Default.
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Get returns the preferences of userID.
+func (s *Store) Get(ctx context.Context, userID string) (*Preferences, error) {
+	return get(s.db.WithContext(ctx), userID)
+}
+
+func get(tx *gorm.DB, userID string) (*Preferences, error) {
+	p := Default(userID)
+
+	var settings settingsRow
+	err := tx.Where("user_id = ?", userID).First(&settings).Error
+	switch {
+	case err == nil:
+		p.Timezone = settings.Timezone
+		p.QuietHours = QuietHours{
+			Enabled: settings.QuietHoursEnabled,
+			Start:   settings.QuietHoursStart,
+			End:     settings.QuietHoursEnd,
+		}
+		p.UpdatedAt = &settings.UpdatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	var subs []subscriptionRow
+	if err := tx.Where("user_id = ?", userID).Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		ch, cat := Channel(sub.Channel), Category(sub.Category)
+		// Rows of a channel or category that no longer exists, or that
+		// has become mandatory, are ignored.
+		if cats, ok := p.Channels[ch]; ok && !Mandatory(ch, cat) {
+			if _, ok := cats[cat]; ok {
+				cats[cat] = sub.Enabled
+			}
+		}
+	}
+	return p, nil
+}
+
+// Update applies u to the preferences of userID and returns the result.
+// Updates that cannot be applied fail with an error wrapping ErrInvalid.
+func (s *Store) Update(ctx context.Context, userID string, u *Update) (*Preferences, error) {
+	if err := u.validate(); err != nil {
+		return nil, err
+	}
+
+	var p *Preferences
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := get(tx, userID)
+		if err != nil {
+			return err
+		}
+		settings := settingsRow{
+			UserID:            userID,
+			Timezone:          current.Timezone,
+			QuietHoursEnabled: current.QuietHours.Enabled,
+			QuietHoursStart:   current.QuietHours.Start,
+			QuietHoursEnd:     current.QuietHours.End,
+		}
+		if u.Timezone != nil {
+			settings.Timezone = *u.Timezone
+		}
+		if u.QuietHours != nil {
+			settings.QuietHoursEnabled = u.QuietHours.Enabled
+			if u.QuietHours.Start != "" || u.QuietHours.End != "" {
+				settings.QuietHoursStart = u.QuietHours.Start
+				settings.QuietHoursEnd = u.QuietHours.End
+			}
+		}
+		// The settings row is written on every update so UpdatedAt follows
+		// changes to the matrix too.
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"timezone", "quiet_hours_enabled", "quiet_hours_start", "quiet_hours_end", "updated_at"}),
+		}).Create(&settings).Error; err != nil {
+			return err
+		}
+
+		var subs []subscriptionRow
+		for ch, cats := range u.Channels {
+			for cat, enabled := range cats {
+				subs = append(subs, subscriptionRow{UserID: userID, Channel: string(ch), Category: string(cat), Enabled: enabled})
+			}
+		}
+		if len(subs) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "channel"}, {Name: "category"}},
+				DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+			}).Create(&subs).Error; err != nil {
+				return err
+			}
+		}
+
+		p, err = get(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Check decides whether a notification of cat may be sent to userID on ch
+// now; see Preferences.Decide. Mandatory notifications are allowed without
+// reading the store.
+func (s *Store) Check(ctx context.Context, userID string, ch Channel, cat Category) (Decision, time.Time, error) {
+	if Mandatory(ch, cat) {
+		metrics.ObservePreferenceCheck(string(ch), string(cat), string(Allow))
+		return Allow, time.Time{}, nil
+	}
+	p, err := s.Get(ctx, userID)
+	if err != nil {
+		metrics.ObservePreferenceCheck(string(ch), string(cat), "error")
+		return "", time.Time{}, err
+	}
+	d, until := p.Decide(ch, cat, time.Now())
+	metrics.ObservePreferenceCheck(string(ch), string(cat), string(d))
+	return d, until, nil
+}
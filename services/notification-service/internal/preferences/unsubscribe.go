@@ -0,0 +1,86 @@
+package preferences
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidToken is returned for unsubscribe tokens that were not signed
+// with the current secret or name an unknown channel or category.
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+// Target is what an unsubscribe link turns off.
+type Target struct {
+	UserID   string
+	Channel  Channel
+	Category Category
+}
+
+// Unsubscriber signs and verifies unsubscribe links. Links do not expire:
+// a mailbox keeps old emails, and their links must keep working. Rotating
+// the secret invalidates every link sent so far.
+type Unsubscriber struct {
+	secret  []byte
+	baseURL string
+}
+
+func NewUnsubscriber(secret, baseURL string) *Unsubscriber {
+	return &Unsubscriber{secret: []byte(secret), baseURL: baseURL}
+}
+
+// Enabled reports whether a signing secret is configured. Without one, no
+// links are added to emails.
+func (u *Unsubscriber) Enabled() bool {
+	return len(u.secret) > 0
+}
+
+// URL returns the link that turns off cat on ch for userID, or "" when
+// links are disabled or the notification is mandatory.
+func (u *Unsubscriber) URL(userID string, ch Channel, cat Category) string {
+	if !u.Enabled() || userID == "" || Mandatory(ch, cat) {
+		return ""
+	}
+	return u.baseURL + "?token=" + url.QueryEscape(u.Token(Target{userID, ch, cat}))
+}
+
+// Token signs t as "<payload>.<mac>", both base64url.
+func (u *Unsubscriber) Token(t Target) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(t.UserID + "\n" + string(t.Channel) + "\n" + string(t.Category)))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(u.mac(payload))
+}
+
+// Parse verifies token and returns its target.
+func (u *Unsubscriber) Parse(token string) (Target, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !u.Enabled() {
+		return Target{}, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, u.mac(payload)) {
+		return Target{}, ErrInvalidToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return Target{}, ErrInvalidToken
+	}
+	parts := strings.Split(string(raw), "\n")
+	if len(parts) != 3 || parts[0] == "" {
+		return Target{}, ErrInvalidToken
+	}
+	ch, ok1 := ParseChannel(parts[1])
+	cat, ok2 := ParseCategory(parts[2])
+	if !ok1 || !ok2 || Mandatory(ch, cat) {
+		return Target{}, ErrInvalidToken
+	}
+	return Target{UserID: parts[0], Channel: ch, Category: cat}, nil
+}
+
+func (u *Unsubscriber) mac(payload string) []byte {
+	h := hmac.New(sha256.New, u.secret)
+	h.Write([]byte("unsubscribe:v1:" + payload))
+	return h.Sum(nil)
+}
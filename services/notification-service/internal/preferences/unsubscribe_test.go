@@ -0,0 +1,68 @@
+package preferences
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	u := NewUnsubscriber("secret", "https://notify.example.com/unsubscribe")
+	target := Target{UserID: "u1", Channel: ChannelEmail, Category: CategoryMarketing}
+
+	got, err := u.Parse(u.Token(target))
+	if err != nil || got != target {
+		t.Fatalf("Parse(Token) = %+v, %v; want %+v", got, err, target)
+	}
+
+	link := u.URL("u1", ChannelEmail, CategoryMarketing)
+	parsed, err := url.Parse(link)
+	if err != nil || !strings.HasPrefix(link, "https://notify.example.com/unsubscribe?token=") {
+		t.Fatalf("URL = %q", link)
+	}
+	if got, err := u.Parse(parsed.Query().Get("token")); err != nil || got != target {
+		t.Errorf("Parse(URL token) = %+v, %v", got, err)
+	}
+}
+
+func TestUnsubscribeTokenRejected(t *testing.T) {
+	u := NewUnsubscriber("secret", "https://notify.example.com/unsubscribe")
+	token := u.Token(Target{UserID: "u1", Channel: ChannelEmail, Category: CategoryMarketing})
+	payload, sig, _ := strings.Cut(token, ".")
+	other := u.Token(Target{UserID: "u2", Channel: ChannelEmail, Category: CategoryMarketing})
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	tests := []struct {
+		name  string
+		u     *Unsubscriber
+		token string
+	}{
+		{"other secret", NewUnsubscriber("rotated", ""), token},
+		{"no secret", NewUnsubscriber("", ""), token},
+		{"payload swapped", u, otherPayload + "." + sig},
+		{"signature cut", u, payload + "." + sig[:len(sig)-2]},
+		{"no signature", u, payload},
+		{"empty", u, ""},
+		{"mandatory target", u, u.Token(Target{UserID: "u1", Channel: ChannelEmail, Category: CategorySecurity})},
+		{"unknown category", u, u.Token(Target{UserID: "u1", Channel: ChannelEmail, Category: "gossip"})},
+		{"no user", u, u.Token(Target{Channel: ChannelEmail, Category: CategoryMarketing})},
+	}
+	for _, tt := range tests {
+		if _, err := tt.u.Parse(tt.token); err != ErrInvalidToken {
+			t.Errorf("%s: Parse = %v; want ErrInvalidToken", tt.name, err)
+		}
+	}
+}
+
+func TestUnsubscribeURLOmitted(t *testing.T) {
+	u := NewUnsubscriber("secret", "https://notify.example.com/unsubscribe")
+	if got := u.URL("u1", ChannelEmail, CategorySecurity); got != "" {
+		t.Errorf("URL for mandatory email = %q; want none", got)
+	}
+	if got := u.URL("", ChannelEmail, CategoryMarketing); got != "" {
+		t.Errorf("URL without a user = %q; want none", got)
+	}
+	if got := NewUnsubscriber("", "https://x").URL("u1", ChannelEmail, CategoryMarketing); got != "" {
+		t.Errorf("URL without a secret = %q; want none", got)
+	}
+}
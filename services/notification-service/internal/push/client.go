@@ -0,0 +1,128 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"notification/internal/kafka/envelope"
+	"strconv"
+	"time"
+)
+
+// Urgency values of RFC 8030 section 5.3.
+const (
+	UrgencyVeryLow = "very-low"
+	UrgencyLow     = "low"
+	UrgencyNormal  = "normal"
+	UrgencyHigh    = "high"
+)
+
+// Options are the RFC 8030 headers of a push message.
+type Options struct {
+	// TTL is how long the push service keeps the message for a browser
+	// that is offline; 0 delivers it now or never.
+	TTL     time.Duration
+	Urgency string
+	// Topic replaces a message of the same topic still waiting for the
+	// browser. At most 32 base64url characters.
+	Topic string
+}
+
+// OptionsFor maps the priority of an event to the delivery options: urgent
+// messages wake the device at once but are useless once stale, while low
+// priority ones can wait for the device to be on power and Wi-Fi.
+func OptionsFor(p envelope.Priority) Options {
+	switch p {
+	case envelope.PriorityLow:
+		return Options{TTL: 7 * 24 * time.Hour, Urgency: UrgencyVeryLow}
+	case envelope.PriorityHigh:
+		return Options{TTL: time.Hour, Urgency: UrgencyHigh}
+	case envelope.PriorityCritical:
+		return Options{TTL: 5 * time.Minute, Urgency: UrgencyHigh}
+	default:
+		return Options{TTL: 24 * time.Hour, Urgency: UrgencyNormal}
+	}
+}
+
+// errGone means the subscription expired or the user revoked the
+// permission: the push service answered 404 or 410.
+var errGone = errors.New("push: subscription is gone")
+
+// statusError is a push service reply other than 201 Created.
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("push: push service replied %d", e.status)
+	}
+	return fmt.Sprintf("push: push service replied %d: %s", e.status, e.body)
+}
+
+// permanent reports whether sending the message again cannot succeed: the
+// request itself was refused. 429 and 5xx replies are worth retrying.
+func (e *statusError) permanent() bool {
+	return e.status < 500 && e.status != http.StatusTooManyRequests && e.status != http.StatusRequestTimeout
+}
+
+// client delivers encrypted messages to push services.
+type client struct {
+	http *http.Client
+	keys *Keys
+}
+
+// deliver encrypts payload for sub and posts it to its push service.
+func (c *client) deliver(ctx context.Context, sub *Subscription, payload []byte, opts Options) error {
+	p256dh, err := decodeBase64(sub.P256dh)
+	if err != nil {
+		return err
+	}
+	auth, err := decodeBase64(sub.Auth)
+	if err != nil {
+		return err
+	}
+	body, err := encrypt(payload, p256dh, auth)
+	if err != nil {
+		return err
+	}
+	authorization, err := c.keys.authorization(sub.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.FormatInt(int64(opts.TTL/time.Second), 10))
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Push services answer with small bodies; read a little for the log.
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusAccepted:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return errGone
+	}
+	return &statusError{status: resp.StatusCode, body: string(bytes.TrimSpace(reply))}
+}
@@ -0,0 +1,248 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"notification/internal/kafka/envelope"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// pushService is a stand-in for a push service: it answers every request
+// with status and keeps the last one.
+type pushService struct {
+	*httptest.Server
+	status int
+
+	mu   sync.Mutex
+	last *http.Request
+	body []byte
+}
+
+func newPushService(t *testing.T, status int) *pushService {
+	t.Helper()
+	ps := &pushService{status: status}
+	ps.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ps.mu.Lock()
+		ps.last, ps.body = r, body
+		ps.mu.Unlock()
+		if ps.status == http.StatusMovedPermanently {
+			w.Header().Set("Location", "http://169.254.169.254/")
+		}
+		w.WriteHeader(ps.status)
+		io.WriteString(w, "reply")
+	}))
+	t.Cleanup(ps.Close)
+	return ps
+}
+
+// browser is the user agent side of a subscription.
+type browser struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newBrowser(t *testing.T) *browser {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return &browser{key: key, auth: auth}
+}
+
+func (b *browser) subscription(userID, endpoint string) *Subscription {
+	enc := base64.RawURLEncoding
+	return &Subscription{UserID: userID, Endpoint: endpoint, P256dh: enc.EncodeToString(b.key.PublicKey().Bytes()), Auth: enc.EncodeToString(b.auth)}
+}
+
+func newTestClient(t *testing.T) *client {
+	return &client{
+		http: &http.Client{
+			Timeout: 5 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		keys: newTestKeys(t),
+	}
+}
+
+// newTestSender returns a sender over an in-memory SQLite database with the
+// push_subscriptions table of migrations/03_create_push_subscriptions.sql.
+func newTestSender(t *testing.T) *Sender {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	err = db.Exec(`CREATE TABLE push_subscriptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		endpoint TEXT NOT NULL UNIQUE,
+		p256dh TEXT NOT NULL,
+		auth TEXT NOT NULL,
+		user_agent TEXT NOT NULL DEFAULT '',
+		expires_at DATETIME,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Sender{store: &Store{db: db}, client: newTestClient(t)}
+}
+
+func TestClientDeliver(t *testing.T) {
+	ps := newPushService(t, http.StatusCreated)
+	b := newBrowser(t)
+	c := newTestClient(t)
+
+	payload := []byte(`{"title":"Hi"}`)
+	opts := OptionsFor(envelope.PriorityHigh)
+	opts.Topic = "welcome"
+	if err := c.deliver(context.Background(), b.subscription("u1", ps.URL+"/push/1"), payload, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	h := ps.last.Header
+	for name, want := range map[string]string{
+		"Content-Encoding": "aes128gcm",
+		"Content-Type":     "application/octet-stream",
+		"TTL":              "3600",
+		"Urgency":          UrgencyHigh,
+		"Topic":            "welcome",
+	} {
+		if got := h.Get(name); got != want {
+			t.Errorf("%s = %q; want %q", name, got, want)
+		}
+	}
+	claims := parseVAPID(t, c.keys, h.Get("Authorization"))
+	if claims["aud"] != ps.URL {
+		t.Errorf("aud = %v; want %s", claims["aud"], ps.URL)
+	}
+	got, err := decrypt(ps.body, b.key, b.auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(payload) {
+		t.Errorf("browser decrypted %q; want %q", got, payload)
+	}
+}
+
+func TestSenderDeliverClassifies(t *testing.T) {
+	tests := []struct {
+		status        int
+		wantGone      bool
+		wantPermanent bool
+	}{
+		{http.StatusCreated, false, false},
+		{http.StatusNotFound, true, false},
+		{http.StatusGone, true, false},
+		{http.StatusTooManyRequests, false, false},
+		{http.StatusRequestTimeout, false, false},
+		{http.StatusInternalServerError, false, false},
+		{http.StatusServiceUnavailable, false, false},
+		{http.StatusBadRequest, false, true},
+		{http.StatusForbidden, false, true},
+		{http.StatusRequestEntityTooLarge, false, true},
+		// Redirects are not followed: they could lead to any host.
+		{http.StatusMovedPermanently, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			s := newTestSender(t)
+			ps := newPushService(t, tt.status)
+			sub := newBrowser(t).subscription("u1", ps.URL+"/push/1")
+			if err := s.store.db.Create(sub).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			err := s.deliver(context.Background(), sub, []byte(`{}`), OptionsFor(envelope.PriorityNormal))
+			if tt.status == http.StatusCreated {
+				if err != nil {
+					t.Fatalf("deliver = %v; want success", err)
+				}
+			} else if err == nil {
+				t.Fatal("deliver succeeded; want an error")
+			}
+			if got := errors.Is(err, errGone); got != tt.wantGone {
+				t.Errorf("gone = %v; want %v (%v)", got, tt.wantGone, err)
+			}
+			if got := IsPermanent(err); got != tt.wantPermanent {
+				t.Errorf("permanent = %v; want %v (%v)", got, tt.wantPermanent, err)
+			}
+
+			// Gone subscriptions are pruned; the others are kept.
+			subs, err := s.store.List(context.Background(), "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept := len(subs) == 1; kept == tt.wantGone {
+				t.Errorf("subscription kept = %v; want %v", kept, !tt.wantGone)
+			}
+		})
+	}
+}
+
+func TestSenderDeliverSkipsExpired(t *testing.T) {
+	s := newTestSender(t)
+	ps := newPushService(t, http.StatusCreated)
+	sub := newBrowser(t).subscription("u1", ps.URL+"/push/1")
+	expired := time.Now().Add(-time.Minute)
+	sub.ExpiresAt = &expired
+	if err := s.store.db.Create(sub).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.deliver(context.Background(), sub, []byte(`{}`), Options{}); !errors.Is(err, errGone) {
+		t.Errorf("deliver to an expired subscription = %v; want errGone", err)
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.last != nil {
+		t.Error("an expired subscription was sent the message")
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	transient := &statusError{status: http.StatusServiceUnavailable}
+	rejected := permanent(&statusError{status: http.StatusBadRequest})
+
+	var o outcome
+	for _, err := range []error{nil, errGone, rejected, transient, nil} {
+		o.add(err)
+	}
+	if o.delivered != 2 || o.pruned != 1 || o.failed != 2 {
+		t.Errorf("outcome = %d delivered, %d pruned, %d failed; want 2, 1, 2", o.delivered, o.pruned, o.failed)
+	}
+	if o.rejected != rejected || o.retry != transient {
+		t.Errorf("outcome kept %v and %v; want the refusal and the transient error", o.rejected, o.retry)
+	}
+	if !strings.Contains(transient.Error(), "503") {
+		t.Errorf("statusError = %q; want the status", transient.Error())
+	}
+}
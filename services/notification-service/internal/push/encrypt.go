@@ -0,0 +1,101 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// recordSize is the rs of the aes128gcm header. Messages are encrypted
+	// as a single record.
+	recordSize = 4096
+	// headerSize is salt (16) + rs (4) + idlen (1) + the sender's public
+	// key (65).
+	headerSize = 16 + 4 + 1 + 65
+	// MaxPayload is the most plaintext that fits the 4096 bytes push
+	// services are required to accept: the header, the padding delimiter
+	// and the 16-byte tag take the rest.
+	MaxPayload = recordSize - headerSize - 1 - 16
+)
+
+var errPayloadTooLarge = fmt.Errorf("push: payload exceeds %d bytes", MaxPayload)
+
+// encrypt encrypts plaintext for a subscription as RFC 8291 specifies: the
+// content encryption key comes from ECDH between a fresh key pair and the
+// browser's p256dh key, mixed with its auth secret, and the body is a single
+// aes128gcm record (RFC 8188).
+func encrypt(plaintext, p256dh, auth []byte) ([]byte, error) {
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptWith(plaintext, p256dh, auth, ephemeral, salt)
+}
+
+func encryptWith(plaintext, p256dh, auth []byte, ephemeral *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(plaintext) > MaxPayload {
+		return nil, errPayloadTooLarge
+	}
+	if len(auth) != 16 {
+		return nil, errors.New("push: auth secret must be 16 bytes")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("push: p256dh key: %w", err)
+	}
+	secret, err := ephemeral.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := ephemeral.PublicKey().Bytes()
+
+	// IKM = HKDF(auth, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	keyInfo := make([]byte, 0, 14+65+65)
+	keyInfo = append(keyInfo, "WebPush: info\x00"...)
+	keyInfo = append(keyInfo, p256dh...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := hkdf.Key(sha256.New, secret, auth, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, headerSize, headerSize+len(plaintext)+1+gcm.Overhead())
+	copy(body, salt)
+	binary.BigEndian.PutUint32(body[16:20], recordSize)
+	body[20] = byte(len(asPublic))
+	copy(body[21:], asPublic)
+
+	// 0x02 ends the last (and only) record; no padding follows.
+	record := make([]byte, 0, len(plaintext)+1)
+	record = append(record, plaintext...)
+	record = append(record, 0x02)
+	return gcm.Seal(body, nonce, record, nil), nil
+}
@@ -0,0 +1,141 @@
+package push
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestEncryptWithRFC8291 checks the example of RFC 8291 section 5.
+func TestEncryptWithRFC8291(t *testing.T) {
+	plaintext := []byte("When I grow up, I want to be a watermelon")
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic := mustDecode(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4")
+	auth := mustDecode(t, "BTBZMqHH6r4Tts7J_aSIgg")
+	salt := mustDecode(t, "DGv6ra1nlYgDCS1FRnbzlw")
+
+	got, err := encryptWith(plaintext, uaPublic, auth, asPrivate, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if enc := base64.RawURLEncoding.EncodeToString(got); enc != want {
+		t.Errorf("encryptWith =\n%s\nwant\n%s", enc, want)
+	}
+}
+
+// decrypt is the browser's side of encrypt.
+func decrypt(body []byte, ua *ecdh.PrivateKey, auth []byte) ([]byte, error) {
+	if len(body) < headerSize {
+		return nil, errors.New("short body")
+	}
+	salt := body[:16]
+	if binary.BigEndian.Uint32(body[16:20]) != recordSize || body[20] != 65 {
+		return nil, errors.New("bad header")
+	}
+	asPublic, err := ecdh.P256().NewPublicKey(body[21:headerSize])
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ua.ECDH(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := append([]byte("WebPush: info\x00"), ua.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublic.Bytes()...)
+	ikm, err := hkdf.Key(sha256.New, secret, auth, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	record, err := gcm.Open(nil, nonce, body[headerSize:], nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		return nil, errors.New("missing last record delimiter")
+	}
+	return record[:len(record)-1], nil
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	ua, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+
+	plaintext := []byte(`{"title":"Xin chào","body":"…"}`)
+	first, err := encrypt(plaintext, ua.PublicKey().Bytes(), auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decrypt(first, ua, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted %q; want %q", got, plaintext)
+	}
+
+	// A fresh key pair and salt per message.
+	second, err := encrypt(plaintext, ua.PublicKey().Bytes(), auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first[:headerSize], second[:headerSize]) {
+		t.Error("two messages share their salt and key")
+	}
+}
+
+func TestEncryptLimits(t *testing.T) {
+	ua, _ := ecdh.P256().GenerateKey(rand.Reader)
+	auth := make([]byte, 16)
+
+	body, err := encrypt(make([]byte, MaxPayload), ua.PublicKey().Bytes(), auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != recordSize {
+		t.Errorf("largest message is %d bytes; want %d", len(body), recordSize)
+	}
+	if _, err := encrypt(make([]byte, MaxPayload+1), ua.PublicKey().Bytes(), auth); !errors.Is(err, errPayloadTooLarge) {
+		t.Errorf("encrypt of too large a payload = %v; want errPayloadTooLarge", err)
+	}
+	if _, err := encrypt([]byte("x"), ua.PublicKey().Bytes(), auth[:8]); err == nil {
+		t.Error("encrypt accepted an 8-byte auth secret")
+	}
+	if _, err := encrypt([]byte("x"), []byte("not a key"), auth); err == nil {
+		t.Error("encrypt accepted an invalid p256dh key")
+	}
+}
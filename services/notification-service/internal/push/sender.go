@@ -0,0 +1,387 @@
+// Package push is the Web Push channel: it keeps the browsers users
+// subscribed, encrypts messages for them (RFC 8291), signs requests with the
+// service's VAPID key (RFC 8292) and reports the outcome on
+// notification.push.sent and notification.push.failed.
+package push
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"music-player/api/logger"
+	"music-player/api/tracing"
+	"net/http"
+	"notification/configs"
+	"notification/internal/i18n"
+	"notification/internal/kafka/envelope"
+	"notification/internal/kafka/producer"
+	"notification/internal/metrics"
+	"notification/internal/preferences"
+	"regexp"
+	"time"
+)
+
+var lg = logger.For("push")
+
+const source = "notification-service"
+
+// Request asks for a push message to every browser of UserID. Its title and
+// body are the translations of inbox.<template>.title and body, the texts
+// of the matching in-app notification.
+type Request struct {
+	UserID   string
+	Template string
+	Category preferences.Category
+	// Locale is the recipient's locale, e.g. "vi"; unsupported ones fall back
+	// to English.
+	Locale string
+	// Args fill the {name} placeholders of the texts.
+	Args map[string]any
+	// URL is opened when the notification is clicked.
+	URL string
+	// Tag replaces an earlier notification with the same tag, on the
+	// device and, while undelivered, at the push service.
+	Tag      string
+	Priority envelope.Priority
+	// CausationID is the message_id of the event that asked for the push.
+	CausationID string
+}
+
+// Payload is the JSON the service worker receives in its push event.
+type Payload struct {
+	Title    string    `json:"title"`
+	Body     string    `json:"body"`
+	Template string    `json:"template"`
+	Category string    `json:"category"`
+	Locale   string    `json:"locale"`
+	URL      string    `json:"url,omitempty"`
+	Tag      string    `json:"tag,omitempty"`
+	SentAt   time.Time `json:"sentAt"`
+}
+
+// Sent is the data of a notification.push.sent event.
+type Sent struct {
+	CausationID string `json:"causation_id,omitempty"`
+	UserID      string `json:"user_id"`
+	Template    string `json:"template"`
+	Locale      string `json:"locale"`
+	// Delivered browsers accepted the message, Pruned ones were gone and
+	// their subscriptions deleted, and Failed ones refused it or could not
+	// be reached.
+	Delivered int       `json:"delivered"`
+	Pruned    int       `json:"pruned"`
+	Failed    int       `json:"failed"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// Failed is the data of a notification.push.failed event.
+type Failed struct {
+	CausationID string    `json:"causation_id,omitempty"`
+	UserID      string    `json:"user_id"`
+	Template    string    `json:"template"`
+	Pruned      int       `json:"pruned"`
+	Error       string    `json:"error"`
+	FailedAt    time.Time `json:"failed_at"`
+}
+
+// topicPattern is what push services accept in the Topic header.
+var topicPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// causationTopic derives a Topic header value from the event's message_id.
+func causationTopic(causationID string) string {
+	sum := sha256.Sum256([]byte(causationID))
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:32]
+}
+
+type Sender struct {
+	store       *Store
+	client      *client
+	keys        *Keys
+	producer    *producer.Producer
+	preferences *preferences.Store
+	catalog     *i18n.Catalog
+}
+
+// NewSender returns a sender that delivers nothing when keys is nil, the
+// VAPID key pair not being configured.
+func NewSender(store *Store, keys *Keys, producer *producer.Producer, prefs *preferences.Store, catalog *i18n.Catalog, cfg *configs.PushConfig) *Sender {
+	s := &Sender{store: store, keys: keys, producer: producer, preferences: prefs, catalog: catalog}
+	if keys != nil {
+		s.client = &client{
+			http: &http.Client{
+				Timeout: cfg.Timeout,
+				// Push services answer directly; following a redirect
+				// would send the message to a host that was not checked.
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			},
+			keys: keys,
+		}
+	}
+	return s
+}
+
+// Enabled reports whether the VAPID key pair is configured.
+func (s *Sender) Enabled() bool {
+	return s.keys != nil
+}
+
+// PublicKey is the VAPID public key browsers subscribe with, "" when push is
+// disabled.
+func (s *Sender) PublicKey() string {
+	if s.keys == nil {
+		return ""
+	}
+	return s.keys.PublicKey()
+}
+
+// Has reports whether template has texts.
+func (s *Sender) Has(template string) bool {
+	return s.catalog.Has("inbox."+template+".title") && s.catalog.Has("inbox."+template+".body")
+}
+
+// Send delivers req to every browser of the user, unless the user turned its
+// category off for push. During the user's quiet hours it returns a
+// *preferences.QuietHoursError instead. Subscriptions the push service
+// reports gone are deleted.
+//
+// Once a browser has the message the send is reported on
+// notification.push.sent and not retried, even if others failed. Otherwise a
+// failure to reach any push service is returned for the consumer to retry,
+// and messages every push service refused are reported on
+// notification.push.failed and returned as permanent.
+func (s *Sender) Send(ctx context.Context, req Request) error {
+	start := time.Now()
+	label := req.Template
+	if !s.Has(label) {
+		label = "unknown"
+	}
+	if s.client == nil {
+		metrics.ObservePush(label, "disabled", time.Since(start))
+		lg.DebugContext(ctx, "Push notification dropped: push is disabled", "template", req.Template, "user_id", req.UserID)
+		return nil
+	}
+	if !s.Has(req.Template) {
+		metrics.ObservePush(label, "failed", time.Since(start))
+		return permanent(fmt.Errorf("push: no texts for template %q", req.Template))
+	}
+	if _, ok := preferences.ParseCategory(string(req.Category)); !ok {
+		metrics.ObservePush(label, "failed", time.Since(start))
+		return permanent(fmt.Errorf("push: template %q needs a known category, got %q", req.Template, req.Category))
+	}
+
+	decision, until, err := s.preferences.Check(ctx, req.UserID, preferences.ChannelPush, req.Category)
+	if err != nil {
+		metrics.ObservePush(label, "error", time.Since(start))
+		return err
+	}
+	switch decision {
+	case preferences.OptedOut:
+		metrics.ObservePush(label, "suppressed", time.Since(start))
+		lg.InfoContext(ctx, "Push notification suppressed by preferences", "template", req.Template, "category", req.Category, "user_id", req.UserID)
+		return nil
+	case preferences.Quiet:
+		metrics.ObservePush(label, "deferred", time.Since(start))
+		lg.InfoContext(ctx, "Push notification held back by quiet hours", "template", req.Template, "category", req.Category, "user_id", req.UserID, "until", until)
+		return &preferences.QuietHoursError{Until: until}
+	}
+
+	subs, err := s.store.List(ctx, req.UserID)
+	if err != nil {
+		metrics.ObservePush(label, "error", time.Since(start))
+		return err
+	}
+	if len(subs) == 0 {
+		metrics.ObservePush(label, "no_subscriptions", time.Since(start))
+		lg.DebugContext(ctx, "Push notification skipped: no subscriptions", "template", req.Template, "user_id", req.UserID)
+		return nil
+	}
+
+	payload, locale, err := s.render(req)
+	if err != nil {
+		metrics.ObservePush(label, "failed", time.Since(start))
+		return permanent(err)
+	}
+	opts := OptionsFor(req.Priority)
+	switch {
+	case topicPattern.MatchString(req.Tag):
+		opts.Topic = req.Tag
+	case req.CausationID != "":
+		// A copy sent again after a redelivery replaces the first one if
+		// the browser has not fetched it yet.
+		opts.Topic = causationTopic(req.CausationID)
+	}
+
+	var out outcome
+	for i := range subs {
+		out.add(s.deliver(ctx, &subs[i], payload, opts))
+	}
+
+	switch {
+	case out.delivered > 0:
+		metrics.ObservePush(label, "sent", time.Since(start))
+		lg.InfoContext(ctx, "Push notification sent", "template", req.Template, "locale", locale, "user_id", req.UserID, "delivered", out.delivered, "pruned", out.pruned, "failed", out.failed)
+		// The message is out: failing to report it must not get it sent
+		// again.
+		s.publish(ctx, envelope.TopicPushSent, req.UserID, Sent{
+			CausationID: req.CausationID,
+			UserID:      req.UserID,
+			Template:    req.Template,
+			Locale:      locale,
+			Delivered:   out.delivered,
+			Pruned:      out.pruned,
+			Failed:      out.failed,
+			SentAt:      time.Now().UTC(),
+		})
+		return nil
+	case out.retry != nil:
+		metrics.ObservePush(label, "error", time.Since(start))
+		return out.retry
+	}
+
+	metrics.ObservePush(label, "failed", time.Since(start))
+	err = out.rejected
+	if err == nil {
+		err = errors.New("push: every subscription is gone")
+	}
+	lg.WarnContext(ctx, "Push notification failed", "template", req.Template, "user_id", req.UserID, "pruned", out.pruned, "error", err)
+	s.publish(ctx, envelope.TopicPushFailed, req.UserID, Failed{
+		CausationID: req.CausationID,
+		UserID:      req.UserID,
+		Template:    req.Template,
+		Pruned:      out.pruned,
+		Error:       err.Error(),
+		FailedAt:    time.Now().UTC(),
+	})
+	if out.rejected == nil {
+		// Nothing is wrong with the message: the user has no browsers
+		// left.
+		return nil
+	}
+	return permanent(err)
+}
+
+func (s *Sender) render(req Request) (payload []byte, locale string, err error) {
+	l := s.catalog.Localizer(req.Locale)
+	args := make([]any, 0, 2*len(req.Args))
+	for k, v := range req.Args {
+		args = append(args, k, v)
+	}
+	title, err := l.T("inbox."+req.Template+".title", args...)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := l.T("inbox."+req.Template+".body", args...)
+	if err != nil {
+		return nil, "", err
+	}
+	payload, err = json.Marshal(Payload{
+		Title:    title,
+		Body:     body,
+		Template: req.Template,
+		Category: string(req.Category),
+		Locale:   l.Locale(),
+		URL:      req.URL,
+		Tag:      req.Tag,
+		SentAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(payload) > MaxPayload {
+		return nil, "", errPayloadTooLarge
+	}
+	return payload, l.Locale(), nil
+}
+
+// deliver sends payload to one browser and deletes its subscription if it
+// is gone.
+func (s *Sender) deliver(ctx context.Context, sub *Subscription, payload []byte, opts Options) error {
+	err := errGone
+	if sub.ExpiresAt == nil || time.Now().Before(*sub.ExpiresAt) {
+		err = s.client.deliver(ctx, sub, payload, opts)
+	}
+	var status *statusError
+	switch {
+	case err == nil:
+		metrics.ObservePushDelivery("delivered")
+	case errors.Is(err, errGone):
+		metrics.ObservePushDelivery("pruned")
+		lg.InfoContext(ctx, "Removing expired push subscription", "subscription_id", sub.ID, "user_id", sub.UserID)
+		if rmErr := s.store.remove(ctx, sub.ID); rmErr != nil {
+			lg.WarnContext(ctx, "Failed to remove push subscription", "subscription_id", sub.ID, "error", rmErr)
+		}
+	case errors.As(err, &status) && status.permanent():
+		metrics.ObservePushDelivery("rejected")
+		lg.WarnContext(ctx, "Push service refused the message", "subscription_id", sub.ID, "status", status.status, "error", err)
+		err = permanent(err)
+	default:
+		metrics.ObservePushDelivery("error")
+		lg.WarnContext(ctx, "Push delivery failed", "subscription_id", sub.ID, "error", err)
+	}
+	return err
+}
+
+// outcome tallies the deliveries of one message. rejected is the last
+// refusal and retry the last error worth retrying.
+type outcome struct {
+	delivered int
+	pruned    int
+	failed    int
+	rejected  error
+	retry     error
+}
+
+func (o *outcome) add(err error) {
+	switch {
+	case err == nil:
+		o.delivered++
+	case errors.Is(err, errGone):
+		o.pruned++
+	case IsPermanent(err):
+		o.failed++
+		o.rejected = err
+	default:
+		o.failed++
+		o.retry = err
+	}
+}
+
+func (s *Sender) publish(ctx context.Context, topic envelope.Topic, key string, data any) {
+	env, err := envelope.NewEnvelope(source, envelope.PriorityNormal, data)
+	if err == nil {
+		if key != "" {
+			env.Metadata = &envelope.Metadata{UserID: key}
+		}
+		tracing.StampEnvelope(ctx, env)
+		var b []byte
+		if b, err = env.Marshal(); err == nil {
+			err = s.producer.Publish(ctx, topic.String(), key, b)
+		}
+	}
+	if err != nil {
+		lg.ErrorContext(ctx, "Failed to publish push outcome", "topic", topic, "error", err)
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether sending failed in a way a retry cannot fix: an
+// unknown template or category, or a message every push service refused.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
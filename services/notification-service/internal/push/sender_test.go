@@ -0,0 +1,16 @@
+package push
+
+import "testing"
+
+func TestCausationTopic(t *testing.T) {
+	topic := causationTopic("1234567890")
+	if !topicPattern.MatchString(topic) {
+		t.Errorf("causationTopic = %q; not a valid Topic header", topic)
+	}
+	if causationTopic("1234567890") != topic {
+		t.Error("causationTopic is not stable")
+	}
+	if causationTopic("1234567891") == topic {
+		t.Error("different events share a topic")
+	}
+}
@@ -0,0 +1,202 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"notification/configs"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Subscription is a browser the user allowed to receive push messages.
+type Subscription struct {
+	ID        int64      `json:"id" gorm:"primaryKey"`
+	UserID    string     `json:"-"`
+	Endpoint  string     `json:"endpoint"`
+	P256dh    string     `json:"-"`
+	Auth      string     `json:"-"`
+	UserAgent string     `json:"userAgent"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+func (Subscription) TableName() string { return "push_subscriptions" }
+
+// SubscriptionKeys are the keys of PushSubscription.toJSON().
+type SubscriptionKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// Subscribe is the body browsers send: PushSubscription.toJSON(), where
+// expirationTime is in milliseconds since the epoch.
+type Subscribe struct {
+	Endpoint       string           `json:"endpoint"`
+	ExpirationTime *int64           `json:"expirationTime"`
+	Keys           SubscriptionKeys `json:"keys"`
+	UserAgent      string           `json:"-"`
+}
+
+var (
+	// ErrInvalid is wrapped by the errors of subscriptions that cannot be
+	// stored.
+	ErrInvalid  = errors.New("invalid push subscription")
+	ErrNotFound = errors.New("push subscription not found")
+)
+
+const maxEndpointLength = 1024
+
+// Store keeps push subscriptions in Postgres. An endpoint belongs to one
+// user: a browser subscribed again after another user signs in moves to
+// them.
+type Store struct {
+	db           *gorm.DB
+	allowedHosts []string
+	maxPerUser   int
+}
+
+// NewStore refuses an empty PUSH_ALLOWED_HOSTS outside development, where
+// any HTTPS endpoint, internal hosts included, would be admitted.
+func NewStore(db *gorm.DB, cfg *configs.PushConfig) (*Store, error) {
+	if len(cfg.AllowedHosts) == 0 && !cfg.Development {
+		return nil, errors.New("push: PUSH_ALLOWED_HOSTS must list the push services outside development")
+	}
+	return &Store{db: db, allowedHosts: cfg.AllowedHosts, maxPerUser: cfg.MaxSubscriptionsPerUser}, nil
+}
+
+// Save stores the subscription of userID and returns it. Beyond the limit
+// per user, the least recently saved subscriptions are dropped.
+func (s *Store) Save(ctx context.Context, userID string, req *Subscribe) (*Subscription, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sub := &Subscription{
+		UserID:    userID,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: truncate(req.UserAgent, 255),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.ExpirationTime != nil {
+		t := time.UnixMilli(*req.ExpirationTime)
+		sub.ExpiresAt = &t
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "endpoint"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "p256dh", "auth", "user_agent", "expires_at", "updated_at"}),
+		}, clause.Returning{}).Create(sub).Error
+		if err != nil || s.maxPerUser <= 0 {
+			return err
+		}
+		return tx.Where(`user_id = ? AND id NOT IN (
+			SELECT id FROM push_subscriptions WHERE user_id = ? ORDER BY updated_at DESC, id DESC LIMIT ?)`,
+			userID, userID, s.maxPerUser).Delete(&Subscription{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// List returns the subscriptions of userID, most recent first.
+func (s *Store) List(ctx context.Context, userID string) ([]Subscription, error) {
+	subs := []Subscription{}
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("updated_at DESC, id DESC").Find(&subs).Error
+	return subs, err
+}
+
+// Delete removes the subscription of userID with endpoint, when the browser
+// unsubscribes.
+func (s *Store) Delete(ctx context.Context, userID, endpoint string) error {
+	res := s.db.WithContext(ctx).Where("user_id = ? AND endpoint = ?", userID, endpoint).Delete(&Subscription{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// remove drops a subscription the push service reported gone.
+func (s *Store) remove(ctx context.Context, id int64) error {
+	return s.db.WithContext(ctx).Delete(&Subscription{}, id).Error
+}
+
+func (s *Store) validate(req *Subscribe) error {
+	if err := s.checkEndpoint(req.Endpoint); err != nil {
+		return err
+	}
+	p256dh, err := decodeBase64(req.Keys.P256dh)
+	if err != nil || len(p256dh) != 65 {
+		return fmt.Errorf("%w: keys.p256dh must be an uncompressed P-256 public key", ErrInvalid)
+	}
+	auth, err := decodeBase64(req.Keys.Auth)
+	if err != nil || len(auth) != 16 {
+		return fmt.Errorf("%w: keys.auth must be 16 bytes", ErrInvalid)
+	}
+	if req.ExpirationTime != nil && time.UnixMilli(*req.ExpirationTime).Before(time.Now()) {
+		return fmt.Errorf("%w: subscription has expired", ErrInvalid)
+	}
+	return nil
+}
+
+// checkEndpoint only admits push services: the service POSTs to endpoints,
+// which must not be a way to reach internal hosts. Plain HTTP is allowed
+// for loopback hosts, which local push service stubs run on.
+func (s *Store) checkEndpoint(endpoint string) error {
+	if endpoint == "" || len(endpoint) > maxEndpointLength {
+		return fmt.Errorf("%w: endpoint is required and at most %d characters", ErrInvalid, maxEndpointLength)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || u.User != nil {
+		return fmt.Errorf("%w: endpoint is not a URL", ErrInvalid)
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && isLoopback(host):
+	default:
+		return fmt.Errorf("%w: endpoint must use https", ErrInvalid)
+	}
+	if len(s.allowedHosts) == 0 {
+		return nil
+	}
+	for _, allowed := range s.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not an allowed push service", ErrInvalid, host)
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	// Do not cut a UTF-8 sequence in half.
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,153 @@
+package push
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// vapidTTL is the lifetime of the signed tokens; push services reject
+	// tokens valid for more than 24 hours.
+	vapidTTL = 12 * time.Hour
+	// vapidRenew is how long before expiry a cached token is replaced.
+	vapidRenew = time.Hour
+)
+
+// Keys is the VAPID (RFC 8292) key pair that identifies the service to push
+// services.
+type Keys struct {
+	private *ecdsa.PrivateKey
+	public  []byte
+	subject string
+
+	mu     sync.Mutex
+	tokens map[string]vapidToken
+}
+
+type vapidToken struct {
+	header  string
+	expires time.Time
+}
+
+// GenerateKeys returns a new key pair, base64url encoded as VAPID_PUBLIC_KEY
+// and VAPID_PRIVATE_KEY expect.
+func GenerateKeys() (public, private string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(key.PublicKey().Bytes()), enc.EncodeToString(key.Bytes()), nil
+}
+
+// ParseKeys loads the key pair. public may be empty, in which case it is
+// derived from private; otherwise it must match.
+func ParseKeys(public, private, subject string) (*Keys, error) {
+	raw, err := decodeBase64(private)
+	if err != nil {
+		return nil, fmt.Errorf("push: VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("push: VAPID private key: %w", err)
+	}
+	pub := key.PublicKey().Bytes()
+	if public != "" {
+		given, err := decodeBase64(public)
+		if err != nil || string(given) != string(pub) {
+			return nil, errors.New("push: VAPID public key does not match the private key")
+		}
+	}
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, fmt.Errorf("push: VAPID subject must be a mailto: or https: URL, got %q", subject)
+	}
+
+	return &Keys{
+		private: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(pub[1:33]),
+				Y:     new(big.Int).SetBytes(pub[33:]),
+			},
+			D: new(big.Int).SetBytes(raw),
+		},
+		public:  pub,
+		subject: subject,
+		tokens:  make(map[string]vapidToken),
+	}, nil
+}
+
+// PublicKey is the applicationServerKey browsers subscribe with.
+func (k *Keys) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(k.public)
+}
+
+// authorization returns the Authorization header for a request to endpoint.
+// Tokens are per push service origin and reused until close to expiry.
+func (k *Keys) authorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	audience := u.Scheme + "://" + u.Host
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if t, ok := k.tokens[audience]; ok && now.Before(t.expires.Add(-vapidRenew)) {
+		return t.header, nil
+	}
+	expires := now.Add(vapidTTL)
+	jwt, err := k.sign(map[string]any{
+		"aud": audience,
+		"exp": expires.Unix(),
+		"sub": k.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	header := "vapid t=" + jwt + ", k=" + k.PublicKey()
+	k.tokens[audience] = vapidToken{header: header, expires: expires}
+	return header, nil
+}
+
+// sign returns an ES256 JWT of claims.
+func (k *Keys) sign(claims map[string]any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	input := enc.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`)) + "." + enc.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, k.private, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS wants r and s as fixed-size big-endian integers, not DER.
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return input + "." + enc.EncodeToString(sig), nil
+}
+
+// decodeBase64 accepts base64url with or without padding, which is how
+// browsers and key tools variously encode keys.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -0,0 +1,141 @@
+package push
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKeys(t *testing.T) *Keys {
+	t.Helper()
+	public, private, err := GenerateKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseKeys(public, private, "mailto:ops@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// parseVAPID checks the signature of an Authorization header and returns
+// its claims.
+func parseVAPID(t *testing.T, keys *Keys, header string) map[string]any {
+	t.Helper()
+	rest, ok := strings.CutPrefix(header, "vapid t=")
+	if !ok {
+		t.Fatalf("header %q is not a vapid one", header)
+	}
+	token, k, ok := strings.Cut(rest, ", k=")
+	if !ok || k != keys.PublicKey() {
+		t.Fatalf("header %q does not carry the public key", header)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %q is not a JWS", token)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		t.Fatalf("signature %q is not 64 bytes", parts[2])
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(&keys.private.PublicKey, digest[:], r, s) {
+		t.Fatal("signature does not verify with the public key")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestAuthorizationClaims(t *testing.T) {
+	keys := newTestKeys(t)
+	now := time.Unix(1_700_000_000, 0)
+
+	header, err := keys.authorization("https://fcm.googleapis.com/fcm/send/abc:def", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := parseVAPID(t, keys, header)
+	if claims["aud"] != "https://fcm.googleapis.com" {
+		t.Errorf("aud = %v; want the push service origin", claims["aud"])
+	}
+	if claims["sub"] != "mailto:ops@example.com" {
+		t.Errorf("sub = %v; want the VAPID subject", claims["sub"])
+	}
+	if exp, _ := claims["exp"].(float64); int64(exp) != now.Add(vapidTTL).Unix() {
+		t.Errorf("exp = %v; want %d", claims["exp"], now.Add(vapidTTL).Unix())
+	}
+}
+
+func TestAuthorizationCache(t *testing.T) {
+	keys := newTestKeys(t)
+	now := time.Unix(1_700_000_000, 0)
+
+	first, err := keys.authorization("https://fcm.googleapis.com/fcm/send/a", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ECDSA signatures are randomized, so an equal header was reused.
+	again, _ := keys.authorization("https://fcm.googleapis.com/fcm/send/b", now.Add(time.Hour))
+	if again != first {
+		t.Error("a token for the same push service was not reused")
+	}
+
+	other, _ := keys.authorization("https://updates.push.services.mozilla.com/wpush/v2/x", now)
+	if other == first {
+		t.Error("two push services share a token")
+	}
+	if aud := parseVAPID(t, keys, other)["aud"]; aud != "https://updates.push.services.mozilla.com" {
+		t.Errorf("aud = %v; want the second origin", aud)
+	}
+
+	// Close to expiry the token is replaced.
+	renewed, _ := keys.authorization("https://fcm.googleapis.com/fcm/send/a", now.Add(vapidTTL-vapidRenew))
+	if renewed == first {
+		t.Error("a token close to expiry was reused")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	public, private, err := GenerateKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublic, _, _ := GenerateKeys()
+
+	tests := []struct {
+		name                     string
+		public, private, subject string
+		wantErr                  bool
+	}{
+		{"pair", public, private, "mailto:ops@example.com", false},
+		{"public derived", "", private, "https://example.com", false},
+		{"padded std base64", "", strings.NewReplacer("-", "+", "_", "/").Replace(private) + "=", "mailto:ops@example.com", false},
+		{"mismatched public key", otherPublic, private, "mailto:ops@example.com", true},
+		{"bad private key", "", "not-a-key", "mailto:ops@example.com", true},
+		{"bad subject", public, private, "ops@example.com", true},
+	}
+	for _, tt := range tests {
+		keys, err := ParseKeys(tt.public, tt.private, tt.subject)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseKeys error = %v; want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && keys.PublicKey() != public {
+			t.Errorf("%s: PublicKey = %s; want %s", tt.name, keys.PublicKey(), public)
+		}
+	}
+}
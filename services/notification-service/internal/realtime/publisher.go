@@ -0,0 +1,74 @@
+// Package realtime publishes user events for the gateway to push to the
+// users' open connections; see music-player/api/realtime for the contract.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"music-player/api/logger"
+	"notification/configs"
+	"notification/internal/metrics"
+	"time"
+
+	"music-player/api/realtime"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var lg = logger.For("realtime")
+
+// Publisher appends events to the users' Redis streams and announces them
+// to the gateway replicas.
+type Publisher struct {
+	client *redis.Client
+	maxLen int64
+	ttl    time.Duration
+}
+
+func NewPublisher(client *redis.Client, cfg *configs.RealtimeConfig) *Publisher {
+	return &Publisher{client: client, maxLen: cfg.StreamMaxLen, ttl: cfg.StreamTTL}
+}
+
+// Publish sends an event of type eventType with data to userID's
+// connections. Delivery is best effort: failures are logged, not returned,
+// because the state the event describes is already stored and clients
+// reload it when they reconnect.
+func (p *Publisher) Publish(ctx context.Context, userID, eventType string, data any) {
+	if err := p.publish(ctx, userID, eventType, data); err != nil {
+		metrics.ObserveRealtimeEvent(eventType, "error")
+		lg.WarnContext(ctx, "Failed to publish realtime event", "type", eventType, "user_id", userID, "error", err)
+		return
+	}
+	metrics.ObserveRealtimeEvent(eventType, "published")
+}
+
+func (p *Publisher) publish(ctx context.Context, userID, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	key := realtime.StreamKey(userID)
+
+	// The entry ID is the event ID, so the stream is written first and the
+	// announcement carries the ID it got.
+	id, err := p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]any{realtime.FieldType: eventType, realtime.FieldData: string(payload)},
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	event, err := json.Marshal(realtime.Event{ID: id, UserID: userID, Type: eventType, Data: payload})
+	if err != nil {
+		return err
+	}
+	_, err = p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Expire(ctx, key, p.ttl)
+		pipe.Publish(ctx, realtime.EventsChannel, event)
+		return nil
+	})
+	return err
+}
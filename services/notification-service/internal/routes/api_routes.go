@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"notification/internal/handlers"
+	"notification/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAPIRoutes registers the /api/v1 routes the gateway forwards to.
+// They are not registered without the internal token shared with the
+// gateway.
+func RegisterAPIRoutes(r *gin.Engine, internalToken string, preferencesHandler *handlers.PreferencesHandler, unsubscribeHandler *handlers.UnsubscribeHandler, inboxHandler *handlers.InboxHandler, pushHandler *handlers.PushHandler) {
+	if internalToken == "" {
+		return
+	}
+	api := r.Group("/api/v1", middleware.RequireGateway(internalToken))
+
+	// Public: the signed token in the link identifies the user.
+	api.GET("/unsubscribe", unsubscribeHandler.Confirm)
+	api.POST("/unsubscribe", unsubscribeHandler.Unsubscribe)
+
+	preferences := api.Group("/preferences", middleware.RequireUser())
+	preferences.GET("", preferencesHandler.Get)
+	preferences.PATCH("", preferencesHandler.Update)
+
+	notifications := api.Group("/notifications", middleware.RequireUser())
+	notifications.GET("", inboxHandler.List)
+	notifications.GET("/unread-count", inboxHandler.UnreadCount)
+	notifications.POST("/read-all", inboxHandler.MarkAllRead)
+	notifications.POST("/:id/read", inboxHandler.MarkRead)
+	notifications.POST("/:id/archive", inboxHandler.Archive)
+
+	push := api.Group("/push", middleware.RequireUser())
+	push.GET("/vapid-public-key", pushHandler.PublicKey)
+	push.GET("/subscriptions", pushHandler.List)
+	push.POST("/subscriptions", pushHandler.Subscribe)
+	push.DELETE("/subscriptions", pushHandler.Unsubscribe)
+}
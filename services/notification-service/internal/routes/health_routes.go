@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"music-player/api/logger"
+	"music-player/api/metrics"
+	"notification/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterHealthRoutes registers liveness and readiness probes at the root of the router.
+func RegisterHealthRoutes(r *gin.Engine, healthHandler *handlers.HealthHandler) {
+	r.GET("/livez", healthHandler.Livez)
+	r.GET("/readyz", healthHandler.Readyz)
+}
+
+// RegisterMetricsRoutes exposes Prometheus metrics at /metrics.
+func RegisterMetricsRoutes(r *gin.Engine) {
+	r.GET("/metrics", metrics.Handler())
+}
+
+// RegisterLogLevelRoutes exposes runtime log level control at /admin/log-levels
+// when an admin token is configured.
+func RegisterLogLevelRoutes(r *gin.Engine, logLevelHandler *logger.LevelHandler) {
+	if !logLevelHandler.Enabled() {
+		return
+	}
+	admin := r.Group("/admin", logLevelHandler.Authorize)
+	admin.GET("/log-levels", logLevelHandler.List)
+	admin.PUT("/log-levels", logLevelHandler.Set)
+}
+
+// RegisterDLQRoutes exposes the dead-letter topic at /admin/dlq when a DLQ
+// admin token is configured.
+func RegisterDLQRoutes(r *gin.Engine, dlqHandler *handlers.DLQHandler) {
+	if !dlqHandler.Enabled() {
+		return
+	}
+	admin := r.Group("/admin/dlq", dlqHandler.Authorize)
+	admin.GET("", dlqHandler.List)
+	admin.POST("/replay", dlqHandler.Replay)
+	admin.DELETE("", dlqHandler.Purge)
+}
+
+// RegisterTemplateRoutes exposes the email template preview at
+// /admin/templates when a preview token is configured.
+func RegisterTemplateRoutes(r *gin.Engine, templateHandler *handlers.TemplateHandler) {
+	if !templateHandler.Enabled() {
+		return
+	}
+	admin := r.Group("/admin/templates", templateHandler.Authorize)
+	admin.GET("", templateHandler.List)
+	admin.GET("/:name/preview", templateHandler.Preview)
+}
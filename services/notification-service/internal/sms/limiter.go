@@ -0,0 +1,46 @@
+package sms
+
+import (
+	"context"
+	"notification/configs"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// limitScript counts a message in the number's window, started by its first
+// message, and returns the count.
+var limitScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// Limiter caps the messages each number receives in a fixed window. It is
+// shared by every replica through Redis.
+type Limiter struct {
+	rdb    *redis.Client
+	limit  int
+	window time.Duration
+}
+
+// NewLimiter returns a limiter that allows everything when SMS_RATE_LIMIT
+// is 0.
+func NewLimiter(rdb *redis.Client, cfg *configs.SMSConfig) *Limiter {
+	return &Limiter{rdb: rdb, limit: cfg.RateLimit, window: cfg.RateWindow}
+}
+
+// Allow counts a message to number and reports whether it is within the
+// limit.
+func (l *Limiter) Allow(ctx context.Context, number string) (bool, error) {
+	if l.limit <= 0 || l.window <= 0 {
+		return true, nil
+	}
+	n, err := limitScript.Run(ctx, l.rdb, []string{"notification:sms:rate:" + number}, l.window.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n <= l.limit, nil
+}
@@ -0,0 +1,158 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"notification/configs"
+	"strconv"
+	"sync/atomic"
+
+	"music-player/api/phone"
+)
+
+// Message is a text message to one number.
+type Message struct {
+	// To is an E.164 number.
+	To   string
+	Body string
+	// IdempotencyKey is the same for every attempt at one message, so a
+	// provider that supports it sends the message once.
+	IdempotencyKey string
+}
+
+// Provider hands messages to an SMS gateway. Send returns the provider's ID
+// of the message; errors for which StatusError.Permanent is true are not
+// worth retrying.
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, msg Message) (id string, err error)
+}
+
+// NewProvider returns the provider SMS_PROVIDER names. It must be named
+// outside development, so that a missing setting does not silently drop
+// every code in production.
+func NewProvider(cfg *configs.SMSConfig) (Provider, error) {
+	switch cfg.Provider {
+	case "":
+		if !cfg.Development {
+			return nil, fmt.Errorf("sms: SMS_PROVIDER is required outside development")
+		}
+		return &FakeProvider{}, nil
+	case "fake":
+		return &FakeProvider{}, nil
+	case "http":
+		if cfg.HTTPURL == "" {
+			return nil, fmt.Errorf("sms: SMS_PROVIDER=http needs SMS_HTTP_URL")
+		}
+		return NewHTTPProvider(cfg), nil
+	}
+	return nil, fmt.Errorf("sms: unknown SMS_PROVIDER %q", cfg.Provider)
+}
+
+// StatusError is a provider reply other than 2xx.
+type StatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("sms: provider replied %d", e.Status)
+	}
+	return fmt.Sprintf("sms: provider replied %d: %s", e.Status, e.Body)
+}
+
+// Permanent reports whether the provider refused the message itself, such
+// as an unreachable number. 408, 429 and 5xx replies are worth retrying.
+func (e *StatusError) Permanent() bool {
+	return e.Status < 500 && e.Status != http.StatusTooManyRequests && e.Status != http.StatusRequestTimeout
+}
+
+// HTTPProvider posts messages as JSON to a configured URL, the shape most
+// SMS gateways accept or a small adapter in front of one can translate.
+type HTTPProvider struct {
+	http  *http.Client
+	url   string
+	token string
+	from  string
+}
+
+func NewHTTPProvider(cfg *configs.SMSConfig) *HTTPProvider {
+	return &HTTPProvider{
+		http:  &http.Client{Timeout: cfg.Timeout},
+		url:   cfg.HTTPURL,
+		token: cfg.HTTPToken,
+		from:  cfg.From,
+	}
+}
+
+func (p *HTTPProvider) Name() string { return "http" }
+
+// Send posts {"from", "to", "body"} and reads the message ID from the "id"
+// or "message_id" field of the reply, if any.
+func (p *HTTPProvider) Send(ctx context.Context, msg Message) (string, error) {
+	body, err := json.Marshal(map[string]string{"from": p.from, "to": msg.To, "body": msg.Body})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	if msg.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", msg.IdempotencyKey)
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(reply) > 512 {
+			reply = reply[:512]
+		}
+		return "", &StatusError{Status: resp.StatusCode, Body: string(bytes.TrimSpace(reply))}
+	}
+
+	var out struct {
+		ID        any `json:"id"`
+		MessageID any `json:"message_id"`
+	}
+	if json.Unmarshal(reply, &out) != nil {
+		return "", nil
+	}
+	for _, id := range []any{out.ID, out.MessageID} {
+		switch v := id.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	}
+	return "", nil
+}
+
+// FakeProvider drops messages, for development. It logs the masked number
+// and not the text, which carries one-time codes.
+type FakeProvider struct {
+	n atomic.Int64
+}
+
+func (p *FakeProvider) Name() string { return "fake" }
+
+func (p *FakeProvider) Send(ctx context.Context, msg Message) (string, error) {
+	id := "fake-" + strconv.FormatInt(p.n.Add(1), 10)
+	lg.InfoContext(ctx, "Fake SMS", "id", id, "to", phone.Mask(msg.To), "length", len(msg.Body))
+	return id, nil
+}
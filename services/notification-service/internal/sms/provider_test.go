@@ -0,0 +1,162 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"notification/configs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      configs.SMSConfig
+		wantName string
+	}{
+		{"unset outside development", configs.SMSConfig{}, ""},
+		{"unset in development", configs.SMSConfig{Development: true}, "fake"},
+		{"fake", configs.SMSConfig{Provider: "fake"}, "fake"},
+		{"http", configs.SMSConfig{Provider: "http", HTTPURL: "https://sms.example.com/send"}, "http"},
+		{"http without a URL", configs.SMSConfig{Provider: "http"}, ""},
+		{"unknown", configs.SMSConfig{Provider: "carrier-pigeon", Development: true}, ""},
+	}
+	for _, tt := range tests {
+		p, err := NewProvider(&tt.cfg)
+		switch {
+		case tt.wantName == "" && err == nil:
+			t.Errorf("%s: NewProvider = %s; want an error", tt.name, p.Name())
+		case tt.wantName != "" && err != nil:
+			t.Errorf("%s: NewProvider = %v; want %s", tt.name, err, tt.wantName)
+		case tt.wantName != "" && p.Name() != tt.wantName:
+			t.Errorf("%s: NewProvider = %s; want %s", tt.name, p.Name(), tt.wantName)
+		}
+	}
+}
+
+// gateway is a stand-in for an SMS gateway that answers every request with
+// status and reply, and keeps the last request.
+type gateway struct {
+	*httptest.Server
+	status int
+	reply  string
+
+	header http.Header
+	body   map[string]string
+}
+
+func newGateway(t *testing.T, status int, reply string) *gateway {
+	t.Helper()
+	g := &gateway{status: status, reply: reply}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&g.body)
+		w.WriteHeader(g.status)
+		w.Write([]byte(g.reply))
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func newTestHTTPProvider(url string) *HTTPProvider {
+	return NewHTTPProvider(&configs.SMSConfig{HTTPURL: url, HTTPToken: "secret", From: "MusicPlayer", Timeout: 5 * time.Second})
+}
+
+func TestHTTPProviderSend(t *testing.T) {
+	g := newGateway(t, http.StatusAccepted, `{"id":"SM123"}`)
+	p := newTestHTTPProvider(g.URL)
+
+	id, err := p.Send(context.Background(), Message{To: "+84912345678", Body: "Your code is 123456", IdempotencyKey: "msg-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "SM123" {
+		t.Errorf("id = %q; want SM123", id)
+	}
+	for name, want := range map[string]string{
+		"Authorization":   "Bearer secret",
+		"Content-Type":    "application/json",
+		"Idempotency-Key": "msg-1",
+	} {
+		if got := g.header.Get(name); got != want {
+			t.Errorf("%s = %q; want %q", name, got, want)
+		}
+	}
+	want := map[string]string{"from": "MusicPlayer", "to": "+84912345678", "body": "Your code is 123456"}
+	for k, v := range want {
+		if g.body[k] != v {
+			t.Errorf("body %s = %q; want %q", k, g.body[k], v)
+		}
+	}
+
+	// Without a key, no header is sent.
+	if _, err := p.Send(context.Background(), Message{To: "+84912345678", Body: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.header["Idempotency-Key"]; ok {
+		t.Error("Idempotency-Key sent without a key")
+	}
+}
+
+func TestHTTPProviderMessageID(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  string
+	}{
+		{`{"id":"SM123"}`, "SM123"},
+		{`{"message_id":42}`, "42"},
+		{`{"id":null,"message_id":"m-1"}`, "m-1"},
+		{`{"status":"queued"}`, ""},
+		{`OK`, ""},
+		{``, ""},
+	}
+	for _, tt := range tests {
+		g := newGateway(t, http.StatusOK, tt.reply)
+		id, err := newTestHTTPProvider(g.URL).Send(context.Background(), Message{To: "+84912345678", Body: "x"})
+		if err != nil || id != tt.want {
+			t.Errorf("reply %s: Send = %q, %v; want %q", tt.reply, id, err, tt.want)
+		}
+	}
+}
+
+func TestHTTPProviderStatus(t *testing.T) {
+	tests := []struct {
+		status        int
+		wantPermanent bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusUnauthorized, true},
+		{http.StatusUnprocessableEntity, true},
+		{http.StatusRequestTimeout, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		g := newGateway(t, tt.status, strings.Repeat("x", 1000))
+		_, err := newTestHTTPProvider(g.URL).Send(context.Background(), Message{To: "+84912345678", Body: "x"})
+		var status *StatusError
+		if !errors.As(err, &status) {
+			t.Fatalf("%d: Send = %v; want a *StatusError", tt.status, err)
+		}
+		if status.Status != tt.status || status.Permanent() != tt.wantPermanent {
+			t.Errorf("%d: StatusError{%d}, permanent %v; want permanent %v", tt.status, status.Status, status.Permanent(), tt.wantPermanent)
+		}
+		if len(status.Body) > 512 {
+			t.Errorf("%d: error keeps %d bytes of the reply; want at most 512", tt.status, len(status.Body))
+		}
+	}
+
+	// Network errors are not status errors: the sender retries them.
+	g := newGateway(t, http.StatusOK, "")
+	g.Close()
+	_, err := newTestHTTPProvider(g.URL).Send(context.Background(), Message{To: "+84912345678", Body: "x"})
+	var status *StatusError
+	if err == nil || errors.As(err, &status) {
+		t.Errorf("Send to a closed server = %v; want a network error", err)
+	}
+}
@@ -0,0 +1,268 @@
+// Package sms is the SMS channel: it renders short texts from the i18n
+// catalog, holds back messages the recipient's preferences or number's rate
+// limit refuse, hands the rest to a Provider and reports the outcome on
+// notification.sms.sent and notification.sms.failed.
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"music-player/api/logger"
+	"music-player/api/tracing"
+	"notification/internal/i18n"
+	"notification/internal/kafka/envelope"
+	"notification/internal/kafka/producer"
+	"notification/internal/metrics"
+	"notification/internal/preferences"
+	"time"
+
+	"music-player/api/phone"
+)
+
+var lg = logger.For("sms")
+
+const source = "notification-service"
+
+// Template names. The text of each is the sms.<name> key of the catalog.
+const (
+	// TemplateLoginCode carries a one-time sign-in code: {code} and
+	// {minutes}, its lifetime.
+	TemplateLoginCode = "login_code"
+	// TemplatePhoneVerification carries the code confirming a new number,
+	// with the same arguments.
+	TemplatePhoneVerification = "phone_verification"
+)
+
+var templateCategories = map[string]preferences.Category{
+	TemplateLoginCode:         preferences.CategorySecurity,
+	TemplatePhoneVerification: preferences.CategorySecurity,
+}
+
+// TemplateCategory returns the preference category of template name, or ""
+// for unknown templates.
+func TemplateCategory(name string) preferences.Category {
+	return templateCategories[name]
+}
+
+// ResolveCategory returns the preference category of a request for
+// template. Like email.ResolveCategory, a requested category must be the
+// template's own: a code sent as "marketing" would be held back by quiet
+// hours or the user's opt-out.
+func ResolveCategory(template string, requested preferences.Category) (preferences.Category, error) {
+	category := TemplateCategory(template)
+	switch {
+	case category == "":
+		category = requested
+	case requested != "" && requested != category:
+		return "", permanent(fmt.Errorf("sms: template %q is in category %q, not %q", template, category, requested))
+	}
+	if _, ok := preferences.ParseCategory(string(category)); !ok {
+		return "", permanent(fmt.Errorf("sms: template %q needs a known category, got %q", template, category))
+	}
+	return category, nil
+}
+
+// ErrRateLimited means the number received its allowance of messages for
+// the current window.
+var ErrRateLimited = errors.New("sms: rate limit of the number exceeded")
+
+// Request asks for a text message to To.
+type Request struct {
+	// To is an E.164 number.
+	To string
+	// UserID, when set, is the recipient whose preferences apply.
+	UserID   string
+	Template string
+	Category preferences.Category
+	// Locale is the recipient's locale, e.g. "vi"; unsupported ones fall back
+	// to English.
+	Locale string
+	// Args fill the {name} placeholders of the text.
+	Args map[string]any
+	// CausationID is the message_id of the event that asked for the message.
+	CausationID string
+}
+
+// Sent is the data of a notification.sms.sent event. To is masked.
+type Sent struct {
+	CausationID string    `json:"causation_id,omitempty"`
+	UserID      string    `json:"user_id,omitempty"`
+	To          string    `json:"to"`
+	Template    string    `json:"template"`
+	Locale      string    `json:"locale"`
+	Provider    string    `json:"provider"`
+	ProviderID  string    `json:"provider_message_id,omitempty"`
+	SentAt      time.Time `json:"sent_at"`
+}
+
+// Failed is the data of a notification.sms.failed event. To is masked.
+type Failed struct {
+	CausationID string    `json:"causation_id,omitempty"`
+	UserID      string    `json:"user_id,omitempty"`
+	To          string    `json:"to"`
+	Template    string    `json:"template"`
+	Error       string    `json:"error"`
+	FailedAt    time.Time `json:"failed_at"`
+}
+
+type Sender struct {
+	provider    Provider
+	limiter     *Limiter
+	producer    *producer.Producer
+	preferences *preferences.Store
+	catalog     *i18n.Catalog
+}
+
+func NewSender(provider Provider, limiter *Limiter, producer *producer.Producer, prefs *preferences.Store, catalog *i18n.Catalog) *Sender {
+	return &Sender{provider: provider, limiter: limiter, producer: producer, preferences: prefs, catalog: catalog}
+}
+
+// Has reports whether template has a text.
+func (s *Sender) Has(template string) bool {
+	return s.catalog.Has("sms." + template)
+}
+
+// Send texts req.To, unless the user turned the category off for SMS or the
+// number reached its rate limit. During the user's quiet hours it returns a
+// *preferences.QuietHoursError instead.
+//
+// A provider failure worth retrying is returned for the consumer to retry;
+// messages that cannot be sent, because the number is invalid, rate limited
+// or refused by the provider, are reported on notification.sms.failed and
+// returned as permanent. A retry counts against the rate limit again.
+func (s *Sender) Send(ctx context.Context, req Request) error {
+	label := req.Template
+	if !s.Has(label) {
+		label = "unknown"
+		metrics.ObserveSMS(label, "failed")
+		return permanent(fmt.Errorf("sms: no text for template %q", req.Template))
+	}
+	if _, ok := preferences.ParseCategory(string(req.Category)); !ok {
+		metrics.ObserveSMS(label, "failed")
+		return permanent(fmt.Errorf("sms: template %q needs a known category, got %q", req.Template, req.Category))
+	}
+	if !phone.Valid(req.To) {
+		metrics.ObserveSMS(label, "failed")
+		return s.fail(ctx, req, phone.ErrInvalid)
+	}
+
+	if req.UserID != "" {
+		decision, until, err := s.preferences.Check(ctx, req.UserID, preferences.ChannelSMS, req.Category)
+		if err != nil {
+			metrics.ObserveSMS(label, "error")
+			return err
+		}
+		switch decision {
+		case preferences.OptedOut:
+			metrics.ObserveSMS(label, "suppressed")
+			lg.InfoContext(ctx, "SMS suppressed by preferences", "template", req.Template, "category", req.Category, "user_id", req.UserID)
+			return nil
+		case preferences.Quiet:
+			metrics.ObserveSMS(label, "deferred")
+			lg.InfoContext(ctx, "SMS held back by quiet hours", "template", req.Template, "category", req.Category, "user_id", req.UserID, "until", until)
+			return &preferences.QuietHoursError{Until: until}
+		}
+	}
+
+	l := s.catalog.Localizer(req.Locale)
+	args := make([]any, 0, 2*len(req.Args))
+	for k, v := range req.Args {
+		args = append(args, k, v)
+	}
+	body, err := l.T("sms."+req.Template, args...)
+	if err != nil {
+		metrics.ObserveSMS(label, "failed")
+		return permanent(err)
+	}
+
+	ok, err := s.limiter.Allow(ctx, req.To)
+	if err != nil {
+		metrics.ObserveSMS(label, "error")
+		return err
+	}
+	if !ok {
+		metrics.ObserveSMS(label, "rate_limited")
+		return s.fail(ctx, req, ErrRateLimited)
+	}
+
+	start := time.Now()
+	id, err := s.provider.Send(ctx, Message{To: req.To, Body: body, IdempotencyKey: req.CausationID})
+	metrics.ObserveSMSProvider(s.provider.Name(), time.Since(start))
+	var status *StatusError
+	switch {
+	case err == nil:
+	case errors.As(err, &status) && status.Permanent():
+		metrics.ObserveSMS(label, "failed")
+		return s.fail(ctx, req, err)
+	default:
+		metrics.ObserveSMS(label, "error")
+		lg.WarnContext(ctx, "SMS delivery failed", "template", req.Template, "to", phone.Mask(req.To), "error", err)
+		return err
+	}
+
+	metrics.ObserveSMS(label, "sent")
+	lg.InfoContext(ctx, "SMS sent", "template", req.Template, "locale", l.Locale(), "to", phone.Mask(req.To), "user_id", req.UserID, "provider", s.provider.Name())
+	// The message is out: failing to report it must not get it sent again.
+	s.publish(ctx, envelope.TopicSMSSent, req.UserID, Sent{
+		CausationID: req.CausationID,
+		UserID:      req.UserID,
+		To:          phone.Mask(req.To),
+		Template:    req.Template,
+		Locale:      l.Locale(),
+		Provider:    s.provider.Name(),
+		ProviderID:  id,
+		SentAt:      time.Now().UTC(),
+	})
+	return nil
+}
+
+// fail reports a message that cannot be sent and returns err as permanent.
+func (s *Sender) fail(ctx context.Context, req Request, err error) error {
+	lg.WarnContext(ctx, "SMS failed", "template", req.Template, "to", phone.Mask(req.To), "user_id", req.UserID, "error", err)
+	s.publish(ctx, envelope.TopicSMSFailed, req.UserID, Failed{
+		CausationID: req.CausationID,
+		UserID:      req.UserID,
+		To:          phone.Mask(req.To),
+		Template:    req.Template,
+		Error:       err.Error(),
+		FailedAt:    time.Now().UTC(),
+	})
+	return permanent(err)
+}
+
+func (s *Sender) publish(ctx context.Context, topic envelope.Topic, key string, data any) {
+	env, err := envelope.NewEnvelope(source, envelope.PriorityNormal, data)
+	if err == nil {
+		if key != "" {
+			env.Metadata = &envelope.Metadata{UserID: key}
+		}
+		tracing.StampEnvelope(ctx, env)
+		var b []byte
+		if b, err = env.Marshal(); err == nil {
+			err = s.producer.Publish(ctx, topic.String(), key, b)
+		}
+	}
+	if err != nil {
+		lg.ErrorContext(ctx, "Failed to publish SMS outcome", "topic", topic, "error", err)
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether sending failed in a way a retry cannot fix: an
+// unknown template or category, an invalid or rate limited number, or a
+// message the provider refused.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
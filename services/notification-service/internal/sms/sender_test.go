@@ -0,0 +1,32 @@
+package sms
+
+import (
+	"notification/internal/preferences"
+	"testing"
+)
+
+func TestResolveCategory(t *testing.T) {
+	tests := []struct {
+		template  string
+		requested preferences.Category
+		want      preferences.Category
+		wantErr   bool
+	}{
+		{TemplateLoginCode, "", preferences.CategorySecurity, false},
+		{TemplateLoginCode, preferences.CategorySecurity, preferences.CategorySecurity, false},
+		// A code sent as marketing would be held back by quiet hours.
+		{TemplateLoginCode, preferences.CategoryMarketing, "", true},
+		{"promo", preferences.CategoryMarketing, preferences.CategoryMarketing, false},
+		{"promo", "", "", true},
+		{"promo", "nope", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveCategory(tt.template, tt.requested)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ResolveCategory(%q, %q) = %q, %v; want %q, error %v", tt.template, tt.requested, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !IsPermanent(err) {
+			t.Errorf("ResolveCategory(%q, %q) error is not permanent", tt.template, tt.requested)
+		}
+	}
+}
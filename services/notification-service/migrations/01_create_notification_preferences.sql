@@ -0,0 +1,25 @@
+-- +goose Up
+-- Cài đặt thông báo của người dùng: múi giờ và khung giờ yên lặng
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id VARCHAR(26) PRIMARY KEY,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Ho_Chi_Minh',
+    quiet_hours_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    quiet_hours_start CHAR(5) NOT NULL DEFAULT '22:00',
+    quiet_hours_end CHAR(5) NOT NULL DEFAULT '07:00',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Ma trận kênh × loại thông báo; chỉ lưu các ô người dùng đã thay đổi
+CREATE TABLE IF NOT EXISTS notification_subscriptions (
+    user_id VARCHAR(26) NOT NULL,
+    channel VARCHAR(16) NOT NULL,
+    category VARCHAR(32) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, channel, category)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_subscriptions;
+DROP TABLE IF EXISTS notification_settings;
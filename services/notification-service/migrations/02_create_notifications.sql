@@ -0,0 +1,26 @@
+-- +goose Up
+-- Hộp thư thông báo trong ứng dụng
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(26) NOT NULL,
+    kind VARCHAR(64) NOT NULL,
+    category VARCHAR(32) NOT NULL,
+    locale VARCHAR(16) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    -- message_id của sự kiện tạo ra thông báo, để không tạo trùng khi xử lý lại
+    source_id VARCHAR(64) NOT NULL,
+    read_at TIMESTAMP,
+    archived_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, source_id)
+);
+
+-- Danh sách thông báo chưa lưu trữ, mới nhất trước
+CREATE INDEX IF NOT EXISTS idx_notifications_user_active ON notifications (user_id, id DESC) WHERE archived_at IS NULL;
+
+-- Đếm thông báo chưa đọc
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications (user_id) WHERE read_at IS NULL AND archived_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
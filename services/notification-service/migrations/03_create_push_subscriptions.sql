@@ -0,0 +1,20 @@
+-- +goose Up
+-- Đăng ký Web Push của trình duyệt; mỗi endpoint thuộc về một người dùng
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(26) NOT NULL,
+    endpoint VARCHAR(1024) NOT NULL UNIQUE,
+    -- Khóa công khai P-256 và auth secret của trình duyệt (base64url)
+    p256dh VARCHAR(128) NOT NULL,
+    auth VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Gửi đến mọi trình duyệt của một người dùng
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions (user_id, updated_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS push_subscriptions;